@@ -1,13 +1,38 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/notify"
+	"github.com/code-farms/go-backend/payments"
+	"github.com/code-farms/go-backend/services/cart"
+	"github.com/code-farms/go-backend/services/idempotency"
+	"github.com/code-farms/go-backend/services/inventory"
+	"github.com/code-farms/go-backend/services/invoice"
+	"github.com/code-farms/go-backend/services/jobs"
+	"github.com/code-farms/go-backend/services/notification"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/services/outbox"
+	"github.com/code-farms/go-backend/services/payment"
+	"github.com/code-farms/go-backend/services/pricing"
 	"github.com/code-farms/go-backend/services/product"
+	"github.com/code-farms/go-backend/services/promotion"
+	"github.com/code-farms/go-backend/services/returns"
+	"github.com/code-farms/go-backend/services/review"
+	"github.com/code-farms/go-backend/services/shipment"
+	"github.com/code-farms/go-backend/services/shipping"
+	"github.com/code-farms/go-backend/services/tax"
 	"github.com/code-farms/go-backend/services/user" // Import the user service package
+	"github.com/code-farms/go-backend/services/webhook"
+	"github.com/code-farms/go-backend/services/wishlist"
+	"github.com/code-farms/go-backend/storage"
+	"github.com/code-farms/go-backend/types"
 	"github.com/gorilla/mux"                         // Import Gorilla Mux for routing
 )
 
@@ -35,19 +60,189 @@ func (s *APIServer) Run() error {
     router := mux.NewRouter().StrictSlash(true)
     subRouter := router.PathPrefix("/api/v1/").Subrouter()
 
+    // Retried POST and PATCH requests carrying an Idempotency-Key get the original response.
+    // Anonymous callers are told apart by their cart, and only uploads may send big bodies
+    idempotencyStore := idempotency.NewStore(s.db)
+    subRouter.Use(idempotency.Middleware(idempotencyStore, idempotency.Config{
+        TTL:     time.Duration(configs.Envs.IdempotencyKeyTTLInSeconds) * time.Second,
+        Lease:   time.Duration(configs.Envs.IdempotencyLeaseInSeconds) * time.Second,
+        Cookies: []string{cart.CookieName},
+        RouteLimits: map[string]int64{
+            "/api/v1/admin/products/import":       configs.Envs.MaxImportSizeInBytes,
+            "/api/v1/products/{id:[0-9]+}/images": configs.Envs.MaxUploadSizeInBytes,
+        },
+    }))
+
     userStore := user.NewStore(s.db)
-    userHandler := user.NewHandler(userStore)
+
+    // Side effects of committed changes, like emails to customers, are published from the
+    // outbox by the relay started below
+    bus := outbox.NewBus()
+
+    // Slow or failing work, like sending emails, runs as retried background jobs
+    jobStore := jobs.NewStore(s.db)
+    worker := jobs.NewWorker(jobStore, int(configs.Envs.JobWorkers))
+    jobHandler := jobs.NewHandler(jobStore, userStore)
+    jobHandler.RegisterRoutes(subRouter)
+
+    // Emails to customers are skipped if they turned them off; stock alerts go to staff
+    notifier, err := newNotifier(worker, jobStore)
+    if err != nil {
+        return err
+    }
+    notificationStore := notification.NewStore(s.db)
+    notificationHandler := notification.NewHandler(notificationStore, userStore)
+    notificationHandler.RegisterRoutes(subRouter)
+    customerNotifier := notify.NewPreferenceFilter(notifier, notificationStore)
+
+    userHandler := user.NewHandler(userStore, userStore, customerNotifier)
     userHandler.RegisterRoutes(subRouter)
+    userHandler.RegisterSubscribers(bus)
+
+    blobStore, err := newBlobStore()
+    if err != nil {
+        return err
+    }
 
     productStore := product.NewStore(s.db)
-    productHandler := product.NewHandler(productStore)
+    productHandler := product.NewHandler(productStore, productStore, blobStore, userStore)
     productHandler.RegisterRoutes(subRouter)
+    productHandler.RegisterJobs(worker)
+
+    inventoryStore := inventory.NewStore(s.db)
+    inventoryHandler := inventory.NewHandler(inventoryStore, inventoryStore, userStore)
+    inventoryHandler.RegisterRoutes(subRouter)
+
+    pricingStore := pricing.NewStore(s.db, product.PublishUpdate)
+    pricingHandler := pricing.NewHandler(pricingStore, userStore)
+    pricingHandler.RegisterRoutes(subRouter)
+
+    cartStore := cart.NewStore(s.db)
+    cartHandler := cart.NewHandler(cartStore, userStore)
+    cartHandler.RegisterRoutes(subRouter)
+
+    promotionStore := promotion.NewStore(s.db)
+    promotionHandler := promotion.NewHandler(promotionStore, productStore, userStore)
+    promotionHandler.RegisterRoutes(subRouter)
+
+    taxStore := tax.NewStore(s.db)
+    taxHandler := tax.NewHandler(taxStore, userStore)
+    taxHandler.RegisterRoutes(subRouter)
+
+    shippingStore := shipping.NewStore(s.db)
+    shippingHandler := shipping.NewHandler(shippingStore, productStore, userStore)
+    shippingHandler.RegisterRoutes(subRouter)
+
+    orderStore := order.NewStore(s.db)
+    orderHandler := order.NewHandler(orderStore, productStore, promotionStore, tax.NewTableCalculator(taxStore), shippingStore, customerNotifier, userStore)
+    orderHandler.RegisterRoutes(subRouter)
+    orderHandler.RegisterSubscribers(bus)
+
+    paymentProvider, err := newPaymentProvider()
+    if err != nil {
+        return err
+    }
+    paymentStore := payment.NewStore(s.db)
+    paymentHandler := payment.NewHandler(paymentStore, orderStore, paymentProvider, userStore)
+    paymentHandler.RegisterRoutes(subRouter)
+    paymentHandler.RegisterJobs(worker)
+
+    shipmentStore := shipment.NewStore(s.db)
+    shipmentHandler := shipment.NewHandler(shipmentStore, orderStore, customerNotifier, userStore)
+    shipmentHandler.RegisterRoutes(subRouter)
+    shipmentHandler.RegisterSubscribers(bus)
+
+    returnStore := returns.NewStore(s.db)
+    returnHandler := returns.NewHandler(returnStore, orderStore, userStore)
+    returnHandler.RegisterRoutes(subRouter)
+
+    invoiceStore := invoice.NewStore(s.db)
+    invoiceHandler := invoice.NewHandler(invoiceStore, orderStore, userStore, blobStore)
+    invoiceHandler.RegisterRoutes(subRouter)
+
+    reviewStore := review.NewStore(s.db)
+    reviewHandler := review.NewHandler(reviewStore, userStore)
+    reviewHandler.RegisterRoutes(subRouter)
+
+    wishlistStore := wishlist.NewStore(s.db)
+    wishlistHandler := wishlist.NewHandler(wishlistStore, userStore)
+    wishlistHandler.RegisterRoutes(subRouter)
+
+    webhookStore := webhook.NewStore(s.db)
+    webhookHandler := webhook.NewHandler(webhookStore, userStore)
+    webhookHandler.RegisterRoutes(subRouter)
+
+    // Background workers stop when the server returns
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    outbox.NewRelay(outbox.NewStore(s.db), bus).Start(ctx, time.Duration(configs.Envs.OutboxRelayIntervalInSeconds)*time.Second)
+    inventory.StartSweeper(ctx, inventoryStore, time.Duration(configs.Envs.ReservationSweepIntervalInSeconds)*time.Second)
+    inventory.NewAlertWatcher(inventoryStore, notifier).Start(ctx, time.Duration(configs.Envs.StockAlertIntervalInSeconds)*time.Second)
+    pricing.StartScheduler(ctx, pricingStore, time.Duration(configs.Envs.PriceSchedulerIntervalInSeconds)*time.Second)
+    idempotency.StartSweeper(ctx, idempotencyStore, time.Duration(configs.Envs.IdempotencySweepIntervalInSeconds)*time.Second)
+    webhook.NewDispatcher(webhookStore, &http.Client{}).Start(ctx, time.Duration(configs.Envs.WebhookDispatchIntervalInSeconds)*time.Second)
+    worker.Start(ctx, time.Duration(configs.Envs.JobPollIntervalInSeconds)*time.Second)
 
     log.Printf("Server is starting on %s...", s.addr)
-    err := http.ListenAndServe(s.addr, router)
+    err = http.ListenAndServe(s.addr, router)
     if err != nil {
         log.Printf("Error starting server: %v", err)
     }
 
     return err
-}
\ No newline at end of file
+}
+
+// newBlobStore creates the storage backend for uploaded files selected by the BLOB_STORE setting.
+func newBlobStore() (types.BlobStore, error) {
+	switch configs.Envs.BlobStore {
+	case "local":
+		return storage.NewLocalStore(configs.Envs.BlobLocalDir)
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  configs.Envs.S3Endpoint,
+			Region:    configs.Envs.S3Region,
+			Bucket:    configs.Envs.S3Bucket,
+			AccessKey: configs.Envs.S3AccessKey,
+			SecretKey: configs.Envs.S3SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown blob store %q", configs.Envs.BlobStore)
+	}
+}
+
+// newNotifier creates the notifier delivering emails with the mailer selected by the
+// MAILER setting. Emails are queued as jobs that worker sends with that mailer.
+func newNotifier(worker *jobs.Worker, queue types.JobQueue) (types.Notifier, error) {
+	var mailer types.Mailer
+	switch configs.Envs.Mailer {
+	case "log":
+		return notify.NewLogNotifier(), nil
+	case "smtp":
+		mailer = notify.NewSMTPMailer(notify.SMTPConfig{
+			Host:     configs.Envs.SMTPHost,
+			Port:     configs.Envs.SMTPPort,
+			Username: configs.Envs.SMTPUsername,
+			Password: configs.Envs.SMTPPassword,
+		})
+	case "file":
+		fileMailer, err := notify.NewFileMailer(configs.Envs.MailDir)
+		if err != nil {
+			return nil, err
+		}
+		mailer = fileMailer
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", configs.Envs.Mailer)
+	}
+	jobs.Handle(worker, notify.SendEmail(mailer))
+	return notify.NewEmailNotifier(notify.NewQueuedMailer(queue), configs.Envs.MailFrom, configs.Envs.PublicHost), nil
+}
+
+// newPaymentProvider creates the payment gateway selected by the PAYMENT_PROVIDER setting.
+func newPaymentProvider() (types.PaymentProvider, error) {
+	switch configs.Envs.PaymentProvider {
+	case "fake":
+		return payments.NewFakeProvider(configs.Envs.PaymentWebhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", configs.Envs.PaymentProvider)
+	}
+}
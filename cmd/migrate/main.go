@@ -21,6 +21,7 @@ func main() {
 		Net:                  "tcp",
 		AllowNativePasswords: true,
 		ParseTime:            true,
+		MultiStatements:      true, // Migrations may contain several statements
 	}
 
 	db, err := db.NewMySQLStorage(cfg)
@@ -0,0 +1 @@
+ALTER TABLE products DROP COLUMN `image`;
\ No newline at end of file
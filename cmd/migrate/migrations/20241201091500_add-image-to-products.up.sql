@@ -0,0 +1,2 @@
+ALTER TABLE products
+    ADD COLUMN `image` VARCHAR(255) NOT NULL DEFAULT '' AFTER `description`;
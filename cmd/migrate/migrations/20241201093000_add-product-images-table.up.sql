@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS product_images (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `position` INT UNSIGNED NOT NULL,
+    `blobKey` VARCHAR(255) NOT NULL,
+    `thumbnailKey` VARCHAR(255) NOT NULL,
+    `contentType` VARCHAR(100) NOT NULL,
+    `size` BIGINT UNSIGNED NOT NULL,
+    `width` INT UNSIGNED NOT NULL,
+    `height` INT UNSIGNED NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `product_position` (`productId`, `position`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1 @@
+ALTER TABLE products DROP INDEX `sku`, DROP COLUMN `sku`;
\ No newline at end of file
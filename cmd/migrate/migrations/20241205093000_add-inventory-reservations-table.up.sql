@@ -0,0 +1,24 @@
+ALTER TABLE products
+    ADD COLUMN `reserved` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `quantity`;
+
+CREATE TABLE IF NOT EXISTS inventory_reservations (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `userId` INT UNSIGNED NOT NULL,
+    `status` ENUM('active', 'committed', 'released', 'expired') NOT NULL DEFAULT 'active',
+    `expires_at` TIMESTAMP NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `status_expires_at` (`status`, `expires_at`),
+    FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS inventory_reservation_items (
+    `reservationId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+    `quantity` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`reservationId`, `productId`),
+    FOREIGN KEY (`reservationId`) REFERENCES inventory_reservations(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`productId`) REFERENCES products(`id`)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS stock_movements (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `quantityChange` INT NOT NULL DEFAULT 0,
+    `reservedChange` INT NOT NULL DEFAULT 0,
+    `reason` ENUM('receipt', 'sale', 'return', 'adjustment', 'reservation', 'release') NOT NULL,
+    `actorId` INT UNSIGNED NULL DEFAULT NULL,
+    `note` VARCHAR(255) NOT NULL DEFAULT '',
+    `reference` VARCHAR(64) NOT NULL DEFAULT '',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `product_id` (`productId`, `id`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`),
+    FOREIGN KEY (`actorId`) REFERENCES users(`id`)
+);
+
+-- Open the ledger with the stock each product already has
+INSERT INTO stock_movements (productId, quantityChange, reservedChange, reason, note)
+SELECT id, quantity, reserved, 'adjustment', 'opening balance' FROM products;
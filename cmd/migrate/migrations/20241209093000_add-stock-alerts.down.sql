@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_subscriptions;
+ALTER TABLE products DROP COLUMN `lowStockAlerted`, DROP COLUMN `reorderThreshold`;
@@ -0,0 +1,17 @@
+ALTER TABLE products
+    ADD COLUMN `reorderThreshold` INT UNSIGNED NULL DEFAULT NULL AFTER `reserved`,
+    ADD COLUMN `lowStockAlerted` TINYINT(1) NOT NULL DEFAULT 0 AFTER `reorderThreshold`;
+
+CREATE TABLE IF NOT EXISTS stock_subscriptions (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `userId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `notified_at` TIMESTAMP NULL DEFAULT NULL,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `user_product` (`userId`, `productId`),
+    KEY `product_pending` (`productId`, `notified_at`),
+    FOREIGN KEY (`userId`) REFERENCES users(`id`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS reviews (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `userId` INT UNSIGNED NOT NULL,
+    `rating` TINYINT UNSIGNED NOT NULL,
+    `title` VARCHAR(255) NOT NULL DEFAULT '',
+    `body` TEXT NOT NULL,
+    `verified` TINYINT(1) NOT NULL DEFAULT 0,
+    `status` ENUM('pending', 'approved', 'rejected') NOT NULL DEFAULT 'pending',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `user_product` (`userId`, `productId`),
+    KEY `product_status` (`productId`, `status`, `created_at`),
+    KEY `status_created` (`status`, `created_at`),
+    CHECK (`rating` BETWEEN 1 AND 5),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS wishlists (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `userId` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(100) NOT NULL,
+    `isDefault` TINYINT(1) NOT NULL DEFAULT 0,
+    -- Only set for the default wishlist so the unique key allows one default per user
+    `defaultUserId` INT UNSIGNED AS (IF(`isDefault`, `userId`, NULL)) STORED,
+    `shareToken` CHAR(64) NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `default_user` (`defaultUserId`),
+    UNIQUE KEY `share_token` (`shareToken`),
+    KEY `user` (`userId`),
+    FOREIGN KEY (`userId`) REFERENCES users(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS wishlist_items (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `wishlistId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+    `priceWhenAdded` DECIMAL(10, 2) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `wishlist_product` (`wishlistId`, `productId`),
+    FOREIGN KEY (`wishlistId`) REFERENCES wishlists(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE
+);
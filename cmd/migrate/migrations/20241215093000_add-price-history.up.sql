@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS scheduled_prices (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `price` DECIMAL(10, 2) NOT NULL,
+    `previousPrice` DECIMAL(10, 2) NULL DEFAULT NULL,
+    `starts_at` TIMESTAMP NOT NULL,
+    `ends_at` TIMESTAMP NULL DEFAULT NULL,
+    `status` ENUM('pending', 'active', 'ended', 'cancelled') NOT NULL DEFAULT 'pending',
+    `actorId` INT UNSIGNED NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `product_starts` (`productId`, `starts_at`),
+    KEY `status_starts` (`status`, `starts_at`),
+    KEY `status_ends` (`status`, `ends_at`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`),
+    FOREIGN KEY (`actorId`) REFERENCES users(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS price_history (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `productId` INT UNSIGNED NOT NULL,
+    `oldPrice` DECIMAL(10, 2) NULL DEFAULT NULL,
+    `newPrice` DECIMAL(10, 2) NOT NULL,
+    `reason` ENUM('created', 'updated', 'imported', 'schedule_started', 'schedule_ended') NOT NULL,
+    `actorId` INT UNSIGNED NULL DEFAULT NULL,
+    `scheduleId` INT UNSIGNED NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `product_id` (`productId`, `id`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`),
+    FOREIGN KEY (`actorId`) REFERENCES users(`id`),
+    FOREIGN KEY (`scheduleId`) REFERENCES scheduled_prices(`id`)
+);
+
+-- Start the history with the price each product already has
+INSERT INTO price_history (productId, newPrice, reason)
+SELECT id, price, 'created' FROM products;
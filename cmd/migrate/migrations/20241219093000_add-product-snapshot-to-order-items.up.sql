@@ -0,0 +1,8 @@
+ALTER TABLE order_items
+    ADD COLUMN `productName` VARCHAR(255) NOT NULL DEFAULT '' AFTER `productId`,
+    ADD COLUMN `productSku` VARCHAR(64) NOT NULL DEFAULT '' AFTER `productName`,
+    ADD COLUMN `productImage` VARCHAR(255) NOT NULL DEFAULT '' AFTER `productSku`;
+
+-- Snapshot the current product details for orders placed before this migration
+UPDATE order_items oi JOIN products p ON p.id = oi.productId
+SET oi.productName = p.name, oi.productSku = COALESCE(p.sku, ''), oi.productImage = p.image;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS order_status_history (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `fromStatus` ENUM('pending', 'processing', 'shipped', 'delivered', 'cancelled') NULL DEFAULT NULL,
+    `toStatus` ENUM('pending', 'processing', 'shipped', 'delivered', 'cancelled') NOT NULL,
+    `actorId` INT UNSIGNED NULL DEFAULT NULL,
+    `note` VARCHAR(255) NOT NULL DEFAULT '',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `order_id` (`orderId`, `id`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`),
+    FOREIGN KEY (`actorId`) REFERENCES users(`id`)
+);
+
+-- Start the history of existing orders with the status they already have
+INSERT INTO order_status_history (orderId, toStatus, note, created_at)
+SELECT id, status, 'status before history was recorded', created_at FROM orders;
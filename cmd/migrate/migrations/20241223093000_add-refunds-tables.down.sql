@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS refund_items;
+DROP TABLE IF EXISTS refunds;
+
+-- Keep the ledger balanced: restocked units are recorded as returns
+UPDATE stock_movements SET reason = 'return' WHERE reason = 'cancel';
+ALTER TABLE stock_movements
+    MODIFY `reason` ENUM('receipt', 'sale', 'return', 'adjustment', 'reservation', 'release') NOT NULL;
+
+ALTER TABLE order_items DROP COLUMN `refunded`;
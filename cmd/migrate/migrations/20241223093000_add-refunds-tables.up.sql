@@ -0,0 +1,33 @@
+ALTER TABLE order_items ADD COLUMN `refunded` INT NOT NULL DEFAULT 0 AFTER `price`;
+
+ALTER TABLE stock_movements
+    MODIFY `reason` ENUM('receipt', 'sale', 'return', 'adjustment', 'reservation', 'release', 'cancel') NOT NULL;
+
+CREATE TABLE IF NOT EXISTS refunds (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+    `reason` VARCHAR(255) NOT NULL DEFAULT '',
+    `actorId` INT UNSIGNED NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `order_id` (`orderId`, `id`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`),
+    FOREIGN KEY (`actorId`) REFERENCES users(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS refund_items (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `refundId` INT UNSIGNED NOT NULL,
+    `orderItemId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+    `quantity` INT NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+    `restocked` BOOLEAN NOT NULL DEFAULT FALSE,
+
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`refundId`) REFERENCES refunds(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`),
+    FOREIGN KEY (`productId`) REFERENCES products(`id`)
+);
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS payments (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `provider` VARCHAR(32) NOT NULL,
+    `providerRef` VARCHAR(255) NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+    `captured` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `refunded` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `currency` CHAR(3) NOT NULL,
+    `status` ENUM('requires_confirmation', 'failed', 'authorized', 'captured', 'refunded') NOT NULL DEFAULT 'requires_confirmation',
+    `failureReason` VARCHAR(255) NOT NULL DEFAULT '',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `provider_ref` (`provider`, `providerRef`),
+    KEY `order_id` (`orderId`, `id`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`)
+);
+
+-- Webhook events already applied, so a redelivered event is ignored
+CREATE TABLE IF NOT EXISTS payment_events (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `provider` VARCHAR(32) NOT NULL,
+    `eventId` VARCHAR(255) NOT NULL,
+    `paymentId` INT UNSIGNED NOT NULL,
+    `status` VARCHAR(32) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `provider_event` (`provider`, `eventId`),
+    FOREIGN KEY (`paymentId`) REFERENCES payments(`id`) ON DELETE CASCADE
+);
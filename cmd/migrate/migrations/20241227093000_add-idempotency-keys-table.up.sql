@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `scope` CHAR(64) NOT NULL,
+    `idempotencyKey` VARCHAR(255) NOT NULL,
+    `fingerprint` CHAR(64) NOT NULL,
+    `status` SMALLINT UNSIGNED NOT NULL DEFAULT 0,
+    `contentType` VARCHAR(255) NOT NULL DEFAULT '',
+    `body` MEDIUMBLOB NULL DEFAULT NULL,
+    `expires_at` TIMESTAMP NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `scope_key` (`scope`, `idempotencyKey`),
+    KEY `expires_at` (`expires_at`)
+);
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS promotion_redemptions;
+
+ALTER TABLE order_items DROP COLUMN `discount`;
+
+ALTER TABLE orders
+    DROP FOREIGN KEY `orders_promotion`,
+    DROP COLUMN `promotionCode`,
+    DROP COLUMN `promotionId`,
+    DROP COLUMN `discount`,
+    DROP COLUMN `subtotal`;
+
+DROP TABLE IF EXISTS promotion_categories;
+DROP TABLE IF EXISTS promotion_products;
+DROP TABLE IF EXISTS promotions;
+
+ALTER TABLE products DROP COLUMN `category`;
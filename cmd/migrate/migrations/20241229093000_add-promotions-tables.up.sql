@@ -0,0 +1,67 @@
+ALTER TABLE products ADD COLUMN `category` VARCHAR(64) NOT NULL DEFAULT '' AFTER `description`;
+
+CREATE TABLE IF NOT EXISTS promotions (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `code` VARCHAR(64) NOT NULL,
+    `description` VARCHAR(255) NOT NULL DEFAULT '',
+    `type` ENUM('percentage', 'fixed', 'free_shipping', 'buy_x_get_y') NOT NULL,
+    `value` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `buyQuantity` INT UNSIGNED NOT NULL DEFAULT 0,
+    `getQuantity` INT UNSIGNED NOT NULL DEFAULT 0,
+    `minOrderValue` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `usageLimit` INT UNSIGNED NULL DEFAULT NULL,
+    `perUserLimit` INT UNSIGNED NULL DEFAULT NULL,
+    `timesUsed` INT UNSIGNED NOT NULL DEFAULT 0,
+    `starts_at` TIMESTAMP NULL DEFAULT NULL,
+    `ends_at` TIMESTAMP NULL DEFAULT NULL,
+    `active` BOOLEAN NOT NULL DEFAULT TRUE,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `code` (`code`)
+);
+
+CREATE TABLE IF NOT EXISTS promotion_products (
+    `promotionId` INT UNSIGNED NOT NULL,
+    `productId` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`promotionId`, `productId`),
+    FOREIGN KEY (`promotionId`) REFERENCES promotions(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`productId`) REFERENCES products(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS promotion_categories (
+    `promotionId` INT UNSIGNED NOT NULL,
+    `category` VARCHAR(64) NOT NULL,
+
+    PRIMARY KEY (`promotionId`, `category`),
+    FOREIGN KEY (`promotionId`) REFERENCES promotions(`id`) ON DELETE CASCADE
+);
+
+ALTER TABLE orders
+    ADD COLUMN `subtotal` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `userId`,
+    ADD COLUMN `discount` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `subtotal`,
+    ADD COLUMN `promotionId` INT UNSIGNED NULL DEFAULT NULL AFTER `discount`,
+    ADD COLUMN `promotionCode` VARCHAR(64) NOT NULL DEFAULT '' AFTER `promotionId`,
+    ADD CONSTRAINT `orders_promotion` FOREIGN KEY (`promotionId`) REFERENCES promotions(`id`);
+
+-- Orders placed before promotions were charged their subtotal
+UPDATE orders SET subtotal = total;
+
+ALTER TABLE order_items ADD COLUMN `discount` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `price`;
+
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `promotionId` INT UNSIGNED NOT NULL,
+    `userId` INT UNSIGNED NOT NULL,
+    `orderId` INT UNSIGNED NOT NULL,
+    `discount` DECIMAL(10, 2) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `order_id` (`orderId`),
+    KEY `promotion_user` (`promotionId`, `userId`),
+    FOREIGN KEY (`promotionId`) REFERENCES promotions(`id`),
+    FOREIGN KEY (`userId`) REFERENCES users(`id`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`)
+);
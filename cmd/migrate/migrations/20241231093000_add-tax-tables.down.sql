@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS order_item_taxes;
+
+ALTER TABLE order_items DROP COLUMN `tax`;
+
+ALTER TABLE orders
+    DROP COLUMN `postalCode`,
+    DROP COLUMN `region`,
+    DROP COLUMN `country`,
+    DROP COLUMN `tax`;
+
+DROP TABLE IF EXISTS tax_rates;
+
+ALTER TABLE products DROP COLUMN `taxClass`;
@@ -0,0 +1,36 @@
+ALTER TABLE products ADD COLUMN `taxClass` VARCHAR(32) NOT NULL DEFAULT 'standard' AFTER `category`;
+
+CREATE TABLE IF NOT EXISTS tax_rates (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `name` VARCHAR(64) NOT NULL,
+    `country` CHAR(2) NOT NULL,
+    `region` VARCHAR(64) NOT NULL DEFAULT '',
+    `postalPrefix` VARCHAR(16) NOT NULL DEFAULT '',
+    `taxClass` VARCHAR(32) NOT NULL DEFAULT 'standard',
+    `rate` DECIMAL(5, 2) NOT NULL,
+    `inclusive` BOOLEAN NOT NULL DEFAULT FALSE,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `jurisdiction` (`name`, `country`, `region`, `postalPrefix`, `taxClass`)
+);
+
+ALTER TABLE orders
+    ADD COLUMN `tax` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `promotionCode`,
+    ADD COLUMN `country` CHAR(2) NOT NULL DEFAULT '' AFTER `address`,
+    ADD COLUMN `region` VARCHAR(64) NOT NULL DEFAULT '' AFTER `country`,
+    ADD COLUMN `postalCode` VARCHAR(16) NOT NULL DEFAULT '' AFTER `region`;
+
+ALTER TABLE order_items ADD COLUMN `tax` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `discount`;
+
+CREATE TABLE IF NOT EXISTS order_item_taxes (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderItemId` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(64) NOT NULL,
+    `rate` DECIMAL(5, 2) NOT NULL,
+    `inclusive` BOOLEAN NOT NULL,
+    `amount` DECIMAL(10, 2) NOT NULL,
+
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`) ON DELETE CASCADE
+);
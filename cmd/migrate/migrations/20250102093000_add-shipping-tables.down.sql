@@ -0,0 +1,17 @@
+ALTER TABLE refunds DROP COLUMN `shipping`;
+
+ALTER TABLE orders
+    DROP FOREIGN KEY `orders_shipping_method`,
+    DROP COLUMN `shipping`,
+    DROP COLUMN `shippingMethod`,
+    DROP COLUMN `shippingMethodId`;
+
+DROP TABLE IF EXISTS shipping_methods;
+DROP TABLE IF EXISTS shipping_zone_countries;
+DROP TABLE IF EXISTS shipping_zones;
+
+ALTER TABLE products
+    DROP COLUMN `height`,
+    DROP COLUMN `width`,
+    DROP COLUMN `length`,
+    DROP COLUMN `weight`;
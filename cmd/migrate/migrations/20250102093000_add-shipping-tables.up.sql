@@ -0,0 +1,48 @@
+ALTER TABLE products
+    ADD COLUMN `weight` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `price`,
+    ADD COLUMN `length` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `weight`,
+    ADD COLUMN `width` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `length`,
+    ADD COLUMN `height` INT UNSIGNED NOT NULL DEFAULT 0 AFTER `width`;
+
+CREATE TABLE IF NOT EXISTS shipping_zones (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `name` VARCHAR(64) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS shipping_zone_countries (
+    `country` CHAR(2) NOT NULL,
+    `zoneId` INT UNSIGNED NOT NULL,
+
+    PRIMARY KEY (`country`),
+    KEY `zone_id` (`zoneId`),
+    FOREIGN KEY (`zoneId`) REFERENCES shipping_zones(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS shipping_methods (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `zoneId` INT UNSIGNED NOT NULL,
+    `name` VARCHAR(64) NOT NULL,
+    `type` ENUM('flat', 'weight') NOT NULL,
+    `price` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `pricePerKg` DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    `freeOver` DECIMAL(10, 2) NULL DEFAULT NULL,
+    `maxWeight` INT UNSIGNED NULL DEFAULT NULL,
+    `minDays` INT UNSIGNED NOT NULL DEFAULT 0,
+    `maxDays` INT UNSIGNED NOT NULL DEFAULT 0,
+    `active` BOOLEAN NOT NULL DEFAULT TRUE,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`zoneId`) REFERENCES shipping_zones(`id`)
+);
+
+ALTER TABLE orders
+    ADD COLUMN `shippingMethodId` INT UNSIGNED NULL DEFAULT NULL AFTER `tax`,
+    ADD COLUMN `shippingMethod` VARCHAR(64) NOT NULL DEFAULT '' AFTER `shippingMethodId`,
+    ADD COLUMN `shipping` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `shippingMethod`,
+    ADD CONSTRAINT `orders_shipping_method` FOREIGN KEY (`shippingMethodId`) REFERENCES shipping_methods(`id`);
+
+ALTER TABLE refunds ADD COLUMN `shipping` DECIMAL(10, 2) NOT NULL DEFAULT 0 AFTER `amount`;
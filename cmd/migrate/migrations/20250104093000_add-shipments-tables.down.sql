@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS shipment_items;
+DROP TABLE IF EXISTS shipments;
+
+ALTER TABLE order_items DROP COLUMN `shipped`;
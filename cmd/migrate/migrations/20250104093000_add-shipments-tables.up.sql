@@ -0,0 +1,34 @@
+ALTER TABLE order_items ADD COLUMN `shipped` INT NOT NULL DEFAULT 0 AFTER `refunded`;
+
+CREATE TABLE IF NOT EXISTS shipments (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `carrier` VARCHAR(64) NOT NULL,
+    `trackingCode` VARCHAR(128) NOT NULL,
+    `trackingUrl` VARCHAR(255) NOT NULL DEFAULT '',
+    `actorId` INT UNSIGNED NULL DEFAULT NULL,
+    `shippedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `deliveredAt` TIMESTAMP NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `order_id` (`orderId`, `id`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`),
+    FOREIGN KEY (`actorId`) REFERENCES users(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS shipment_items (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `shipmentId` INT UNSIGNED NOT NULL,
+    `orderItemId` INT UNSIGNED NOT NULL,
+    `quantity` INT NOT NULL,
+
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`shipmentId`) REFERENCES shipments(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`)
+);
+
+-- Orders shipped before shipments were recorded count as fully shipped
+UPDATE order_items oi JOIN orders o ON o.id = oi.orderId
+SET oi.shipped = oi.quantity - oi.refunded
+WHERE o.status IN ('shipped', 'delivered');
@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS `returns` (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `userId` INT UNSIGNED NOT NULL,
+    `status` ENUM('requested', 'approved', 'received', 'accepted', 'rejected') NOT NULL DEFAULT 'requested',
+    `comment` TEXT NOT NULL,
+    `note` VARCHAR(255) NOT NULL DEFAULT '',
+    `restocked` BOOLEAN NOT NULL DEFAULT FALSE,
+    `refundId` INT UNSIGNED NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    KEY `order_id` (`orderId`, `id`),
+    KEY `status` (`status`, `id`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`),
+    FOREIGN KEY (`userId`) REFERENCES users(`id`),
+    FOREIGN KEY (`refundId`) REFERENCES refunds(`id`)
+);
+
+CREATE TABLE IF NOT EXISTS return_items (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `returnId` INT UNSIGNED NOT NULL,
+    `orderItemId` INT UNSIGNED NOT NULL,
+    `quantity` INT NOT NULL,
+    `reason` ENUM('damaged', 'defective', 'wrong_item', 'not_as_described', 'no_longer_needed', 'other') NOT NULL,
+
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`returnId`) REFERENCES `returns`(`id`) ON DELETE CASCADE,
+    FOREIGN KEY (`orderItemId`) REFERENCES order_items(`id`)
+);
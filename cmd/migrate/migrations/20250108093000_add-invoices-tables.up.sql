@@ -0,0 +1,24 @@
+-- The last invoice number issued in every year, locked while the next one is taken so
+-- numbers are handed out without gaps
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    `year` SMALLINT UNSIGNED NOT NULL,
+    `lastNumber` INT UNSIGNED NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (`year`)
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `orderId` INT UNSIGNED NOT NULL,
+    `number` VARCHAR(32) NOT NULL,
+    `year` SMALLINT UNSIGNED NOT NULL,
+    `sequence` INT UNSIGNED NOT NULL,
+    `storageKey` VARCHAR(255) NOT NULL,
+    `issuedAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `order_id` (`orderId`),
+    UNIQUE KEY `number` (`number`),
+    UNIQUE KEY `year_sequence` (`year`, `sequence`),
+    FOREIGN KEY (`orderId`) REFERENCES orders(`id`)
+);
@@ -0,0 +1,10 @@
+-- Optional notifications a user changed; kinds without a row are sent
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    `userId` INT UNSIGNED NOT NULL,
+    `kind` VARCHAR(32) NOT NULL,
+    `email` BOOLEAN NOT NULL,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`userId`, `kind`),
+    FOREIGN KEY (`userId`) REFERENCES users(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `url` VARCHAR(2048) NOT NULL,
+    `secret` VARCHAR(128) NOT NULL,
+    `description` VARCHAR(255) NOT NULL DEFAULT '',
+    `active` BOOLEAN NOT NULL DEFAULT TRUE,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `updated_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_events (
+    `webhookId` INT UNSIGNED NOT NULL,
+    `event` VARCHAR(64) NOT NULL,
+
+    PRIMARY KEY (`webhookId`, `event`),
+    KEY `event` (`event`),
+    FOREIGN KEY (`webhookId`) REFERENCES webhooks(`id`) ON DELETE CASCADE
+);
+
+-- One row per event and webhook. The payload is kept as sent so redeliveries are
+-- byte-for-byte identical
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `webhookId` INT UNSIGNED NOT NULL,
+    `eventId` CHAR(32) NOT NULL,
+    `event` VARCHAR(64) NOT NULL,
+    `payload` MEDIUMTEXT NOT NULL,
+    `status` ENUM('pending', 'succeeded', 'failed') NOT NULL DEFAULT 'pending',
+    `attempts` INT UNSIGNED NOT NULL DEFAULT 0,
+    `nextAttemptAt` TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    `lastResponseCode` SMALLINT UNSIGNED NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `deliveredAt` TIMESTAMP NULL,
+
+    PRIMARY KEY (`id`),
+    KEY `due` (`status`, `nextAttemptAt`),
+    KEY `webhook` (`webhookId`, `id`),
+    FOREIGN KEY (`webhookId`) REFERENCES webhooks(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `deliveryId` INT UNSIGNED NOT NULL,
+    `succeeded` BOOLEAN NOT NULL,
+    `responseCode` SMALLINT UNSIGNED NULL,
+    `responseBody` VARCHAR(1024) NOT NULL DEFAULT '',
+    `error` VARCHAR(1024) NOT NULL DEFAULT '',
+    `durationMs` INT UNSIGNED NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    FOREIGN KEY (`deliveryId`) REFERENCES webhook_deliveries(`id`) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS outbox_handled;
+DROP TABLE IF EXISTS outbox_events;
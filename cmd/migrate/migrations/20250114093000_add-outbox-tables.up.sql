@@ -0,0 +1,26 @@
+-- Domain events written in the same transaction as the change they describe, kept until
+-- every subscriber has handled them
+CREATE TABLE IF NOT EXISTS outbox_events (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `type` VARCHAR(64) NOT NULL,
+    `payload` TEXT NOT NULL,
+    `status` ENUM('pending', 'published', 'failed') NOT NULL DEFAULT 'pending',
+    `attempts` INT UNSIGNED NOT NULL DEFAULT 0,
+    `nextAttemptAt` TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP,
+    `lastError` VARCHAR(1024) NOT NULL DEFAULT '',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `publishedAt` TIMESTAMP NULL,
+
+    PRIMARY KEY (`id`),
+    KEY `due` (`status`, `nextAttemptAt`)
+);
+
+-- The subscribers that handled an event, so retrying it only calls the ones that failed
+CREATE TABLE IF NOT EXISTS outbox_handled (
+    `eventId` INT UNSIGNED NOT NULL,
+    `subscriber` VARCHAR(64) NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`eventId`, `subscriber`),
+    FOREIGN KEY (`eventId`) REFERENCES outbox_events(`id`) ON DELETE CASCADE
+);
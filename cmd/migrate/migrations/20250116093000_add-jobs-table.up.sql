@@ -0,0 +1,18 @@
+-- Background jobs. runAt is when a pending job is due and, while a job is running, when
+-- the lease of its worker ends, so workers claim both with one indexed condition
+CREATE TABLE IF NOT EXISTS jobs (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `kind` VARCHAR(64) NOT NULL,
+    `payload` MEDIUMTEXT NOT NULL,
+    `status` ENUM('pending', 'running', 'succeeded', 'dead') NOT NULL DEFAULT 'pending',
+    `attempts` INT UNSIGNED NOT NULL DEFAULT 0,
+    `maxAttempts` INT UNSIGNED NOT NULL,
+    `runAt` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `lastError` VARCHAR(1024) NOT NULL DEFAULT '',
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `finishedAt` TIMESTAMP NULL,
+
+    PRIMARY KEY (`id`),
+    KEY `due` (`status`, `runAt`),
+    KEY `kind` (`kind`, `status`)
+);
@@ -0,0 +1,6 @@
+DELETE FROM jobs WHERE kind = 'settle_refund';
+
+ALTER TABLE refunds
+    DROP FOREIGN KEY `refunds_payment`,
+    DROP COLUMN `paymentId`,
+    DROP COLUMN `paymentStatus`;
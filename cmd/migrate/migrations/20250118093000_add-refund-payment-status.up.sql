@@ -0,0 +1,10 @@
+-- Refunds give the money back through the payment provider in a settle_refund job queued
+-- with the refund. paymentId is the payment refunded, NULL until then or if nothing was paid
+ALTER TABLE refunds
+    ADD COLUMN `paymentStatus` ENUM('pending', 'refunded', 'none') NOT NULL DEFAULT 'pending' AFTER `actorId`,
+    ADD COLUMN `paymentId` INT UNSIGNED NULL DEFAULT NULL AFTER `paymentStatus`,
+    ADD CONSTRAINT `refunds_payment` FOREIGN KEY (`paymentId`) REFERENCES payments(`id`);
+
+-- Refunds recorded before never reached the provider, so they are settled now
+INSERT INTO jobs (kind, payload, maxAttempts)
+SELECT 'settle_refund', JSON_OBJECT('refundId', id), 8 FROM refunds;
@@ -0,0 +1,8 @@
+ALTER TABLE idempotency_keys
+    ADD COLUMN `contentType` VARCHAR(255) NOT NULL DEFAULT '' AFTER `status`;
+
+UPDATE idempotency_keys
+SET contentType = COALESCE(JSON_UNQUOTE(JSON_EXTRACT(headers, '$."Content-Type"[0]')), '')
+WHERE headers IS NOT NULL;
+
+ALTER TABLE idempotency_keys DROP COLUMN `headers`;
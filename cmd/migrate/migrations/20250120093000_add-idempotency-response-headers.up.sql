@@ -0,0 +1,10 @@
+-- The response headers are replayed along with the body, e.g. Set-Cookie and Location.
+-- They are kept as a JSON object of header names to lists of values
+ALTER TABLE idempotency_keys
+    ADD COLUMN `headers` TEXT NULL DEFAULT NULL AFTER `status`;
+
+UPDATE idempotency_keys
+SET headers = JSON_OBJECT('Content-Type', JSON_ARRAY(contentType))
+WHERE contentType <> '';
+
+ALTER TABLE idempotency_keys DROP COLUMN `contentType`;
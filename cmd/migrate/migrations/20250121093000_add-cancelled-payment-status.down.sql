@@ -0,0 +1,4 @@
+UPDATE payments SET status = 'failed' WHERE status = 'cancelled';
+
+ALTER TABLE payments
+    MODIFY COLUMN `status` ENUM('requires_confirmation', 'failed', 'authorized', 'captured', 'refunded') NOT NULL DEFAULT 'requires_confirmation';
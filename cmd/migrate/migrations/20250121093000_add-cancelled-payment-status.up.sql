@@ -0,0 +1,3 @@
+-- The authorization of a payment is released when its order is cancelled before capture
+ALTER TABLE payments
+    MODIFY COLUMN `status` ENUM('requires_confirmation', 'failed', 'authorized', 'captured', 'refunded', 'cancelled') NOT NULL DEFAULT 'requires_confirmation';
@@ -0,0 +1,4 @@
+UPDATE refunds SET paymentStatus = 'pending', paymentId = NULL WHERE paymentStatus = 'refunding';
+
+ALTER TABLE refunds
+    MODIFY COLUMN `paymentStatus` ENUM('pending', 'refunded', 'none') NOT NULL DEFAULT 'pending';
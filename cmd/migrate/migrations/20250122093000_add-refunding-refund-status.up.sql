@@ -0,0 +1,4 @@
+-- A refund is refunding from the moment the payment provider is asked to give the money
+-- back until the provider confirmed it, so a retried settle_refund job resumes it
+ALTER TABLE refunds
+    MODIFY COLUMN `paymentStatus` ENUM('pending', 'refunding', 'refunded', 'none') NOT NULL DEFAULT 'pending';
@@ -0,0 +1,13 @@
+-- Password reset links sent by email. Only a hash of the token is kept, so the table alone
+-- can't be used to reset passwords; a reset deletes all the links of the user
+CREATE TABLE IF NOT EXISTS password_resets (
+    `id` INT UNSIGNED NOT NULL AUTO_INCREMENT,
+    `userId` INT UNSIGNED NOT NULL,
+    `tokenHash` CHAR(64) NOT NULL,
+    `expires_at` TIMESTAMP NOT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `tokenHash` (`tokenHash`),
+    FOREIGN KEY (`userId`) REFERENCES users(`id`) ON DELETE CASCADE
+);
@@ -18,6 +18,41 @@ type Config struct {
 	DBName     string  // Name of the database
 	JWTExpirationInSeconds int64 // JWT expiration time
 	JWTSecret string // JWT secret key
+	PasswordResetTTLInSeconds int64 // How long a password reset link can be used
+	BlobStore string // Blob storage backend for uploads, either "local" or "s3"
+	BlobLocalDir string // Directory used by the local blob storage backend
+	S3Endpoint string // Endpoint of the S3-compatible storage, e.g. "http://127.0.0.1:9000"
+	S3Region string // Region used to sign S3 requests
+	S3Bucket string // Bucket that holds the uploaded objects
+	S3AccessKey string // S3 access key ID
+	S3SecretKey string // S3 secret access key
+	MaxImageSizeInBytes int64 // Maximum size of a single uploaded image
+	MaxUploadSizeInBytes int64 // Maximum size of a whole upload request body
+	MaxImportSizeInBytes int64 // Maximum size of a catalog import file
+	ReservationTTLInSeconds int64 // How long a checkout may hold reserved stock
+	ReservationSweepIntervalInSeconds int64 // How often expired reservations are released
+	StockAlertIntervalInSeconds int64 // How often low-stock and back-in-stock notifications are sent
+	PriceSchedulerIntervalInSeconds int64 // How often scheduled prices are started and ended
+	PaymentProvider string // Payment gateway used at checkout; only "fake" is built in
+	PaymentWebhookSecret string // Secret used to verify the signature of payment webhooks
+	PaymentCurrency string // ISO 4217 currency code that orders are charged in
+	IdempotencyKeyTTLInSeconds int64 // How long the response to an Idempotency-Key is replayed
+	IdempotencySweepIntervalInSeconds int64 // How often expired idempotency keys are deleted
+	IdempotencyLeaseInSeconds int64 // How long a request holds its Idempotency-Key before a retry can take it over
+	InvoiceIssuer string // Name of the business printed on invoices
+	InvoiceIssuerAddress string // Address of the business printed on invoices, lines separated by semicolons
+	InvoiceIssuerTaxID string // VAT or tax number printed on invoices, if any
+	Mailer string // How notification emails are delivered: "log", "smtp" or "file"
+	MailFrom string // Sender of notification emails, optionally with a display name
+	MailDir string // Directory the file mailer writes .eml files into
+	SMTPHost string // Host name of the mail server
+	SMTPPort string // Port of the mail server
+	SMTPUsername string // Username for the mail server, empty to send without authentication
+	SMTPPassword string // Password for the mail server
+	WebhookDispatchIntervalInSeconds int64 // How often due webhook deliveries are sent
+	OutboxRelayIntervalInSeconds int64 // How often committed domain events are published to subscribers
+	JobWorkers int64 // How many background jobs run at once
+	JobPollIntervalInSeconds int64 // How often idle job workers look for due jobs
 }
 
 // Envs variable holds the application configuration, initialized using initConfig()
@@ -38,7 +73,42 @@ func initConfig() Config {
 		DBAddress: fmt.Sprintf("%s:%s", getEnv("DB_HOST", "127.0.0.1"), getEnv("DB_PORT", "3306")),  // Default: "127.0.0.1:3306"
 		DBName: getEnv("DB_NAME", "go_backend"),  // Default: "go_backend"
 		JWTSecret: getEnv("JWT_SECRET", "secret"),
+		PasswordResetTTLInSeconds: getEnvAsInt("PASSWORD_RESET_TTL", 3600),  // Default: 1 hour
 		JWTExpirationInSeconds: getEnvAsInt("JWT_EXPIRATION", 3600 * 24 * 7),  // Default: 3600 seconds (1 hour)
+		BlobStore: getEnv("BLOB_STORE", "local"),  // Default: "local"
+		BlobLocalDir: getEnv("BLOB_LOCAL_DIR", "uploads"),  // Default: "uploads"
+		S3Endpoint: getEnv("S3_ENDPOINT", "http://127.0.0.1:9000"),  // Default: a local MinIO
+		S3Region: getEnv("S3_REGION", "us-east-1"),  // Default: "us-east-1"
+		S3Bucket: getEnv("S3_BUCKET", "go-backend"),  // Default: "go-backend"
+		S3AccessKey: getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey: getEnv("S3_SECRET_KEY", ""),
+		MaxImageSizeInBytes: getEnvAsInt("MAX_IMAGE_SIZE", 5 << 20),  // Default: 5 MiB
+		MaxUploadSizeInBytes: getEnvAsInt("MAX_UPLOAD_SIZE", 20 << 20),  // Default: 20 MiB
+		MaxImportSizeInBytes: getEnvAsInt("MAX_IMPORT_SIZE", 50 << 20),  // Default: 50 MiB
+		ReservationTTLInSeconds: getEnvAsInt("RESERVATION_TTL", 15 * 60),  // Default: 15 minutes
+		ReservationSweepIntervalInSeconds: getEnvAsInt("RESERVATION_SWEEP_INTERVAL", 60),  // Default: 1 minute
+		StockAlertIntervalInSeconds: getEnvAsInt("STOCK_ALERT_INTERVAL", 60),  // Default: 1 minute
+		PriceSchedulerIntervalInSeconds: getEnvAsInt("PRICE_SCHEDULER_INTERVAL", 30),  // Default: 30 seconds
+		PaymentProvider: getEnv("PAYMENT_PROVIDER", "fake"),  // Default: the offline fake gateway
+		PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", "secret"),
+		PaymentCurrency: getEnv("PAYMENT_CURRENCY", "usd"),  // Default: "usd"
+		IdempotencyKeyTTLInSeconds: getEnvAsInt("IDEMPOTENCY_KEY_TTL", 24 * 3600),  // Default: 24 hours
+		IdempotencySweepIntervalInSeconds: getEnvAsInt("IDEMPOTENCY_SWEEP_INTERVAL", 3600),  // Default: 1 hour
+		IdempotencyLeaseInSeconds: getEnvAsInt("IDEMPOTENCY_LEASE", 300),  // Default: 5 minutes
+		InvoiceIssuer: getEnv("INVOICE_ISSUER", "Go Backend Store"),
+		InvoiceIssuerAddress: getEnv("INVOICE_ISSUER_ADDRESS", ""),
+		InvoiceIssuerTaxID: getEnv("INVOICE_ISSUER_TAX_ID", ""),
+		Mailer: getEnv("MAILER", "log"),  // Default: only log notifications
+		MailFrom: getEnv("MAIL_FROM", "Go Backend Store <no-reply@localhost>"),
+		MailDir: getEnv("MAIL_DIR", "mail"),  // Default: "mail"
+		SMTPHost: getEnv("SMTP_HOST", "127.0.0.1"),  // Default: "127.0.0.1"
+		SMTPPort: getEnv("SMTP_PORT", "587"),  // Default: the submission port
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		WebhookDispatchIntervalInSeconds: getEnvAsInt("WEBHOOK_DISPATCH_INTERVAL", 5),  // Default: 5 seconds
+		OutboxRelayIntervalInSeconds: getEnvAsInt("OUTBOX_RELAY_INTERVAL", 1),  // Default: 1 second
+		JobWorkers: getEnvAsInt("JOB_WORKERS", 4),  // Default: 4 jobs at once
+		JobPollIntervalInSeconds: getEnvAsInt("JOB_POLL_INTERVAL", 1),  // Default: 1 second
 	}
 }
 
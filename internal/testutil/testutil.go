@@ -0,0 +1,45 @@
+// Package testutil holds helpers shared by the tests of the services.
+package testutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+)
+
+// Token returns a JWT for userID, signed with the configured secret, to send in the
+// Authorization header of test requests.
+func Token(t testing.TB, userID int) string {
+	t.Helper()
+
+	token, err := auth.CreateJWT([]byte(configs.Envs.JWTSecret), userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// UserStore is an in-memory types.UserStore holding the users the auth middleware looks
+// up by ID. It can't look users up by email or create them.
+type UserStore struct {
+	Users map[int]*types.User
+}
+
+func (m *UserStore) GetUserByEmail(email string) (*types.User, error) {
+	return nil, fmt.Errorf("user not found")
+}
+
+func (m *UserStore) GetUserById(id int) (*types.User, error) {
+	u, ok := m.Users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return u, nil
+}
+
+func (m *UserStore) CreateUser(u types.User) error {
+	return nil
+}
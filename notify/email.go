@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// defaultTemplate renders the Body of notifications of kinds without their own template.
+const defaultTemplate = "default"
+
+// funcs are the helpers available to the templates.
+var funcs = map[string]any{
+	"money": func(amount float64) string { return utils.FormatCents(utils.ToCents(amount)) },
+}
+
+// emailTemplate holds the text and HTML versions of one kind of email.
+type emailTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// templates maps each notification kind to its email, parsed once from templates/.
+// Every <kind>.txt has a <kind>.html defining "content" inside layout.html.
+var templates = mustParseTemplates()
+
+func mustParseTemplates() map[string]emailTemplate {
+	names, err := templateFS.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	layout := htmltemplate.Must(htmltemplate.New("layout.html").Funcs(funcs).ParseFS(templateFS, "templates/layout.html"))
+
+	parsed := map[string]emailTemplate{}
+	for _, entry := range names {
+		kind, ok := strings.CutSuffix(entry.Name(), ".txt")
+		if !ok {
+			continue
+		}
+		text := texttemplate.Must(texttemplate.New(entry.Name()).Funcs(funcs).ParseFS(templateFS, path.Join("templates", entry.Name())))
+		html := htmltemplate.Must(htmltemplate.Must(layout.Clone()).ParseFS(templateFS, path.Join("templates", kind+".html")))
+		parsed[kind] = emailTemplate{text: text, html: html}
+	}
+	return parsed
+}
+
+// templateData is what the templates are executed with.
+type templateData struct {
+	Subject string         // Subject of the notification
+	Body    string         // Plain text message of the notification
+	SiteURL string         // Address of the store, for links
+	Data    map[string]any // Kind-specific values, e.g. "order" and "user"
+}
+
+// EmailNotifier delivers notifications as emails rendered from the templates of their
+// kind. Each recipient gets their own email so addresses are not disclosed to each other.
+type EmailNotifier struct {
+	mailer  types.Mailer
+	from    string
+	siteURL string
+}
+
+func NewEmailNotifier(mailer types.Mailer, from, siteURL string) *EmailNotifier {
+	return &EmailNotifier{mailer: mailer, from: from, siteURL: siteURL}
+}
+
+func (n *EmailNotifier) Notify(ctx context.Context, notification types.Notification) error {
+	email, err := n.Render(notification)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, to := range notification.Recipients {
+		email.To = []string{to}
+		if err := n.mailer.Send(ctx, email); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send %s email to %s: %w", notification.Kind, to, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Render returns the email for a notification, without recipients.
+func (n *EmailNotifier) Render(notification types.Notification) (types.Email, error) {
+	tmpl, ok := templates[notification.Kind]
+	if !ok {
+		tmpl = templates[defaultTemplate]
+	}
+	data := templateData{Subject: notification.Subject, Body: notification.Body, SiteURL: n.siteURL, Data: notification.Data}
+
+	var text, html strings.Builder
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return types.Email{}, fmt.Errorf("failed to render %s email: %w", notification.Kind, err)
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return types.Email{}, fmt.Errorf("failed to render %s email: %w", notification.Kind, err)
+	}
+	return types.Email{From: n.from, Subject: notification.Subject, Text: text.String(), HTML: html.String()}, nil
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestEmailNotifier(t *testing.T) {
+	mailer := &recordingMailer{}
+	notifier := NewEmailNotifier(mailer, "Store <shop@example.com>", "https://shop.example.com")
+
+	user := types.User{ID: 1, FirstName: "Jane", Email: "jane@example.com"}
+	order := types.Order{
+		ID: 7, Subtotal: 39.98, Discount: 4, ShippingMethod: "Standard", Shipping: 4.5, Tax: 6.83, Total: 47.31,
+		Items: []types.OrderItem{{ProductName: "Mug <XL>", Quantity: 2, Price: 19.99}},
+	}
+	data := map[string]any{
+		"user":     user,
+		"order":    order,
+		"shipment": types.Shipment{Carrier: "UPS", TrackingCode: "1Z999", TrackingURL: "https://ups.example.com/1Z999"},
+		"resetURL": "https://shop.example.com/reset?token=abc",
+	}
+
+	t.Run("should render every kind of email", func(t *testing.T) {
+		for kind := range templates {
+			email, err := notifier.Render(types.Notification{Kind: kind, Subject: "Subject", Body: "Body", Data: data})
+			if err != nil {
+				t.Fatalf("%s: %v", kind, err)
+			}
+			if email.Text == "" || !strings.Contains(email.HTML, "<html>") {
+				t.Errorf("%s: expected a text and an HTML body", kind)
+			}
+			if strings.Contains(email.Text+email.HTML, "<no value>") {
+				t.Errorf("%s: the template uses missing values:\n%s", kind, email.Text)
+			}
+		}
+	})
+
+	t.Run("should fill in the order", func(t *testing.T) {
+		email, err := notifier.Render(types.Notification{Kind: types.NotificationOrderConfirmation, Subject: "Order #7", Data: data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{"Hi Jane,", "order #7", "2 x Mug <XL>: 19.99", "Discount: -4.00", "Shipping (Standard): 4.50", "Total: 47.31"} {
+			if !strings.Contains(email.Text, want) {
+				t.Errorf("expected the text to contain %q:\n%s", want, email.Text)
+			}
+		}
+		if !strings.Contains(email.HTML, "Mug &lt;XL&gt;") {
+			t.Error("expected product names to be escaped in HTML")
+		}
+	})
+
+	t.Run("should fall back to the body for kinds without a template", func(t *testing.T) {
+		email, err := notifier.Render(types.Notification{Kind: types.NotificationLowStock, Body: "Mug is low on stock."})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(email.Text) != "Mug is low on stock." {
+			t.Errorf("unexpected text %q", email.Text)
+		}
+	})
+
+	t.Run("should send each recipient their own email", func(t *testing.T) {
+		err := notifier.Notify(context.Background(), types.Notification{
+			Kind: types.NotificationLowStock, Recipients: []string{"a@example.com", "b@example.com"}, Subject: "Low stock",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mailer.sent) != 2 || mailer.sent[0].To[0] != "a@example.com" || mailer.sent[1].To[0] != "b@example.com" {
+			t.Fatalf("unexpected emails %+v", mailer.sent)
+		}
+		if mailer.sent[0].From != "Store <shop@example.com>" || mailer.sent[0].Subject != "Low stock" {
+			t.Errorf("unexpected email %+v", mailer.sent[0])
+		}
+	})
+}
+
+func TestPreferenceFilter(t *testing.T) {
+	next := &recordingNotifier{}
+	prefs := &mockPreferenceStore{prefs: map[int][]types.NotificationPreference{
+		1: {{Kind: types.NotificationOrderShipped, Email: false}, {Kind: types.NotificationOrderDelivered, Email: true}},
+	}}
+	filter := NewPreferenceFilter(next, prefs)
+
+	for _, n := range []types.Notification{
+		{Kind: types.NotificationOrderShipped, UserID: 1},
+		{Kind: types.NotificationOrderDelivered, UserID: 1},
+		{Kind: types.NotificationOrderShipped, UserID: 2},
+		{Kind: types.NotificationLowStock},
+	} {
+		if err := filter.Notify(context.Background(), n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(next.sent) != 3 || next.sent[0].Kind != types.NotificationOrderDelivered || next.sent[1].UserID != 2 {
+		t.Errorf("expected only the turned off notification to be dropped but got %+v", next.sent)
+	}
+
+	prefs.err = errors.New("database down")
+	if err := filter.Notify(context.Background(), types.Notification{Kind: types.NotificationOrderShipped, UserID: 1}); err == nil {
+		t.Error("expected the error reading preferences")
+	}
+}
+
+type recordingMailer struct {
+	sent []types.Email
+}
+
+func (m *recordingMailer) Send(ctx context.Context, e types.Email) error {
+	m.sent = append(m.sent, e)
+	return nil
+}
+
+type recordingNotifier struct {
+	sent []types.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification types.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+type mockPreferenceStore struct {
+	prefs map[int][]types.NotificationPreference
+	err   error
+}
+
+func (m *mockPreferenceStore) GetNotificationPreferences(userID int) ([]types.NotificationPreference, error) {
+	return m.prefs[userID], m.err
+}
+
+func (m *mockPreferenceStore) UpdateNotificationPreferences(userID int, prefs []types.NotificationPreferencePayload) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// FileMailer writes each email as an .eml file into a directory instead of sending it,
+// so messages can be opened with a mail client during development.
+type FileMailer struct {
+	dir string
+	seq atomic.Int64 // Tells apart emails written in the same nanosecond
+}
+
+// NewFileMailer creates dir if needed and returns a mailer writing into it.
+func NewFileMailer(dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, e types.Email) error {
+	now := time.Now()
+	msg, err := buildMessage(e, now)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d.eml", now.UTC().Format("20060102T150405.000000000"), m.seq.Add(1))
+	return os.WriteFile(filepath.Join(m.dir, name), msg, 0o644)
+}
@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// QueuedMailer hands emails to the job queue instead of sending them, so a slow or
+// unavailable mail server doesn't hold up the caller and failed sends are retried.
+type QueuedMailer struct {
+	queue types.JobQueue
+}
+
+func NewQueuedMailer(queue types.JobQueue) *QueuedMailer {
+	return &QueuedMailer{queue: queue}
+}
+
+func (m *QueuedMailer) Send(ctx context.Context, e types.Email) error {
+	_, err := m.queue.EnqueueJob(types.SendEmailJob{Email: e}, types.JobOptions{})
+	return err
+}
+
+// SendEmail returns the job handler delivering queued emails with mailer.
+func SendEmail(mailer types.Mailer) func(ctx context.Context, job types.SendEmailJob) error {
+	return func(ctx context.Context, job types.SendEmailJob) error {
+		return mailer.Send(ctx, job.Email)
+	}
+}
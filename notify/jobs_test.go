@@ -0,0 +1,38 @@
+package notify
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestQueuedMailer(t *testing.T) {
+	queue := &recordingQueue{}
+	email := types.Email{From: "shop@example.com", To: []string{"jane@example.com"}, Subject: "Hi", Text: "Hello"}
+
+	if err := NewQueuedMailer(queue).Send(context.Background(), email); err != nil {
+		t.Fatal(err)
+	}
+	if len(queue.jobs) != 1 {
+		t.Fatalf("expected one job but got %d", len(queue.jobs))
+	}
+
+	mailer := &recordingMailer{}
+	if err := SendEmail(mailer)(context.Background(), queue.jobs[0].(types.SendEmailJob)); err != nil {
+		t.Fatal(err)
+	}
+	if len(mailer.sent) != 1 || !reflect.DeepEqual(mailer.sent[0], email) {
+		t.Errorf("expected the queued email to be sent but got %+v", mailer.sent)
+	}
+}
+
+type recordingQueue struct {
+	jobs []types.JobPayload
+}
+
+func (q *recordingQueue) EnqueueJob(payload types.JobPayload, opts types.JobOptions) (int, error) {
+	q.jobs = append(q.jobs, payload)
+	return len(q.jobs), nil
+}
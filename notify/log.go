@@ -0,0 +1,22 @@
+package notify
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// LogNotifier writes notifications to the application log instead of delivering them.
+// It is the default when no delivery channel is configured.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification types.Notification) error {
+	log.Printf("notification %s to %s: %s", notification.Kind, strings.Join(notification.Recipients, ", "), notification.Subject)
+	return nil
+}
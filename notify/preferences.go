@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// PreferenceFilter drops notifications their user turned off before passing the others on.
+// Notifications without a user, like staff alerts, are always passed on.
+type PreferenceFilter struct {
+	next  types.Notifier
+	prefs types.NotificationPreferenceStore
+}
+
+func NewPreferenceFilter(next types.Notifier, prefs types.NotificationPreferenceStore) *PreferenceFilter {
+	return &PreferenceFilter{next: next, prefs: prefs}
+}
+
+func (f *PreferenceFilter) Notify(ctx context.Context, n types.Notification) error {
+	if n.UserID != 0 {
+		prefs, err := f.prefs.GetNotificationPreferences(n.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to read notification preferences: %w", err)
+		}
+		for _, p := range prefs {
+			if p.Kind == n.Kind && !p.Email {
+				return nil
+			}
+		}
+	}
+	return f.next.Notify(ctx, n)
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// SMTPConfig holds the address and credentials of a mail server.
+type SMTPConfig struct {
+	Host     string // Host name of the server, also used to verify its certificate
+	Port     string // Port, usually 587 for submission with STARTTLS
+	Username string // Username, empty to send without authentication
+	Password string // Password of the user
+}
+
+// SMTPMailer sends emails through a mail server. It opens one connection per email and
+// upgrades it with STARTTLS whenever the server offers it.
+type SMTPMailer struct {
+	config SMTPConfig
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{config: config}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, e types.Email) error {
+	msg, err := buildMessage(e, time.Now())
+	if err != nil {
+		return err
+	}
+	from, _ := mail.ParseAddress(e.From)
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.config.Host, m.config.Port))
+	if err != nil {
+		return fmt.Errorf("failed to connect to mail server: %w", err)
+	}
+	// Bound the whole conversation by the context, as net/smtp does not take one
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: m.config.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	for _, addr := range e.To {
+		to, err := mail.ParseAddress(addr)
+		if err != nil {
+			return err
+		}
+		if err := client.Rcpt(to.Address); err != nil {
+			return fmt.Errorf("recipient %s refused: %w", to.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// smtpStandIn is a minimal SMTP server accepting every message it is sent.
+type smtpStandIn struct {
+	listener net.Listener
+	rcpts    []string
+	messages chan string
+}
+
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStandIn{listener: listener, messages: make(chan string, 1)}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpStandIn) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "DATA"):
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var msg strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				l = strings.TrimPrefix(l, ".")
+				msg.WriteString(l)
+			}
+			s.messages <- msg.String()
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "QUIT"):
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	server := startSMTPStandIn(t)
+	host, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	mailer := NewSMTPMailer(SMTPConfig{Host: host, Port: port})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := mailer.Send(ctx, types.Email{
+		From:    "Store <shop@example.com>",
+		To:      []string{"jane@example.com"},
+		Subject: "Your order is on its way – #7",
+		Text:    "Hi Jane,\n.\nBye",
+		HTML:    "<p>Hi Jane</p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw := <-server.messages
+	if len(server.rcpts) != 1 || server.rcpts[0] != "jane@example.com" {
+		t.Errorf("unexpected recipients %v", server.rcpts)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatalf("failed to parse the message: %v", err)
+	}
+	from, _ := mail.ParseAddress(msg.Header.Get("From"))
+	if from == nil || from.Address != "shop@example.com" {
+		t.Errorf("unexpected sender %q", msg.Header.Get("From"))
+	}
+	decoded, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if decoded != "Your order is on its way – #7" {
+		t.Errorf("unexpected subject %q", decoded)
+	}
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/alternative") {
+		t.Errorf("expected a text and an HTML part but got %q", msg.Header.Get("Content-Type"))
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if !strings.Contains(string(body), "Hi Jane,\r\n.\r\nBye") || !strings.Contains(string(body), "<p>Hi Jane</p>") {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := NewFileMailer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		err := mailer.Send(context.Background(), types.Email{From: "shop@example.com", To: []string{"jane@example.com"}, Subject: "Hello", Text: "Hi"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 2 || filepath.Ext(files[0].Name()) != ".eml" {
+		t.Fatalf("expected two .eml files but got %v", files)
+	}
+	raw, _ := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("To") != "<jane@example.com>" || msg.Header.Get("Subject") != "Hello" {
+		t.Errorf("unexpected headers %v", msg.Header)
+	}
+}
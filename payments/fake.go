@@ -0,0 +1,192 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// Card numbers understood by FakeProvider. Any other payment method is declined as invalid.
+const (
+	FakeCardSuccess           = "4242424242424242" // Always authorized
+	FakeCardDeclined          = "4000000000000002" // Always declined with "card_declined"
+	FakeCardInsufficientFunds = "4000000000009995" // Always declined with "insufficient_funds"
+)
+
+// FakeSignatureHeader carries the hex HMAC-SHA256 of a FakeProvider webhook body.
+const FakeSignatureHeader = "Fake-Signature"
+
+var (
+	ErrUnknownIntent    = errors.New("unknown payment intent")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrIntentState      = errors.New("payment intent is not in a state that allows this")
+)
+
+// FakeProvider is a PaymentProvider that runs in memory with deterministic card numbers,
+// so the whole payment flow works offline and in tests. Intents are lost on restart.
+type FakeProvider struct {
+	secret  []byte // Key used to sign webhooks
+	mu      sync.Mutex
+	intents map[string]*types.PaymentIntent
+	refunds map[string]string // Intent IDs by the idempotency key of their refunds
+	seq     int
+}
+
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{secret: []byte(webhookSecret), intents: map[string]*types.PaymentIntent{}, refunds: map[string]string{}}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, req types.PaymentIntentRequest) (*types.PaymentIntent, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	intent := &types.PaymentIntent{
+		ID:       fmt.Sprintf("pi_fake_%d_%d", req.OrderID, p.seq),
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Status:   types.PaymentRequiresConfirmation,
+	}
+	p.intents[intent.ID] = intent
+	return copyIntent(intent), nil
+}
+
+// Confirm may be retried after a decline, like with real gateways.
+func (p *FakeProvider) Confirm(ctx context.Context, intentID string, paymentMethod string) (*types.PaymentIntent, error) {
+	return p.update(intentID, func(intent *types.PaymentIntent) error {
+		if intent.Status != types.PaymentRequiresConfirmation && intent.Status != types.PaymentFailed {
+			return ErrIntentState
+		}
+
+		intent.Status, intent.FailureReason = types.PaymentFailed, ""
+		switch paymentMethod {
+		case FakeCardSuccess:
+			intent.Status = types.PaymentAuthorized
+		case FakeCardDeclined:
+			intent.FailureReason = "card_declined"
+		case FakeCardInsufficientFunds:
+			intent.FailureReason = "insufficient_funds"
+		default:
+			intent.FailureReason = "invalid_payment_method"
+		}
+		return nil
+	})
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, intentID string, amount int64) (*types.PaymentIntent, error) {
+	return p.update(intentID, func(intent *types.PaymentIntent) error {
+		if intent.Status != types.PaymentAuthorized || amount <= 0 || amount > intent.Amount {
+			return ErrIntentState
+		}
+		intent.Captured, intent.Status = amount, types.PaymentCaptured
+		return nil
+	})
+}
+
+// Refund remembers idempotency keys for good, like gateways do for at least a day.
+func (p *FakeProvider) Refund(ctx context.Context, intentID string, amount int64, idempotencyKey string) (*types.PaymentIntent, error) {
+	return p.update(intentID, func(intent *types.PaymentIntent) error {
+		if refunded, ok := p.refunds[idempotencyKey]; idempotencyKey != "" && ok {
+			if refunded != intentID {
+				return fmt.Errorf("idempotency key %q was used for another intent", idempotencyKey)
+			}
+			return nil
+		}
+		if intent.Status != types.PaymentCaptured || amount <= 0 || intent.Refunded+amount > intent.Captured {
+			return ErrIntentState
+		}
+		if idempotencyKey != "" {
+			p.refunds[idempotencyKey] = intentID
+		}
+		if intent.Refunded += amount; intent.Refunded == intent.Captured {
+			intent.Status = types.PaymentRefunded
+		}
+		return nil
+	})
+}
+
+func (p *FakeProvider) Cancel(ctx context.Context, intentID string) (*types.PaymentIntent, error) {
+	return p.update(intentID, func(intent *types.PaymentIntent) error {
+		if intent.Status != types.PaymentAuthorized && intent.Status != types.PaymentCancelled {
+			return ErrIntentState
+		}
+		intent.Status = types.PaymentCancelled
+		return nil
+	})
+}
+
+func (p *FakeProvider) VerifyWebhook(payload []byte, header http.Header) (*types.PaymentEvent, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.mac(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event types.PaymentEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	return &event, nil
+}
+
+// Event builds the signed webhook the gateway would send for the current state of an
+// intent. It returns the body and the value of the FakeSignatureHeader header.
+func (p *FakeProvider) Event(eventID, intentID string) ([]byte, string, error) {
+	p.mu.Lock()
+	intent, ok := p.intents[intentID]
+	var event types.PaymentEvent
+	if ok {
+		event = types.PaymentEvent{ID: eventID, Type: "payment." + intent.Status, Intent: *intent}
+	}
+	p.mu.Unlock()
+	if !ok {
+		return nil, "", ErrUnknownIntent
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, "", err
+	}
+	return payload, hex.EncodeToString(p.mac(payload)), nil
+}
+
+// update applies fn to an intent under the lock and returns a copy of the result.
+func (p *FakeProvider) update(intentID string, fn func(*types.PaymentIntent) error) (*types.PaymentIntent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	intent, ok := p.intents[intentID]
+	if !ok {
+		return nil, ErrUnknownIntent
+	}
+	if err := fn(intent); err != nil {
+		return nil, err
+	}
+	return copyIntent(intent), nil
+}
+
+func (p *FakeProvider) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func copyIntent(intent *types.PaymentIntent) *types.PaymentIntent {
+	c := *intent
+	return &c
+}
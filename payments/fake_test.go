@@ -0,0 +1,110 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+var _ types.PaymentProvider = (*FakeProvider)(nil)
+
+func TestFakeProvider(t *testing.T) {
+	ctx := context.Background()
+	provider := NewFakeProvider("whsec")
+
+	newIntent := func(t *testing.T) *types.PaymentIntent {
+		intent, err := provider.CreateIntent(ctx, types.PaymentIntentRequest{OrderID: 1, Amount: 4018, Currency: "usd"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return intent
+	}
+
+	t.Run("should decline the failure cards deterministically", func(t *testing.T) {
+		for card, reason := range map[string]string{
+			FakeCardDeclined:          "card_declined",
+			FakeCardInsufficientFunds: "insufficient_funds",
+			"1234":                    "invalid_payment_method",
+		} {
+			intent, err := provider.Confirm(ctx, newIntent(t).ID, card)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if intent.Status != types.PaymentFailed || intent.FailureReason != reason {
+				t.Errorf("card %s: expected a failure with %q but got %+v", card, reason, intent)
+			}
+		}
+	})
+
+	t.Run("should authorize, capture and refund the success card", func(t *testing.T) {
+		id := newIntent(t).ID
+		if _, err := provider.Confirm(ctx, id, FakeCardDeclined); err != nil {
+			t.Fatal(err)
+		}
+		intent, err := provider.Confirm(ctx, id, FakeCardSuccess)
+		if err != nil || intent.Status != types.PaymentAuthorized || intent.FailureReason != "" {
+			t.Fatalf("expected the retry to be authorized but got %+v (%v)", intent, err)
+		}
+
+		if _, err := provider.Refund(ctx, id, 100, ""); !errors.Is(err, ErrIntentState) {
+			t.Errorf("expected refunds before capture to fail but got %v", err)
+		}
+		if intent, err = provider.Capture(ctx, id, 4018); err != nil || intent.Status != types.PaymentCaptured {
+			t.Fatalf("unexpected capture %+v (%v)", intent, err)
+		}
+		for i := 0; i < 2; i++ {
+			if intent, err = provider.Refund(ctx, id, 18, "refund-1"); err != nil || intent.Status != types.PaymentCaptured || intent.Refunded != 18 {
+				t.Fatalf("expected a retried partial refund to refund once but got %+v (%v)", intent, err)
+			}
+		}
+		if _, err := provider.Refund(ctx, id, 4001, ""); !errors.Is(err, ErrIntentState) {
+			t.Errorf("expected refunding more than captured to fail but got %v", err)
+		}
+		if intent, err = provider.Refund(ctx, id, 4000, "refund-2"); err != nil || intent.Status != types.PaymentRefunded {
+			t.Errorf("expected the payment to be fully refunded but got %+v (%v)", intent, err)
+		}
+	})
+
+	t.Run("should release a cancelled authorization", func(t *testing.T) {
+		id := newIntent(t).ID
+		if _, err := provider.Cancel(ctx, id); !errors.Is(err, ErrIntentState) {
+			t.Errorf("expected cancelling before authorization to fail but got %v", err)
+		}
+		if _, err := provider.Confirm(ctx, id, FakeCardSuccess); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 2; i++ {
+			if intent, err := provider.Cancel(ctx, id); err != nil || intent.Status != types.PaymentCancelled {
+				t.Fatalf("expected the intent to be cancelled but got %+v (%v)", intent, err)
+			}
+		}
+		if _, err := provider.Capture(ctx, id, 4018); !errors.Is(err, ErrIntentState) {
+			t.Errorf("expected capturing a cancelled intent to fail but got %v", err)
+		}
+	})
+
+	t.Run("should sign and verify webhooks", func(t *testing.T) {
+		id := newIntent(t).ID
+		payload, signature, err := provider.Event("evt_1", id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		header := http.Header{}
+		header.Set(FakeSignatureHeader, signature)
+		event, err := provider.VerifyWebhook(payload, header)
+		if err != nil || event.ID != "evt_1" || event.Type != "payment.requires_confirmation" || event.Intent.ID != id {
+			t.Errorf("unexpected event %+v (%v)", event, err)
+		}
+
+		if _, err := NewFakeProvider("other").VerifyWebhook(payload, header); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected a signature from another secret to be rejected but got %v", err)
+		}
+		if _, err := provider.VerifyWebhook(append(payload, ' '), header); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("expected a tampered payload to be rejected but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+// Package pdf writes simple PDF documents made of text and lines, enough for invoices and
+// receipts, without any external tool.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// A4 page size in points, 1/72 of an inch.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Document is a PDF being built page by page.
+type Document struct {
+	Title   string    // Shown by readers instead of the file name
+	Author  string    // The issuer of the document
+	Created time.Time // The creation date recorded in the document, zero to leave it out
+	pages   []*Page
+}
+
+// Page is one page of a document. Coordinates are in points from the top left corner, so
+// layouts can move down the page with a growing y.
+type Page struct {
+	content bytes.Buffer
+}
+
+// New returns an empty document.
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage appends an A4 page to the document and returns it.
+func (d *Document) AddPage() *Page {
+	p := &Page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Text writes s with its baseline starting at x, y.
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td ", font+1, num(size), num(x), num(PageHeight-y))
+	p.content.Write(literal(s))
+	p.content.WriteString(" Tj ET\n")
+}
+
+// TextRight writes s with its baseline ending at x, y, e.g. for amounts in a column.
+func (p *Page) TextRight(x, y float64, font Font, size float64, s string) {
+	p.Text(x-Width(font, size, s), y, font, size, s)
+}
+
+// Line draws a black line from x1, y1 to x2, y2.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// WriteTo writes the document to w. A document without pages gets an empty one, since a
+// PDF needs at least one.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pages := d.pages
+	if len(pages) == 0 {
+		pages = []*Page{{}}
+	}
+
+	// Objects 1 and 2 are the catalog and page tree, then come the fonts, the info
+	// dictionary and a page and content stream per page.
+	fontObj := 3
+	infoObj := fontObj + len(baseFonts)
+	firstPageObj := infoObj + 1
+
+	var buf bytes.Buffer
+	offsets := []int{}
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := &bytes.Buffer{}
+	for i := range pages {
+		fmt.Fprintf(kids, "%d 0 R ", firstPageObj+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(pages)))
+
+	for _, name := range baseFonts {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+
+	info := &bytes.Buffer{}
+	info.WriteString("<< /Producer (go-backend)")
+	if d.Title != "" {
+		fmt.Fprintf(info, " /Title %s", literal(d.Title))
+	}
+	if d.Author != "" {
+		fmt.Fprintf(info, " /Author %s", literal(d.Author))
+	}
+	if !d.Created.IsZero() {
+		fmt.Fprintf(info, " /CreationDate (D:%s)", d.Created.UTC().Format("20060102150405Z"))
+	}
+	info.WriteString(" >>")
+	object(info.String())
+
+	fonts := &bytes.Buffer{}
+	for i := range baseFonts {
+		fmt.Fprintf(fonts, "/F%d %d 0 R ", i+1, fontObj+i)
+	}
+	for i, p := range pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s>> >> /Contents %d 0 R >>",
+			num(PageWidth), num(PageHeight), fonts, firstPageObj+2*i+1,
+		))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, infoObj, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// Bytes returns the document as written by WriteTo.
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// literal returns s as a PDF string literal, escaping the characters with a meaning
+// inside parentheses.
+func literal(s string) []byte {
+	out := []byte{'('}
+	for _, b := range encode(s) {
+		switch b {
+		case '(', ')', '\\':
+			out = append(out, '\\', b)
+		case '\n', '\r':
+			out = append(out, ' ')
+		default:
+			out = append(out, b)
+		}
+	}
+	return append(out, ')')
+}
+
+// num formats a coordinate or size with at most two decimals, as PDF readers expect plain
+// decimal numbers.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,74 @@
+package pdf
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDocument(t *testing.T) {
+	doc := New()
+	doc.Title = "Invoice (draft)"
+	doc.Created = time.Date(2025, 1, 2, 9, 30, 0, 0, time.UTC)
+	page := doc.AddPage()
+	page.Text(50, 60, HelveticaBold, 18, "Invoice")
+	page.TextRight(545, 60, Helvetica, 10, `Caf\é (1) 5€`)
+	page.Line(50, 70, 545, 70, 0.5)
+	doc.AddPage().Text(50, 60, Helvetica, 10, "Page 2")
+
+	out := doc.Bytes()
+
+	t.Run("should write a well-formed file", func(t *testing.T) {
+		if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+			t.Fatalf("missing header or trailer in %q", out)
+		}
+		if !bytes.Contains(out, []byte("/Count 2")) {
+			t.Error("expected two pages")
+		}
+	})
+
+	t.Run("should point the cross-reference table at every object", func(t *testing.T) {
+		start := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+		if start == nil {
+			t.Fatal("missing startxref")
+		}
+		xref, _ := strconv.Atoi(string(start[1]))
+		if !bytes.HasPrefix(out[xref:], []byte("xref\n")) {
+			t.Fatalf("startxref %d does not point at the xref table", xref)
+		}
+
+		entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+		if len(entries) != 9 {
+			t.Fatalf("expected 9 objects but got %d", len(entries))
+		}
+		for i, entry := range entries {
+			offset, _ := strconv.Atoi(string(entry[1]))
+			if want := strconv.Itoa(i+1) + " 0 obj\n"; !bytes.HasPrefix(out[offset:], []byte(want)) {
+				t.Errorf("object %d: offset %d points at %q", i+1, offset, out[offset:offset+10])
+			}
+		}
+	})
+
+	t.Run("should escape and encode text", func(t *testing.T) {
+		if !bytes.Contains(out, []byte("(Invoice \\(draft\\))")) {
+			t.Error("expected parentheses in the title to be escaped")
+		}
+		if !bytes.Contains(out, []byte("(Caf\\\\\xe9 \\(1\\) 5\x80) Tj")) {
+			t.Errorf("expected the text in WinAnsiEncoding but got %q", out)
+		}
+		if !bytes.Contains(out, []byte("/CreationDate (D:20250102093000Z)")) {
+			t.Error("expected the creation date")
+		}
+	})
+}
+
+func TestWidth(t *testing.T) {
+	if w := Width(Helvetica, 10, "Total"); w != 22.23 {
+		t.Errorf("expected 22.23 but got %v", w)
+	}
+	if Width(HelveticaBold, 10, "Total") <= Width(Helvetica, 10, "Total") {
+		t.Error("expected bold text to be wider")
+	}
+}
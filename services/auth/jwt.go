@@ -1,13 +1,29 @@
 package auth
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
 	"github.com/golang-jwt/jwt/v5"
 )
 
+type contextKey string
+
+// UserKey and RoleKey are the context keys under which WithJWTAuth stores the
+// authenticated user's ID and role.
+const (
+	UserKey contextKey = "userID"
+	RoleKey contextKey = "userRole"
+)
+
 func CreateJWT(secret []byte, userId int) (string, error) {
 	expiration := time.Second * time.Duration(configs.Envs.JWTExpirationInSeconds)
 
@@ -16,10 +32,120 @@ func CreateJWT(secret []byte, userId int) (string, error) {
 		"expiredAt": time.Now().Add(expiration).Unix(),
 	})
 
-	tokenString, err := token.SignedString(secret) 
+	tokenString, err := token.SignedString(secret)
 	if err != nil {
 		return "", err
 	}
 
 	return tokenString, nil
-}
\ No newline at end of file
+}
+
+// WithJWTAuth wraps a handler so that it only runs for requests carrying a valid token.
+// The authenticated user's ID and role are added to the request context.
+func WithJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Step 1: Validate the token sent by the client
+		userID, err := userIDFromToken(getTokenFromRequest(r))
+		if err != nil {
+			log.Printf("failed to validate token: %v", err)
+			permissionDenied(w)
+			return
+		}
+
+		// Step 2: Make sure the user still exists
+		u, err := store.GetUserById(userID)
+		if err != nil {
+			log.Printf("failed to get user by id: %v", err)
+			permissionDenied(w)
+			return
+		}
+
+		// Step 3: Store the user in the request context and call the wrapped handler
+		ctx := context.WithValue(r.Context(), UserKey, u.ID)
+		ctx = context.WithValue(ctx, RoleKey, u.Role)
+		handlerFunc(w, r.WithContext(ctx))
+	}
+}
+
+// WithOptionalJWTAuth is like WithJWTAuth but also lets anonymous requests through.
+// Requests without a token reach the handler with no user in the context, while a token
+// that is invalid is still rejected.
+func WithOptionalJWTAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	authenticated := WithJWTAuth(handlerFunc, store)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if getTokenFromRequest(r) == "" {
+			handlerFunc(w, r)
+			return
+		}
+		authenticated(w, r)
+	}
+}
+
+// WithAdminAuth is like WithJWTAuth but additionally requires the user to be an admin.
+func WithAdminAuth(handlerFunc http.HandlerFunc, store types.UserStore) http.HandlerFunc {
+	return WithJWTAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r.Context()) {
+			utils.WriteError(w, http.StatusForbidden, fmt.Errorf("admin access required"))
+			return
+		}
+		handlerFunc(w, r)
+	}, store)
+}
+
+// GetUserIDFromContext returns the ID stored by WithJWTAuth, or -1 if there is none.
+func GetUserIDFromContext(ctx context.Context) int {
+	userID, ok := ctx.Value(UserKey).(int)
+	if !ok {
+		return -1
+	}
+	return userID
+}
+
+// IsAdmin reports whether the authenticated user stored in ctx is an admin.
+func IsAdmin(ctx context.Context) bool {
+	role, _ := ctx.Value(RoleKey).(string)
+	return role == types.RoleAdmin
+}
+
+// getTokenFromRequest reads the token from the Authorization header,
+// accepting both "Bearer <token>" and a bare token.
+func getTokenFromRequest(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// userIDFromToken validates the signature and expiry of a token and returns its user ID.
+func userIDFromToken(tokenString string) (int, error) {
+	if tokenString == "" {
+		return 0, fmt.Errorf("missing token")
+	}
+
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (any, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(configs.Envs.JWTSecret), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return 0, fmt.Errorf("invalid token")
+	}
+
+	expiredAt, ok := claims["expiredAt"].(float64)
+	if !ok || time.Now().Unix() > int64(expiredAt) {
+		return 0, fmt.Errorf("token expired")
+	}
+
+	userID, ok := claims["userId"].(string)
+	if !ok {
+		return 0, fmt.Errorf("invalid userId claim")
+	}
+	return strconv.Atoi(userID)
+}
+
+func permissionDenied(w http.ResponseWriter) {
+	utils.WriteError(w, http.StatusForbidden, fmt.Errorf("permission denied"))
+}
@@ -1,7 +1,13 @@
 package auth
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+
+	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/types"
 )
 
 func TestCreateJWT(t *testing.T) {
@@ -15,4 +21,100 @@ func TestCreateJWT(t *testing.T) {
 	if token == "" {
 		t.Error("expected token to be not empty")
 	}
-}
\ No newline at end of file
+}
+func TestWithJWTAuth(t *testing.T) {
+	store := &mockUserStore{users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleAdmin},
+		2: {ID: 2, Role: types.RoleCustomer},
+	}}
+
+	handler := WithJWTAuth(func(w http.ResponseWriter, r *http.Request) {
+		if GetUserIDFromContext(r.Context()) != 2 {
+			t.Errorf("expected user 2 in the context")
+		}
+		w.WriteHeader(http.StatusOK)
+	}, store)
+
+	t.Run("should reject requests without a token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should reject tokens signed with another secret", func(t *testing.T) {
+		token, _ := CreateJWT([]byte("not-the-secret"), 2)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should pass the user to the handler", func(t *testing.T) {
+		token, _ := CreateJWT([]byte(configs.Envs.JWTSecret), 2)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should reject customers on admin routes", func(t *testing.T) {
+		admin := WithAdminAuth(func(w http.ResponseWriter, r *http.Request) {}, store)
+		token, _ := CreateJWT([]byte(configs.Envs.JWTSecret), 2)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		admin(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should let anonymous requests through optional auth", func(t *testing.T) {
+		optional := WithOptionalJWTAuth(func(w http.ResponseWriter, r *http.Request) {
+			if GetUserIDFromContext(r.Context()) != -1 {
+				t.Errorf("expected no user in the context")
+			}
+		}, store)
+		rr := httptest.NewRecorder()
+		optional(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer not-a-token")
+		rr = httptest.NewRecorder()
+		optional(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d for an invalid token but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+}
+
+type mockUserStore struct {
+	users map[int]*types.User
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
+	return nil, fmt.Errorf("user not found")
+}
+
+func (m *mockUserStore) GetUserById(id int) (*types.User, error) {
+	u, ok := m.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user not found")
+	}
+	return u, nil
+}
+
+func (m *mockUserStore) CreateUser(u types.User) error {
+	return nil
+}
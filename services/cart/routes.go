@@ -0,0 +1,271 @@
+package cart
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+// CookieName is the cookie holding the token of an anonymous cart.
+const CookieName = "cart_token"
+
+// cookieMaxAge keeps anonymous carts for 30 days after they were last changed.
+const cookieMaxAge = 30 * 24 * 60 * 60
+
+type Handler struct {
+	store     types.CartStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.CartStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+// RegisterRoutes registers the cart routes. They work with and without a token: logged-in
+// users get their own cart and visitors an anonymous one identified by a cookie.
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/cart", auth.WithOptionalJWTAuth(h.handleGetCart, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/cart/items", auth.WithOptionalJWTAuth(h.handleAddItem, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/cart/items/{productID:[0-9]+}", auth.WithOptionalJWTAuth(h.handleUpdateItem, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/cart/items/{productID:[0-9]+}", auth.WithOptionalJWTAuth(h.handleRemoveItem, h.userStore)).Methods(http.MethodDelete)
+}
+
+func (h *Handler) handleGetCart(w http.ResponseWriter, r *http.Request) {
+	cartID, ok := h.resolveCart(w, r, false)
+	if !ok {
+		return
+	}
+
+	h.writeCart(w, http.StatusOK, cartID)
+}
+
+func (h *Handler) handleAddItem(w http.ResponseWriter, r *http.Request) {
+	var payload types.AddCartItemPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	cartID, ok := h.resolveCart(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := h.store.AddCartItem(cartID, payload.ProductID, payload.Quantity); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.writeCart(w, http.StatusCreated, cartID)
+}
+
+func (h *Handler) handleUpdateItem(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["productID"])
+
+	var payload types.UpdateCartItemPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	cartID, ok := h.resolveCart(w, r, false)
+	if !ok {
+		return
+	}
+	if cartID == 0 {
+		writeStoreError(w, ErrItemNotFound)
+		return
+	}
+
+	if err := h.store.SetCartItemQuantity(cartID, productID, payload.Quantity); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.writeCart(w, http.StatusOK, cartID)
+}
+
+func (h *Handler) handleRemoveItem(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["productID"])
+
+	cartID, ok := h.resolveCart(w, r, false)
+	if !ok {
+		return
+	}
+	if cartID == 0 {
+		writeStoreError(w, ErrItemNotFound)
+		return
+	}
+
+	if err := h.store.RemoveCartItem(cartID, productID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.writeCart(w, http.StatusOK, cartID)
+}
+
+// resolveCart returns the ID of the cart the request works on, or 0 when a visitor has no
+// cart yet and create is false.
+//
+// The first request a user makes after logging in with an anonymous cart cookie merges that
+// cart into theirs and clears the cookie, so items added before logging in are kept.
+func (h *Handler) resolveCart(w http.ResponseWriter, r *http.Request, create bool) (int, bool) {
+	var token string
+	if cookie, err := r.Cookie(CookieName); err == nil {
+		token = cookie.Value
+	}
+
+	if userID := auth.GetUserIDFromContext(r.Context()); userID > 0 {
+		cartID, err := h.store.GetUserCartID(userID)
+		if err != nil {
+			writeStoreError(w, err)
+			return 0, false
+		}
+		if token == "" {
+			return cartID, true
+		}
+
+		guestID, err := h.store.GetGuestCartID(token)
+		if err == nil {
+			err = h.store.MergeCarts(guestID, cartID)
+		}
+		if err != nil && !errors.Is(err, ErrCartNotFound) {
+			writeStoreError(w, err)
+			return 0, false
+		}
+		http.SetCookie(w, &http.Cookie{Name: CookieName, Path: "/", MaxAge: -1, HttpOnly: true})
+		return cartID, true
+	}
+
+	if token != "" {
+		cartID, err := h.store.GetGuestCartID(token)
+		if err == nil {
+			setCartCookie(w, r, token)
+			return cartID, true
+		}
+		if !errors.Is(err, ErrCartNotFound) {
+			writeStoreError(w, err)
+			return 0, false
+		}
+	}
+	if !create {
+		return 0, true
+	}
+
+	token, err := newCartToken()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return 0, false
+	}
+	cartID, err := h.store.CreateGuestCart(token)
+	if err != nil {
+		writeStoreError(w, err)
+		return 0, false
+	}
+	setCartCookie(w, r, token)
+	return cartID, true
+}
+
+// writeCart revalidates the cart against the current prices and stock and writes it.
+// Price changes are reported once and then remembered.
+func (h *Handler) writeCart(w http.ResponseWriter, status int, cartID int) {
+	items := []types.CartItem{}
+	if cartID != 0 {
+		var err error
+		if items, err = h.store.GetCartItems(cartID); err != nil {
+			writeStoreError(w, err)
+			return
+		}
+	}
+
+	cart := buildCart(items)
+	for _, item := range cart.Items {
+		if item.PreviousPrice != nil {
+			if err := h.store.AcknowledgePrices(cartID); err != nil {
+				writeStoreError(w, err)
+				return
+			}
+			break
+		}
+	}
+
+	utils.WriteJSON(w, status, cart)
+}
+
+// buildCart computes the line totals, subtotal and stock problems of the items.
+func buildCart(items []types.CartItem) types.Cart {
+	cart := types.Cart{Items: items, CheckoutReady: len(items) > 0}
+	for i := range cart.Items {
+		item := &cart.Items[i]
+		item.LineTotal = roundCents(item.Price * float64(item.Quantity))
+		switch {
+		case item.Available <= 0:
+			item.Problem = types.CartItemUnavailable
+		case item.Quantity > item.Available:
+			item.Problem = types.CartItemInsufficientStock
+		}
+		if item.Problem != "" {
+			cart.CheckoutReady = false
+		}
+
+		cart.ItemCount += item.Quantity
+		cart.Subtotal += item.LineTotal
+	}
+	cart.Subtotal = roundCents(cart.Subtotal)
+	return cart
+}
+
+// roundCents rounds an amount to whole cents.
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func setCartCookie(w http.ResponseWriter, r *http.Request, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   cookieMaxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// newCartToken returns 32 random bytes, hex encoded, so anonymous carts cannot be guessed.
+func newCartToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrItemNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrInsufficientStock):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,260 @@
+package cart
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestCartHandlers(t *testing.T) {
+	store := newMockCartStore(map[int]*mockProduct{
+		1: {price: 10.10, available: 5},
+		2: {price: 2.50, available: 1},
+	})
+	userStore := &testutil.UserStore{Users: map[int]*types.User{1: {ID: 1, Role: types.RoleCustomer}}}
+
+	handler := NewHandler(store, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, cookie *http.Cookie, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if userID != 0 {
+			req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder) types.Cart {
+		var cart types.Cart
+		json.NewDecoder(rr.Body).Decode(&cart)
+		return cart
+	}
+
+	var guest *http.Cookie
+
+	t.Run("should give visitors an anonymous cart", func(t *testing.T) {
+		rr := send(http.MethodPost, "/cart/items", 0, nil, types.AddCartItemPayload{ProductID: 1, Quantity: 3})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		for _, c := range rr.Result().Cookies() {
+			if c.Name == CookieName {
+				guest = c
+			}
+		}
+		if guest == nil || len(guest.Value) != 64 || !guest.HttpOnly {
+			t.Fatalf("expected an anonymous cart cookie but got %v", guest)
+		}
+
+		cart := decode(send(http.MethodGet, "/cart", 0, guest, nil))
+		if cart.ItemCount != 3 || cart.Subtotal != 30.30 || !cart.CheckoutReady {
+			t.Errorf("unexpected cart %+v", cart)
+		}
+	})
+
+	t.Run("should not add more than is available", func(t *testing.T) {
+		rr := send(http.MethodPost, "/cart/items", 0, guest, types.AddCartItemPayload{ProductID: 1, Quantity: 3})
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should report price and stock changes on read", func(t *testing.T) {
+		store.products[1].price = 9.00
+		store.products[1].available = 2
+
+		cart := decode(send(http.MethodGet, "/cart", 0, guest, nil))
+		item := cart.Items[0]
+		if item.PreviousPrice == nil || *item.PreviousPrice != 10.10 || item.Problem != types.CartItemInsufficientStock || cart.CheckoutReady {
+			t.Errorf("unexpected cart %+v", cart)
+		}
+		if cart.Subtotal != 27 {
+			t.Errorf("expected the subtotal at the current price but got %v", cart.Subtotal)
+		}
+
+		cart = decode(send(http.MethodGet, "/cart", 0, guest, nil))
+		if cart.Items[0].PreviousPrice != nil {
+			t.Errorf("expected the price change to be reported once")
+		}
+	})
+
+	t.Run("should merge the anonymous cart on login", func(t *testing.T) {
+		store.products[1].available = 5
+		send(http.MethodPost, "/cart/items", 1, nil, types.AddCartItemPayload{ProductID: 2, Quantity: 1})
+
+		rr := send(http.MethodGet, "/cart", 1, guest, nil)
+		cart := decode(rr)
+		if len(cart.Items) != 2 || cart.ItemCount != 4 {
+			t.Errorf("expected both carts to be merged but got %+v", cart)
+		}
+		cleared := false
+		for _, c := range rr.Result().Cookies() {
+			cleared = cleared || (c.Name == CookieName && c.MaxAge < 0)
+		}
+		if !cleared {
+			t.Errorf("expected the anonymous cart cookie to be cleared")
+		}
+		if _, err := store.GetGuestCartID(guest.Value); err == nil {
+			t.Errorf("expected the anonymous cart to be deleted")
+		}
+	})
+
+	t.Run("should update and remove items", func(t *testing.T) {
+		rr := send(http.MethodPut, "/cart/items/1", 1, nil, types.UpdateCartItemPayload{Quantity: 1})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		rr = send(http.MethodDelete, "/cart/items/2", 1, nil, nil)
+		cart := decode(rr)
+		if len(cart.Items) != 1 || cart.ItemCount != 1 {
+			t.Errorf("unexpected cart %+v", cart)
+		}
+
+		rr = send(http.MethodDelete, "/cart/items/2", 1, nil, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
+type mockProduct struct {
+	price     float64
+	available int
+}
+
+type mockLine struct {
+	productID, quantity int
+	shownPrice          float64
+}
+
+type mockCartStore struct {
+	products map[int]*mockProduct
+	userCart map[int]int
+	guests   map[string]int
+	lines    map[int][]*mockLine
+	nextID   int
+}
+
+func newMockCartStore(products map[int]*mockProduct) *mockCartStore {
+	return &mockCartStore{products: products, userCart: map[int]int{}, guests: map[string]int{}, lines: map[int][]*mockLine{}}
+}
+
+func (m *mockCartStore) GetUserCartID(userID int) (int, error) {
+	if _, ok := m.userCart[userID]; !ok {
+		m.nextID++
+		m.userCart[userID] = m.nextID
+	}
+	return m.userCart[userID], nil
+}
+
+func (m *mockCartStore) GetGuestCartID(token string) (int, error) {
+	id, ok := m.guests[token]
+	if !ok {
+		return 0, ErrCartNotFound
+	}
+	return id, nil
+}
+
+func (m *mockCartStore) CreateGuestCart(token string) (int, error) {
+	m.nextID++
+	m.guests[token] = m.nextID
+	return m.nextID, nil
+}
+
+func (m *mockCartStore) GetCartItems(cartID int) ([]types.CartItem, error) {
+	items := []types.CartItem{}
+	for _, l := range m.lines[cartID] {
+		p := m.products[l.productID]
+		item := types.CartItem{ProductID: l.productID, Quantity: l.quantity, Price: p.price, Available: p.available}
+		if shown := l.shownPrice; shown != p.price {
+			item.PreviousPrice = &shown
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (m *mockCartStore) line(cartID, productID int) *mockLine {
+	for _, l := range m.lines[cartID] {
+		if l.productID == productID {
+			return l
+		}
+	}
+	return nil
+}
+
+func (m *mockCartStore) AddCartItem(cartID, productID, quantity int) error {
+	p, ok := m.products[productID]
+	if !ok {
+		return ErrProductNotFound
+	}
+	l := m.line(cartID, productID)
+	if l == nil {
+		l = &mockLine{productID: productID}
+		m.lines[cartID] = append(m.lines[cartID], l)
+	}
+	if l.quantity+quantity > p.available {
+		return ErrInsufficientStock
+	}
+	l.quantity += quantity
+	l.shownPrice = p.price
+	return nil
+}
+
+func (m *mockCartStore) SetCartItemQuantity(cartID, productID, quantity int) error {
+	l := m.line(cartID, productID)
+	if l == nil {
+		return ErrItemNotFound
+	}
+	if quantity > m.products[productID].available {
+		return ErrInsufficientStock
+	}
+	l.quantity = quantity
+	return nil
+}
+
+func (m *mockCartStore) RemoveCartItem(cartID, productID int) error {
+	for i, l := range m.lines[cartID] {
+		if l.productID == productID {
+			m.lines[cartID] = append(m.lines[cartID][:i], m.lines[cartID][i+1:]...)
+			return nil
+		}
+	}
+	return ErrItemNotFound
+}
+
+func (m *mockCartStore) AcknowledgePrices(cartID int) error {
+	for _, l := range m.lines[cartID] {
+		l.shownPrice = m.products[l.productID].price
+	}
+	return nil
+}
+
+func (m *mockCartStore) MergeCarts(fromCartID, toCartID int) error {
+	for _, from := range m.lines[fromCartID] {
+		if l := m.line(toCartID, from.productID); l != nil {
+			l.quantity += from.quantity
+		} else {
+			m.lines[toCartID] = append(m.lines[toCartID], from)
+		}
+	}
+	delete(m.lines, fromCartID)
+	for token, id := range m.guests {
+		if id == fromCartID {
+			delete(m.guests, token)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package cart
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+var (
+	ErrCartNotFound      = errors.New("cart not found")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrItemNotFound      = errors.New("product is not in the cart")
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetUserCartID(userID int) (int, error) {
+	// The unique key on userId turns this into a no-op once the cart exists
+	if _, err := s.db.Exec("INSERT IGNORE INTO carts (userId) VALUES (?)", userID); err != nil {
+		return 0, err
+	}
+
+	var id int
+	err := s.db.QueryRow("SELECT id FROM carts WHERE userId = ?", userID).Scan(&id)
+	return id, err
+}
+
+func (s *Store) GetGuestCartID(token string) (int, error) {
+	var id int
+	err := s.db.QueryRow("SELECT id FROM carts WHERE token = ? AND userId IS NULL", token).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrCartNotFound
+	}
+	return id, err
+}
+
+func (s *Store) CreateGuestCart(token string) (int, error) {
+	result, err := s.db.Exec("INSERT INTO carts (token) VALUES (?)", token)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *Store) GetCartItems(cartID int) ([]types.CartItem, error) {
+	rows, err := s.db.Query(`
+		SELECT p.id, p.name, p.image, i.quantity, p.price, i.price,
+			GREATEST(CAST(p.quantity AS SIGNED) - CAST(p.reserved AS SIGNED), 0)
+		FROM cart_items i
+		JOIN products p ON p.id = i.productId
+		WHERE i.cartId = ?
+		ORDER BY i.id`, cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []types.CartItem{}
+	for rows.Next() {
+		var item types.CartItem
+		var shownPrice float64
+		if err := rows.Scan(&item.ProductID, &item.Name, &item.Image, &item.Quantity, &item.Price, &shownPrice, &item.Available); err != nil {
+			return nil, err
+		}
+		if shownPrice != item.Price {
+			item.PreviousPrice = &shownPrice
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// AddCartItem refuses to put more units in the cart than are available. The price shown
+// on the product page is the one the customer just saw, so it is stored as seen.
+func (s *Store) AddCartItem(cartID, productID, quantity int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, _, err := lockCartItem(tx, cartID, productID)
+	if err != nil {
+		return err
+	}
+	if err := checkStock(tx, productID, current+quantity); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO cart_items (cartId, productId, quantity, price)
+		SELECT ?, id, ?, price FROM products WHERE id = ?
+		ON DUPLICATE KEY UPDATE quantity = quantity + ?, price = (SELECT price FROM products WHERE id = ?)`,
+		cartID, quantity, productID, quantity, productID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) SetCartItemQuantity(cartID, productID, quantity int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, found, err := lockCartItem(tx, cartID, productID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrItemNotFound
+	}
+	if err := checkStock(tx, productID, quantity); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE cart_items SET quantity = ? WHERE cartId = ? AND productId = ?", quantity, cartID, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) RemoveCartItem(cartID, productID int) error {
+	result, err := s.db.Exec("DELETE FROM cart_items WHERE cartId = ? AND productId = ?", cartID, productID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrItemNotFound
+	}
+	return err
+}
+
+func (s *Store) AcknowledgePrices(cartID int) error {
+	_, err := s.db.Exec(`
+		UPDATE cart_items i JOIN products p ON p.id = i.productId
+		SET i.price = p.price
+		WHERE i.cartId = ? AND i.price <> p.price`, cartID)
+	return err
+}
+
+func (s *Store) MergeCarts(fromCartID, toCartID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT productId, quantity, price FROM cart_items WHERE cartId = ? FOR UPDATE", fromCartID)
+	if err != nil {
+		return err
+	}
+	type line struct {
+		productID, quantity int
+		price               float64
+	}
+	lines := []line{}
+	for rows.Next() {
+		var l line
+		if err := rows.Scan(&l.productID, &l.quantity, &l.price); err != nil {
+			rows.Close()
+			return err
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, l := range lines {
+		_, err := tx.Exec(`
+			INSERT INTO cart_items (cartId, productId, quantity, price) VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE quantity = quantity + ?`,
+			toCartID, l.productID, l.quantity, l.price, l.quantity,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to merge product %d: %w", l.productID, err)
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM carts WHERE id = ?", fromCartID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockCartItem returns the quantity of a product in the cart, locking the line if it exists.
+func lockCartItem(tx *sql.Tx, cartID, productID int) (int, bool, error) {
+	var quantity int
+	err := tx.QueryRow("SELECT quantity FROM cart_items WHERE cartId = ? AND productId = ? FOR UPDATE", cartID, productID).Scan(&quantity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	return quantity, err == nil, err
+}
+
+// checkStock returns ErrInsufficientStock when fewer than quantity units of the product
+// can be bought. Stock is not reserved; checkout checks it again.
+func checkStock(tx *sql.Tx, productID, quantity int) error {
+	var available int
+	err := tx.QueryRow("SELECT CAST(quantity AS SIGNED) - CAST(reserved AS SIGNED) FROM products WHERE id = ?", productID).Scan(&available)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if quantity > available {
+		return fmt.Errorf("%w: only %d units of product %d are available", ErrInsufficientStock, max(available, 0), productID)
+	}
+	return nil
+}
@@ -0,0 +1,254 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+// Header names used by the middleware.
+const (
+	KeyHeader      = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed" // Set to "true" on responses replayed from the store
+)
+
+// maxKeyLength is the longest Idempotency-Key accepted, matching the column size.
+const maxKeyLength = 255
+
+// defaultLease is how long a request holds its key when Config doesn't say otherwise.
+const defaultLease = 5 * time.Minute
+
+// defaultMaxBodySize limits the body read when Config doesn't say otherwise.
+const defaultMaxBodySize = 1 << 20
+
+// hopByHopHeaders only apply to one connection, so they are not stored with a response.
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Config configures Middleware.
+type Config struct {
+	TTL         time.Duration    // How long the response to a key is replayed
+	Lease       time.Duration    // How long a request may run before a retry can take its key over; 5 minutes if zero
+	Cookies     []string         // Cookies that identify callers without an Authorization header, like the cart cookie
+	MaxBodySize int64            // Largest request body read, unless the route has its own limit; 1 MiB if zero
+	RouteLimits map[string]int64 // Largest request body read by the path template of the route, for uploads
+}
+
+// Middleware makes POST and PATCH requests carrying an Idempotency-Key header safe to
+// retry. The first request with a key runs normally and its response is stored for
+// cfg.TTL; a retry with the same key and body gets the stored response without running
+// the handler again. A retry while the first request is still running gets 409, unless
+// it ran past cfg.Lease, like a request that crashed; the retry then takes the key over.
+// Reusing a key for a different request gets 422. Server errors are not stored, so the
+// request can be retried with the same key. The response headers are replayed too, so a
+// retry gets the same cookies and Location as the first request.
+//
+// Keys are scoped to the caller's credentials, so requests without any are passed
+// through. Responses marked Cache-Control: no-store, like the tokens of a login, are
+// never stored either.
+func Middleware(store types.IdempotencyStore, cfg Config) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(KeyHeader)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			scope, ok := requestScope(r, cfg.Cookies)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxKeyLength {
+				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("%s must be at most %d characters", KeyHeader, maxKeyLength))
+				return
+			}
+
+			// Step 1: Fingerprint the request, keeping the body readable for the handler
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, cfg.bodyLimit(r)))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body must be at most %d bytes", tooLarge.Limit))
+				return
+			}
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("failed to read request body: %v", err))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := requestFingerprint(r, body)
+
+			// Step 2: Claim the key until the lease runs out, or answer from the request that
+			// already claimed it. The lease is stored to the second and tells claims apart.
+			now := time.Now()
+			leaseUntil := now.Add(cfg.lease()).Truncate(time.Second)
+			existing, err := store.BeginIdempotentRequest(scope, key, fingerprint, now, leaseUntil)
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, err)
+				return
+			}
+			if existing != nil {
+				replay(w, existing, fingerprint)
+				return
+			}
+
+			// Step 3: Run the handler and store its response, giving the key back if it fails
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed {
+					if err := store.ReleaseIdempotentRequest(scope, key, leaseUntil); err != nil {
+						log.Printf("failed to release idempotency key: %v", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			header := rec.sentHeader()
+			if rec.status >= http.StatusInternalServerError || noStore(header) {
+				return
+			}
+			if err := store.CompleteIdempotentRequest(scope, key, leaseUntil, rec.status, header, rec.body.Bytes(), time.Now().Add(cfg.TTL)); err != nil {
+				log.Printf("failed to store idempotent response: %v", err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// replay answers a request whose key was already claimed.
+func replay(w http.ResponseWriter, existing *types.IdempotentRequest, fingerprint string) {
+	switch {
+	case existing.Fingerprint != fingerprint:
+		utils.WriteError(w, http.StatusUnprocessableEntity, fmt.Errorf("%s was already used for a different request", KeyHeader))
+	case existing.Status == 0:
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("a request with this %s is still in progress", KeyHeader))
+	default:
+		for name, values := range existing.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(ReplayedHeader, "true")
+		w.WriteHeader(existing.Status)
+		w.Write(existing.Body)
+	}
+}
+
+// lease returns how long a request holds its key.
+func (cfg Config) lease() time.Duration {
+	if cfg.Lease >= time.Second {
+		return cfg.Lease
+	}
+	return defaultLease
+}
+
+// bodyLimit returns the largest body read for the route of r.
+func (cfg Config) bodyLimit(r *http.Request) int64 {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			if limit, ok := cfg.RouteLimits[template]; ok {
+				return limit
+			}
+		}
+	}
+	if cfg.MaxBodySize > 0 {
+		return cfg.MaxBodySize
+	}
+	return defaultMaxBodySize
+}
+
+// requestScope identifies the caller by a hash of their credentials: the Authorization
+// header and the given cookies. Two clients that happen to pick the same key don't see
+// each other's responses, like the cart cookie set for an anonymous caller. It returns
+// false if the request carries no credentials at all.
+func requestScope(r *http.Request, cookies []string) (string, bool) {
+	h := sha256.New()
+	auth := r.Header.Get("Authorization")
+	found := auth != ""
+	fmt.Fprintf(h, "%s\n", auth)
+	for _, name := range cookies {
+		if c, err := r.Cookie(name); err == nil && c.Value != "" {
+			fmt.Fprintf(h, "%s=%s\n", name, c.Value)
+			found = true
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), found
+}
+
+// noStore reports whether a response must not be kept, like one carrying a login token.
+func noStore(header http.Header) bool {
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// requestFingerprint hashes what makes two requests the same: method, path, query and body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder passes a response through while keeping a copy of its status, headers and body.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	header http.Header // The headers as they were sent, nil until then
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.header == nil {
+		r.header = r.Header().Clone()
+	}
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.header == nil {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// sentHeader returns the headers of the response without the hop-by-hop ones. Changes
+// made to the headers after they were sent are ignored, as they never reached the client.
+func (r *recorder) sentHeader() http.Header {
+	header := r.header
+	if header == nil {
+		header = r.Header().Clone()
+	}
+	for _, name := range hopByHopHeaders {
+		header.Del(name)
+	}
+	return header
+}
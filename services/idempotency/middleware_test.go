@@ -0,0 +1,221 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestMiddleware(t *testing.T) {
+	store := &mockIdempotencyStore{requests: map[string]*types.IdempotentRequest{}}
+	calls := 0
+	status := http.StatusCreated
+
+	router := mux.NewRouter()
+	router.Use(Middleware(store, Config{TTL: time.Hour, Cookies: []string{"cart_token"}, MaxBodySize: 64}))
+	router.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Connection", "close")
+		http.SetCookie(w, &http.Cookie{Name: "cart_token", Value: "c1", Path: "/"})
+		http.SetCookie(w, &http.Cookie{Name: "theme", Value: "dark", Path: "/"})
+		w.WriteHeader(status)
+		w.Header().Set("X-Late", "ignored")
+		w.Write([]byte(`{"id":1}`))
+	}).Methods(http.MethodPost, http.MethodGet)
+	router.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "no-store")
+		w.Write([]byte(`{"token":"secret"}`))
+	}).Methods(http.MethodPost)
+
+	send := func(method, key, auth, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/orders", strings.NewReader(body))
+		req.Header.Set(KeyHeader, key)
+		req.Header.Set("Authorization", auth)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should replay the stored response on retries", func(t *testing.T) {
+		first := send(http.MethodPost, "k1", "Bearer a", `{"items":[]}`)
+		retry := send(http.MethodPost, "k1", "Bearer a", `{"items":[]}`)
+		if calls != 1 {
+			t.Fatalf("expected the handler to run once but it ran %d times", calls)
+		}
+		if retry.Code != first.Code || retry.Body.String() != first.Body.String() || retry.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("expected the retry to get %d %s but got %d %s", first.Code, first.Body, retry.Code, retry.Body)
+		}
+		if retry.Header().Get(ReplayedHeader) != "true" || first.Header().Get(ReplayedHeader) != "" {
+			t.Errorf("expected only the retry to be marked as replayed")
+		}
+	})
+
+	t.Run("should replay the cookies set by the handler", func(t *testing.T) {
+		first := send(http.MethodPost, "k5", "Bearer a", "{}")
+		retry := send(http.MethodPost, "k5", "Bearer a", "{}")
+		want := first.Header().Values("Set-Cookie")
+		got := retry.Header().Values("Set-Cookie")
+		if len(want) != 2 || strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("expected the retry to set the cookies %q but got %q", want, got)
+		}
+		if retry.Header().Get("Connection") != "" || retry.Header().Get("X-Late") != "" {
+			t.Errorf("expected only the headers sent to the client to be replayed but got %v", retry.Header())
+		}
+	})
+
+	t.Run("should reject a key reused for a different body", func(t *testing.T) {
+		if rr := send(http.MethodPost, "k1", "Bearer a", `{"items":[1]}`); rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+	})
+
+	t.Run("should keep keys of different callers apart", func(t *testing.T) {
+		calls = 0
+		send(http.MethodPost, "k1", "Bearer b", `{"items":[]}`)
+		if calls != 1 {
+			t.Errorf("expected the handler to run for another caller")
+		}
+	})
+
+	t.Run("should keep keys of anonymous carts apart", func(t *testing.T) {
+		calls = 0
+		for _, token := range []string{"c1", "c2"} {
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader("{}"))
+			req.Header.Set(KeyHeader, "k6")
+			req.AddCookie(&http.Cookie{Name: "cart_token", Value: token})
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		if calls != 2 {
+			t.Errorf("expected the handler to run for each cart but it ran %d times", calls)
+		}
+	})
+
+	t.Run("should not store requests without credentials", func(t *testing.T) {
+		calls = 0
+		send(http.MethodPost, "k7", "", "{}")
+		send(http.MethodPost, "k7", "", "{}")
+		if calls != 2 {
+			t.Errorf("expected the handler to run every time but it ran %d times", calls)
+		}
+	})
+
+	t.Run("should not store responses marked no-store", func(t *testing.T) {
+		calls = 0
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("{}"))
+			req.Header.Set(KeyHeader, "k9")
+			req.Header.Set("Authorization", "Bearer a")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+		if calls != 2 || store.requests[scopeOf("Bearer a")+"/k9"] != nil {
+			t.Errorf("expected the login to run every time without being stored but it ran %d times", calls)
+		}
+	})
+
+	t.Run("should reject bodies over the limit", func(t *testing.T) {
+		if rr := send(http.MethodPost, "k8", "Bearer a", strings.Repeat("x", 65)); rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status code %d but got %d", http.StatusRequestEntityTooLarge, rr.Code)
+		}
+	})
+
+	t.Run("should reject retries while the request is in flight", func(t *testing.T) {
+		store.requests[scopeOf("Bearer a")+"/k2"] = &types.IdempotentRequest{
+			Fingerprint: requestFingerprint(httptest.NewRequest(http.MethodPost, "/orders", nil), []byte("{}")),
+			ExpiresAt:   time.Now().Add(time.Minute),
+		}
+		if rr := send(http.MethodPost, "k2", "Bearer a", "{}"); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should take over the key of a request that ran past its lease", func(t *testing.T) {
+		calls = 0
+		store.requests[scopeOf("Bearer a")+"/k10"] = &types.IdempotentRequest{
+			Fingerprint: requestFingerprint(httptest.NewRequest(http.MethodPost, "/orders", nil), []byte("{}")),
+			ExpiresAt:   time.Now().Add(-time.Second),
+		}
+		if rr := send(http.MethodPost, "k10", "Bearer a", "{}"); rr.Code != http.StatusCreated || calls != 1 {
+			t.Errorf("expected the retry to run the handler but got %d after %d calls", rr.Code, calls)
+		}
+		if r := store.requests[scopeOf("Bearer a")+"/k10"]; r.Status != http.StatusCreated || time.Until(r.ExpiresAt) < 59*time.Minute {
+			t.Errorf("expected the response to be kept for the TTL but got %+v", r)
+		}
+	})
+
+	t.Run("should let requests that failed be retried", func(t *testing.T) {
+		calls, status = 0, http.StatusInternalServerError
+		send(http.MethodPost, "k3", "Bearer a", "{}")
+		status = http.StatusCreated
+		if rr := send(http.MethodPost, "k3", "Bearer a", "{}"); rr.Code != http.StatusCreated || calls != 2 {
+			t.Errorf("expected the retry to run the handler again but got %d after %d calls", rr.Code, calls)
+		}
+	})
+
+	t.Run("should ignore the key on other methods", func(t *testing.T) {
+		calls = 0
+		send(http.MethodGet, "k4", "Bearer a", "")
+		send(http.MethodGet, "k4", "Bearer a", "")
+		if calls != 2 {
+			t.Errorf("expected GET requests to run every time but the handler ran %d times", calls)
+		}
+	})
+}
+
+// scopeOf returns the scope of requests sent with the given Authorization header.
+func scopeOf(auth string) string {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Authorization", auth)
+	scope, _ := requestScope(req, nil)
+	return scope
+}
+
+type mockIdempotencyStore struct {
+	mu       sync.Mutex
+	requests map[string]*types.IdempotentRequest
+}
+
+func (m *mockIdempotencyStore) BeginIdempotentRequest(scope, key, fingerprint string, now, leaseUntil time.Time) (*types.IdempotentRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.requests[scope+"/"+key]; ok && r.ExpiresAt.After(now) {
+		c := *r
+		return &c, nil
+	}
+	m.requests[scope+"/"+key] = &types.IdempotentRequest{Scope: scope, Key: key, Fingerprint: fingerprint, ExpiresAt: leaseUntil}
+	return nil, nil
+}
+
+func (m *mockIdempotencyStore) CompleteIdempotentRequest(scope, key string, leaseUntil time.Time, status int, header http.Header, body []byte, expiresAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, ok := m.requests[scope+"/"+key]
+	if !ok || r.Status != 0 || !r.ExpiresAt.Equal(leaseUntil) {
+		return ErrLeaseLost
+	}
+	r.Status, r.Header, r.Body, r.ExpiresAt = status, header, body, expiresAt
+	return nil
+}
+
+func (m *mockIdempotencyStore) ReleaseIdempotentRequest(scope, key string, leaseUntil time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.requests[scope+"/"+key]; ok && r.Status == 0 && r.ExpiresAt.Equal(leaseUntil) {
+		delete(m.requests, scope+"/"+key)
+	}
+	return nil
+}
+
+func (m *mockIdempotencyStore) DeleteExpiredIdempotentRequests(now time.Time) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,137 @@
+package idempotency
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDuplicateEntry is the MySQL error number for a unique key violation.
+const mysqlDuplicateEntry = 1062
+
+// ErrLeaseLost is returned when a request ran past the lease on its key and a retry took
+// the key over.
+var ErrLeaseLost = errors.New("idempotency key was taken over by a retry")
+
+// requestColumns lists the columns read by scanRowIntoRequest, in scan order.
+const requestColumns = "scope, idempotencyKey, fingerprint, status, headers, body, expires_at, created_at"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// BeginIdempotentRequest relies on the unique key over scope and key, so of two requests
+// racing for the same key exactly one inserts the row. While the request is in flight,
+// expires_at holds the end of its lease, so a crashed request's key is reclaimed like an
+// expired one.
+func (s *Store) BeginIdempotentRequest(scope, key, fingerprint string, now, leaseUntil time.Time) (*types.IdempotentRequest, error) {
+	_, err := s.db.Exec("DELETE FROM idempotency_keys WHERE scope = ? AND idempotencyKey = ? AND expires_at <= ?", scope, key, now)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(
+		"INSERT INTO idempotency_keys (scope, idempotencyKey, fingerprint, expires_at) VALUES (?, ?, ?, ?)",
+		scope, key, fingerprint, leaseUntil,
+	)
+	if err == nil {
+		return nil, nil
+	}
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != mysqlDuplicateEntry {
+		return nil, fmt.Errorf("failed to insert idempotency key: %w", err)
+	}
+
+	rows, err := s.db.Query("SELECT "+requestColumns+" FROM idempotency_keys WHERE scope = ? AND idempotencyKey = ?", scope, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		// The other request released the key in the meantime
+		return s.BeginIdempotentRequest(scope, key, fingerprint, now, leaseUntil)
+	}
+	return scanRowIntoRequest(rows)
+}
+
+// CompleteIdempotentRequest and ReleaseIdempotentRequest tell the claims of a key apart by
+// the end of their lease, which moves forward every time the key is taken over.
+func (s *Store) CompleteIdempotentRequest(scope, key string, leaseUntil time.Time, status int, header http.Header, body []byte, expiresAt time.Time) error {
+	headers, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("failed to encode response headers: %w", err)
+	}
+
+	result, err := s.db.Exec(
+		"UPDATE idempotency_keys SET status = ?, headers = ?, body = ?, expires_at = ? WHERE scope = ? AND idempotencyKey = ? AND status = 0 AND expires_at = ?",
+		status, headers, body, expiresAt, scope, key, leaseUntil,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (s *Store) ReleaseIdempotentRequest(scope, key string, leaseUntil time.Time) error {
+	_, err := s.db.Exec(
+		"DELETE FROM idempotency_keys WHERE scope = ? AND idempotencyKey = ? AND status = 0 AND expires_at = ?",
+		scope, key, leaseUntil,
+	)
+	return err
+}
+
+func (s *Store) DeleteExpiredIdempotentRequests(now time.Time) (int, error) {
+	result, err := s.db.Exec("DELETE FROM idempotency_keys WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+func scanRowIntoRequest(rows *sql.Rows) (*types.IdempotentRequest, error) {
+	r := new(types.IdempotentRequest)
+	var headers sql.NullString
+	err := rows.Scan(
+		&r.Scope,
+		&r.Key,
+		&r.Fingerprint,
+		&r.Status,
+		&headers,
+		&r.Body,
+		&r.ExpiresAt,
+		&r.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if headers.Valid {
+		if err := json.Unmarshal([]byte(headers.String), &r.Header); err != nil {
+			return nil, fmt.Errorf("failed to decode response headers: %w", err)
+		}
+	}
+	return r, nil
+}
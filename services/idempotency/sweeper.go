@@ -0,0 +1,38 @@
+package idempotency
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// StartSweeper deletes expired idempotency keys every interval until ctx is cancelled.
+// It runs in its own goroutine and returns immediately.
+func StartSweeper(ctx context.Context, store types.IdempotencyStore, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				sweep(store, now)
+			}
+		}
+	}()
+}
+
+// sweep runs one pass of the sweeper. Errors are logged and retried on the next tick.
+func sweep(store types.IdempotencyStore, now time.Time) {
+	deleted, err := store.DeleteExpiredIdempotentRequests(now)
+	if err != nil {
+		log.Printf("failed to delete expired idempotency keys: %v", err)
+	}
+	if deleted > 0 {
+		log.Printf("deleted %d expired idempotency keys", deleted)
+	}
+}
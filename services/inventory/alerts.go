@@ -0,0 +1,250 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// ErrInStock is returned when a customer subscribes to a product that can be bought right away.
+var ErrInStock = errors.New("product is in stock")
+
+// AlertWatcher periodically tells staff about products running low and customers about
+// products they are waiting for.
+type AlertWatcher struct {
+	store    types.StockAlertStore
+	notifier types.Notifier
+}
+
+func NewAlertWatcher(store types.StockAlertStore, notifier types.Notifier) *AlertWatcher {
+	return &AlertWatcher{store: store, notifier: notifier}
+}
+
+// Start runs Check every interval until ctx is cancelled. It returns immediately.
+func (a *AlertWatcher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := a.Check(ctx); err != nil {
+					log.Printf("failed to send stock alerts: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Check sends the pending low-stock and back-in-stock notifications. A notification that
+// fails to send is retried on the next check.
+func (a *AlertWatcher) Check(ctx context.Context) error {
+	if err := a.checkLowStock(ctx); err != nil {
+		return err
+	}
+	return a.checkBackInStock(ctx)
+}
+
+func (a *AlertWatcher) checkLowStock(ctx context.Context) error {
+	if err := a.store.ResetRestockedAlerts(); err != nil {
+		return err
+	}
+
+	products, err := a.store.GetLowStockProducts()
+	if err != nil || len(products) == 0 {
+		return err
+	}
+
+	staff, err := a.store.GetStaffEmails()
+	if err != nil {
+		return err
+	}
+	if len(staff) == 0 {
+		log.Printf("%d products are low on stock but there is no admin to notify", len(products))
+		return nil
+	}
+
+	for _, p := range products {
+		err := a.notifier.Notify(ctx, types.Notification{
+			Kind:       types.NotificationLowStock,
+			Recipients: staff,
+			Subject:    fmt.Sprintf("Low stock: %s", p.Name),
+			Body: fmt.Sprintf("%s (SKU %s, product %d) has %d units available, below the reorder threshold of %d.",
+				p.Name, p.SKU, p.ProductID, p.Available, p.Threshold),
+			Data: map[string]any{"product": p},
+		})
+		if err != nil {
+			log.Printf("failed to send low-stock alert for product %d: %v", p.ProductID, err)
+			continue
+		}
+		if err := a.store.SetLowStockAlerted(p.ProductID, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *AlertWatcher) checkBackInStock(ctx context.Context) error {
+	subscriptions, err := a.store.GetDueStockSubscriptions()
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subscriptions {
+		err := a.notifier.Notify(ctx, types.Notification{
+			Kind:       types.NotificationBackInStock,
+			Recipients: []string{sub.Email},
+			Subject:    fmt.Sprintf("%s is back in stock", sub.ProductName),
+			Body:       fmt.Sprintf("Good news: %s is available again.", sub.ProductName),
+			Data:       map[string]any{"subscription": sub},
+		})
+		if err != nil {
+			log.Printf("failed to send back-in-stock notification %d: %v", sub.ID, err)
+			continue
+		}
+		if err := a.store.MarkStockSubscriptionNotified(sub.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) SetReorderThreshold(productID int, threshold *int) error {
+	result, err := s.db.Exec("UPDATE products SET reorderThreshold = ?, lowStockAlerted = 0 WHERE id = ?", threshold, productID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return s.ensureProductExists(productID)
+	}
+	return nil
+}
+
+func (s *Store) GetLowStockProducts() ([]types.LowStockProduct, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, COALESCE(sku, ''), CAST(quantity AS SIGNED) - CAST(reserved AS SIGNED), reorderThreshold
+		FROM products
+		WHERE reorderThreshold IS NOT NULL AND lowStockAlerted = 0
+			AND CAST(quantity AS SIGNED) - CAST(reserved AS SIGNED) < reorderThreshold
+		ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []types.LowStockProduct{}
+	for rows.Next() {
+		var p types.LowStockProduct
+		if err := rows.Scan(&p.ProductID, &p.Name, &p.SKU, &p.Available, &p.Threshold); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+func (s *Store) SetLowStockAlerted(productID int, alerted bool) error {
+	_, err := s.db.Exec("UPDATE products SET lowStockAlerted = ? WHERE id = ?", alerted, productID)
+	return err
+}
+
+func (s *Store) ResetRestockedAlerts() error {
+	_, err := s.db.Exec(`
+		UPDATE products SET lowStockAlerted = 0
+		WHERE lowStockAlerted = 1
+			AND (reorderThreshold IS NULL OR CAST(quantity AS SIGNED) - CAST(reserved AS SIGNED) >= reorderThreshold)`)
+	return err
+}
+
+func (s *Store) GetStaffEmails() ([]string, error) {
+	rows, err := s.db.Query("SELECT email FROM users WHERE role = ? ORDER BY id", types.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []string{}
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, rows.Err()
+}
+
+// CreateStockSubscription is idempotent; subscribing again after being notified re-arms the subscription.
+func (s *Store) CreateStockSubscription(userID, productID int) error {
+	var available int
+	err := s.db.QueryRow("SELECT CAST(quantity AS SIGNED) - CAST(reserved AS SIGNED) FROM products WHERE id = ?", productID).Scan(&available)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if available > 0 {
+		return ErrInStock
+	}
+
+	_, err = s.db.Exec(
+		"INSERT INTO stock_subscriptions (userId, productId) VALUES (?, ?) ON DUPLICATE KEY UPDATE notified_at = NULL",
+		userID, productID,
+	)
+	return err
+}
+
+func (s *Store) DeleteStockSubscription(userID, productID int) error {
+	_, err := s.db.Exec("DELETE FROM stock_subscriptions WHERE userId = ? AND productId = ? AND notified_at IS NULL", userID, productID)
+	return err
+}
+
+func (s *Store) GetDueStockSubscriptions() ([]types.StockSubscription, error) {
+	rows, err := s.db.Query(`
+		SELECT s.id, s.userId, u.email, p.id, p.name
+		FROM stock_subscriptions s
+		JOIN users u ON u.id = s.userId
+		JOIN products p ON p.id = s.productId
+		WHERE s.notified_at IS NULL AND p.quantity > p.reserved
+		ORDER BY s.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subscriptions := []types.StockSubscription{}
+	for rows.Next() {
+		var sub types.StockSubscription
+		if err := rows.Scan(&sub.ID, &sub.UserID, &sub.Email, &sub.ProductID, &sub.ProductName); err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, sub)
+	}
+	return subscriptions, rows.Err()
+}
+
+func (s *Store) MarkStockSubscriptionNotified(id int) error {
+	_, err := s.db.Exec("UPDATE stock_subscriptions SET notified_at = CURRENT_TIMESTAMP WHERE id = ?", id)
+	return err
+}
+
+// ensureProductExists returns ErrProductNotFound for unknown products and nil otherwise.
+func (s *Store) ensureProductExists(productID int) error {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = ?)", productID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrProductNotFound
+	}
+	return nil
+}
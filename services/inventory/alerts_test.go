@@ -0,0 +1,203 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestAlertWatcher(t *testing.T) {
+	store := newMockAlertStore()
+	store.products[1] = &mockAlertProduct{name: "Mug", available: 2, threshold: intPtr(5)}
+	store.products[2] = &mockAlertProduct{name: "Plate", available: 0}
+	store.subscriptions = []types.StockSubscription{{ID: 1, UserID: 7, Email: "jane@example.com", ProductID: 2, ProductName: "Plate"}}
+	notifier := &recordingNotifier{}
+	watcher := NewAlertWatcher(store, notifier)
+
+	t.Run("should alert staff once per dip below the threshold", func(t *testing.T) {
+		watcher.Check(context.Background())
+		watcher.Check(context.Background())
+
+		if len(notifier.sent) != 1 || notifier.sent[0].Kind != types.NotificationLowStock {
+			t.Fatalf("expected a single low-stock alert but got %+v", notifier.sent)
+		}
+		if notifier.sent[0].Recipients[0] != "admin@example.com" {
+			t.Errorf("expected the alert to go to staff but got %v", notifier.sent[0].Recipients)
+		}
+	})
+
+	t.Run("should alert again after a restock and a new dip", func(t *testing.T) {
+		store.products[1].available = 10
+		watcher.Check(context.Background())
+		store.products[1].available = 1
+		watcher.Check(context.Background())
+
+		if len(notifier.sent) != 2 {
+			t.Errorf("expected a second low-stock alert but got %d notifications", len(notifier.sent))
+		}
+	})
+
+	t.Run("should tell subscribers when a product is back", func(t *testing.T) {
+		notifier.sent = nil
+		store.products[2].available = 3
+		watcher.Check(context.Background())
+		watcher.Check(context.Background())
+
+		if len(notifier.sent) != 1 || notifier.sent[0].Kind != types.NotificationBackInStock {
+			t.Fatalf("expected a single back-in-stock notification but got %+v", notifier.sent)
+		}
+		if notifier.sent[0].Recipients[0] != "jane@example.com" {
+			t.Errorf("expected the subscriber to be notified but got %v", notifier.sent[0].Recipients)
+		}
+	})
+
+	t.Run("should retry notifications that failed", func(t *testing.T) {
+		store.products[3] = &mockAlertProduct{name: "Bowl", available: 0, threshold: intPtr(1)}
+		notifier.err = errors.New("smtp down")
+		watcher.Check(context.Background())
+		notifier.err = nil
+		notifier.sent = nil
+		watcher.Check(context.Background())
+
+		if len(notifier.sent) != 1 {
+			t.Errorf("expected the failed alert to be retried but got %d notifications", len(notifier.sent))
+		}
+	})
+}
+
+func TestStockSubscriptionHandlers(t *testing.T) {
+	alertStore := newMockAlertStore()
+	alertStore.products[1] = &mockAlertProduct{name: "Mug", available: 0}
+	alertStore.products[2] = &mockAlertProduct{name: "Plate", available: 4}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{1: {ID: 1, Role: types.RoleCustomer}}}
+
+	handler := NewHandler(newMockInventoryStore(map[int]int{}), alertStore, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	subscribe := func(productID string) int {
+		req := httptest.NewRequest(http.MethodPost, "/inventory/products/"+productID+"/subscription", nil)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 1))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := subscribe("1"); code != http.StatusCreated {
+		t.Errorf("expected status code %d but got %d", http.StatusCreated, code)
+	}
+	if code := subscribe("2"); code != http.StatusConflict {
+		t.Errorf("expected status code %d for an in-stock product but got %d", http.StatusConflict, code)
+	}
+	if code := subscribe("3"); code != http.StatusNotFound {
+		t.Errorf("expected status code %d for an unknown product but got %d", http.StatusNotFound, code)
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+type recordingNotifier struct {
+	sent []types.Notification
+	err  error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification types.Notification) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+type mockAlertProduct struct {
+	name      string
+	available int
+	threshold *int
+	alerted   bool
+}
+
+type mockAlertStore struct {
+	products      map[int]*mockAlertProduct
+	subscriptions []types.StockSubscription
+	notified      map[int]bool
+}
+
+func newMockAlertStore() *mockAlertStore {
+	return &mockAlertStore{products: map[int]*mockAlertProduct{}, notified: map[int]bool{}}
+}
+
+func (m *mockAlertStore) SetReorderThreshold(productID int, threshold *int) error {
+	p, ok := m.products[productID]
+	if !ok {
+		return ErrProductNotFound
+	}
+	p.threshold, p.alerted = threshold, false
+	return nil
+}
+
+func (m *mockAlertStore) GetLowStockProducts() ([]types.LowStockProduct, error) {
+	products := []types.LowStockProduct{}
+	for id, p := range m.products {
+		if p.threshold != nil && !p.alerted && p.available < *p.threshold {
+			products = append(products, types.LowStockProduct{ProductID: id, Name: p.name, Available: p.available, Threshold: *p.threshold})
+		}
+	}
+	return products, nil
+}
+
+func (m *mockAlertStore) SetLowStockAlerted(productID int, alerted bool) error {
+	m.products[productID].alerted = alerted
+	return nil
+}
+
+func (m *mockAlertStore) ResetRestockedAlerts() error {
+	for _, p := range m.products {
+		if p.alerted && (p.threshold == nil || p.available >= *p.threshold) {
+			p.alerted = false
+		}
+	}
+	return nil
+}
+
+func (m *mockAlertStore) GetStaffEmails() ([]string, error) {
+	return []string{"admin@example.com"}, nil
+}
+
+func (m *mockAlertStore) CreateStockSubscription(userID, productID int) error {
+	p, ok := m.products[productID]
+	if !ok {
+		return ErrProductNotFound
+	}
+	if p.available > 0 {
+		return ErrInStock
+	}
+	m.subscriptions = append(m.subscriptions, types.StockSubscription{ID: len(m.subscriptions) + 1, UserID: userID, ProductID: productID, ProductName: p.name})
+	return nil
+}
+
+func (m *mockAlertStore) DeleteStockSubscription(userID, productID int) error {
+	return nil
+}
+
+func (m *mockAlertStore) GetDueStockSubscriptions() ([]types.StockSubscription, error) {
+	due := []types.StockSubscription{}
+	for _, sub := range m.subscriptions {
+		if !m.notified[sub.ID] && m.products[sub.ProductID].available > 0 {
+			due = append(due, sub)
+		}
+	}
+	return due, nil
+}
+
+func (m *mockAlertStore) MarkStockSubscriptionNotified(id int) error {
+	m.notified[id] = true
+	return nil
+}
@@ -0,0 +1,162 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// ErrBelowReserved is returned when an adjustment would leave fewer units on hand than are reserved.
+var ErrBelowReserved = errors.New("quantity cannot drop below the reserved quantity")
+
+// movementColumns lists the columns read by scanRowIntoMovement, in scan order.
+const movementColumns = "id, productId, quantityChange, reservedChange, reason, actorId, note, reference, created_at"
+
+// RecordMovement appends a movement to the ledger. It must run in the same transaction
+// as the change to the product counters so the two never disagree.
+func RecordMovement(tx *sql.Tx, m types.StockMovement) (int, error) {
+	result, err := tx.Exec(
+		"INSERT INTO stock_movements (productId, quantityChange, reservedChange, reason, actorId, note, reference) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		m.ProductID, m.QuantityChange, m.ReservedChange, m.Reason, m.ActorID, m.Note, m.Reference,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record stock movement: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// Actor returns a pointer suitable for StockMovement.ActorID, treating non-positive IDs as "no actor".
+func Actor(userID int) *int {
+	if userID <= 0 {
+		return nil
+	}
+	return &userID
+}
+
+func (s *Store) AdjustStock(productID int, payload types.StockAdjustmentPayload, actorID int) (*types.StockMovement, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Compare as signed numbers: the columns are unsigned and the change may be negative
+	result, err := tx.Exec(
+		"UPDATE products SET quantity = quantity + ? WHERE id = ? AND CAST(quantity AS SIGNED) + ? >= CAST(reserved AS SIGNED)",
+		payload.Change, productID, payload.Change,
+	)
+	if err != nil {
+		return nil, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		if err := s.ensureProductExists(productID); err != nil {
+			return nil, err
+		}
+		return nil, ErrBelowReserved
+	}
+
+	id, err := RecordMovement(tx, types.StockMovement{
+		ProductID:      productID,
+		QuantityChange: payload.Change,
+		Reason:         payload.Reason,
+		ActorID:        Actor(actorID),
+		Note:           payload.Note,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.getStockMovement(id)
+}
+
+func (s *Store) GetStockMovements(productID int, limit, offset int) ([]types.StockMovement, error) {
+	rows, err := s.db.Query(
+		"SELECT "+movementColumns+" FROM stock_movements WHERE productId = ? ORDER BY id DESC LIMIT ? OFFSET ?",
+		productID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []types.StockMovement{}
+	for rows.Next() {
+		m, err := scanRowIntoMovement(rows)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, *m)
+	}
+	return movements, rows.Err()
+}
+
+func (s *Store) GetStockDiscrepancies() ([]types.StockDiscrepancy, error) {
+	rows, err := s.db.Query(`
+		SELECT p.id, p.quantity, COALESCE(SUM(m.quantityChange), 0), p.reserved, COALESCE(SUM(m.reservedChange), 0)
+		FROM products p
+		LEFT JOIN stock_movements m ON m.productId = p.id
+		GROUP BY p.id, p.quantity, p.reserved
+		HAVING p.quantity <> COALESCE(SUM(m.quantityChange), 0) OR p.reserved <> COALESCE(SUM(m.reservedChange), 0)
+		ORDER BY p.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	discrepancies := []types.StockDiscrepancy{}
+	for rows.Next() {
+		var d types.StockDiscrepancy
+		if err := rows.Scan(&d.ProductID, &d.Quantity, &d.LedgerQuantity, &d.Reserved, &d.LedgerReserved); err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, d)
+	}
+	return discrepancies, rows.Err()
+}
+
+func (s *Store) getStockMovement(id int) (*types.StockMovement, error) {
+	rows, err := s.db.Query("SELECT "+movementColumns+" FROM stock_movements WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("stock movement %d not found", id)
+	}
+	return scanRowIntoMovement(rows)
+}
+
+func scanRowIntoMovement(rows *sql.Rows) (*types.StockMovement, error) {
+	m := new(types.StockMovement)
+	var actorID sql.NullInt64
+	err := rows.Scan(
+		&m.ID,
+		&m.ProductID,
+		&m.QuantityChange,
+		&m.ReservedChange,
+		&m.Reason,
+		&actorID,
+		&m.Note,
+		&m.Reference,
+		&m.CreatedAt,
+	)
+	if actorID.Valid {
+		m.ActorID = Actor(int(actorID.Int64))
+	}
+	return m, err
+}
@@ -0,0 +1,238 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.InventoryStore
+	alertStore types.StockAlertStore
+	userStore  types.UserStore
+}
+
+func NewHandler(store types.InventoryStore, alertStore types.StockAlertStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, alertStore: alertStore, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/inventory/products/{id:[0-9]+}", h.handleGetStockLevel).Methods(http.MethodGet)
+	router.HandleFunc("/inventory/reservations", auth.WithJWTAuth(h.handleCreateReservation, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/inventory/reservations/{id:[0-9]+}", auth.WithJWTAuth(h.handleGetReservation, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/inventory/reservations/{id:[0-9]+}", auth.WithJWTAuth(h.handleReleaseReservation, h.userStore)).Methods(http.MethodDelete)
+
+	router.HandleFunc("/admin/inventory/products/{id:[0-9]+}/movements", auth.WithAdminAuth(h.handleGetMovements, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/inventory/products/{id:[0-9]+}/movements", auth.WithAdminAuth(h.handleAdjustStock, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/inventory/discrepancies", auth.WithAdminAuth(h.handleGetDiscrepancies, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/inventory/products/{id:[0-9]+}/threshold", auth.WithAdminAuth(h.handleSetThreshold, h.userStore)).Methods(http.MethodPut)
+
+	router.HandleFunc("/inventory/products/{id:[0-9]+}/subscription", auth.WithJWTAuth(h.handleSubscribe, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/inventory/products/{id:[0-9]+}/subscription", auth.WithJWTAuth(h.handleUnsubscribe, h.userStore)).Methods(http.MethodDelete)
+}
+
+func (h *Handler) handleGetStockLevel(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	level, err := h.store.GetStockLevel(productID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, level)
+}
+
+// handleCreateReservation holds stock for the authenticated user while they check out.
+// The reservation expires after RESERVATION_TTL seconds unless an order is placed with it first.
+func (h *Handler) handleCreateReservation(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateReservationPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	expiresAt := time.Now().Add(time.Duration(configs.Envs.ReservationTTLInSeconds) * time.Second)
+	reservation, err := h.store.CreateReservation(auth.GetUserIDFromContext(r.Context()), payload.Items, expiresAt)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, reservation)
+}
+
+func (h *Handler) handleGetReservation(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := h.ownReservation(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, reservation)
+}
+
+// handleReleaseReservation lets a customer abandon a checkout before the reservation expires.
+func (h *Handler) handleReleaseReservation(w http.ResponseWriter, r *http.Request) {
+	reservation, ok := h.ownReservation(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.ReleaseReservation(reservation.ID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetMovements returns the current stock of a product together with a page of its
+// ledger, newest movement first.
+func (h *Handler) handleGetMovements(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	page, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	level, err := h.store.GetStockLevel(productID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	movements, err := h.store.GetStockMovements(productID, page.Limit, page.Offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"stock":     level,
+		"movements": movements,
+		"page":      page.Page,
+		"limit":     page.Limit,
+	})
+}
+
+// handleAdjustStock records a receipt, return or manual correction made by an admin.
+func (h *Handler) handleAdjustStock(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.StockAdjustmentPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	movement, err := h.store.AdjustStock(productID, payload, auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, movement)
+}
+
+// handleGetDiscrepancies lists products whose counters no longer match their ledger.
+func (h *Handler) handleGetDiscrepancies(w http.ResponseWriter, r *http.Request) {
+	discrepancies, err := h.store.GetStockDiscrepancies()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, discrepancies)
+}
+
+// handleSetThreshold sets the available quantity below which staff get a low-stock alert.
+func (h *Handler) handleSetThreshold(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ReorderThresholdPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	if err := h.alertStore.SetReorderThreshold(productID, payload.Threshold); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, payload)
+}
+
+// handleSubscribe asks to be notified when an out-of-stock product can be bought again.
+func (h *Handler) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.alertStore.CreateStockSubscription(auth.GetUserIDFromContext(r.Context()), productID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]string{"message": "you will be notified when the product is back in stock"})
+}
+
+func (h *Handler) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.alertStore.DeleteStockSubscription(auth.GetUserIDFromContext(r.Context()), productID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownReservation loads the reservation addressed by the request, answering 404 when it
+// belongs to someone else so other users' reservation IDs are not disclosed.
+func (h *Handler) ownReservation(w http.ResponseWriter, r *http.Request) (*types.Reservation, bool) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	reservation, err := h.store.GetReservation(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return nil, false
+	}
+	if reservation.UserID != auth.GetUserIDFromContext(r.Context()) && !auth.IsAdmin(r.Context()) {
+		writeStoreError(w, ErrReservationNotFound)
+		return nil, false
+	}
+
+	return reservation, true
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrReservationNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrInsufficientStock), errors.Is(err, ErrReservationNotActive), errors.Is(err, ErrBelowReserved),
+		errors.Is(err, ErrInStock):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,280 @@
+package inventory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestInventoryHandlers(t *testing.T) {
+	store := newMockInventoryStore(map[int]int{1: 1, 2: 5})
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleCustomer},
+	}}
+
+	handler := NewHandler(store, newMockAlertStore(), userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	reserve := func(userID int, items []types.ReservationItem) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.CreateReservationPayload{Items: items})
+		req := httptest.NewRequest(http.MethodPost, "/inventory/reservations", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should reserve the last unit only once", func(t *testing.T) {
+		rr := reserve(1, []types.ReservationItem{{ProductID: 1, Quantity: 1}})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		rr = reserve(2, []types.ReservationItem{{ProductID: 1, Quantity: 1}})
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should report reserved and available stock", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/inventory/products/1", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var level types.StockLevel
+		json.NewDecoder(rr.Body).Decode(&level)
+		if level.Quantity != 1 || level.Reserved != 1 || level.Available != 0 {
+			t.Errorf("unexpected stock level %+v", level)
+		}
+	})
+
+	t.Run("should reject invalid quantities", func(t *testing.T) {
+		rr := reserve(1, []types.ReservationItem{{ProductID: 2, Quantity: 0}})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should hide other users' reservations", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/inventory/reservations/1", nil)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 2))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should release a reservation", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/inventory/reservations/1", nil)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 1))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("expected status code %d but got %d", http.StatusNoContent, rr.Code)
+		}
+		if store.reserved[1] != 0 {
+			t.Errorf("expected the unit to be released but %d are reserved", store.reserved[1])
+		}
+	})
+}
+
+func TestStockAdjustmentHandlers(t *testing.T) {
+	store := newMockInventoryStore(map[int]int{1: 5})
+	store.reserved[1] = 4
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleAdmin},
+		2: {ID: 2, Role: types.RoleCustomer},
+	}}
+
+	handler := NewHandler(store, newMockAlertStore(), userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	adjust := func(userID int, payload types.StockAdjustmentPayload) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/admin/inventory/products/1/movements", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should record a receipt with its actor", func(t *testing.T) {
+		rr := adjust(1, types.StockAdjustmentPayload{Change: 10, Reason: types.MovementReceipt, Note: "PO-17"})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var movement types.StockMovement
+		json.NewDecoder(rr.Body).Decode(&movement)
+		if movement.QuantityChange != 10 || movement.ActorID == nil || *movement.ActorID != 1 {
+			t.Errorf("unexpected movement %+v", movement)
+		}
+	})
+
+	t.Run("should not drop below the reserved quantity", func(t *testing.T) {
+		rr := adjust(1, types.StockAdjustmentPayload{Change: -12, Reason: types.MovementAdjustment})
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should only accept manual reasons", func(t *testing.T) {
+		rr := adjust(1, types.StockAdjustmentPayload{Change: -1, Reason: types.MovementSale})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should only allow admins", func(t *testing.T) {
+		rr := adjust(2, types.StockAdjustmentPayload{Change: 1, Reason: types.MovementReceipt})
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should list the movement history", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/inventory/products/1/movements", nil)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 1))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var body struct {
+			Stock     types.StockLevel      `json:"stock"`
+			Movements []types.StockMovement `json:"movements"`
+		}
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body.Stock.Available != 11 || len(body.Movements) != 1 {
+			t.Errorf("unexpected history %+v", body)
+		}
+	})
+}
+
+func TestSweep(t *testing.T) {
+	store := newMockInventoryStore(map[int]int{1: 3})
+	now := time.Now()
+	store.CreateReservation(1, []types.ReservationItem{{ProductID: 1, Quantity: 2}}, now.Add(-time.Minute))
+	store.CreateReservation(1, []types.ReservationItem{{ProductID: 1, Quantity: 1}}, now.Add(time.Minute))
+
+	sweep(store, now)
+
+	if store.reservations[1].Status != types.ReservationExpired {
+		t.Errorf("expected the first reservation to expire but it is %s", store.reservations[1].Status)
+	}
+	if store.reservations[2].Status != types.ReservationActive {
+		t.Errorf("expected the second reservation to stay active but it is %s", store.reservations[2].Status)
+	}
+	if store.reserved[1] != 1 {
+		t.Errorf("expected 1 unit to stay reserved but got %d", store.reserved[1])
+	}
+}
+
+// mockInventoryStore mirrors the reservation rules of Store in memory.
+type mockInventoryStore struct {
+	quantity     map[int]int
+	reserved     map[int]int
+	reservations map[int]*types.Reservation
+	movements    []types.StockMovement
+}
+
+func newMockInventoryStore(quantity map[int]int) *mockInventoryStore {
+	return &mockInventoryStore{quantity: quantity, reserved: map[int]int{}, reservations: map[int]*types.Reservation{}}
+}
+
+func (m *mockInventoryStore) GetStockLevel(productID int) (*types.StockLevel, error) {
+	q, ok := m.quantity[productID]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	return &types.StockLevel{ProductID: productID, Quantity: q, Reserved: m.reserved[productID], Available: q - m.reserved[productID]}, nil
+}
+
+func (m *mockInventoryStore) CreateReservation(userID int, items []types.ReservationItem, expiresAt time.Time) (*types.Reservation, error) {
+	for _, item := range items {
+		if m.quantity[item.ProductID]-m.reserved[item.ProductID] < item.Quantity {
+			return nil, fmt.Errorf("%w: product %d", ErrInsufficientStock, item.ProductID)
+		}
+	}
+	for _, item := range items {
+		m.reserved[item.ProductID] += item.Quantity
+	}
+	r := &types.Reservation{ID: len(m.reservations) + 1, UserID: userID, Status: types.ReservationActive, Items: items, ExpiresAt: expiresAt}
+	m.reservations[r.ID] = r
+	return r, nil
+}
+
+func (m *mockInventoryStore) GetReservation(id int) (*types.Reservation, error) {
+	r, ok := m.reservations[id]
+	if !ok {
+		return nil, ErrReservationNotFound
+	}
+	return r, nil
+}
+
+func (m *mockInventoryStore) finish(id int, status string) error {
+	r, ok := m.reservations[id]
+	if !ok {
+		return ErrReservationNotFound
+	}
+	if r.Status != types.ReservationActive {
+		return ErrReservationNotActive
+	}
+	for _, item := range r.Items {
+		m.reserved[item.ProductID] -= item.Quantity
+	}
+	r.Status = status
+	return nil
+}
+
+func (m *mockInventoryStore) ReleaseReservation(id int) error {
+	return m.finish(id, types.ReservationReleased)
+}
+
+func (m *mockInventoryStore) AdjustStock(productID int, payload types.StockAdjustmentPayload, actorID int) (*types.StockMovement, error) {
+	q, ok := m.quantity[productID]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	if q+payload.Change < m.reserved[productID] {
+		return nil, ErrBelowReserved
+	}
+	m.quantity[productID] += payload.Change
+	movement := types.StockMovement{ID: len(m.movements) + 1, ProductID: productID, QuantityChange: payload.Change, Reason: payload.Reason, ActorID: Actor(actorID), Note: payload.Note}
+	m.movements = append(m.movements, movement)
+	return &movement, nil
+}
+
+func (m *mockInventoryStore) GetStockMovements(productID int, limit, offset int) ([]types.StockMovement, error) {
+	movements := []types.StockMovement{}
+	for i := len(m.movements) - 1; i >= 0; i-- {
+		if m.movements[i].ProductID == productID {
+			movements = append(movements, m.movements[i])
+		}
+	}
+	return movements, nil
+}
+
+func (m *mockInventoryStore) GetStockDiscrepancies() ([]types.StockDiscrepancy, error) {
+	return []types.StockDiscrepancy{}, nil
+}
+
+func (m *mockInventoryStore) ReleaseExpiredReservations(now time.Time) (int, error) {
+	expired := 0
+	for id, r := range m.reservations {
+		if r.Status == types.ReservationActive && !r.ExpiresAt.After(now) {
+			m.finish(id, types.ReservationExpired)
+			expired++
+		}
+	}
+	return expired, nil
+}
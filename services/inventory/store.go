@@ -0,0 +1,344 @@
+package inventory
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+var (
+	ErrProductNotFound      = errors.New("product not found")
+	ErrInsufficientStock    = errors.New("insufficient stock")
+	ErrReservationNotFound  = errors.New("reservation not found")
+	ErrReservationNotActive = errors.New("reservation is no longer active")
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// GetStockLevel sums the ledger of the product rather than trusting the counters on the
+// products row, which only exist to make reservations atomic.
+func (s *Store) GetStockLevel(productID int) (*types.StockLevel, error) {
+	level := &types.StockLevel{ProductID: productID}
+	err := s.db.QueryRow(`
+		SELECT COALESCE(SUM(m.quantityChange), 0), COALESCE(SUM(m.reservedChange), 0)
+		FROM products p
+		LEFT JOIN stock_movements m ON m.productId = p.id
+		WHERE p.id = ?
+		GROUP BY p.id`, productID).Scan(&level.Quantity, &level.Reserved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	level.Available = max(level.Quantity-level.Reserved, 0)
+	return level, nil
+}
+
+// CreateReservation reserves the items with one conditional UPDATE per product, so two
+// concurrent checkouts can never both take the last unit. Products are updated in ID order
+// to keep the lock order stable and avoid deadlocks.
+func (s *Store) CreateReservation(userID int, items []types.ReservationItem, expiresAt time.Time) (*types.Reservation, error) {
+	items = mergeItems(items)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, item := range items {
+		result, err := tx.Exec(
+			"UPDATE products SET reserved = reserved + ? WHERE id = ? AND quantity >= reserved + ?",
+			item.Quantity, item.ProductID, item.Quantity,
+		)
+		if err != nil {
+			return nil, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if affected == 0 {
+			return nil, s.explainFailedReservation(tx, item.ProductID)
+		}
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO inventory_reservations (userId, status, expires_at) VALUES (?, ?, ?)",
+		userID, types.ReservationActive, expiresAt.UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		_, err := tx.Exec(
+			"INSERT INTO inventory_reservation_items (reservationId, productId, quantity) VALUES (?, ?, ?)",
+			id, item.ProductID, item.Quantity,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = RecordMovement(tx, types.StockMovement{
+			ProductID:      item.ProductID,
+			ReservedChange: item.Quantity,
+			Reason:         types.MovementReservation,
+			ActorID:        Actor(userID),
+			Reference:      reservationReference(int(id)),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetReservation(int(id))
+}
+
+func (s *Store) GetReservation(id int) (*types.Reservation, error) {
+	r := &types.Reservation{ID: id}
+	err := s.db.QueryRow("SELECT userId, status, expires_at, created_at FROM inventory_reservations WHERE id = ?", id).
+		Scan(&r.UserID, &r.Status, &r.ExpiresAt, &r.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.Items, err = getReservationItems(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *Store) ReleaseReservation(id int) error {
+	return s.finishReservation(id, types.ReservationReleased)
+}
+
+func (s *Store) ReleaseExpiredReservations(now time.Time) (int, error) {
+	rows, err := s.db.Query(
+		"SELECT id FROM inventory_reservations WHERE status = ? AND expires_at <= ?",
+		types.ReservationActive, now.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		err := s.finishReservation(id, types.ReservationExpired)
+		// The reservation may have been committed or released since it was listed
+		if errors.Is(err, ErrReservationNotActive) {
+			continue
+		}
+		if err != nil {
+			return expired, fmt.Errorf("failed to expire reservation %d: %w", id, err)
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// finishReservation moves an active reservation to status and gives its units back.
+// Expired reservations are recorded without an actor because the sweeper released them.
+func (s *Store) finishReservation(id int, status string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var current string
+	var userID int
+	err = tx.QueryRow("SELECT status, userId FROM inventory_reservations WHERE id = ? FOR UPDATE", id).Scan(&current, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrReservationNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if current != types.ReservationActive {
+		return ErrReservationNotActive
+	}
+
+	items, err := getReservationItems(tx, id)
+	if err != nil {
+		return err
+	}
+
+	if status == types.ReservationExpired {
+		userID = 0
+	}
+
+	for _, item := range items {
+		movement := types.StockMovement{
+			ProductID:      item.ProductID,
+			ReservedChange: -item.Quantity,
+			Reason:         types.MovementRelease,
+			ActorID:        Actor(userID),
+			Reference:      reservationReference(id),
+		}
+
+		if _, err := tx.Exec("UPDATE products SET reserved = reserved - ? WHERE id = ?", item.Quantity, item.ProductID); err != nil {
+			return err
+		}
+
+		if _, err := RecordMovement(tx, movement); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE inventory_reservations SET status = ? WHERE id = ?", status, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CommitReservation ends the active reservation id of userID as part of a checkout in tx
+// that sells items. Reserved units that are not sold are given back right away. It
+// returns how many of the sold units of each product the reservation held, which the
+// caller must take out of products.reserved as it takes them out of products.quantity.
+func CommitReservation(tx *sql.Tx, id, userID int, sold []types.ReservationItem) (map[int]int, error) {
+	var owner int
+	var status string
+	var expiresAt time.Time
+	err := tx.QueryRow("SELECT userId, status, expires_at FROM inventory_reservations WHERE id = ? FOR UPDATE", id).
+		Scan(&owner, &status, &expiresAt)
+	// Other users' reservations are reported as missing so their IDs can't be probed
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && owner != userID) {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// An expired reservation the sweeper hasn't released yet no longer holds anything
+	if status != types.ReservationActive || !expiresAt.After(time.Now()) {
+		return nil, ErrReservationNotActive
+	}
+
+	items, err := getReservationItems(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[int]int{}
+	for _, item := range sold {
+		wanted[item.ProductID] += item.Quantity
+	}
+
+	held := make(map[int]int, len(items))
+	for _, item := range items {
+		held[item.ProductID] = min(item.Quantity, wanted[item.ProductID])
+		unsold := item.Quantity - held[item.ProductID]
+		if unsold == 0 {
+			continue
+		}
+
+		if _, err := tx.Exec("UPDATE products SET reserved = reserved - ? WHERE id = ?", unsold, item.ProductID); err != nil {
+			return nil, err
+		}
+		_, err := RecordMovement(tx, types.StockMovement{
+			ProductID:      item.ProductID,
+			ReservedChange: -unsold,
+			Reason:         types.MovementRelease,
+			ActorID:        Actor(userID),
+			Reference:      reservationReference(id),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE inventory_reservations SET status = ? WHERE id = ?", types.ReservationCommitted, id); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+// explainFailedReservation tells a missing product apart from one that is out of stock.
+func (s *Store) explainFailedReservation(tx *sql.Tx, productID int) error {
+	var quantity, reserved int
+	err := tx.QueryRow("SELECT quantity, reserved FROM products WHERE id = ?", productID).Scan(&quantity, &reserved)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: product %d has %d available", ErrInsufficientStock, productID, max(quantity-reserved, 0))
+}
+
+func reservationReference(id int) string {
+	return fmt.Sprintf("reservation:%d", id)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+func getReservationItems(q queryer, reservationID int) ([]types.ReservationItem, error) {
+	rows, err := q.Query("SELECT productId, quantity FROM inventory_reservation_items WHERE reservationId = ? ORDER BY productId", reservationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []types.ReservationItem{}
+	for rows.Next() {
+		var item types.ReservationItem
+		if err := rows.Scan(&item.ProductID, &item.Quantity); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// mergeItems combines items for the same product and sorts them by product ID.
+func mergeItems(items []types.ReservationItem) []types.ReservationItem {
+	quantities := map[int]int{}
+	for _, item := range items {
+		quantities[item.ProductID] += item.Quantity
+	}
+
+	merged := make([]types.ReservationItem, 0, len(quantities))
+	for productID, quantity := range quantities {
+		merged = append(merged, types.ReservationItem{ProductID: productID, Quantity: quantity})
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].ProductID < merged[j].ProductID })
+	return merged
+}
@@ -0,0 +1,38 @@
+package inventory
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// StartSweeper releases expired reservations every interval until ctx is cancelled.
+// It runs in its own goroutine and returns immediately.
+func StartSweeper(ctx context.Context, store types.InventoryStore, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				sweep(store, now)
+			}
+		}
+	}()
+}
+
+// sweep runs one pass of the sweeper. Errors are logged and retried on the next tick.
+func sweep(store types.InventoryStore, now time.Time) {
+	expired, err := store.ReleaseExpiredReservations(now)
+	if err != nil {
+		log.Printf("failed to release expired reservations: %v", err)
+	}
+	if expired > 0 {
+		log.Printf("released %d expired reservations", expired)
+	}
+}
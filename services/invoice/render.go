@@ -0,0 +1,209 @@
+package invoice
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/code-farms/go-backend/pdf"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+)
+
+// Issuer is the business invoices are issued by.
+type Issuer struct {
+	Name    string   // The legal name of the business
+	Address []string // The address, printed one line after the other
+	TaxID   string   // The VAT or tax number, empty if none is printed
+}
+
+// Layout of an A4 page in points from the top left corner.
+const (
+	marginLeft   = 50.0
+	marginRight  = pdf.PageWidth - 50
+	pageBottom   = pdf.PageHeight - 60
+	lineHeight   = 14.0
+	fontSize     = 9.0
+	maxNameWidth = 250.0
+)
+
+// columns are the right edges of the amount columns of the item table, after the
+// description.
+var columns = []struct {
+	title string
+	right float64
+}{
+	{"Qty", 340},
+	{"Unit price", 405},
+	{"Discount", 460},
+	{"Tax", 500},
+	{"Amount", marginRight},
+}
+
+// Render lays out the invoice of an order for customer as a PDF. Amounts are shown in
+// currency, an ISO 4217 code.
+func Render(inv types.Invoice, o types.Order, customer types.User, issuer Issuer, currency string) []byte {
+	currency = strings.ToUpper(currency)
+	doc := pdf.New()
+	doc.Title = "Invoice " + inv.Number
+	doc.Author = issuer.Name
+	doc.Created = inv.IssuedAt
+
+	page := doc.AddPage()
+	y := 60.0
+
+	// Issuer on the left, invoice details on the right
+	page.Text(marginLeft, y, pdf.HelveticaBold, 14, issuer.Name)
+	page.TextRight(marginRight, y, pdf.HelveticaBold, 18, "INVOICE")
+	left := []string{}
+	left = append(left, issuer.Address...)
+	if issuer.TaxID != "" {
+		left = append(left, "Tax ID: "+issuer.TaxID)
+	}
+	right := []string{
+		"Invoice number: " + inv.Number,
+		"Invoice date: " + inv.IssuedAt.Format("2006-01-02"),
+		fmt.Sprintf("Order: #%d of %s", o.ID, o.CreatedAt.Format("2006-01-02")),
+	}
+	y = twoColumns(page, y+lineHeight+4, left, right)
+
+	// Who is billed and where the order goes
+	y += lineHeight
+	page.Text(marginLeft, y, pdf.HelveticaBold, fontSize, "Bill to")
+	page.Text(300, y, pdf.HelveticaBold, fontSize, "Ship to")
+	billTo := []string{strings.TrimSpace(customer.FirstName + " " + customer.LastName), customer.Email}
+	shipTo := shippingAddress(o)
+	y = twoColumnsAt(page, y+lineHeight, billTo, 300, shipTo)
+
+	// Items, continued on new pages as needed
+	y += lineHeight
+	y = tableHeader(page, y)
+	for _, item := range o.Items {
+		if y > pageBottom {
+			page = doc.AddPage()
+			y = tableHeader(page, 60)
+		}
+		name := item.ProductName
+		if item.ProductSKU != "" {
+			name += " (" + item.ProductSKU + ")"
+		}
+		amount := utils.ToCents(item.Price)*int64(item.Quantity) - utils.ToCents(item.Discount) + utils.ToCents(item.Tax)
+		page.Text(marginLeft, y, pdf.Helvetica, fontSize, truncate(name, maxNameWidth))
+		cells := []string{
+			fmt.Sprint(item.Quantity),
+			money(item.Price),
+			money(-item.Discount),
+			money(item.Tax),
+			utils.FormatCents(amount),
+		}
+		for i, cell := range cells {
+			page.TextRight(columns[i].right, y, pdf.Helvetica, fontSize, cell)
+		}
+		y += lineHeight
+	}
+	page.Line(marginLeft, y-lineHeight+4, marginRight, y-lineHeight+4, 0.5)
+
+	// Totals
+	totals := [][2]string{{"Subtotal", money(o.Subtotal)}}
+	if o.Discount != 0 {
+		label := "Discount"
+		if o.PromotionCode != "" {
+			label += " (" + o.PromotionCode + ")"
+		}
+		totals = append(totals, [2]string{label, money(-o.Discount)})
+	}
+	if o.ShippingMethod != "" {
+		totals = append(totals, [2]string{"Shipping (" + o.ShippingMethod + ")", money(o.Shipping)})
+	}
+	for _, tax := range o.Taxes {
+		label := fmt.Sprintf("%s %s%%", tax.Name, strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.3f", tax.Rate), "0"), "."))
+		if tax.Inclusive {
+			label += " (included)"
+		}
+		totals = append(totals, [2]string{label, money(tax.Amount)})
+	}
+	if y+lineHeight*float64(len(totals)+2) > pageBottom {
+		page = doc.AddPage()
+		y = 60
+	}
+	y += 4
+	for _, total := range totals {
+		page.TextRight(columns[3].right+15, y, pdf.Helvetica, fontSize, total[0])
+		page.TextRight(marginRight, y, pdf.Helvetica, fontSize, total[1])
+		y += lineHeight
+	}
+	page.TextRight(columns[3].right+15, y+2, pdf.HelveticaBold, 11, "Total ("+currency+")")
+	page.TextRight(marginRight, y+2, pdf.HelveticaBold, 11, money(o.Total))
+
+	page.Text(marginLeft, pdf.PageHeight-40, pdf.Helvetica, 8, fmt.Sprintf("%s - invoice %s", issuer.Name, inv.Number))
+	return doc.Bytes()
+}
+
+// tableHeader writes the titles of the item table at y and returns the y of its first row.
+func tableHeader(page *pdf.Page, y float64) float64 {
+	page.Text(marginLeft, y, pdf.HelveticaBold, fontSize, "Item")
+	for _, c := range columns {
+		page.TextRight(c.right, y, pdf.HelveticaBold, fontSize, c.title)
+	}
+	page.Line(marginLeft, y+4, marginRight, y+4, 0.5)
+	return y + lineHeight + 2
+}
+
+// twoColumns writes left at the left margin and right aligned to the right margin, one
+// line each, and returns the y below the longer column.
+func twoColumns(page *pdf.Page, y float64, left, right []string) float64 {
+	for i := 0; i < max(len(left), len(right)); i++ {
+		if i < len(left) {
+			page.Text(marginLeft, y, pdf.Helvetica, fontSize, left[i])
+		}
+		if i < len(right) {
+			page.TextRight(marginRight, y, pdf.Helvetica, fontSize, right[i])
+		}
+		y += lineHeight
+	}
+	return y
+}
+
+// twoColumnsAt is twoColumns with the second column left aligned at x.
+func twoColumnsAt(page *pdf.Page, y float64, left []string, x float64, right []string) float64 {
+	for i := 0; i < max(len(left), len(right)); i++ {
+		if i < len(left) {
+			page.Text(marginLeft, y, pdf.Helvetica, fontSize, left[i])
+		}
+		if i < len(right) {
+			page.Text(x, y, pdf.Helvetica, fontSize, right[i])
+		}
+		y += lineHeight
+	}
+	return y
+}
+
+// shippingAddress returns the lines of the delivery address of an order.
+func shippingAddress(o types.Order) []string {
+	lines := []string{}
+	for _, line := range strings.Split(o.Address, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	last := strings.TrimSpace(strings.Join([]string{o.PostalCode, o.Region, o.Country}, " "))
+	if last != "" {
+		lines = append(lines, strings.Join(strings.Fields(last), " "))
+	}
+	return lines
+}
+
+// truncate shortens s with an ellipsis so it fits in width at the table font size.
+func truncate(s string, width float64) string {
+	if pdf.Width(pdf.Helvetica, fontSize, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && pdf.Width(pdf.Helvetica, fontSize, string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+func money(amount float64) string {
+	return utils.FormatCents(utils.ToCents(amount))
+}
@@ -0,0 +1,63 @@
+package invoice
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestRender(t *testing.T) {
+	inv := types.Invoice{Number: "INV-2025-000042", IssuedAt: time.Date(2025, 1, 8, 10, 0, 0, 0, time.UTC)}
+	o := types.Order{
+		ID: 7, Subtotal: 39.98, Discount: 4, PromotionCode: "WINTER", ShippingMethod: "Standard", Shipping: 4.5, Total: 47.31,
+		Address: "1 Main Street\nFlat 2", Country: "DE", PostalCode: "10115",
+		Items: []types.OrderItem{
+			{ProductName: "Mug", ProductSKU: "MUG-1", Quantity: 2, Price: 19.99, Discount: 4, Tax: 6.83},
+		},
+		Taxes: []types.TaxLine{{Name: "VAT", Rate: 19, Amount: 6.83}},
+	}
+	customer := types.User{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"}
+	issuer := Issuer{Name: "Shop GmbH", Address: []string{"Street 1", "Berlin"}, TaxID: "DE123"}
+
+	out := Render(inv, o, customer, issuer, "eur")
+
+	for _, want := range []string{
+		"(Invoice number: INV-2025-000042) Tj",
+		"(Tax ID: DE123) Tj",
+		"(Ada Lovelace) Tj",
+		"(Flat 2) Tj",
+		"(10115 DE) Tj",
+		"(Mug \\(MUG-1\\)) Tj",
+		"(42.81) Tj",
+		"(Discount \\(WINTER\\)) Tj",
+		"(Shipping \\(Standard\\)) Tj",
+		"(VAT 19%) Tj",
+		"(Total \\(EUR\\)) Tj",
+		"(47.31) Tj",
+	} {
+		if !bytes.Contains(out, []byte(want)) {
+			t.Errorf("expected the invoice to contain %q", want)
+		}
+	}
+
+	t.Run("should continue long orders on new pages", func(t *testing.T) {
+		long := o
+		long.Items = nil
+		for i := 0; i < 80; i++ {
+			long.Items = append(long.Items, types.OrderItem{ProductName: strings.Repeat("Item ", 20), Quantity: 1, Price: 1})
+		}
+		out := Render(inv, long, customer, issuer, "eur")
+		if bytes.Contains(out, []byte("/Count 1")) {
+			t.Error("expected more than one page")
+		}
+		if bytes.Count(out, []byte("(Unit price) Tj")) < 2 {
+			t.Error("expected the table header on every page")
+		}
+		if !bytes.Contains(out, []byte("...) Tj")) {
+			t.Error("expected long names to be truncated")
+		}
+	})
+}
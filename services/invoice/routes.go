@@ -0,0 +1,110 @@
+package invoice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/storage"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.InvoiceStore
+	orderStore types.OrderStore
+	userStore  types.UserStore
+	blobs      types.BlobStore
+}
+
+func NewHandler(store types.InvoiceStore, orderStore types.OrderStore, userStore types.UserStore, blobs types.BlobStore) *Handler {
+	return &Handler{store: store, orderStore: orderStore, userStore: userStore, blobs: blobs}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orders/{id:[0-9]+}/invoice.pdf", auth.WithJWTAuth(h.handleGetInvoice, h.userStore)).Methods(http.MethodGet)
+}
+
+// handleGetInvoice serves the invoice of an order to its customer or an admin, issuing it
+// on first request. The document is rendered once and stored, so it is served unchanged
+// afterwards even if the order or the issuer details change.
+func (h *Handler) handleGetInvoice(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	o, err := h.orderStore.GetOrder(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if o.UserID != auth.GetUserIDFromContext(r.Context()) && !auth.IsAdmin(r.Context()) {
+		writeStoreError(w, order.ErrOrderNotFound)
+		return
+	}
+
+	inv, err := h.store.IssueInvoice(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	blob, err := h.blobs.Get(r.Context(), inv.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		// First request, or the upload after issuing failed: the number is already taken,
+		// so render the document for it now
+		blob, err = h.storeDocument(r, *inv, *o)
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", inv.Number+".pdf"))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, blob)
+}
+
+// storeDocument renders the invoice, stores it under its key and returns its content.
+func (h *Handler) storeDocument(r *http.Request, inv types.Invoice, o types.Order) (io.ReadCloser, error) {
+	customer, err := h.userStore.GetUserById(o.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := Render(inv, o, *customer, configuredIssuer(), configs.Envs.PaymentCurrency)
+	if err := h.blobs.Put(r.Context(), inv.StorageKey, bytes.NewReader(doc), int64(len(doc)), "application/pdf"); err != nil {
+		return nil, fmt.Errorf("failed to store invoice: %w", err)
+	}
+	return io.NopCloser(bytes.NewReader(doc)), nil
+}
+
+// configuredIssuer returns the issuer set by the INVOICE_ISSUER settings.
+func configuredIssuer() Issuer {
+	issuer := Issuer{Name: configs.Envs.InvoiceIssuer, TaxID: configs.Envs.InvoiceIssuerTaxID}
+	for _, line := range strings.Split(configs.Envs.InvoiceIssuerAddress, ";") {
+		if line = strings.TrimSpace(line); line != "" {
+			issuer.Address = append(issuer.Address, line)
+		}
+	}
+	return issuer
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, order.ErrOrderNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrOrderNotInvoiceable):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
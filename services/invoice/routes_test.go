@@ -0,0 +1,196 @@
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/storage"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestGetInvoice(t *testing.T) {
+	orderStore := &mockOrderStore{orders: map[int]*types.Order{
+		1: {ID: 1, UserID: 1, Status: types.OrderShipped, Total: 19.99, Items: []types.OrderItem{
+			{ID: 1, OrderID: 1, ProductID: 1, ProductName: "Mug", Quantity: 1, Price: 19.99},
+		}},
+		2: {ID: 2, UserID: 1, Status: types.OrderPending},
+	}}
+	store := &mockInvoiceStore{orders: orderStore, invoices: map[int]*types.Invoice{}, last: map[int]int{2025: 41}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer, FirstName: "Ada", LastName: "Lovelace"},
+		2: {ID: 2, Role: types.RoleCustomer},
+		3: {ID: 3, Role: types.RoleAdmin},
+	}}
+	blobs, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewHandler(store, orderStore, userStore, blobs)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	get := func(path string, userID int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should hide other users' invoices", func(t *testing.T) {
+		if rr := get("/orders/1/invoice.pdf", 2); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+		if len(store.invoices) != 0 {
+			t.Error("expected no invoice to be issued")
+		}
+	})
+
+	t.Run("should not invoice unpaid orders", func(t *testing.T) {
+		if rr := get("/orders/2/invoice.pdf", 1); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should issue, render and store the invoice", func(t *testing.T) {
+		rr := get("/orders/1/invoice.pdf", 1)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/pdf" {
+			t.Errorf("expected application/pdf but got %q", ct)
+		}
+		if cd := rr.Header().Get("Content-Disposition"); cd != `inline; filename="INV-2025-000042.pdf"` {
+			t.Errorf("unexpected Content-Disposition %q", cd)
+		}
+		if !bytes.HasPrefix(rr.Body.Bytes(), []byte("%PDF-")) {
+			t.Error("expected a PDF document")
+		}
+
+		blob, err := blobs.Get(context.Background(), "invoices/2025/INV-2025-000042.pdf")
+		if err != nil {
+			t.Fatalf("expected the document to be stored: %v", err)
+		}
+		defer blob.Close()
+		stored, _ := io.ReadAll(blob)
+		if !bytes.Equal(stored, rr.Body.Bytes()) {
+			t.Error("expected the stored document to be served")
+		}
+	})
+
+	t.Run("should serve the stored document again", func(t *testing.T) {
+		orderStore.orders[1].Items[0].ProductName = "Renamed"
+
+		rr := get("/orders/1/invoice.pdf", 3)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if cd := rr.Header().Get("Content-Disposition"); cd != `inline; filename="INV-2025-000042.pdf"` {
+			t.Errorf("expected the same number but got %q", cd)
+		}
+		if bytes.Contains(rr.Body.Bytes(), []byte("Renamed")) {
+			t.Error("expected the document issued first")
+		}
+		if store.last[2025] != 42 {
+			t.Errorf("expected one number to be taken but the sequence is at %d", store.last[2025])
+		}
+	})
+}
+
+func TestInvoiceNumber(t *testing.T) {
+	if got := invoiceNumber(2025, 42); got != "INV-2025-000042" {
+		t.Errorf("expected INV-2025-000042 but got %s", got)
+	}
+}
+
+// mockInvoiceStore issues invoices in 2025, numbering them from a sequence per year.
+type mockInvoiceStore struct {
+	orders   *mockOrderStore
+	invoices map[int]*types.Invoice
+	last     map[int]int
+}
+
+func (m *mockInvoiceStore) IssueInvoice(orderID int) (*types.Invoice, error) {
+	o, err := m.orders.GetOrder(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if inv, ok := m.invoices[orderID]; ok {
+		return inv, nil
+	}
+	if !invoiceable(o.Status) {
+		return nil, ErrOrderNotInvoiceable
+	}
+
+	m.last[2025]++
+	inv := &types.Invoice{
+		ID:       len(m.invoices) + 1,
+		OrderID:  orderID,
+		Number:   invoiceNumber(2025, m.last[2025]),
+		Year:     2025,
+		Sequence: m.last[2025],
+		IssuedAt: time.Date(2025, 1, 8, 10, 0, 0, 0, time.UTC),
+	}
+	inv.StorageKey = fmt.Sprintf("invoices/%d/%s.pdf", inv.Year, inv.Number)
+	m.invoices[orderID] = inv
+	return inv, nil
+}
+
+type mockOrderStore struct {
+	orders map[int]*types.Order
+}
+
+func (m *mockOrderStore) CreateOrder(o types.Order, items []types.OrderItem, reservationID int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockOrderStore) GetOrders(filter types.OrderFilter, limit, offset int) ([]types.Order, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetOrder(id int) (*types.Order, error) {
+	o, ok := m.orders[id]
+	if !ok {
+		return nil, order.ErrOrderNotFound
+	}
+	c := *o
+	return &c, nil
+}
+
+func (m *mockOrderStore) TransitionOrder(id int, to string, actorID int, note string) (*types.OrderStatusChange, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetOrderStatusHistory(orderID int) ([]types.OrderStatusChange, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) CancelOrder(id int, actorID int, note string) (*types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) RefundOrder(id int, payload types.RefundPayload, actorID int) (*types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetRefunds(orderID int) ([]types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetRefund(id int) (*types.Refund, error) {
+	return nil, order.ErrRefundNotFound
+}
+
+func (m *mockOrderStore) SettleRefund(id int, status string, paymentID *int) error {
+	return nil
+}
@@ -0,0 +1,120 @@
+package invoice
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+)
+
+// ErrOrderNotInvoiceable is returned for orders that were not paid or were cancelled.
+var ErrOrderNotInvoiceable = errors.New("only orders being fulfilled or delivered can be invoiced")
+
+// invoiceColumns lists the columns read by scanning an invoice, in scan order.
+const invoiceColumns = "id, orderId, number, year, sequence, storageKey, issuedAt"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// IssueInvoice locks the order so an invoice requested twice at once is issued once, then
+// takes the next number of the year from its locked sequence row. A transaction that
+// fails after taking a number rolls the sequence back with it, so no number is skipped.
+func (s *Store) IssueInvoice(orderID int) (*types.Invoice, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, order.ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := scanInvoice(tx.QueryRow("SELECT "+invoiceColumns+" FROM invoices WHERE orderId = ?", orderID))
+	if err == nil {
+		return inv, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if !invoiceable(status) {
+		return nil, ErrOrderNotInvoiceable
+	}
+
+	issuedAt := time.Now().UTC().Truncate(time.Second)
+	year := issuedAt.Year()
+	if _, err := tx.Exec("INSERT IGNORE INTO invoice_sequences (year) VALUES (?)", year); err != nil {
+		return nil, err
+	}
+	var last int
+	if err := tx.QueryRow("SELECT lastNumber FROM invoice_sequences WHERE year = ? FOR UPDATE", year).Scan(&last); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE invoice_sequences SET lastNumber = ? WHERE year = ?", last+1, year); err != nil {
+		return nil, err
+	}
+
+	inv = &types.Invoice{
+		OrderID:  orderID,
+		Number:   invoiceNumber(year, last+1),
+		Year:     year,
+		Sequence: last + 1,
+		IssuedAt: issuedAt,
+	}
+	inv.StorageKey = fmt.Sprintf("invoices/%d/%s.pdf", year, inv.Number)
+	result, err := tx.Exec(
+		"INSERT INTO invoices (orderId, number, year, sequence, storageKey, issuedAt) VALUES (?, ?, ?, ?, ?, ?)",
+		inv.OrderID, inv.Number, inv.Year, inv.Sequence, inv.StorageKey, inv.IssuedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert invoice: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	inv.ID = int(id)
+
+	return inv, tx.Commit()
+}
+
+// invoiceable reports whether an order in status can get an invoice. Pending orders are
+// not paid yet and cancelled ones were refunded.
+func invoiceable(status string) bool {
+	return status == types.OrderProcessing || status == types.OrderShipped || status == types.OrderDelivered
+}
+
+// invoiceNumber formats the invoice number of the sequence-th invoice of year.
+func invoiceNumber(year, sequence int) string {
+	return fmt.Sprintf("INV-%d-%06d", year, sequence)
+}
+
+func scanInvoice(row *sql.Row) (*types.Invoice, error) {
+	inv := new(types.Invoice)
+	err := row.Scan(
+		&inv.ID,
+		&inv.OrderID,
+		&inv.Number,
+		&inv.Year,
+		&inv.Sequence,
+		&inv.StorageKey,
+		&inv.IssuedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
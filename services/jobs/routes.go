@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.JobStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.JobStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/jobs", auth.WithAdminAuth(h.handleGetJobs, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/jobs/{id:[0-9]+}", auth.WithAdminAuth(h.handleGetJob, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/jobs/{id:[0-9]+}/retry", auth.WithAdminAuth(h.handleRetryJob, h.userStore)).Methods(http.MethodPost)
+}
+
+// handleGetJobs returns a page of jobs, newest first, optionally only those in the status
+// and of the kind given by the status and kind parameters.
+func (h *Handler) handleGetJobs(w http.ResponseWriter, r *http.Request) {
+	page, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter := types.JobFilter{Status: r.URL.Query().Get("status"), Kind: r.URL.Query().Get("kind")}
+	switch filter.Status {
+	case "", types.JobPending, types.JobRunning, types.JobSucceeded, types.JobDead:
+	default:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid status %q", filter.Status))
+		return
+	}
+
+	jobs, err := h.store.GetJobs(filter, page.Limit, page.Offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"jobs":  jobs,
+		"page":  page.Page,
+		"limit": page.Limit,
+	})
+}
+
+func (h *Handler) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	job, err := h.store.GetJob(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, job)
+}
+
+// handleRetryJob runs a dead job again with a fresh set of attempts, e.g. once the service
+// it kept failing on is back. The payload stays the same.
+func (h *Handler) handleRetryJob(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.store.RetryJob(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	job, err := h.store.GetJob(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, job)
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrJobNotDead):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestJobs(t *testing.T) {
+	store := &mockJobStore{}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleAdmin},
+	}}
+
+	handler := NewHandler(store, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	store.EnqueueJob(greetJob{Name: "ada"}, types.JobOptions{})
+	store.EnqueueJob(types.SendEmailJob{Email: types.Email{To: []string{"ada@example.com"}}}, types.JobOptions{})
+	store.get(2).Status = types.JobDead
+
+	t.Run("should only let admins see jobs", func(t *testing.T) {
+		if rr := send(http.MethodGet, "/admin/jobs", 1); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should filter jobs by status and kind", func(t *testing.T) {
+		rr := send(http.MethodGet, "/admin/jobs?status=dead&kind=send_email", 2)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		var body struct {
+			Jobs []types.Job `json:"jobs"`
+		}
+		json.NewDecoder(rr.Body).Decode(&body)
+		if len(body.Jobs) != 1 || body.Jobs[0].ID != 2 {
+			t.Errorf("expected only the dead email job but got %+v", body.Jobs)
+		}
+
+		if rr := send(http.MethodGet, "/admin/jobs?status=failed", 2); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should only retry dead jobs", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/admin/jobs/1/retry", 2); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+		if rr := send(http.MethodPost, "/admin/jobs/9/retry", 2); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+
+		rr := send(http.MethodPost, "/admin/jobs/2/retry", 2)
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusAccepted, rr.Code, rr.Body)
+		}
+		var job types.Job
+		json.NewDecoder(rr.Body).Decode(&job)
+		if job.Status != types.JobPending || job.Attempts != 0 {
+			t.Errorf("expected the job to be pending again but got %+v", job)
+		}
+	})
+}
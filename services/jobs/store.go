@@ -0,0 +1,242 @@
+package jobs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobNotDead  = errors.New("only dead jobs can be retried")
+)
+
+// DefaultMaxAttempts is how many times a job is tried unless its options say otherwise.
+const DefaultMaxAttempts = 8
+
+// maxErrorLength is the size of the jobs.lastError column.
+const maxErrorLength = 1024
+
+// jobColumns lists the columns read by scanRowIntoJob, in scan order.
+const jobColumns = "id, kind, payload, status, attempts, maxAttempts, runAt, lastError, created_at, finishedAt"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func (s *Store) EnqueueJob(payload types.JobPayload, opts types.JobOptions) (int, error) {
+	return enqueue(s.db, payload, opts)
+}
+
+// Enqueue stores a job inside tx, so it only runs if the transaction commits.
+func Enqueue(tx *sql.Tx, payload types.JobPayload, opts types.JobOptions) (int, error) {
+	return enqueue(tx, payload, opts)
+}
+
+// enqueue schedules delayed jobs with the database clock, which every worker shares.
+func enqueue(db execer, payload types.JobPayload, opts types.JobOptions) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode %s job: %w", payload.JobKind(), err)
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	var result sql.Result
+	if opts.RunAt != nil {
+		result, err = db.Exec(
+			"INSERT INTO jobs (kind, payload, maxAttempts, runAt) VALUES (?, ?, ?, ?)",
+			payload.JobKind(), body, maxAttempts, *opts.RunAt,
+		)
+	} else {
+		result, err = db.Exec(
+			"INSERT INTO jobs (kind, payload, maxAttempts, runAt) VALUES (?, ?, ?, CURRENT_TIMESTAMP + INTERVAL ? SECOND)",
+			payload.JobKind(), body, maxAttempts, int(opts.Delay.Seconds()),
+		)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue %s job: %w", payload.JobKind(), err)
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *Store) GetJobs(filter types.JobFilter, limit, offset int) ([]types.Job, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if filter.Status != "" {
+		where, args = append(where, "status = ?"), append(args, filter.Status)
+	}
+	if filter.Kind != "" {
+		where, args = append(where, "kind = ?"), append(args, filter.Kind)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+jobColumns+" FROM jobs WHERE "+strings.Join(where, " AND ")+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []types.Job{}
+	for rows.Next() {
+		j, err := scanRowIntoJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, *j)
+	}
+	return jobs, rows.Err()
+}
+
+func (s *Store) GetJob(id int) (*types.Job, error) {
+	rows, err := s.db.Query("SELECT "+jobColumns+" FROM jobs WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrJobNotFound
+	}
+	return scanRowIntoJob(rows)
+}
+
+func (s *Store) RetryJob(id int) error {
+	result, err := s.db.Exec(
+		`UPDATE jobs SET status = ?, attempts = 0, runAt = CURRENT_TIMESTAMP, finishedAt = NULL
+		WHERE id = ? AND status = ?`,
+		types.JobPending, id, types.JobDead,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM jobs WHERE id = ?)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrJobNotFound
+	}
+	return ErrJobNotDead
+}
+
+// ClaimJob skips jobs claimed by other workers instead of waiting for them, and counts the
+// claim as an attempt so a job that keeps crashing its worker still ends up dead.
+func (s *Store) ClaimJob(kinds []string, lease time.Duration) (*types.Job, error) {
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	args := []any{types.JobPending, types.JobRunning}
+	for _, kind := range kinds {
+		args = append(args, kind)
+	}
+	rows, err := tx.Query(
+		`SELECT `+jobColumns+` FROM jobs
+		WHERE status IN (?, ?) AND runAt <= CURRENT_TIMESTAMP AND kind IN (?`+strings.Repeat(", ?", len(kinds)-1)+`)
+		ORDER BY runAt, id LIMIT 1
+		FOR UPDATE SKIP LOCKED`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	job, err := scanRowIntoJob(rows)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(
+		"UPDATE jobs SET status = ?, attempts = attempts + 1, runAt = CURRENT_TIMESTAMP + INTERVAL ? SECOND WHERE id = ?",
+		types.JobRunning, int(lease.Seconds()), job.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.Status = types.JobRunning
+	job.Attempts++
+
+	return job, tx.Commit()
+}
+
+func (s *Store) CompleteJob(id int) error {
+	_, err := s.db.Exec(
+		"UPDATE jobs SET status = ?, lastError = '', finishedAt = CURRENT_TIMESTAMP WHERE id = ?",
+		types.JobSucceeded, id,
+	)
+	return err
+}
+
+func (s *Store) FailJob(id int, retryAfter time.Duration, lastError string) error {
+	if len(lastError) > maxErrorLength {
+		lastError = lastError[:maxErrorLength]
+		for !utf8.ValidString(lastError) {
+			lastError = lastError[:len(lastError)-1]
+		}
+	}
+	if retryAfter > 0 {
+		_, err := s.db.Exec(
+			"UPDATE jobs SET status = ?, lastError = ?, runAt = CURRENT_TIMESTAMP + INTERVAL ? SECOND WHERE id = ?",
+			types.JobPending, lastError, int(retryAfter.Seconds()), id,
+		)
+		return err
+	}
+	_, err := s.db.Exec(
+		"UPDATE jobs SET status = ?, lastError = ?, finishedAt = CURRENT_TIMESTAMP WHERE id = ?",
+		types.JobDead, lastError, id,
+	)
+	return err
+}
+
+func scanRowIntoJob(rows *sql.Rows) (*types.Job, error) {
+	j := new(types.Job)
+	err := rows.Scan(
+		&j.ID,
+		&j.Kind,
+		&j.Payload,
+		&j.Status,
+		&j.Attempts,
+		&j.MaxAttempts,
+		&j.RunAt,
+		&j.LastError,
+		&j.CreatedAt,
+		&j.FinishedAt,
+	)
+	return j, err
+}
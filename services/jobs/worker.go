@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+const (
+	// jobTimeout bounds a single attempt of a job.
+	jobTimeout = 5 * time.Minute
+
+	// lease is how long a claimed job is held. It outlasts jobTimeout so a job is only
+	// claimed again once its worker is surely gone.
+	lease = jobTimeout + time.Minute
+
+	// firstRetry is the wait after the first failed attempt, doubled after every other one.
+	firstRetry = 30 * time.Second
+
+	// maxRetry caps the wait between two attempts.
+	maxRetry = 6 * time.Hour
+)
+
+// Worker runs the jobs of the kinds it has handlers for, several at a time.
+type Worker struct {
+	store       types.JobStore
+	concurrency int
+	handlers    map[string]func(ctx context.Context, payload json.RawMessage) error
+}
+
+// NewWorker returns a worker running up to concurrency jobs at once.
+func NewWorker(store types.JobStore, concurrency int) *Worker {
+	return &Worker{store: store, concurrency: max(concurrency, 1), handlers: map[string]func(context.Context, json.RawMessage) error{}}
+}
+
+// Handle registers fn to run the jobs of the kind of T, decoding their payload into a T.
+// It must be called before Start, and panics if the kind already has a handler.
+func Handle[T types.JobPayload](w *Worker, fn func(ctx context.Context, payload T) error) {
+	var zero T
+	kind := zero.JobKind()
+	if _, ok := w.handlers[kind]; ok {
+		panic(fmt.Sprintf("jobs: %s already has a handler", kind))
+	}
+	w.handlers[kind] = func(ctx context.Context, raw json.RawMessage) error {
+		var payload T
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			return fmt.Errorf("invalid payload: %w", err)
+		}
+		return fn(ctx, payload)
+	}
+}
+
+// Start runs jobs on concurrency goroutines until ctx is cancelled. Each one looks for a
+// due job every interval, and right away after running one. It returns immediately.
+func (w *Worker) Start(ctx context.Context, interval time.Duration) {
+	for i := 0; i < w.concurrency; i++ {
+		go func() {
+			for ctx.Err() == nil {
+				ran, err := w.RunNext(ctx)
+				if err != nil {
+					log.Printf("failed to run job: %v", err)
+				}
+				if ran && err == nil {
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+				case <-time.After(interval):
+				}
+			}
+		}()
+	}
+}
+
+// RunNext claims and runs the next due job and reports whether there was one.
+func (w *Worker) RunNext(ctx context.Context) (bool, error) {
+	job, err := w.store.ClaimJob(w.kinds(), lease)
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	// The claim counts as an attempt, so this is a job whose last attempt never finished
+	if job.Attempts > job.MaxAttempts {
+		return true, w.store.FailJob(job.ID, 0, "the worker stopped during the last attempt")
+	}
+
+	if err := w.run(ctx, job); err != nil {
+		var retryAfter time.Duration
+		if job.Attempts < job.MaxAttempts {
+			retryAfter = backoff(job.Attempts)
+		} else {
+			log.Printf("job %d (%s) is dead after %d attempts: %v", job.ID, job.Kind, job.Attempts, err)
+		}
+		return true, w.store.FailJob(job.ID, retryAfter, err.Error())
+	}
+	return true, w.store.CompleteJob(job.ID)
+}
+
+// run calls the handler of a job, turning a panic into an error so one bad job can't stop
+// the worker.
+func (w *Worker) run(ctx context.Context, job *types.Job) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return w.handlers[job.Kind](ctx, job.Payload)
+}
+
+// kinds returns the job kinds the worker has handlers for.
+func (w *Worker) kinds() []string {
+	kinds := make([]string, 0, len(w.handlers))
+	for kind := range w.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// backoff returns how long to wait before the attempt after the given number of failed
+// ones: 30s, 1m, 2m, 4m... up to maxRetry.
+func backoff(failed int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < failed && wait < maxRetry; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetry)
+}
@@ -0,0 +1,251 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+type greetJob struct {
+	Name string `json:"name"`
+}
+
+func (greetJob) JobKind() string { return "greet" }
+
+func TestWorker(t *testing.T) {
+	store := &mockJobStore{}
+	worker := NewWorker(store, 2)
+
+	greeted := []string{}
+	var fail error
+	Handle(worker, func(ctx context.Context, job greetJob) error {
+		if job.Name == "panic" {
+			panic("nil map")
+		}
+		if fail != nil {
+			return fail
+		}
+		greeted = append(greeted, job.Name)
+		return nil
+	})
+
+	t.Run("should report when there is nothing to run", func(t *testing.T) {
+		ran, err := worker.RunNext(context.Background())
+		if err != nil || ran {
+			t.Errorf("expected no job to run but got %v, %v", ran, err)
+		}
+	})
+
+	t.Run("should decode the payload and complete the job", func(t *testing.T) {
+		id, _ := store.EnqueueJob(greetJob{Name: "ada"}, types.JobOptions{})
+		if ran, err := worker.RunNext(context.Background()); err != nil || !ran {
+			t.Fatalf("expected the job to run but got %v, %v", ran, err)
+		}
+
+		if len(greeted) != 1 || greeted[0] != "ada" {
+			t.Errorf("expected ada to be greeted but got %v", greeted)
+		}
+		if j := store.get(id); j.Status != types.JobSucceeded || j.Attempts != 1 {
+			t.Errorf("expected the job to succeed on the first attempt but got %+v", j.Job)
+		}
+	})
+
+	t.Run("should not run jobs before they are due", func(t *testing.T) {
+		id, _ := store.EnqueueJob(greetJob{Name: "later"}, types.JobOptions{Delay: time.Hour})
+		if ran, _ := worker.RunNext(context.Background()); ran {
+			t.Errorf("expected the delayed job to wait but got %+v", store.get(id).Job)
+		}
+		store.get(id).RunAt = time.Now()
+		if ran, _ := worker.RunNext(context.Background()); !ran || store.get(id).Status != types.JobSucceeded {
+			t.Errorf("expected the job to run once due but got %+v", store.get(id).Job)
+		}
+	})
+
+	t.Run("should retry failed jobs with backoff", func(t *testing.T) {
+		fail = errors.New("smtp unavailable")
+		id, _ := store.EnqueueJob(greetJob{Name: "grace"}, types.JobOptions{})
+		if _, err := worker.RunNext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		j := store.get(id)
+		if j.Status != types.JobPending || j.retryAfter != firstRetry || j.LastError != "smtp unavailable" {
+			t.Errorf("expected the job to be retried but got %+v", j.Job)
+		}
+	})
+
+	t.Run("should mark jobs dead after the last attempt", func(t *testing.T) {
+		id, _ := store.EnqueueJob(greetJob{Name: "linus"}, types.JobOptions{MaxAttempts: 2})
+		for i := 0; i < 2; i++ {
+			store.get(id).RunAt = time.Now()
+			if _, err := worker.RunNext(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if j := store.get(id); j.Status != types.JobDead || j.Attempts != 2 || j.FinishedAt == nil {
+			t.Errorf("expected the job to be dead after 2 attempts but got %+v", j.Job)
+		}
+	})
+
+	t.Run("should recover from handlers that panic", func(t *testing.T) {
+		fail = nil
+		id, _ := store.EnqueueJob(greetJob{Name: "panic"}, types.JobOptions{})
+		if _, err := worker.RunNext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if j := store.get(id); j.Status != types.JobPending || !strings.Contains(j.LastError, "panic: nil map") {
+			t.Errorf("expected the panic to be retried but got %+v", j.Job)
+		}
+	})
+
+	t.Run("should not run a job again once its attempts are used up", func(t *testing.T) {
+		id, _ := store.EnqueueJob(greetJob{Name: "crash"}, types.JobOptions{MaxAttempts: 1})
+		store.get(id).Attempts = 1
+		if _, err := worker.RunNext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if j := store.get(id); j.Status != types.JobDead || len(greeted) != 2 {
+			t.Errorf("expected the job to die without running but got %+v and %v", j.Job, greeted)
+		}
+	})
+}
+
+func TestHandleTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected handling the same kind twice to panic")
+		}
+	}()
+
+	worker := NewWorker(&mockJobStore{}, 1)
+	handler := func(ctx context.Context, job greetJob) error { return nil }
+	Handle(worker, handler)
+	Handle(worker, handler)
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute, 20: maxRetry}
+	for failed, want := range cases {
+		if got := backoff(failed); got != want {
+			t.Errorf("backoff(%d): expected %v but got %v", failed, want, got)
+		}
+	}
+}
+
+type mockJob struct {
+	types.Job
+	retryAfter time.Duration
+}
+
+// mockJobStore keeps the queue in memory.
+type mockJobStore struct {
+	jobs []*mockJob
+}
+
+func (m *mockJobStore) get(id int) *mockJob {
+	return m.jobs[id-1]
+}
+
+func (m *mockJobStore) EnqueueJob(payload types.JobPayload, opts types.JobOptions) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	runAt := time.Now().Add(opts.Delay)
+	if opts.RunAt != nil {
+		runAt = *opts.RunAt
+	}
+
+	m.jobs = append(m.jobs, &mockJob{Job: types.Job{
+		ID:          len(m.jobs) + 1,
+		Kind:        payload.JobKind(),
+		Payload:     body,
+		Status:      types.JobPending,
+		MaxAttempts: maxAttempts,
+		RunAt:       runAt,
+		CreatedAt:   time.Now(),
+	}})
+	return len(m.jobs), nil
+}
+
+func (m *mockJobStore) GetJobs(filter types.JobFilter, limit, offset int) ([]types.Job, error) {
+	jobs := []types.Job{}
+	for i := len(m.jobs) - 1; i >= 0; i-- {
+		j := m.jobs[i]
+		if (filter.Status == "" || j.Status == filter.Status) && (filter.Kind == "" || j.Kind == filter.Kind) {
+			jobs = append(jobs, j.Job)
+		}
+	}
+	if offset >= len(jobs) {
+		return []types.Job{}, nil
+	}
+	return jobs[offset:min(offset+limit, len(jobs))], nil
+}
+
+func (m *mockJobStore) GetJob(id int) (*types.Job, error) {
+	if id < 1 || id > len(m.jobs) {
+		return nil, ErrJobNotFound
+	}
+	j := m.get(id).Job
+	return &j, nil
+}
+
+func (m *mockJobStore) RetryJob(id int) error {
+	if id < 1 || id > len(m.jobs) {
+		return ErrJobNotFound
+	}
+	j := m.get(id)
+	if j.Status != types.JobDead {
+		return ErrJobNotDead
+	}
+	j.Status, j.Attempts, j.RunAt, j.FinishedAt = types.JobPending, 0, time.Now(), nil
+	return nil
+}
+
+func (m *mockJobStore) ClaimJob(kinds []string, lease time.Duration) (*types.Job, error) {
+	for _, j := range m.jobs {
+		if j.Status != types.JobPending || j.RunAt.After(time.Now()) {
+			continue
+		}
+		for _, kind := range kinds {
+			if j.Kind == kind {
+				j.Status = types.JobRunning
+				j.Attempts++
+				claimed := j.Job
+				return &claimed, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockJobStore) CompleteJob(id int) error {
+	j := m.get(id)
+	now := time.Now()
+	j.Status, j.LastError, j.FinishedAt = types.JobSucceeded, "", &now
+	return nil
+}
+
+func (m *mockJobStore) FailJob(id int, retryAfter time.Duration, lastError string) error {
+	j := m.get(id)
+	j.retryAfter, j.LastError = retryAfter, lastError
+	if retryAfter > 0 {
+		j.Status, j.RunAt = types.JobPending, time.Now().Add(retryAfter)
+		return nil
+	}
+	now := time.Now()
+	j.Status, j.FinishedAt = types.JobDead, &now
+	return nil
+}
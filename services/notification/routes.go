@@ -0,0 +1,58 @@
+package notification
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.NotificationPreferenceStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.NotificationPreferenceStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/notifications/preferences", auth.WithJWTAuth(h.handleGetPreferences, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/notifications/preferences", auth.WithJWTAuth(h.handleUpdatePreferences, h.userStore)).Methods(http.MethodPut)
+}
+
+// handleGetPreferences lists the optional notifications of the authenticated user and
+// whether each is sent by email.
+func (h *Handler) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	prefs, err := h.store.GetNotificationPreferences(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, prefs)
+}
+
+// handleUpdatePreferences turns optional notifications on or off. Kinds left out of the
+// payload keep their setting.
+func (h *Handler) handleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	var payload types.NotificationPreferencesPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	userID := auth.GetUserIDFromContext(r.Context())
+	if err := h.store.UpdateNotificationPreferences(userID, payload.Preferences); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.handleGetPreferences(w, r)
+}
@@ -0,0 +1,108 @@
+package notification
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestPreferences(t *testing.T) {
+	store := &mockPreferenceStore{saved: map[int]map[string]bool{}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{1: {ID: 1}, 2: {ID: 2}}}
+
+	handler := NewHandler(store, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method string, userID int, payload any) ([]types.NotificationPreference, *httptest.ResponseRecorder) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, "/notifications/preferences", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var prefs []types.NotificationPreference
+		json.Unmarshal(rr.Body.Bytes(), &prefs)
+		return prefs, rr
+	}
+	enabled := func(prefs []types.NotificationPreference, kind string) bool {
+		for _, p := range prefs {
+			if p.Kind == kind {
+				return p.Email
+			}
+		}
+		t.Fatalf("missing %s in %+v", kind, prefs)
+		return false
+	}
+
+	t.Run("should enable every optional notification by default", func(t *testing.T) {
+		prefs, rr := send(http.MethodGet, 1, nil)
+		if rr.Code != http.StatusOK || len(prefs) != len(types.OptionalNotifications) {
+			t.Fatalf("unexpected response %d: %s", rr.Code, rr.Body)
+		}
+		for _, p := range prefs {
+			if !p.Email {
+				t.Errorf("expected %s to be enabled", p.Kind)
+			}
+		}
+	})
+
+	t.Run("should turn notifications off", func(t *testing.T) {
+		off := false
+		payload := types.NotificationPreferencesPayload{Preferences: []types.NotificationPreferencePayload{
+			{Kind: types.NotificationOrderShipped, Email: &off},
+		}}
+		prefs, rr := send(http.MethodPut, 1, payload)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if enabled(prefs, types.NotificationOrderShipped) || !enabled(prefs, types.NotificationOrderConfirmation) {
+			t.Errorf("unexpected preferences %+v", prefs)
+		}
+
+		other, _ := send(http.MethodGet, 2, nil)
+		if !enabled(other, types.NotificationOrderShipped) {
+			t.Error("expected other users to keep their preferences")
+		}
+	})
+
+	t.Run("should not let users turn off account emails", func(t *testing.T) {
+		off := false
+		payload := types.NotificationPreferencesPayload{Preferences: []types.NotificationPreferencePayload{
+			{Kind: types.NotificationPasswordReset, Email: &off},
+		}}
+		if _, rr := send(http.MethodPut, 1, payload); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+		missing := types.NotificationPreferencesPayload{Preferences: []types.NotificationPreferencePayload{
+			{Kind: types.NotificationOrderShipped},
+		}}
+		if _, rr := send(http.MethodPut, 1, missing); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d without a setting but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+type mockPreferenceStore struct {
+	saved map[int]map[string]bool
+}
+
+func (m *mockPreferenceStore) GetNotificationPreferences(userID int) ([]types.NotificationPreference, error) {
+	return withDefaults(m.saved[userID]), nil
+}
+
+func (m *mockPreferenceStore) UpdateNotificationPreferences(userID int, prefs []types.NotificationPreferencePayload) error {
+	if m.saved[userID] == nil {
+		m.saved[userID] = map[string]bool{}
+	}
+	for _, p := range prefs {
+		m.saved[userID][p.Kind] = *p.Email
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package notification
+
+import (
+	"database/sql"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetNotificationPreferences(userID int) ([]types.NotificationPreference, error) {
+	rows, err := s.db.Query("SELECT kind, email FROM notification_preferences WHERE userId = ?", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	saved := map[string]bool{}
+	for rows.Next() {
+		var kind string
+		var email bool
+		if err := rows.Scan(&kind, &email); err != nil {
+			return nil, err
+		}
+		saved[kind] = email
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return withDefaults(saved), nil
+}
+
+func (s *Store) UpdateNotificationPreferences(userID int, prefs []types.NotificationPreferencePayload) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, p := range prefs {
+		_, err := tx.Exec(
+			"INSERT INTO notification_preferences (userId, kind, email) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE email = VALUES(email)",
+			userID, p.Kind, *p.Email,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// withDefaults lists every optional kind in a fixed order, enabled unless saved says
+// otherwise. Saved kinds that are no longer optional are left out.
+func withDefaults(saved map[string]bool) []types.NotificationPreference {
+	prefs := make([]types.NotificationPreference, len(types.OptionalNotifications))
+	for i, kind := range types.OptionalNotifications {
+		email, ok := saved[kind]
+		prefs[i] = types.NotificationPreference{Kind: kind, Email: email || !ok}
+	}
+	return prefs
+}
@@ -0,0 +1,175 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/code-farms/go-backend/services/inventory"
+	"github.com/code-farms/go-backend/services/outbox"
+	"github.com/code-farms/go-backend/types"
+)
+
+// ErrInvalidTransition is returned when the order lifecycle does not allow a status change.
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+// transitions lists the statuses an order may move to from each status. Delivered and
+// cancelled orders are final.
+var transitions = map[string][]string{
+	types.OrderPending:    {types.OrderProcessing, types.OrderCancelled},
+	types.OrderProcessing: {types.OrderShipped, types.OrderCancelled},
+	types.OrderShipped:    {types.OrderDelivered},
+	types.OrderDelivered:  {},
+	types.OrderCancelled:  {},
+}
+
+// CanTransition reports whether an order in status from may move to status to.
+func CanTransition(from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// historyColumns lists the columns read by scanRowIntoStatusChange, in scan order.
+const historyColumns = "id, orderId, COALESCE(fromStatus, ''), toStatus, actorId, note, created_at"
+
+// RecordStatusChange appends a transition to the status history of an order. It must run
+// in the same transaction as the change to orders.status so the two never disagree.
+func RecordStatusChange(tx *sql.Tx, c types.OrderStatusChange) (int, error) {
+	result, err := tx.Exec(
+		"INSERT INTO order_status_history (orderId, fromStatus, toStatus, actorId, note) VALUES (?, ?, ?, ?, ?)",
+		c.OrderID, sql.NullString{String: c.FromStatus, Valid: c.FromStatus != ""}, c.ToStatus, c.ActorID, c.Note,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record order status change: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+// TransitionOrder locks the order so concurrent transitions are checked one after another.
+// Cancelling goes through cancel so the order's stock and money are given back.
+func (s *Store) TransitionOrder(id int, to string, actorID int, note string) (*types.OrderStatusChange, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var change int
+	if to == types.OrderCancelled {
+		_, change, err = cancel(tx, id, actorID, note)
+	} else {
+		change, err = Transition(tx, id, to, actorID, note)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.getStatusChange(change)
+}
+
+func (s *Store) GetOrderStatusHistory(orderID int) ([]types.OrderStatusChange, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM orders WHERE id = ?)", orderID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+
+	rows, err := s.db.Query("SELECT "+historyColumns+" FROM order_status_history WHERE orderId = ? ORDER BY id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []types.OrderStatusChange{}
+	for rows.Next() {
+		c, err := scanRowIntoStatusChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *c)
+	}
+	return history, rows.Err()
+}
+
+// Transition validates and applies a status change inside tx and returns the ID of the
+// recorded history entry. It does not restock or refund, so cancellations go through
+// Store.TransitionOrder or Store.CancelOrder instead.
+func Transition(tx *sql.Tx, id int, to string, actorID int, note string) (int, error) {
+	var from string
+	var userID int
+	err := tx.QueryRow("SELECT status, userId FROM orders WHERE id = ? FOR UPDATE", id).Scan(&from, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrOrderNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !CanTransition(from, to) {
+		return 0, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+	}
+
+	if _, err := tx.Exec("UPDATE orders SET status = ? WHERE id = ?", to, id); err != nil {
+		return 0, err
+	}
+	change, err := RecordStatusChange(tx, types.OrderStatusChange{
+		OrderID:    id,
+		FromStatus: from,
+		ToStatus:   to,
+		ActorID:    inventory.Actor(actorID),
+		Note:       note,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	err = outbox.Record(tx, types.OrderStatusChanged{OrderID: id, UserID: userID, FromStatus: from, ToStatus: to})
+	if err != nil {
+		return 0, err
+	}
+	return change, publish(tx, types.EventOrderStatusChanged, id, from)
+}
+
+func (s *Store) getStatusChange(id int) (*types.OrderStatusChange, error) {
+	rows, err := s.db.Query("SELECT "+historyColumns+" FROM order_status_history WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("order status change %d not found", id)
+	}
+	return scanRowIntoStatusChange(rows)
+}
+
+func scanRowIntoStatusChange(rows *sql.Rows) (*types.OrderStatusChange, error) {
+	c := new(types.OrderStatusChange)
+	var actorID sql.NullInt64
+	err := rows.Scan(
+		&c.ID,
+		&c.OrderID,
+		&c.FromStatus,
+		&c.ToStatus,
+		&actorID,
+		&c.Note,
+		&c.CreatedAt,
+	)
+	if actorID.Valid {
+		c.ActorID = inventory.Actor(int(actorID.Int64))
+	}
+	return c, err
+}
@@ -0,0 +1,93 @@
+package order
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{types.OrderPending, types.OrderProcessing, true},
+		{types.OrderPending, types.OrderCancelled, true},
+		{types.OrderProcessing, types.OrderShipped, true},
+		{types.OrderShipped, types.OrderDelivered, true},
+		{types.OrderPending, types.OrderShipped, false},
+		{types.OrderShipped, types.OrderCancelled, false},
+		{types.OrderDelivered, types.OrderPending, false},
+		{types.OrderCancelled, types.OrderProcessing, false},
+		{types.OrderPending, types.OrderPending, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.allowed {
+			t.Errorf("CanTransition(%s, %s) = %v, expected %v", tt.from, tt.to, got, tt.allowed)
+		}
+	}
+}
+
+func TestTransitionHandlers(t *testing.T) {
+	store := &mockOrderStore{orders: []types.Order{{ID: 1, UserID: 2, Status: types.OrderPending}}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleAdmin},
+		2: {ID: 2, Role: types.RoleCustomer},
+	}}
+
+	handler := NewHandler(store, &mockProductStore{}, &mockPromotionStore{}, &mockTaxCalculator{}, &mockShippingStore{}, &recordingNotifier{}, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	move := func(userID int, status string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(types.OrderStatusPayload{Status: status, Note: "step"})
+		req := httptest.NewRequest(http.MethodPost, "/admin/orders/1/status", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should move an order through the workflow", func(t *testing.T) {
+		for _, status := range []string{types.OrderProcessing, types.OrderShipped, types.OrderDelivered} {
+			if rr := move(1, status); rr.Code != http.StatusOK {
+				t.Fatalf("expected status code %d for %s but got %d: %s", http.StatusOK, status, rr.Code, rr.Body)
+			}
+		}
+	})
+
+	t.Run("should reject illegal transitions", func(t *testing.T) {
+		if rr := move(1, types.OrderPending); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should only allow admins", func(t *testing.T) {
+		if rr := move(2, types.OrderCancelled); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should record every transition with its actor", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/admin/orders/1/history", nil)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 1))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var history []types.OrderStatusChange
+		json.NewDecoder(rr.Body).Decode(&history)
+		if len(history) != 3 || history[2].FromStatus != types.OrderShipped || history[2].ToStatus != types.OrderDelivered {
+			t.Fatalf("unexpected history %+v", history)
+		}
+		if history[0].ActorID == nil || *history[0].ActorID != 1 || history[0].Note != "step" {
+			t.Errorf("expected the admin and note to be recorded but got %+v", history[0])
+		}
+	})
+}
@@ -0,0 +1,397 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/code-farms/go-backend/services/inventory"
+	"github.com/code-farms/go-backend/services/jobs"
+	"github.com/code-farms/go-backend/services/webhook"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+)
+
+var (
+	ErrOrderItemNotFound = errors.New("order item not found")
+	ErrRefundTooLarge    = errors.New("refund exceeds the units not refunded yet")
+	ErrRestockShipped    = errors.New("units that have shipped can only be restocked by a return")
+	ErrRefundNotFound    = errors.New("refund not found")
+	ErrRefundSettled     = errors.New("refund is already settled")
+)
+
+// refundColumns lists the columns read by scanRowIntoRefund, in scan order.
+const refundColumns = "id, orderId, amount, shipping, reason, actorId, paymentStatus, paymentId, created_at"
+
+// refundItemColumns lists the columns read by scanRowIntoRefundItem, in scan order.
+const refundItemColumns = "id, refundId, orderItemId, productId, quantity, amount, restocked"
+
+// CancelOrder refunds the whole remainder of the order in the same transaction as the
+// status change, so a cancelled order never keeps stock or money it should have given back.
+func (s *Store) CancelOrder(id int, actorID int, note string) (*types.Refund, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	refundID, _, err := cancel(tx, id, actorID, note)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if refundID == 0 {
+		return nil, nil
+	}
+	return s.GetRefund(refundID)
+}
+
+func (s *Store) RefundOrder(id int, payload types.RefundPayload, actorID int) (*types.Refund, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM orders WHERE id = ? FOR UPDATE", id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if payload.Restock && (status == types.OrderShipped || status == types.OrderDelivered) {
+		return nil, ErrRestockShipped
+	}
+
+	items, err := lockItems(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	movement := ""
+	if payload.Restock {
+		movement = types.MovementCancel
+	}
+	refundID, err := refund(tx, id, items, payload.Items, 0, movement, payload.Reason, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.GetRefund(refundID)
+}
+
+func (s *Store) GetRefunds(orderID int) ([]types.Refund, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM orders WHERE id = ?)", orderID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrOrderNotFound
+	}
+	return s.queryRefunds("orderId = ?", orderID)
+}
+
+func (s *Store) GetRefund(id int) (*types.Refund, error) {
+	refunds, err := s.queryRefunds("id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(refunds) == 0 {
+		return nil, ErrRefundNotFound
+	}
+	return &refunds[0], nil
+}
+
+// SettleRefund only moves refunds forward, so a refund is never settled twice.
+func (s *Store) SettleRefund(id int, status string, paymentID *int) error {
+	from := types.RefundPaymentPending
+	if status == types.RefundPaymentRefunded {
+		from = types.RefundPaymentRefunding
+	}
+	result, err := s.db.Exec(
+		"UPDATE refunds SET paymentStatus = ?, paymentId = ? WHERE id = ? AND paymentStatus IN (?, ?)",
+		status, paymentID, id, types.RefundPaymentPending, from,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	if _, err := s.GetRefund(id); err != nil {
+		return err
+	}
+	return ErrRefundSettled
+}
+
+// queryRefunds returns the refunds matching where, oldest first, with their items.
+func (s *Store) queryRefunds(where string, arg any) ([]types.Refund, error) {
+	rows, err := s.db.Query("SELECT "+refundColumns+" FROM refunds WHERE "+where+" ORDER BY id", arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	refunds := []types.Refund{}
+	index := map[int]int{}
+	for rows.Next() {
+		r, err := scanRowIntoRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		r.Items = []types.RefundItem{}
+		index[r.ID] = len(refunds)
+		refunds = append(refunds, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	itemRows, err := s.db.Query(
+		"SELECT "+refundItemColumns+" FROM refund_items WHERE refundId IN (SELECT id FROM refunds WHERE "+where+") ORDER BY id", arg,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		item, err := scanRowIntoRefundItem(itemRows)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[item.RefundID]; ok {
+			refunds[i].Items = append(refunds[i].Items, *item)
+		}
+	}
+	return refunds, itemRows.Err()
+}
+
+// cancel moves an order to cancelled inside tx, then refunds the shipping cost and refunds
+// and restocks every unit not refunded yet. It returns the ID of the refund, 0 if there was
+// nothing left to refund, and the ID of the recorded status change.
+func cancel(tx *sql.Tx, id int, actorID int, note string) (int, int, error) {
+	changeID, err := Transition(tx, id, types.OrderCancelled, actorID, note)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	items, err := lockItems(tx, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	remaining := []types.RefundItemPayload{}
+	for _, item := range items {
+		if left := item.Quantity - item.Refunded; left > 0 {
+			remaining = append(remaining, types.RefundItemPayload{OrderItemID: item.ID, Quantity: left})
+		}
+	}
+	var shipping float64
+	if err := tx.QueryRow("SELECT shipping FROM orders WHERE id = ?", id).Scan(&shipping); err != nil {
+		return 0, 0, err
+	}
+	if len(remaining) == 0 && shipping == 0 {
+		return 0, changeID, nil
+	}
+
+	refundID, err := refund(tx, id, items, remaining, utils.ToCents(shipping), types.MovementCancel, note, actorID)
+	return refundID, changeID, err
+}
+
+// Refund refunds the requested units of an order inside tx and returns the ID of the
+// refund. Unlike RefundOrder it does not check the order status, so returns can restock
+// units that have shipped once they are back, recording a stock movement with the given
+// reason; an empty movement leaves the units out of stock.
+func Refund(tx *sql.Tx, orderID int, requested []types.RefundItemPayload, movement string, reason string, actorID int) (int, error) {
+	items, err := lockItems(tx, orderID)
+	if err != nil {
+		return 0, err
+	}
+	return refund(tx, orderID, items, requested, 0, movement, reason, actorID)
+}
+
+// refund records a refund of the requested units and shipping cents inside tx and, unless
+// movement is empty, puts the units back in products.quantity with a stock movement of
+// that reason. The money is given back by a settle_refund job queued in tx.
+func refund(tx *sql.Tx, orderID int, items []types.OrderItem, requested []types.RefundItemPayload, shipping int64, movement string, reason string, actorID int) (int, error) {
+	lines, total, err := refundLines(items, requested)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO refunds (orderId, amount, shipping, reason, actorId) VALUES (?, ?, ?, ?, ?)",
+		orderID, utils.FormatCents(total+shipping), utils.FormatCents(shipping), strings.TrimSpace(reason), inventory.Actor(actorID),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert refund: %w", err)
+	}
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	refundID := int(lastID)
+
+	for _, line := range lines {
+		if _, err := tx.Exec("UPDATE order_items SET refunded = refunded + ? WHERE id = ?", line.Quantity, line.OrderItemID); err != nil {
+			return 0, err
+		}
+
+		_, err := tx.Exec(
+			"INSERT INTO refund_items (refundId, orderItemId, productId, quantity, amount, restocked) VALUES (?, ?, ?, ?, ?, ?)",
+			refundID, line.OrderItemID, line.ProductID, line.Quantity, utils.FormatCents(utils.ToCents(line.Amount)), movement != "",
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert refund item: %w", err)
+		}
+
+		if movement == "" {
+			continue
+		}
+		if _, err := tx.Exec("UPDATE products SET quantity = quantity + ? WHERE id = ?", line.Quantity, line.ProductID); err != nil {
+			return 0, err
+		}
+		_, err = inventory.RecordMovement(tx, types.StockMovement{
+			ProductID:      line.ProductID,
+			QuantityChange: line.Quantity,
+			Reason:         movement,
+			ActorID:        inventory.Actor(actorID),
+			Reference:      fmt.Sprintf("refund:%d", refundID),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := jobs.Enqueue(tx, types.SettleRefundJob{RefundID: refundID}, types.JobOptions{}); err != nil {
+		return 0, err
+	}
+
+	for i := range lines {
+		lines[i].RefundID, lines[i].Restocked = refundID, movement != ""
+	}
+	err = webhook.Enqueue(tx, types.EventOrderRefunded, types.Refund{
+		ID:            refundID,
+		OrderID:       orderID,
+		Amount:        utils.FromCents(total + shipping),
+		Shipping:      utils.FromCents(shipping),
+		Reason:        strings.TrimSpace(reason),
+		ActorID:       inventory.Actor(actorID),
+		PaymentStatus: types.RefundPaymentPending,
+		Items:         lines,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return refundID, nil
+}
+
+// lockItems reads the items of an order inside tx, locking them so concurrent refunds of
+// the same units are checked one after another.
+func lockItems(tx *sql.Tx, orderID int) ([]types.OrderItem, error) {
+	rows, err := tx.Query("SELECT "+itemColumns+" FROM order_items WHERE orderId = ? ORDER BY id FOR UPDATE", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []types.OrderItem{}
+	for rows.Next() {
+		item, err := scanRowIntoItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *item)
+	}
+	return items, rows.Err()
+}
+
+// refundLines prices the requested units at what was paid for them, the item's discount
+// taken off and its tax added, adding up units of the same item requested more than once.
+// Each refund takes the share of the line up to the units refunded so far minus what was
+// already given back, in whole cents, so refunding every unit always adds up to exactly
+// what was charged. It returns the lines and their total in cents.
+func refundLines(items []types.OrderItem, requested []types.RefundItemPayload) ([]types.RefundItem, int64, error) {
+	byID := make(map[int]types.OrderItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	lines := []types.RefundItem{}
+	index := map[int]int{}
+	for _, req := range requested {
+		item, ok := byID[req.OrderItemID]
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: %d", ErrOrderItemNotFound, req.OrderItemID)
+		}
+		if i, ok := index[item.ID]; ok {
+			lines[i].Quantity += req.Quantity
+			continue
+		}
+		index[item.ID] = len(lines)
+		lines = append(lines, types.RefundItem{OrderItemID: item.ID, ProductID: item.ProductID, Quantity: req.Quantity})
+	}
+
+	var total int64
+	for i, line := range lines {
+		item := byID[line.OrderItemID]
+		if left := item.Quantity - item.Refunded; line.Quantity > left {
+			return nil, 0, fmt.Errorf("%w: only %d units of item %d can be refunded", ErrRefundTooLarge, left, item.ID)
+		}
+		net := utils.ToCents(item.Price)*int64(item.Quantity) - utils.ToCents(item.Discount) + utils.ToCents(item.Tax)
+		qty := int64(item.Quantity)
+		cents := net*int64(item.Refunded+line.Quantity)/qty - net*int64(item.Refunded)/qty
+		lines[i].Amount = utils.FromCents(cents)
+		total += cents
+	}
+	return lines, total, nil
+}
+
+func scanRowIntoRefund(rows *sql.Rows) (*types.Refund, error) {
+	r := new(types.Refund)
+	var actorID, paymentID sql.NullInt64
+	err := rows.Scan(
+		&r.ID,
+		&r.OrderID,
+		&r.Amount,
+		&r.Shipping,
+		&r.Reason,
+		&actorID,
+		&r.PaymentStatus,
+		&paymentID,
+		&r.CreatedAt,
+	)
+	if actorID.Valid {
+		r.ActorID = inventory.Actor(int(actorID.Int64))
+	}
+	if paymentID.Valid {
+		id := int(paymentID.Int64)
+		r.PaymentID = &id
+	}
+	return r, err
+}
+
+func scanRowIntoRefundItem(rows *sql.Rows) (*types.RefundItem, error) {
+	item := new(types.RefundItem)
+	err := rows.Scan(
+		&item.ID,
+		&item.RefundID,
+		&item.OrderItemID,
+		&item.ProductID,
+		&item.Quantity,
+		&item.Amount,
+		&item.Restocked,
+	)
+	return item, err
+}
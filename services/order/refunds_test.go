@@ -0,0 +1,186 @@
+package order
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+func TestRefundLines(t *testing.T) {
+	items := []types.OrderItem{
+		{ID: 1, ProductID: 1, Quantity: 3, Price: 0.10},
+		{ID: 2, ProductID: 2, Quantity: 3, Price: 19.99, Refunded: 1},
+	}
+
+	t.Run("should compute amounts in exact cents", func(t *testing.T) {
+		lines, total, err := refundLines(items, []types.RefundItemPayload{
+			{OrderItemID: 1, Quantity: 1},
+			{OrderItemID: 2, Quantity: 2},
+			{OrderItemID: 1, Quantity: 2},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 4028 || utils.FormatCents(total) != "40.28" {
+			t.Errorf("expected a total of 40.28 but got %s", utils.FormatCents(total))
+		}
+		if len(lines) != 2 || lines[0].Quantity != 3 || lines[0].Amount != 0.3 || lines[1].Amount != 39.98 {
+			t.Errorf("unexpected lines %+v", lines)
+		}
+	})
+
+	t.Run("should refund discounted items net of the discount", func(t *testing.T) {
+		discounted := []types.OrderItem{{ID: 1, ProductID: 1, Quantity: 3, Price: 10, Discount: 1}}
+
+		var total int64
+		for i := 0; i < 3; i++ {
+			lines, cents, err := refundLines(discounted, []types.RefundItemPayload{{OrderItemID: 1, Quantity: 1}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []float64{9.66, 9.67, 9.67}[i]; lines[0].Amount != want {
+				t.Errorf("refund %d: expected %.2f but got %.2f", i+1, want, lines[0].Amount)
+			}
+			discounted[0].Refunded++
+			total += cents
+		}
+		if total != 2900 {
+			t.Errorf("expected the refunds to add up to 29.00 but got %s", utils.FormatCents(total))
+		}
+	})
+
+	t.Run("should refund the tax added to the items", func(t *testing.T) {
+		taxed := []types.OrderItem{{ID: 1, ProductID: 1, Quantity: 2, Price: 10, Tax: 1.9}}
+		lines, _, err := refundLines(taxed, []types.RefundItemPayload{{OrderItemID: 1, Quantity: 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines[0].Amount != 10.95 {
+			t.Errorf("expected 10.95 but got %.2f", lines[0].Amount)
+		}
+	})
+
+	t.Run("should not refund more than is left", func(t *testing.T) {
+		_, _, err := refundLines(items, []types.RefundItemPayload{{OrderItemID: 2, Quantity: 3}})
+		if !errors.Is(err, ErrRefundTooLarge) {
+			t.Errorf("expected ErrRefundTooLarge but got %v", err)
+		}
+	})
+
+	t.Run("should reject items of other orders", func(t *testing.T) {
+		_, _, err := refundLines(items, []types.RefundItemPayload{{OrderItemID: 9, Quantity: 1}})
+		if !errors.Is(err, ErrOrderItemNotFound) {
+			t.Errorf("expected ErrOrderItemNotFound but got %v", err)
+		}
+	})
+}
+
+func TestCancelAndRefund(t *testing.T) {
+	productStore := &mockProductStore{products: map[int]*types.Product{
+		1: {ID: 1, Price: 19.99, Quantity: 5},
+		2: {ID: 2, Price: 0.10, Quantity: 5},
+	}}
+	store := &mockOrderStore{products: productStore, orders: []types.Order{
+		{ID: 1, UserID: 1, Status: types.OrderPending, Items: []types.OrderItem{
+			{ID: 1, ProductID: 1, Quantity: 2, Price: 19.99},
+			{ID: 2, ProductID: 2, Quantity: 3, Price: 0.10, Refunded: 1},
+		}},
+		{ID: 2, UserID: 1, Status: types.OrderShipped, Items: []types.OrderItem{
+			{ID: 3, ProductID: 1, Quantity: 2, Price: 19.99},
+		}},
+	}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleCustomer},
+		3: {ID: 3, Role: types.RoleAdmin},
+	}}
+
+	handler := NewHandler(store, productStore, &mockPromotionStore{}, &mockTaxCalculator{}, &mockShippingStore{}, &recordingNotifier{}, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should hide other users' orders", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/orders/1/cancel", 2, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should cancel the order and restock the units not refunded yet", func(t *testing.T) {
+		rr := send(http.MethodPost, "/orders/1/cancel", 1, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+
+		var order types.Order
+		json.NewDecoder(rr.Body).Decode(&order)
+		if order.Status != types.OrderCancelled {
+			t.Errorf("expected the order to be cancelled but got %s", order.Status)
+		}
+		if productStore.products[1].Quantity != 7 || productStore.products[2].Quantity != 7 {
+			t.Errorf("expected 7 units of each product but got %d and %d", productStore.products[1].Quantity, productStore.products[2].Quantity)
+		}
+		if len(store.refunds) != 1 || store.refunds[0].Amount != 40.18 {
+			t.Errorf("expected a refund of 40.18 but got %+v", store.refunds)
+		}
+	})
+
+	t.Run("should not cancel orders that have shipped", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/orders/2/cancel", 1, nil); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should let admins refund part of an order", func(t *testing.T) {
+		payload := types.RefundPayload{Items: []types.RefundItemPayload{{OrderItemID: 3, Quantity: 1}}, Reason: "damaged"}
+		if rr := send(http.MethodPost, "/admin/orders/2/refunds", 1, payload); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d for a customer but got %d", http.StatusForbidden, rr.Code)
+		}
+
+		rr := send(http.MethodPost, "/admin/orders/2/refunds", 3, payload)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var refund types.Refund
+		json.NewDecoder(rr.Body).Decode(&refund)
+		if refund.Amount != 19.99 || len(refund.Items) != 1 {
+			t.Errorf("unexpected refund %+v", refund)
+		}
+
+		if rr := send(http.MethodPost, "/admin/orders/2/refunds", 3, types.RefundPayload{Items: []types.RefundItemPayload{{OrderItemID: 3, Quantity: 2}}}); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d when refunding too much but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should not restock units that have shipped", func(t *testing.T) {
+		payload := types.RefundPayload{Items: []types.RefundItemPayload{{OrderItemID: 3, Quantity: 1}}, Restock: true}
+		if rr := send(http.MethodPost, "/admin/orders/2/refunds", 3, payload); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should list the refunds of an order", func(t *testing.T) {
+		rr := send(http.MethodGet, "/orders/2/refunds", 1, nil)
+		var refunds []types.Refund
+		json.NewDecoder(rr.Body).Decode(&refunds)
+		if rr.Code != http.StatusOK || len(refunds) != 1 || refunds[0].Reason != "damaged" {
+			t.Errorf("unexpected refunds %+v", refunds)
+		}
+	})
+}
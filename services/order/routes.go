@@ -0,0 +1,501 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/services/inventory"
+	"github.com/code-farms/go-backend/services/promotion"
+	"github.com/code-farms/go-backend/services/shipping"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store          types.OrderStore
+	productStore   types.ProductStore
+	promotionStore types.PromotionStore
+	taxCalculator  types.TaxCalculator
+	shippingStore  types.ShippingStore
+	notifier       types.Notifier
+	userStore      types.UserStore
+}
+
+func NewHandler(store types.OrderStore, productStore types.ProductStore, promotionStore types.PromotionStore, taxCalculator types.TaxCalculator, shippingStore types.ShippingStore, notifier types.Notifier, userStore types.UserStore) *Handler {
+	return &Handler{
+		store:          store,
+		productStore:   productStore,
+		promotionStore: promotionStore,
+		taxCalculator:  taxCalculator,
+		shippingStore:  shippingStore,
+		notifier:       notifier,
+		userStore:      userStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/checkout", auth.WithJWTAuth(h.handleCheckout, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/orders", auth.WithJWTAuth(h.handleGetOrders, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/orders/{id:[0-9]+}", auth.WithJWTAuth(h.handleGetOrder, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/orders/{id:[0-9]+}/cancel", auth.WithJWTAuth(h.handleCancelOrder, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/orders/{id:[0-9]+}/refunds", auth.WithJWTAuth(h.handleGetRefunds, h.userStore)).Methods(http.MethodGet)
+
+	router.HandleFunc("/admin/orders/{id:[0-9]+}/status", auth.WithAdminAuth(h.handleTransitionOrder, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/orders/{id:[0-9]+}/history", auth.WithAdminAuth(h.handleGetStatusHistory, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/orders/{id:[0-9]+}/refunds", auth.WithAdminAuth(h.handleRefundOrder, h.userStore)).Methods(http.MethodPost)
+}
+
+// RegisterSubscribers subscribes the handler to the domain events it reacts to.
+func (h *Handler) RegisterSubscribers(bus types.EventBus) {
+	bus.Subscribe(types.OrderPlacedEvent, "order_confirmation_email", h.sendConfirmation)
+}
+
+// handleCheckout places an order for the authenticated user. Prices and the total are taken
+// from the catalog, never from the client, an optional promotion code discounts them, and
+// taxes and the chosen shipping method are priced for the delivery address.
+func (h *Handler) handleCheckout(w http.ResponseWriter, r *http.Request) {
+	// Step 1: Parse and validate the payload
+	var payload types.CheckoutPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	// Step 2: Look up every product and check that enough of it is in stock
+	requested := mergeItems(payload.Items)
+	ids := make([]int, len(requested))
+	for i, item := range requested {
+		ids[i] = item.ProductID
+	}
+	products, err := h.productStore.GetProductsByID(ids)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	byID := make(map[int]types.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	// Step 3: Snapshot the prices and compute the total
+	items, total, err := priceItems(requested, byID, payload.ReservationID != 0)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	order := types.Order{
+		UserID:     auth.GetUserIDFromContext(r.Context()),
+		Subtotal:   total,
+		Status:     types.OrderPending,
+		Address:    strings.TrimSpace(payload.Address),
+		Country:    strings.ToUpper(strings.TrimSpace(payload.Country)),
+		Region:     strings.TrimSpace(payload.Region),
+		PostalCode: strings.TrimSpace(payload.PostalCode),
+	}
+
+	// Step 4: Apply the promotion code to the priced items
+	freeShipping := false
+	if code := strings.TrimSpace(payload.PromoCode); code != "" {
+		basket := make([]types.PromotionItem, len(items))
+		for i, item := range items {
+			basket[i] = types.PromotionItem{ProductID: item.ProductID, Category: byID[item.ProductID].Category, Quantity: item.Quantity, Price: item.Price}
+		}
+		result, err := promotion.ApplyCode(h.promotionStore, code, order.UserID, basket, time.Now())
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		for i := range items {
+			items[i].Discount = result.Items[i].Discount
+		}
+		order.PromotionID, order.PromotionCode = &result.PromotionID, result.Code
+		order.Discount = result.Discount
+		freeShipping = result.FreeShipping
+	}
+
+	// Step 5: Tax the discounted items
+	taxable := make([]types.TaxableItem, len(items))
+	for i, item := range items {
+		line := utils.ToCents(item.Price)*int64(item.Quantity) - utils.ToCents(item.Discount)
+		taxable[i] = types.TaxableItem{TaxClass: byID[item.ProductID].TaxClass, Amount: utils.FromCents(line)}
+	}
+	taxes, err := h.taxCalculator.Calculate(types.TaxAddress{Country: order.Country, Region: order.Region, PostalCode: order.PostalCode}, taxable)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	order.Tax = addTaxes(items, taxes)
+
+	// Step 6: Price the chosen shipping method for the parcel
+	if payload.ShippingMethodID != 0 {
+		if order.Country == "" {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("a country is required to choose a shipping method"))
+			return
+		}
+		weight, err := shipping.Weigh(requested, byID)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		methods, err := h.shippingStore.GetShippingMethodsForCountry(order.Country)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		parcel := types.Parcel{Weight: weight, Value: utils.FromCents(utils.ToCents(order.Subtotal) - utils.ToCents(order.Discount))}
+		rate, err := shipping.QuoteMethod(methods, payload.ShippingMethodID, parcel)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		order.ShippingMethodID, order.ShippingMethod = &rate.MethodID, rate.Name
+		if !freeShipping {
+			order.Shipping = rate.Cost
+		}
+	}
+	order.Total = utils.FromCents(utils.ToCents(order.Subtotal) - utils.ToCents(order.Discount) + utils.ToCents(order.Tax) + utils.ToCents(order.Shipping))
+
+	// Step 7: Create the order, redeem the code, commit the reservation and take the items
+	// out of stock in one transaction
+	id, err := h.store.CreateOrder(order, items, payload.ReservationID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	created, err := h.store.GetOrder(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// sendConfirmation emails the customer a summary of the order they placed once it is
+// committed.
+func (h *Handler) sendConfirmation(ctx context.Context, event types.OutboxEvent) error {
+	var placed types.OrderPlaced
+	if err := json.Unmarshal(event.Payload, &placed); err != nil {
+		return err
+	}
+	o, err := h.store.GetOrder(placed.OrderID)
+	if err != nil {
+		return err
+	}
+	customer, err := h.userStore.GetUserById(o.UserID)
+	if err != nil {
+		return err
+	}
+
+	return h.notifier.Notify(ctx, types.Notification{
+		Kind:       types.NotificationOrderConfirmation,
+		UserID:     o.UserID,
+		Recipients: []string{customer.Email},
+		Subject:    fmt.Sprintf("Order #%d confirmed", o.ID),
+		Body:       fmt.Sprintf("Thank you for your order #%d of %s.", o.ID, utils.FormatCents(utils.ToCents(o.Total))),
+		Data:       map[string]any{"order": *o, "user": *customer},
+	})
+}
+
+// handleGetOrders returns a page of orders, newest first. Customers only see their own
+// orders; admins see everyone's and can narrow the list down with the userId parameter.
+// The list can be filtered by status and by a from/to date range.
+func (h *Handler) handleGetOrders(w http.ResponseWriter, r *http.Request) {
+	page, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filter, err := parseOrderFilter(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !auth.IsAdmin(r.Context()) {
+		filter.UserID = auth.GetUserIDFromContext(r.Context())
+	}
+
+	orders, err := h.store.GetOrders(filter, page.Limit, page.Offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"orders": orders,
+		"page":   page.Page,
+		"limit":  page.Limit,
+	})
+}
+
+// handleGetOrder returns an order with its items.
+func (h *Handler) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	order, err := h.ownOrder(r, id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, order)
+}
+
+// handleCancelOrder lets a customer cancel their order while it is pending or processing.
+// The units are put back in stock and refunded, and the cancelled order is returned.
+func (h *Handler) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if _, err := h.ownOrder(r, id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if _, err := h.store.CancelOrder(id, auth.GetUserIDFromContext(r.Context()), "cancelled by the customer"); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	order, err := h.store.GetOrder(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, order)
+}
+
+func (h *Handler) handleGetRefunds(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if _, err := h.ownOrder(r, id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	refunds, err := h.store.GetRefunds(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, refunds)
+}
+
+// handleRefundOrder refunds some or all of the units of an order's items. The amount is
+// computed from the prices paid, never taken from the client.
+func (h *Handler) handleRefundOrder(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.RefundPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	refund, err := h.store.RefundOrder(id, payload, auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, refund)
+}
+
+// handleTransitionOrder moves an order to the next status of its lifecycle, answering 409
+// for transitions the lifecycle does not allow.
+func (h *Handler) handleTransitionOrder(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.OrderStatusPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	change, err := h.store.TransitionOrder(id, payload.Status, auth.GetUserIDFromContext(r.Context()), payload.Note)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, change)
+}
+
+func (h *Handler) handleGetStatusHistory(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	history, err := h.store.GetOrderStatusHistory(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, history)
+}
+
+// ownOrder returns the order if it belongs to the authenticated user or the user is an
+// admin. Other users' orders are reported as not found so their IDs are not disclosed.
+func (h *Handler) ownOrder(r *http.Request, id int) (*types.Order, error) {
+	order, err := h.store.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != auth.GetUserIDFromContext(r.Context()) && !auth.IsAdmin(r.Context()) {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// parseOrderFilter reads the status, from, to and userId query parameters. Dates are
+// either RFC 3339 timestamps or plain YYYY-MM-DD days; a plain day in `to` includes the
+// whole day.
+func parseOrderFilter(r *http.Request) (types.OrderFilter, error) {
+	query := r.URL.Query()
+	filter := types.OrderFilter{Status: query.Get("status")}
+
+	switch filter.Status {
+	case "", types.OrderPending, types.OrderProcessing, types.OrderShipped, types.OrderDelivered, types.OrderCancelled:
+	default:
+		return filter, fmt.Errorf("invalid status %q", filter.Status)
+	}
+
+	if v := query.Get("userId"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil || userID < 1 {
+			return filter, fmt.Errorf("invalid userId %q", v)
+		}
+		filter.UserID = userID
+	}
+
+	for _, param := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		v := query.Get(param.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			day, dayErr := time.Parse(time.DateOnly, v)
+			if dayErr != nil {
+				return filter, fmt.Errorf("invalid %s date %q: use YYYY-MM-DD or RFC 3339", param.name, v)
+			}
+			if t = day; param.name == "to" {
+				t = day.AddDate(0, 0, 1)
+			}
+		}
+		*param.dst = &t
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, fmt.Errorf("from must be before to")
+	}
+	return filter, nil
+}
+
+// mergeItems adds up the quantities of products listed more than once, keeping the order
+// in which they first appear.
+func mergeItems(items []types.CheckoutItem) []types.CheckoutItem {
+	merged := []types.CheckoutItem{}
+	index := map[int]int{}
+	for _, item := range items {
+		if i, ok := index[item.ProductID]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(merged)
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// priceItems turns the requested items into order items priced from the catalog and
+// returns their total. The total is added up in cents to avoid rounding drift. Units held
+// by reservations can't be bought, except with a reservation, which may hold any of them;
+// how many it holds is checked when it is committed.
+func priceItems(requested []types.CheckoutItem, products map[int]types.Product, withReservation bool) ([]types.OrderItem, float64, error) {
+	items := make([]types.OrderItem, 0, len(requested))
+	var cents int64
+	for _, item := range requested {
+		p, ok := products[item.ProductID]
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: product %d", ErrProductNotFound, item.ProductID)
+		}
+		available := p.Quantity - p.Reserved
+		if withReservation {
+			available = p.Quantity
+		}
+		if available < item.Quantity {
+			return nil, 0, fmt.Errorf("%w: only %d units of product %d are available", ErrInsufficientStock, max(available, 0), p.ID)
+		}
+
+		items = append(items, types.OrderItem{
+			ProductID:    p.ID,
+			ProductName:  p.Name,
+			ProductSKU:   p.SKU,
+			ProductImage: p.Image,
+			Quantity:     item.Quantity,
+			Price:        p.Price,
+		})
+		cents += utils.ToCents(p.Price) * int64(item.Quantity)
+	}
+	return items, utils.FromCents(cents), nil
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrOrderNotFound), errors.Is(err, ErrOrderItemNotFound),
+		errors.Is(err, promotion.ErrPromotionNotFound), errors.Is(err, inventory.ErrReservationNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrInsufficientStock), errors.Is(err, ErrPriceChanged), errors.Is(err, ErrInvalidTransition),
+		errors.Is(err, ErrRefundTooLarge), errors.Is(err, ErrRestockShipped), errors.Is(err, inventory.ErrReservationNotActive),
+		errors.Is(err, promotion.ErrPromotionUsedUp), errors.Is(err, promotion.ErrPromotionUserLimit):
+		utils.WriteError(w, http.StatusConflict, err)
+	case errors.Is(err, promotion.ErrPromotionNotApplicable), errors.Is(err, shipping.ErrShippingUnavailable):
+		utils.WriteError(w, http.StatusUnprocessableEntity, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// addTaxes attaches the tax lines to the items and sets the tax added on top of each item.
+// It returns the tax added to the whole order.
+func addTaxes(items []types.OrderItem, taxes [][]types.TaxLine) float64 {
+	var total int64
+	for i := range items {
+		items[i].Taxes = taxes[i]
+
+		var cents int64
+		for _, line := range taxes[i] {
+			if !line.Inclusive {
+				cents += utils.ToCents(line.Amount)
+			}
+		}
+		items[i].Tax = utils.FromCents(cents)
+		total += cents
+	}
+	return utils.FromCents(total)
+}
@@ -0,0 +1,696 @@
+package order
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/services/inventory"
+	"github.com/code-farms/go-backend/services/promotion"
+	"github.com/code-farms/go-backend/services/shipping"
+	"github.com/code-farms/go-backend/services/tax"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+func TestCheckout(t *testing.T) {
+	productStore := &mockProductStore{products: map[int]*types.Product{
+		1: {ID: 1, Price: 19.99, Quantity: 3},
+		2: {ID: 2, Price: 0.10, Quantity: 10},
+		3: {ID: 3, Price: 10, Quantity: 5, TaxClass: "reduced"},
+		4: {ID: 4, Price: 5, Quantity: 10, Weight: 1500},
+		5: {ID: 5, Price: 8, Quantity: 2, Reserved: 2},
+	}}
+	store := &mockOrderStore{products: productStore, reservations: map[int]*types.Reservation{
+		1: {ID: 1, UserID: 1, Status: types.ReservationActive, Items: []types.ReservationItem{{ProductID: 5, Quantity: 2}}},
+		2: {ID: 2, UserID: 2, Status: types.ReservationActive, Items: []types.ReservationItem{}},
+	}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{1: {ID: 1, Role: types.RoleCustomer, Email: "jane@example.com"}}}
+	notifier := &recordingNotifier{}
+
+	promotionStore := &mockPromotionStore{promotions: []types.Promotion{
+		{ID: 1, Code: "SAVE10", Type: types.PromotionPercentage, Value: 10, Active: true},
+		{ID: 2, Code: "BIG", Type: types.PromotionFixed, Value: 5, MinOrderValue: 100, Active: true},
+		{ID: 3, Code: "SHIPFREE", Type: types.PromotionFreeShipping, Active: true},
+	}}
+
+	taxCalculator := &mockTaxCalculator{rates: []types.TaxRate{
+		{Name: "VAT", Country: "GB", TaxClass: "reduced", Rate: 5, Inclusive: true},
+		{Name: "Sales tax", Country: "US", Region: "CA", TaxClass: "reduced", Rate: 7.25},
+		{Name: "Sales tax", Country: "US", Region: "CA", PostalPrefix: "900", TaxClass: "reduced", Rate: 9.5},
+	}}
+
+	maxWeight := 2000
+	shippingStore := &mockShippingStore{countries: map[string]int{"US": 1}, methods: []types.ShippingMethod{
+		{ID: 1, ZoneID: 1, Name: "Standard", Type: types.ShippingWeight, Price: 2, PricePerKg: 1.5, Active: true},
+		{ID: 2, ZoneID: 1, Name: "Letter", Type: types.ShippingFlat, Price: 1, MaxWeight: &maxWeight, Active: true},
+	}}
+
+	handler := NewHandler(store, productStore, promotionStore, taxCalculator, shippingStore, notifier, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	bus := &mockBus{}
+	handler.RegisterSubscribers(bus)
+
+	checkout := func(payload types.CheckoutPayload) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/checkout", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 1))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should compute the total on the server", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", Items: []types.CheckoutItem{
+			{ProductID: 1, Quantity: 1},
+			{ProductID: 2, Quantity: 2},
+			{ProductID: 1, Quantity: 1},
+		}})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		var order types.Order
+		json.NewDecoder(rr.Body).Decode(&order)
+		if order.Total != 40.18 || len(order.Items) != 2 || order.Items[0].Quantity != 2 || order.Status != types.OrderPending {
+			t.Errorf("unexpected order %+v", order)
+		}
+		if productStore.products[1].Quantity != 1 {
+			t.Errorf("expected 1 unit to be left but got %d", productStore.products[1].Quantity)
+		}
+		if len(notifier.sent) != 0 {
+			t.Fatalf("expected no email before the event is published but got %+v", notifier.sent)
+		}
+		bus.publish(t, store.events...)
+		if len(notifier.sent) != 1 || notifier.sent[0].Kind != types.NotificationOrderConfirmation || notifier.sent[0].UserID != 1 ||
+			notifier.sent[0].Recipients[0] != "jane@example.com" {
+			t.Errorf("expected an order confirmation but got %+v", notifier.sent)
+		}
+	})
+
+	t.Run("should reject orders for more than is in stock", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", Items: []types.CheckoutItem{{ProductID: 1, Quantity: 2}}})
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should reject unknown products", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", Items: []types.CheckoutItem{{ProductID: 9, Quantity: 1}}})
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should leave stock untouched when the order fails", func(t *testing.T) {
+		store.err = ErrPriceChanged
+		defer func() { store.err = nil }()
+
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", Items: []types.CheckoutItem{{ProductID: 2, Quantity: 1}}})
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+		if productStore.products[2].Quantity != 8 {
+			t.Errorf("expected the stock to be unchanged but got %d", productStore.products[2].Quantity)
+		}
+	})
+
+	t.Run("should apply a promotion code to the total", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", PromoCode: "save10", Items: []types.CheckoutItem{{ProductID: 2, Quantity: 5}}})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		var order types.Order
+		json.NewDecoder(rr.Body).Decode(&order)
+		if order.Subtotal != 0.5 || order.Discount != 0.05 || order.Total != 0.45 || order.PromotionCode != "SAVE10" || order.Items[0].Discount != 0.05 {
+			t.Errorf("unexpected order %+v", order)
+		}
+		if o := store.orders[len(store.orders)-1]; o.PromotionID == nil || *o.PromotionID != 1 {
+			t.Errorf("expected the order to redeem promotion 1 but got %v", o.PromotionID)
+		}
+	})
+
+	t.Run("should reject codes that do not apply", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", PromoCode: "BIG", Items: []types.CheckoutItem{{ProductID: 2, Quantity: 1}}})
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+		rr = checkout(types.CheckoutPayload{Address: "1 Main St", PromoCode: "NOPE", Items: []types.CheckoutItem{{ProductID: 2, Quantity: 1}}})
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should add exclusive taxes to the total", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", Country: "us", Region: "CA", PostalCode: "90012", Items: []types.CheckoutItem{{ProductID: 3, Quantity: 2}}})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		var order types.Order
+		json.NewDecoder(rr.Body).Decode(&order)
+		if order.Subtotal != 20 || order.Tax != 1.9 || order.Total != 21.9 || order.Country != "US" {
+			t.Errorf("unexpected order %+v", order)
+		}
+		if taxes := order.Items[0].Taxes; order.Items[0].Tax != 1.9 || len(taxes) != 1 || taxes[0].Rate != 9.5 {
+			t.Errorf("expected the postal code rate to replace the state rate but got %+v", order.Items[0])
+		}
+	})
+
+	t.Run("should not add inclusive taxes to the total", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 High St", Country: "GB", Items: []types.CheckoutItem{{ProductID: 3, Quantity: 1}}})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		var order types.Order
+		json.NewDecoder(rr.Body).Decode(&order)
+		if order.Tax != 0 || order.Total != 10 || len(order.Items[0].Taxes) != 1 || order.Items[0].Taxes[0].Amount != 0.48 {
+			t.Errorf("unexpected order %+v", order)
+		}
+	})
+
+	t.Run("should add the cost of the shipping method to the total", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", Country: "US", ShippingMethodID: 1, Items: []types.CheckoutItem{{ProductID: 4, Quantity: 2}}})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		var order types.Order
+		json.NewDecoder(rr.Body).Decode(&order)
+		if order.Shipping != 6.5 || order.ShippingMethod != "Standard" || order.Total != 16.5 {
+			t.Errorf("unexpected order %+v", order)
+		}
+	})
+
+	t.Run("should ship for free with a free shipping code", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", Country: "US", ShippingMethodID: 1, PromoCode: "SHIPFREE", Items: []types.CheckoutItem{{ProductID: 4, Quantity: 1}}})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		var order types.Order
+		json.NewDecoder(rr.Body).Decode(&order)
+		if order.Shipping != 0 || order.ShippingMethod != "Standard" || order.Total != 5 {
+			t.Errorf("unexpected order %+v", order)
+		}
+	})
+
+	t.Run("should reject shipping methods that cannot deliver the order", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", Country: "US", ShippingMethodID: 2, Items: []types.CheckoutItem{{ProductID: 4, Quantity: 2}}})
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d for a parcel too heavy but got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+		rr = checkout(types.CheckoutPayload{Address: "1 High St", Country: "GB", ShippingMethodID: 1, Items: []types.CheckoutItem{{ProductID: 4, Quantity: 1}}})
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d for a country not shipped to but got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+		rr = checkout(types.CheckoutPayload{Address: "1 Main St", ShippingMethodID: 1, Items: []types.CheckoutItem{{ProductID: 4, Quantity: 1}}})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d without a country but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should sell the units held by the customer's reservation", func(t *testing.T) {
+		orders := len(store.orders)
+		rr := checkout(types.CheckoutPayload{Address: "1 Main St", Items: []types.CheckoutItem{{ProductID: 5, Quantity: 2}}})
+		if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "only 0 units") {
+			t.Fatalf("expected status code %d for reserved units without the reservation but got %d: %s", http.StatusConflict, rr.Code, rr.Body)
+		}
+		if len(store.orders) != orders {
+			t.Fatalf("expected no order to be placed")
+		}
+
+		rr = checkout(types.CheckoutPayload{Address: "1 Main St", ReservationID: 1, Items: []types.CheckoutItem{{ProductID: 5, Quantity: 2}}})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		if productStore.products[5].Quantity != 0 || productStore.products[5].Reserved != 0 || store.reservations[1].Status != types.ReservationCommitted {
+			t.Errorf("expected the reserved units to be sold but got %d on hand, %d reserved and a %s reservation",
+				productStore.products[5].Quantity, productStore.products[5].Reserved, store.reservations[1].Status)
+		}
+
+		rr = checkout(types.CheckoutPayload{Address: "1 Main St", ReservationID: 1, Items: []types.CheckoutItem{{ProductID: 2, Quantity: 1}}})
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d for a committed reservation but got %d", http.StatusConflict, rr.Code)
+		}
+		rr = checkout(types.CheckoutPayload{Address: "1 Main St", ReservationID: 2, Items: []types.CheckoutItem{{ProductID: 2, Quantity: 1}}})
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d for another customer's reservation but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should require an address and items", func(t *testing.T) {
+		rr := checkout(types.CheckoutPayload{})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+func TestOrderHistory(t *testing.T) {
+	now := time.Now()
+	store := &mockOrderStore{orders: []types.Order{
+		{ID: 1, UserID: 1, Status: types.OrderDelivered, CreatedAt: now.AddDate(0, 0, -10)},
+		{ID: 2, UserID: 2, Status: types.OrderPending, CreatedAt: now.AddDate(0, 0, -1)},
+		{ID: 3, UserID: 1, Status: types.OrderPending, CreatedAt: now},
+	}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleCustomer},
+		3: {ID: 3, Role: types.RoleAdmin},
+	}}
+
+	handler := NewHandler(store, &mockProductStore{}, &mockPromotionStore{}, &mockTaxCalculator{}, &mockShippingStore{}, &recordingNotifier{}, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	get := func(path string, userID int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	list := func(path string, userID int) []types.Order {
+		var body struct {
+			Orders []types.Order `json:"orders"`
+		}
+		rr := get(path, userID)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		json.NewDecoder(rr.Body).Decode(&body)
+		return body.Orders
+	}
+
+	t.Run("should only list the customer's own orders", func(t *testing.T) {
+		orders := list("/orders?userId=2", 1)
+		if len(orders) != 2 || orders[0].ID != 3 || orders[1].ID != 1 {
+			t.Errorf("unexpected orders %+v", orders)
+		}
+	})
+
+	t.Run("should filter by status and date range", func(t *testing.T) {
+		if orders := list("/orders?status=delivered", 1); len(orders) != 1 || orders[0].ID != 1 {
+			t.Errorf("unexpected orders %+v", orders)
+		}
+		from := now.AddDate(0, 0, -2).Format(time.DateOnly)
+		if orders := list("/orders?from="+from, 1); len(orders) != 1 || orders[0].ID != 3 {
+			t.Errorf("unexpected orders %+v", orders)
+		}
+	})
+
+	t.Run("should reject invalid filters", func(t *testing.T) {
+		for _, query := range []string{"status=lost", "from=yesterday", "from=2024-12-02&to=2024-12-01"} {
+			if rr := get("/orders?"+query, 1); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %d for %q but got %d", http.StatusBadRequest, query, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should let admins list and read any order", func(t *testing.T) {
+		if orders := list("/orders?userId=2", 3); len(orders) != 1 || orders[0].ID != 2 {
+			t.Errorf("unexpected orders %+v", orders)
+		}
+		if rr := get("/orders/2", 3); rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+	})
+
+	t.Run("should hide other users' orders", func(t *testing.T) {
+		if rr := get("/orders/2", 1); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+		if rr := get("/orders/3", 1); rr.Code != http.StatusOK {
+			t.Errorf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+	})
+}
+
+// mockOrderStore takes the items out of the mock product store, all or nothing. Units
+// reserved by the products can only be bought with the reservation holding them.
+type mockOrderStore struct {
+	products     *mockProductStore
+	reservations map[int]*types.Reservation
+	orders       []types.Order
+	history      []types.OrderStatusChange
+	refunds      []types.Refund
+	events       []types.DomainEvent
+	err          error
+}
+
+func (m *mockOrderStore) CreateOrder(order types.Order, items []types.OrderItem, reservationID int) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	held := map[int]int{}
+	if reservationID != 0 {
+		r, ok := m.reservations[reservationID]
+		if !ok || r.UserID != order.UserID {
+			return 0, inventory.ErrReservationNotFound
+		}
+		if r.Status != types.ReservationActive {
+			return 0, inventory.ErrReservationNotActive
+		}
+		for _, item := range r.Items {
+			held[item.ProductID] = item.Quantity
+		}
+	}
+	for _, item := range items {
+		held[item.ProductID] = min(held[item.ProductID], item.Quantity)
+		if p := m.products.products[item.ProductID]; p.Quantity-p.Reserved+held[item.ProductID] < item.Quantity {
+			return 0, ErrInsufficientStock
+		}
+	}
+	for _, item := range items {
+		m.products.products[item.ProductID].Quantity -= item.Quantity
+	}
+	if reservationID != 0 {
+		for _, item := range m.reservations[reservationID].Items {
+			m.products.products[item.ProductID].Reserved -= item.Quantity
+		}
+		m.reservations[reservationID].Status = types.ReservationCommitted
+	}
+	order.ID = len(m.orders) + 1
+	order.Items = items
+	order.CreatedAt = time.Now()
+	m.orders = append(m.orders, order)
+	m.events = append(m.events, types.OrderPlaced{OrderID: order.ID, UserID: order.UserID})
+	return order.ID, nil
+}
+
+func (m *mockOrderStore) GetOrders(filter types.OrderFilter, limit, offset int) ([]types.Order, error) {
+	orders := []types.Order{}
+	for i := len(m.orders) - 1; i >= 0; i-- {
+		o := m.orders[i]
+		if (filter.UserID == 0 || o.UserID == filter.UserID) && (filter.Status == "" || o.Status == filter.Status) &&
+			(filter.From == nil || !o.CreatedAt.Before(*filter.From)) && (filter.To == nil || o.CreatedAt.Before(*filter.To)) {
+			o.Items = nil
+			orders = append(orders, o)
+		}
+	}
+	return orders, nil
+}
+
+func (m *mockOrderStore) GetOrder(id int) (*types.Order, error) {
+	if id < 1 || id > len(m.orders) {
+		return nil, ErrOrderNotFound
+	}
+	order := m.orders[id-1]
+	return &order, nil
+}
+
+func (m *mockOrderStore) TransitionOrder(id int, to string, actorID int, note string) (*types.OrderStatusChange, error) {
+	if id < 1 || id > len(m.orders) {
+		return nil, ErrOrderNotFound
+	}
+	order := &m.orders[id-1]
+	if !CanTransition(order.Status, to) {
+		return nil, ErrInvalidTransition
+	}
+	change := types.OrderStatusChange{ID: len(m.history) + 1, OrderID: id, FromStatus: order.Status, ToStatus: to, ActorID: &actorID, Note: note}
+	order.Status = to
+	m.history = append(m.history, change)
+	return &change, nil
+}
+
+func (m *mockOrderStore) GetOrderStatusHistory(orderID int) ([]types.OrderStatusChange, error) {
+	if orderID < 1 || orderID > len(m.orders) {
+		return nil, ErrOrderNotFound
+	}
+	history := []types.OrderStatusChange{}
+	for _, c := range m.history {
+		if c.OrderID == orderID {
+			history = append(history, c)
+		}
+	}
+	return history, nil
+}
+
+func (m *mockOrderStore) CancelOrder(id int, actorID int, note string) (*types.Refund, error) {
+	if _, err := m.TransitionOrder(id, types.OrderCancelled, actorID, note); err != nil {
+		return nil, err
+	}
+	remaining := []types.RefundItemPayload{}
+	for _, item := range m.orders[id-1].Items {
+		if left := item.Quantity - item.Refunded; left > 0 {
+			remaining = append(remaining, types.RefundItemPayload{OrderItemID: item.ID, Quantity: left})
+		}
+	}
+	shipping := utils.ToCents(m.orders[id-1].Shipping)
+	if len(remaining) == 0 && shipping == 0 {
+		return nil, nil
+	}
+	return m.refund(id, remaining, shipping, true, note, actorID)
+}
+
+func (m *mockOrderStore) RefundOrder(id int, payload types.RefundPayload, actorID int) (*types.Refund, error) {
+	if id < 1 || id > len(m.orders) {
+		return nil, ErrOrderNotFound
+	}
+	if status := m.orders[id-1].Status; payload.Restock && (status == types.OrderShipped || status == types.OrderDelivered) {
+		return nil, ErrRestockShipped
+	}
+	return m.refund(id, payload.Items, 0, payload.Restock, payload.Reason, actorID)
+}
+
+func (m *mockOrderStore) refund(id int, requested []types.RefundItemPayload, shipping int64, restock bool, reason string, actorID int) (*types.Refund, error) {
+	order := &m.orders[id-1]
+	lines, total, err := refundLines(order.Items, requested)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range lines {
+		for i := range order.Items {
+			if order.Items[i].ID == line.OrderItemID {
+				order.Items[i].Refunded += line.Quantity
+			}
+		}
+		if restock {
+			m.products.products[line.ProductID].Quantity += line.Quantity
+		}
+	}
+	refund := types.Refund{
+		ID:            len(m.refunds) + 1,
+		OrderID:       id,
+		Amount:        utils.FromCents(total + shipping),
+		Shipping:      utils.FromCents(shipping),
+		Reason:        reason,
+		ActorID:       &actorID,
+		PaymentStatus: types.RefundPaymentPending,
+		Items:         lines,
+	}
+	m.refunds = append(m.refunds, refund)
+	return &refund, nil
+}
+
+func (m *mockOrderStore) GetRefunds(orderID int) ([]types.Refund, error) {
+	refunds := []types.Refund{}
+	for _, r := range m.refunds {
+		if r.OrderID == orderID {
+			refunds = append(refunds, r)
+		}
+	}
+	return refunds, nil
+}
+
+func (m *mockOrderStore) GetRefund(id int) (*types.Refund, error) {
+	if id < 1 || id > len(m.refunds) {
+		return nil, ErrRefundNotFound
+	}
+	r := m.refunds[id-1]
+	return &r, nil
+}
+
+func (m *mockOrderStore) SettleRefund(id int, status string, paymentID *int) error {
+	r := &m.refunds[id-1]
+	from := types.RefundPaymentPending
+	if status == types.RefundPaymentRefunded {
+		from = types.RefundPaymentRefunding
+	}
+	if r.PaymentStatus != types.RefundPaymentPending && r.PaymentStatus != from {
+		return ErrRefundSettled
+	}
+	r.PaymentStatus, r.PaymentID = status, paymentID
+	return nil
+}
+
+type mockProductStore struct {
+	products map[int]*types.Product
+}
+
+func (m *mockProductStore) GetProductByID(id int) (*types.Product, error) {
+	p, ok := m.products[id]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	return p, nil
+}
+
+func (m *mockProductStore) GetProductsByID(ids []int) ([]types.Product, error) {
+	products := []types.Product{}
+	for _, id := range ids {
+		if p, ok := m.products[id]; ok {
+			products = append(products, *p)
+		}
+	}
+	return products, nil
+}
+
+func (m *mockProductStore) GetProducts() ([]*types.Product, error) {
+	return nil, nil
+}
+
+func (m *mockProductStore) CreateProduct(p types.CreateProductPayload) (int, error) {
+	return 0, nil
+}
+
+func (m *mockProductStore) UpdateProduct(p types.Product) error {
+	return nil
+}
+
+func (m *mockProductStore) UpsertProducts(rows []types.ProductImportRow, actorID int) (int, int, error) {
+	return 0, 0, nil
+}
+
+func (m *mockProductStore) ExportProducts(fn func(types.Product) error) error {
+	return nil
+}
+
+type mockPromotionStore struct {
+	promotions []types.Promotion
+}
+
+func (m *mockPromotionStore) GetPromotions(limit, offset int) ([]types.Promotion, error) {
+	return m.promotions, nil
+}
+
+func (m *mockPromotionStore) GetPromotion(id int) (*types.Promotion, error) {
+	for _, p := range m.promotions {
+		if p.ID == id {
+			return &p, nil
+		}
+	}
+	return nil, promotion.ErrPromotionNotFound
+}
+
+func (m *mockPromotionStore) GetPromotionByCode(code string) (*types.Promotion, error) {
+	for _, p := range m.promotions {
+		if strings.EqualFold(p.Code, code) {
+			return &p, nil
+		}
+	}
+	return nil, promotion.ErrPromotionNotFound
+}
+
+func (m *mockPromotionStore) CreatePromotion(payload types.PromotionPayload) (int, error) {
+	return 0, nil
+}
+
+func (m *mockPromotionStore) UpdatePromotion(id int, payload types.PromotionPayload) error {
+	return nil
+}
+
+func (m *mockPromotionStore) CountUserRedemptions(promotionID, userID int) (int, error) {
+	return 0, nil
+}
+
+// mockTaxCalculator computes taxes from fixed rates.
+type mockTaxCalculator struct {
+	rates []types.TaxRate
+}
+
+func (m *mockTaxCalculator) Calculate(address types.TaxAddress, items []types.TaxableItem) ([][]types.TaxLine, error) {
+	return tax.Calculate(m.rates, address, items), nil
+}
+
+// mockShippingStore only serves the methods of the zones countries belong to.
+type recordingNotifier struct {
+	sent []types.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification types.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+// mockBus is an EventBus whose events are published by the test instead of a relay.
+type mockBus struct {
+	handlers map[string][]types.EventHandler
+}
+
+func (b *mockBus) Subscribe(eventType, name string, handler types.EventHandler) {
+	if b.handlers == nil {
+		b.handlers = map[string][]types.EventHandler{}
+	}
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// publish hands the events to their subscribers, failing the test if one of them fails.
+func (b *mockBus) publish(t *testing.T, events ...types.DomainEvent) {
+	for _, e := range events {
+		payload, _ := json.Marshal(e)
+		for _, handler := range b.handlers[e.EventType()] {
+			if err := handler(context.Background(), types.OutboxEvent{Type: e.EventType(), Payload: payload}); err != nil {
+				t.Fatalf("failed to handle %s: %v", e.EventType(), err)
+			}
+		}
+	}
+}
+
+type mockShippingStore struct {
+	countries map[string]int
+	methods   []types.ShippingMethod
+}
+
+func (m *mockShippingStore) GetShippingZones() ([]types.ShippingZone, error) {
+	return []types.ShippingZone{}, nil
+}
+
+func (m *mockShippingStore) GetShippingZone(id int) (*types.ShippingZone, error) {
+	return nil, shipping.ErrZoneNotFound
+}
+
+func (m *mockShippingStore) CreateShippingZone(payload types.ShippingZonePayload) (int, error) {
+	return 0, nil
+}
+
+func (m *mockShippingStore) UpdateShippingZone(id int, payload types.ShippingZonePayload) error {
+	return nil
+}
+
+func (m *mockShippingStore) GetShippingMethods() ([]types.ShippingMethod, error) {
+	return m.methods, nil
+}
+
+func (m *mockShippingStore) GetShippingMethod(id int) (*types.ShippingMethod, error) {
+	return nil, shipping.ErrMethodNotFound
+}
+
+func (m *mockShippingStore) GetShippingMethodsForCountry(country string) ([]types.ShippingMethod, error) {
+	methods := []types.ShippingMethod{}
+	for _, method := range m.methods {
+		if zoneID, ok := m.countries[country]; ok && method.ZoneID == zoneID {
+			methods = append(methods, method)
+		}
+	}
+	return methods, nil
+}
+
+func (m *mockShippingStore) CreateShippingMethod(payload types.ShippingMethodPayload) (int, error) {
+	return 0, nil
+}
+
+func (m *mockShippingStore) UpdateShippingMethod(id int, payload types.ShippingMethodPayload) error {
+	return nil
+}
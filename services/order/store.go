@@ -0,0 +1,386 @@
+package order
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/code-farms/go-backend/services/inventory"
+	"github.com/code-farms/go-backend/services/outbox"
+	"github.com/code-farms/go-backend/services/promotion"
+	"github.com/code-farms/go-backend/services/webhook"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+)
+
+var (
+	ErrOrderNotFound     = errors.New("order not found")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrPriceChanged      = errors.New("price changed during checkout")
+)
+
+// orderColumns lists the columns read by scanRowIntoOrder, in scan order.
+const orderColumns = "id, userId, subtotal, discount, promotionId, promotionCode, tax, shippingMethodId, shippingMethod, shipping, total, status, address, country, region, postalCode, created_at"
+
+// itemColumns lists the columns read by scanRowIntoItem, in scan order.
+const itemColumns = "id, orderId, productId, productName, productSku, productImage, quantity, price, discount, tax, refunded, shipped"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateOrder takes each item out of stock with a conditional update, so two customers
+// buying the last unit can't both succeed. Units held by the reservation, if any, move from
+// reserved to sold instead, and the reservation is committed. An order with a promotion
+// also redeems it, so a code used up meanwhile fails the order. Any failure rolls the whole
+// order back.
+func (s *Store) CreateOrder(order types.Order, items []types.OrderItem, reservationID int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		`INSERT INTO orders (userId, subtotal, discount, promotionId, promotionCode, tax, shippingMethodId, shippingMethod, shipping, total,
+			status, address, country, region, postalCode)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		order.UserID, order.Subtotal, order.Discount, order.PromotionID, order.PromotionCode, order.Tax,
+		order.ShippingMethodID, order.ShippingMethod, order.Shipping, order.Total,
+		order.Status, order.Address, order.Country, order.Region, order.PostalCode,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert order: %w", err)
+	}
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	orderID := int(lastID)
+
+	_, err = RecordStatusChange(tx, types.OrderStatusChange{
+		OrderID:  orderID,
+		ToStatus: order.Status,
+		ActorID:  inventory.Actor(order.UserID),
+		Note:     "order placed",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if order.PromotionID != nil {
+		if err := promotion.Redeem(tx, *order.PromotionID, order.UserID, orderID, order.Discount); err != nil {
+			return 0, err
+		}
+	}
+
+	held := map[int]int{}
+	if reservationID != 0 {
+		sold := make([]types.ReservationItem, len(items))
+		for i, item := range items {
+			sold[i] = types.ReservationItem{ProductID: item.ProductID, Quantity: item.Quantity}
+		}
+		held, err = inventory.CommitReservation(tx, reservationID, order.UserID, sold)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, item := range items {
+		if err := takeStock(tx, item, held[item.ProductID]); err != nil {
+			return 0, err
+		}
+
+		_, err := inventory.RecordMovement(tx, types.StockMovement{
+			ProductID:      item.ProductID,
+			QuantityChange: -item.Quantity,
+			ReservedChange: -held[item.ProductID],
+			Reason:         types.MovementSale,
+			ActorID:        inventory.Actor(order.UserID),
+			Reference:      fmt.Sprintf("order:%d", orderID),
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		result, err := tx.Exec(
+			"INSERT INTO order_items (orderId, productId, productName, productSku, productImage, quantity, price, discount, tax) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			orderID, item.ProductID, item.ProductName, item.ProductSKU, item.ProductImage, item.Quantity, item.Price, item.Discount, item.Tax,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert order item: %w", err)
+		}
+		itemID, err := result.LastInsertId()
+		if err != nil {
+			return 0, err
+		}
+
+		for _, line := range item.Taxes {
+			_, err := tx.Exec(
+				"INSERT INTO order_item_taxes (orderItemId, name, rate, inclusive, amount) VALUES (?, ?, ?, ?, ?)",
+				itemID, line.Name, line.Rate, line.Inclusive, line.Amount,
+			)
+			if err != nil {
+				return 0, fmt.Errorf("failed to insert order item tax: %w", err)
+			}
+		}
+	}
+
+	if err := outbox.Record(tx, types.OrderPlaced{OrderID: orderID, UserID: order.UserID}); err != nil {
+		return 0, err
+	}
+	if err := publish(tx, types.EventOrderCreated, orderID, ""); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return orderID, nil
+}
+
+func (s *Store) GetOrders(filter types.OrderFilter, limit, offset int) ([]types.Order, error) {
+	where, args := []string{"1 = 1"}, []any{}
+	if filter.UserID != 0 {
+		where, args = append(where, "userId = ?"), append(args, filter.UserID)
+	}
+	if filter.Status != "" {
+		where, args = append(where, "status = ?"), append(args, filter.Status)
+	}
+	if filter.From != nil {
+		where, args = append(where, "created_at >= ?"), append(args, *filter.From)
+	}
+	if filter.To != nil {
+		where, args = append(where, "created_at < ?"), append(args, *filter.To)
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+orderColumns+" FROM orders WHERE "+strings.Join(where, " AND ")+" ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []types.Order{}
+	for rows.Next() {
+		o, err := scanRowIntoOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *o)
+	}
+	return orders, rows.Err()
+}
+
+func (s *Store) GetOrder(id int) (*types.Order, error) {
+	return loadOrder(s.db, id)
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx, so orders can be read inside the
+// transaction that changes them.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// loadOrder reads an order with its items and tax lines.
+func loadOrder(q querier, id int) (*types.Order, error) {
+	rows, err := q.Query("SELECT "+orderColumns+" FROM orders WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrOrderNotFound
+	}
+	order, err := scanRowIntoOrder(rows)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	itemRows, err := q.Query("SELECT "+itemColumns+" FROM order_items WHERE orderId = ? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	order.Items = []types.OrderItem{}
+	for itemRows.Next() {
+		item, err := scanRowIntoItem(itemRows)
+		if err != nil {
+			return nil, err
+		}
+		order.Items = append(order.Items, *item)
+	}
+	if err := itemRows.Err(); err != nil {
+		return nil, err
+	}
+	itemRows.Close()
+
+	if err := loadTaxes(q, order); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// loadTaxes attaches the tax lines to the items of an order and sums them up per tax.
+func loadTaxes(q querier, order *types.Order) error {
+	rows, err := q.Query(`
+		SELECT t.orderItemId, t.name, t.rate, t.inclusive, t.amount FROM order_item_taxes t
+		JOIN order_items i ON i.id = t.orderItemId
+		WHERE i.orderId = ? ORDER BY t.id`,
+		order.ID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	index := make(map[int]int, len(order.Items))
+	for i, item := range order.Items {
+		index[item.ID] = i
+	}
+	for rows.Next() {
+		var itemID int
+		var line types.TaxLine
+		if err := rows.Scan(&itemID, &line.Name, &line.Rate, &line.Inclusive, &line.Amount); err != nil {
+			return err
+		}
+		item := &order.Items[index[itemID]]
+		item.Taxes = append(item.Taxes, line)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	order.Taxes = sumTaxes(order.Items)
+	return nil
+}
+
+// publish queues a webhook event with the order as it is inside tx.
+func publish(tx *sql.Tx, event string, id int, previousStatus string) error {
+	order, err := loadOrder(tx, id)
+	if err != nil {
+		return err
+	}
+	return webhook.Enqueue(tx, event, types.OrderEvent{Order: *order, PreviousStatus: previousStatus})
+}
+
+// sumTaxes adds up the tax lines of all items by tax name, rate and inclusiveness, in the
+// order they first appear.
+func sumTaxes(items []types.OrderItem) []types.TaxLine {
+	type key struct {
+		name      string
+		rate      int64
+		inclusive bool
+	}
+	sums := []types.TaxLine{}
+	cents := []int64{}
+	index := map[key]int{}
+	for _, item := range items {
+		for _, line := range item.Taxes {
+			k := key{line.Name, utils.ToCents(line.Rate), line.Inclusive}
+			i, ok := index[k]
+			if !ok {
+				i = len(sums)
+				index[k] = i
+				sums = append(sums, line)
+				cents = append(cents, 0)
+			}
+			cents[i] += utils.ToCents(line.Amount)
+		}
+	}
+	for i := range sums {
+		sums[i].Amount = utils.FromCents(cents[i])
+	}
+	return sums
+}
+
+// takeStock removes the ordered units from products.quantity, the held ones of which the
+// customer's own reservation also releases from products.reserved. The other units must
+// not be held by any reservation, and the update only matches while the product still has
+// the snapshotted price so the customer is never charged a price they didn't see. The
+// price is compared as an exact decimal, as float64 values don't match DECIMAL columns.
+func takeStock(tx *sql.Tx, item types.OrderItem, held int) error {
+	result, err := tx.Exec(`
+		UPDATE products SET quantity = quantity - ?, reserved = reserved - ?
+		WHERE id = ? AND price = ? AND CAST(quantity AS SIGNED) - CAST(reserved AS SIGNED) >= ?`,
+		item.Quantity, held, item.ProductID, utils.FormatCents(utils.ToCents(item.Price)), item.Quantity-held,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+
+	var price float64
+	var available int
+	err = tx.QueryRow(
+		"SELECT price, CAST(quantity AS SIGNED) - CAST(reserved AS SIGNED) FROM products WHERE id = ?", item.ProductID,
+	).Scan(&price, &available)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: product %d", ErrProductNotFound, item.ProductID)
+	}
+	if err != nil {
+		return err
+	}
+	if utils.ToCents(price) != utils.ToCents(item.Price) {
+		return fmt.Errorf("%w: product %d now costs %.2f", ErrPriceChanged, item.ProductID, price)
+	}
+	return fmt.Errorf("%w: only %d units of product %d are available", ErrInsufficientStock, max(available, 0)+held, item.ProductID)
+}
+
+func scanRowIntoOrder(rows *sql.Rows) (*types.Order, error) {
+	o := new(types.Order)
+	err := rows.Scan(
+		&o.ID,
+		&o.UserID,
+		&o.Subtotal,
+		&o.Discount,
+		&o.PromotionID,
+		&o.PromotionCode,
+		&o.Tax,
+		&o.ShippingMethodID,
+		&o.ShippingMethod,
+		&o.Shipping,
+		&o.Total,
+		&o.Status,
+		&o.Address,
+		&o.Country,
+		&o.Region,
+		&o.PostalCode,
+		&o.CreatedAt,
+	)
+	return o, err
+}
+
+func scanRowIntoItem(rows *sql.Rows) (*types.OrderItem, error) {
+	item := new(types.OrderItem)
+	err := rows.Scan(
+		&item.ID,
+		&item.OrderID,
+		&item.ProductID,
+		&item.ProductName,
+		&item.ProductSKU,
+		&item.ProductImage,
+		&item.Quantity,
+		&item.Price,
+		&item.Discount,
+		&item.Tax,
+		&item.Refunded,
+		&item.Shipped,
+	)
+	return item, err
+}
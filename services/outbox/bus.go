@@ -0,0 +1,46 @@
+package outbox
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// Bus keeps the in-process subscribers of each domain event type. Events reach it through
+// the Relay, never directly, so a subscriber only sees changes that were committed.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]subscriber
+}
+
+type subscriber struct {
+	name    string
+	handler types.EventHandler
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: map[string][]subscriber{}}
+}
+
+// Subscribe panics if name is already subscribed to eventType, since the outbox could not
+// tell the two apart.
+func (b *Bus) Subscribe(eventType, name string, handler types.EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, s := range b.subscribers[eventType] {
+		if s.name == name {
+			panic(fmt.Sprintf("outbox: %s is already subscribed to %s", name, eventType))
+		}
+	}
+	b.subscribers[eventType] = append(b.subscribers[eventType], subscriber{name: name, handler: handler})
+}
+
+// subscribersOf returns the subscribers of eventType in the order they subscribed.
+func (b *Bus) subscribersOf(eventType string) []subscriber {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.subscribers[eventType]
+}
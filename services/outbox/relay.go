@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+const (
+	// MaxAttempts is how many times an event is published before it is given up.
+	MaxAttempts = 10
+
+	// firstRetry is the wait after the first failed attempt, doubled after every other one.
+	firstRetry = 10 * time.Second
+
+	// maxRetry caps the wait between two attempts.
+	maxRetry = time.Hour
+
+	// lease is how long a claimed batch is held, and so how long subscribers have to
+	// handle it before another relay may publish the events again.
+	lease = 5 * time.Minute
+
+	// batchSize is how many events are claimed at once.
+	batchSize = 50
+)
+
+// Relay publishes the events of the outbox to the subscribers of a Bus, retrying the
+// subscribers that fail.
+type Relay struct {
+	store types.OutboxStore
+	bus   *Bus
+}
+
+func NewRelay(store types.OutboxStore, bus *Bus) *Relay {
+	return &Relay{store: store, bus: bus}
+}
+
+// Start runs Relay every interval until ctx is cancelled. It returns immediately.
+func (r *Relay) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.Relay(ctx); err != nil {
+					log.Printf("failed to relay outbox events: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Relay publishes the events that are due, a batch at a time, until none are left.
+func (r *Relay) Relay(ctx context.Context) error {
+	for ctx.Err() == nil {
+		events, err := r.store.ClaimOutboxEvents(batchSize, lease)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := r.publish(ctx, event); err != nil {
+				return err
+			}
+		}
+		if len(events) < batchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// publish hands an event to every subscriber that has not handled it yet. Each success is
+// recorded right away, so a retry only calls the subscribers that failed.
+func (r *Relay) publish(ctx context.Context, event types.OutboxEvent) error {
+	handled := make(map[string]bool, len(event.Handled))
+	for _, name := range event.Handled {
+		handled[name] = true
+	}
+
+	failures := []string{}
+	for _, s := range r.bus.subscribersOf(event.Type) {
+		if handled[s.name] {
+			continue
+		}
+		if err := call(ctx, s.handler, event); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", s.name, err))
+			continue
+		}
+		if err := r.store.MarkOutboxEventHandled(event.ID, s.name); err != nil {
+			return err
+		}
+	}
+	if len(failures) == 0 {
+		return r.store.CompleteOutboxEvent(event.ID)
+	}
+
+	var retryAfter time.Duration
+	if event.Attempts+1 < MaxAttempts {
+		retryAfter = backoff(event.Attempts + 1)
+	}
+	lastError := strings.Join(failures, "; ")
+	if retryAfter == 0 {
+		log.Printf("giving up %s event %d after %d attempts: %s", event.Type, event.ID, MaxAttempts, lastError)
+	}
+	return r.store.RetryOutboxEvent(event.ID, retryAfter, lastError)
+}
+
+// call runs a handler, turning a panic into an error so one bad event can't stop the relay.
+func call(ctx context.Context, handler types.EventHandler, event types.OutboxEvent) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return handler(ctx, event)
+}
+
+// backoff returns how long to wait before the attempt after the given number of failed
+// ones: 10s, 20s, 40s... up to maxRetry.
+func backoff(failed int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < failed && wait < maxRetry; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetry)
+}
@@ -0,0 +1,184 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestRelay(t *testing.T) {
+	store := &mockOutboxStore{}
+	bus := NewBus()
+	relay := NewRelay(store, bus)
+
+	received := map[string][]int{}
+	fail := false
+	bus.Subscribe(types.OrderPlacedEvent, "email", func(ctx context.Context, event types.OutboxEvent) error {
+		var placed types.OrderPlaced
+		if err := json.Unmarshal(event.Payload, &placed); err != nil {
+			return err
+		}
+		received["email"] = append(received["email"], placed.OrderID)
+		return nil
+	})
+	bus.Subscribe(types.OrderPlacedEvent, "crm", func(ctx context.Context, event types.OutboxEvent) error {
+		received["crm"] = append(received["crm"], event.ID)
+		if fail {
+			return errors.New("crm unavailable")
+		}
+		return nil
+	})
+
+	t.Run("should publish events to every subscriber", func(t *testing.T) {
+		store.add(types.OrderPlaced{OrderID: 7, UserID: 1})
+		store.add(types.ProductUpdated{ProductID: 3})
+		if err := relay.Relay(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(received["email"]) != 1 || received["email"][0] != 7 || len(received["crm"]) != 1 {
+			t.Errorf("expected both subscribers to get the order but got %v", received)
+		}
+		for _, e := range store.events {
+			if e.status != "published" {
+				t.Errorf("expected event %d to be published but got %s", e.ID, e.status)
+			}
+		}
+	})
+
+	t.Run("should only retry the subscribers that failed", func(t *testing.T) {
+		received = map[string][]int{}
+		fail = true
+		store.add(types.OrderPlaced{OrderID: 8, UserID: 1})
+		if err := relay.Relay(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		e := store.events[len(store.events)-1]
+		if e.status != "pending" || e.retryAfter != firstRetry || !strings.Contains(e.lastError, "crm: crm unavailable") {
+			t.Fatalf("expected the event to be retried but got %+v", e)
+		}
+
+		fail = false
+		if err := relay.Relay(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(received["email"]) != 1 || len(received["crm"]) != 2 {
+			t.Errorf("expected only crm to get the event again but got %v", received)
+		}
+		if e := store.events[len(store.events)-1]; e.status != "published" {
+			t.Errorf("expected the event to be published but got %s", e.status)
+		}
+	})
+
+	t.Run("should give up after the last attempt", func(t *testing.T) {
+		fail = true
+		store.add(types.OrderPlaced{OrderID: 9, UserID: 1})
+		e := store.events[len(store.events)-1]
+		e.Attempts = MaxAttempts - 1
+		if err := relay.Relay(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if e.status != "failed" {
+			t.Errorf("expected the event to fail but got %s", e.status)
+		}
+	})
+
+	t.Run("should recover from subscribers that panic", func(t *testing.T) {
+		bus.Subscribe(types.UserRegisteredEvent, "broken", func(ctx context.Context, event types.OutboxEvent) error {
+			panic("nil map")
+		})
+		store.add(types.UserRegistered{UserID: 1})
+		if err := relay.Relay(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if e := store.events[len(store.events)-1]; e.status != "pending" || !strings.Contains(e.lastError, "panic: nil map") {
+			t.Errorf("expected the panic to be retried but got %+v", e)
+		}
+	})
+}
+
+func TestSubscribeTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected subscribing the same name twice to panic")
+		}
+	}()
+
+	bus := NewBus()
+	handler := func(ctx context.Context, event types.OutboxEvent) error { return nil }
+	bus.Subscribe(types.OrderPlacedEvent, "email", handler)
+	bus.Subscribe(types.OrderStatusChangedEvent, "email", handler)
+	bus.Subscribe(types.OrderPlacedEvent, "email", handler)
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 4: 80 * time.Second, 20: maxRetry}
+	for failed, want := range cases {
+		if got := backoff(failed); got != want {
+			t.Errorf("backoff(%d): expected %v but got %v", failed, want, got)
+		}
+	}
+}
+
+type mockEvent struct {
+	types.OutboxEvent
+	status     string
+	retryAfter time.Duration
+	lastError  string
+}
+
+// mockOutboxStore keeps the outbox in memory. Retried events are due again right away.
+type mockOutboxStore struct {
+	events []*mockEvent
+}
+
+func (m *mockOutboxStore) add(event types.DomainEvent) {
+	payload, _ := json.Marshal(event)
+	m.events = append(m.events, &mockEvent{
+		OutboxEvent: types.OutboxEvent{ID: len(m.events) + 1, Type: event.EventType(), Payload: payload, CreatedAt: time.Now()},
+		status:      "pending",
+	})
+}
+
+func (m *mockOutboxStore) get(id int) *mockEvent {
+	return m.events[id-1]
+}
+
+func (m *mockOutboxStore) ClaimOutboxEvents(limit int, lease time.Duration) ([]types.OutboxEvent, error) {
+	events := []types.OutboxEvent{}
+	for _, e := range m.events {
+		if e.status == "pending" && len(events) < limit {
+			events = append(events, e.OutboxEvent)
+		}
+	}
+	return events, nil
+}
+
+func (m *mockOutboxStore) MarkOutboxEventHandled(eventID int, subscriber string) error {
+	e := m.get(eventID)
+	e.Handled = append(e.Handled, subscriber)
+	return nil
+}
+
+func (m *mockOutboxStore) CompleteOutboxEvent(eventID int) error {
+	e := m.get(eventID)
+	e.Attempts++
+	e.status = "published"
+	return nil
+}
+
+func (m *mockOutboxStore) RetryOutboxEvent(eventID int, retryAfter time.Duration, lastError string) error {
+	e := m.get(eventID)
+	e.Attempts++
+	e.retryAfter, e.lastError = retryAfter, lastError
+	if retryAfter == 0 {
+		e.status = "failed"
+	}
+	return nil
+}
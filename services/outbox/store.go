@@ -0,0 +1,150 @@
+package outbox
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// maxErrorLength is the size of the outbox_events.lastError column.
+const maxErrorLength = 1024
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Record writes a domain event to the outbox. It must run inside the transaction making
+// the change, so the event is published if and only if the change is committed.
+func Record(tx *sql.Tx, event types.DomainEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.EventType(), err)
+	}
+	if _, err := tx.Exec("INSERT INTO outbox_events (type, payload) VALUES (?, ?)", event.EventType(), payload); err != nil {
+		return fmt.Errorf("failed to record %s event: %w", event.EventType(), err)
+	}
+	return nil
+}
+
+// ClaimOutboxEvents pushes the next attempt of the claimed events back by lease, so a relay
+// that dies while publishing leaves them to be retried rather than stuck. Rows claimed by
+// another relay are skipped instead of waited for.
+func (s *Store) ClaimOutboxEvents(limit int, lease time.Duration) ([]types.OutboxEvent, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT id, type, payload, attempts, created_at FROM outbox_events
+		WHERE status = 'pending' AND nextAttemptAt <= CURRENT_TIMESTAMP
+		ORDER BY id LIMIT ?
+		FOR UPDATE SKIP LOCKED`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []types.OutboxEvent{}
+	index := map[int]int{}
+	for rows.Next() {
+		e := types.OutboxEvent{}
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.Attempts, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		index[e.ID] = len(events)
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(events) == 0 {
+		return events, nil
+	}
+
+	ids := make([]any, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	in := "(?" + strings.Repeat(", ?", len(ids)-1) + ")"
+
+	handledRows, err := tx.Query("SELECT eventId, subscriber FROM outbox_handled WHERE eventId IN "+in, ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer handledRows.Close()
+
+	for handledRows.Next() {
+		var eventID int
+		var subscriber string
+		if err := handledRows.Scan(&eventID, &subscriber); err != nil {
+			return nil, err
+		}
+		if i, ok := index[eventID]; ok {
+			events[i].Handled = append(events[i].Handled, subscriber)
+		}
+	}
+	if err := handledRows.Err(); err != nil {
+		return nil, err
+	}
+	handledRows.Close()
+
+	_, err = tx.Exec(
+		"UPDATE outbox_events SET nextAttemptAt = CURRENT_TIMESTAMP + INTERVAL ? SECOND WHERE id IN "+in,
+		append([]any{int(lease.Seconds())}, ids...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, tx.Commit()
+}
+
+func (s *Store) MarkOutboxEventHandled(eventID int, subscriber string) error {
+	_, err := s.db.Exec("INSERT IGNORE INTO outbox_handled (eventId, subscriber) VALUES (?, ?)", eventID, subscriber)
+	return err
+}
+
+func (s *Store) CompleteOutboxEvent(eventID int) error {
+	_, err := s.db.Exec(
+		`UPDATE outbox_events SET status = 'published', attempts = attempts + 1, nextAttemptAt = NULL,
+			publishedAt = CURRENT_TIMESTAMP WHERE id = ?`,
+		eventID,
+	)
+	return err
+}
+
+func (s *Store) RetryOutboxEvent(eventID int, retryAfter time.Duration, lastError string) error {
+	if len(lastError) > maxErrorLength {
+		lastError = lastError[:maxErrorLength]
+		for !utf8.ValidString(lastError) {
+			lastError = lastError[:len(lastError)-1]
+		}
+	}
+	if retryAfter > 0 {
+		_, err := s.db.Exec(
+			`UPDATE outbox_events SET attempts = attempts + 1, lastError = ?,
+				nextAttemptAt = CURRENT_TIMESTAMP + INTERVAL ? SECOND WHERE id = ?`,
+			lastError, int(retryAfter.Seconds()), eventID,
+		)
+		return err
+	}
+	_, err := s.db.Exec(
+		"UPDATE outbox_events SET status = 'failed', attempts = attempts + 1, lastError = ?, nextAttemptAt = NULL WHERE id = ?",
+		lastError, eventID,
+	)
+	return err
+}
@@ -0,0 +1,125 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/code-farms/go-backend/services/jobs"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+)
+
+// RegisterJobs registers the handlers of the background jobs of payments with worker.
+func (h *Handler) RegisterJobs(worker *jobs.Worker) {
+	jobs.Handle(worker, h.settleRefund)
+}
+
+// settleRefund gives the money of an order refund back through the provider, from the
+// captured payment of the order. A refund of an order that was never captured is settled
+// without moving money: the authorization of a cancelled order is released, and the
+// capture of any other order collects less. The refund is marked refunding before the
+// provider is called with the refund's idempotency key, so a job retried after a crash or
+// a timeout finishes the same provider refund instead of giving the money back twice.
+func (h *Handler) settleRefund(ctx context.Context, job types.SettleRefundJob) error {
+	refund, err := h.orderStore.GetRefund(job.RefundID)
+	if err != nil {
+		return err
+	}
+
+	var paid *types.Payment
+	switch refund.PaymentStatus {
+	case types.RefundPaymentPending:
+		if paid, err = h.startRefund(ctx, refund); err != nil || paid == nil {
+			return err
+		}
+	case types.RefundPaymentRefunding:
+		if paid, err = h.store.GetPayment(*refund.PaymentID); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	intent, err := h.provider.Refund(ctx, paid.ProviderRef, utils.ToCents(refund.Amount), fmt.Sprintf("refund-%d", refund.ID))
+	if err != nil {
+		return err
+	}
+	if err := h.orderStore.SettleRefund(refund.ID, types.RefundPaymentRefunded, &paid.ID); err != nil {
+		return err
+	}
+	_, err = h.store.ApplyPaymentUpdate(paid.ID, *intent, "")
+	return err
+}
+
+// startRefund picks the payment a pending refund is given back from and marks the refund
+// refunding. It returns nil if the refund was settled without moving money instead.
+func (h *Handler) startRefund(ctx context.Context, refund *types.Refund) (*types.Payment, error) {
+	payments, err := h.store.GetOrderPayments(refund.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	var paid, held *types.Payment
+	for i := range payments {
+		switch payments[i].Status {
+		case types.PaymentCaptured, types.PaymentRefunded:
+			paid = &payments[i]
+		case types.PaymentAuthorized:
+			held = &payments[i]
+		}
+	}
+	if held != nil {
+		if err := h.releaseIfCancelled(ctx, held); err != nil {
+			return nil, err
+		}
+	}
+	amount := utils.ToCents(refund.Amount)
+	if paid == nil || amount == 0 {
+		return nil, h.orderStore.SettleRefund(refund.ID, types.RefundPaymentNone, nil)
+	}
+
+	left, err := h.refundable(paid, refund.ID)
+	if err != nil {
+		return nil, err
+	}
+	if amount > left {
+		return nil, fmt.Errorf("%w: refund %d is %s but payment %d has %s left", ErrRefundTooLarge, refund.ID, utils.FormatCents(amount), paid.ID, utils.FormatCents(left))
+	}
+	return paid, h.orderStore.SettleRefund(refund.ID, types.RefundPaymentRefunding, &paid.ID)
+}
+
+// refundable returns the cents of a captured payment that can still be given back: what
+// was not refunded yet, less the order refunds other than except that are still waiting
+// for their money to go back.
+func (h *Handler) refundable(p *types.Payment, except int) (int64, error) {
+	refunds, err := h.orderStore.GetRefunds(p.OrderID)
+	if err != nil {
+		return 0, err
+	}
+
+	left := utils.ToCents(p.Captured) - utils.ToCents(p.Refunded)
+	for _, r := range refunds {
+		if r.ID != except && (r.PaymentStatus == types.RefundPaymentPending || r.PaymentStatus == types.RefundPaymentRefunding) {
+			left -= utils.ToCents(r.Amount)
+		}
+	}
+	return left, nil
+}
+
+// releaseIfCancelled cancels the authorization of a payment whose order was cancelled, so
+// the money held on the customer's payment method is freed.
+func (h *Handler) releaseIfCancelled(ctx context.Context, held *types.Payment) error {
+	o, err := h.orderStore.GetOrder(held.OrderID)
+	if err != nil {
+		return err
+	}
+	if o.Status != types.OrderCancelled {
+		return nil
+	}
+
+	intent, err := h.provider.Cancel(ctx, held.ProviderRef)
+	if err != nil {
+		return err
+	}
+	_, err = h.store.ApplyPaymentUpdate(held.ID, *intent, "")
+	return err
+}
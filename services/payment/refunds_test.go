@@ -0,0 +1,190 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/payments"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+func TestSettleRefund(t *testing.T) {
+	orderStore := &mockOrderStore{
+		orders: map[int]*types.Order{
+			1: {ID: 1, UserID: 1, Total: 40.18, Status: types.OrderCancelled},
+			2: {ID: 2, UserID: 1, Total: 5, Status: types.OrderCancelled},
+		},
+		refunds: map[int]*types.Refund{
+			1: {ID: 1, OrderID: 1, Amount: 10, PaymentStatus: types.RefundPaymentPending},
+			2: {ID: 2, OrderID: 2, Amount: 5, PaymentStatus: types.RefundPaymentPending},
+		},
+	}
+	store := &mockPaymentStore{orders: orderStore, events: map[string]bool{}}
+	provider := payments.NewFakeProvider("whsec")
+	userStore := &testutil.UserStore{Users: map[int]*types.User{1: {ID: 1, Role: types.RoleAdmin}}}
+	handler := NewHandler(store, orderStore, provider, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	ctx := context.Background()
+	intent, err := provider.CreateIntent(ctx, types.PaymentIntentRequest{OrderID: 1, Amount: 4018, Currency: "usd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Confirm(ctx, intent.ID, payments.FakeCardSuccess); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.Capture(ctx, intent.ID, 4018); err != nil {
+		t.Fatal(err)
+	}
+	store.CreatePayment(types.Payment{OrderID: 1, Provider: "fake", ProviderRef: intent.ID, Amount: 40.18, Captured: 40.18, Status: types.PaymentCaptured})
+
+	t.Run("should give the money back through the provider once", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			if err := handler.settleRefund(ctx, types.SettleRefundJob{RefundID: 1}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		r := orderStore.refunds[1]
+		if r.PaymentStatus != types.RefundPaymentRefunded || r.PaymentID == nil || *r.PaymentID != 1 {
+			t.Errorf("expected the refund to be settled with payment 1 but got %+v", r)
+		}
+		if p := store.payments[0]; p.Refunded != 10 || p.Status != types.PaymentCaptured {
+			t.Errorf("expected 10.00 of the payment to be refunded once but got %+v", p)
+		}
+	})
+
+	t.Run("should settle refunds of unpaid orders without a payment", func(t *testing.T) {
+		if err := handler.settleRefund(ctx, types.SettleRefundJob{RefundID: 2}); err != nil {
+			t.Fatal(err)
+		}
+		if r := orderStore.refunds[2]; r.PaymentStatus != types.RefundPaymentNone || r.PaymentID != nil {
+			t.Errorf("expected the refund to be settled without a payment but got %+v", r)
+		}
+	})
+
+	t.Run("should keep refunds larger than what is left pending", func(t *testing.T) {
+		orderStore.refunds[3] = &types.Refund{ID: 3, OrderID: 1, Amount: 30.19, PaymentStatus: types.RefundPaymentPending}
+		err := handler.settleRefund(ctx, types.SettleRefundJob{RefundID: 3})
+		if !errors.Is(err, ErrRefundTooLarge) {
+			t.Errorf("expected %v but got %v", ErrRefundTooLarge, err)
+		}
+		if r := orderStore.refunds[3]; r.PaymentStatus != types.RefundPaymentPending {
+			t.Errorf("expected the refund to stay pending but got %+v", r)
+		}
+		if p := store.payments[0]; p.Refunded != 10 {
+			t.Errorf("expected nothing more to be refunded but got %+v", p)
+		}
+	})
+
+	t.Run("should finish a refund interrupted after the provider call without refunding twice", func(t *testing.T) {
+		paymentID := 1
+		orderStore.refunds[4] = &types.Refund{ID: 4, OrderID: 1, Amount: 5, PaymentStatus: types.RefundPaymentRefunding, PaymentID: &paymentID}
+		if _, err := provider.Refund(ctx, intent.ID, 500, "refund-4"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := handler.settleRefund(ctx, types.SettleRefundJob{RefundID: 4}); err != nil {
+			t.Fatal(err)
+		}
+		if r := orderStore.refunds[4]; r.PaymentStatus != types.RefundPaymentRefunded {
+			t.Errorf("expected the refund to be settled but got %+v", r)
+		}
+		if p := store.payments[0]; p.Refunded != 15 {
+			t.Errorf("expected 5.00 more to be refunded once but got %+v", p)
+		}
+	})
+
+	t.Run("should keep admin refunds from taking what order refunds wait for", func(t *testing.T) {
+		body := bytes.NewReader([]byte(`{"amount":1}`))
+		req := httptest.NewRequest(http.MethodPost, "/admin/payments/1/refunds", body)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 1))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d while refund 3 is pending but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+}
+
+func TestCancelledOrderCapture(t *testing.T) {
+	orderStore := &mockOrderStore{
+		orders: map[int]*types.Order{
+			1: {ID: 1, UserID: 1, Total: 40.18, Status: types.OrderProcessing},
+			2: {ID: 2, UserID: 1, Total: 5, Status: types.OrderProcessing},
+		},
+		refunds: map[int]*types.Refund{},
+	}
+	store := &mockPaymentStore{orders: orderStore, events: map[string]bool{}}
+	provider := payments.NewFakeProvider("whsec")
+	userStore := &testutil.UserStore{Users: map[int]*types.User{1: {ID: 1, Role: types.RoleAdmin}}}
+	handler := NewHandler(store, orderStore, provider, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	ctx := context.Background()
+	authorize := func(orderID int, amount int64) int {
+		intent, err := provider.CreateIntent(ctx, types.PaymentIntentRequest{OrderID: orderID, Amount: amount, Currency: "usd"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := provider.Confirm(ctx, intent.ID, payments.FakeCardSuccess); err != nil {
+			t.Fatal(err)
+		}
+		id, _ := store.CreatePayment(types.Payment{OrderID: orderID, Provider: "fake", ProviderRef: intent.ID, Amount: utils.FromCents(amount), Status: types.PaymentAuthorized})
+		return id
+	}
+	capture := func(paymentID int) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/admin/payments/%d/capture", paymentID), nil)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 1))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should release the authorization of a cancelled order and never capture it", func(t *testing.T) {
+		paymentID := authorize(1, 4018)
+		orderStore.orders[1].Status = types.OrderCancelled
+		orderStore.refunds[1] = &types.Refund{ID: 1, OrderID: 1, Amount: 40.18, PaymentStatus: types.RefundPaymentPending}
+
+		if rr := capture(paymentID); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d before the refund is settled but got %d", http.StatusConflict, rr.Code)
+		}
+		if err := handler.settleRefund(ctx, types.SettleRefundJob{RefundID: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if p := store.payments[paymentID-1]; p.Status != types.PaymentCancelled || p.Captured != 0 {
+			t.Errorf("expected the authorization to be released but got %+v", p)
+		}
+		if r := orderStore.refunds[1]; r.PaymentStatus != types.RefundPaymentNone {
+			t.Errorf("expected the refund to be settled without moving money but got %+v", r)
+		}
+		if rr := capture(paymentID); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d after the cancellation but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should capture less than authorized once refunds covered part of it", func(t *testing.T) {
+		paymentID := authorize(2, 500)
+		orderStore.refunds[2] = &types.Refund{ID: 2, OrderID: 2, Amount: 1.5, PaymentStatus: types.RefundPaymentPending}
+		if err := handler.settleRefund(ctx, types.SettleRefundJob{RefundID: 2}); err != nil {
+			t.Fatal(err)
+		}
+
+		if rr := capture(paymentID); rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if p := store.payments[paymentID-1]; p.Status != types.PaymentCaptured || p.Captured != 3.5 {
+			t.Errorf("expected 3.50 to be captured but got %+v", p)
+		}
+	})
+}
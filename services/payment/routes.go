@@ -0,0 +1,340 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/services/idempotency"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+// maxWebhookSize limits the body of a provider webhook.
+const maxWebhookSize = 1 << 20
+
+type Handler struct {
+	store      types.PaymentStore
+	orderStore types.OrderStore
+	provider   types.PaymentProvider
+	userStore  types.UserStore
+}
+
+func NewHandler(store types.PaymentStore, orderStore types.OrderStore, provider types.PaymentProvider, userStore types.UserStore) *Handler {
+	return &Handler{store: store, orderStore: orderStore, provider: provider, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orders/{id:[0-9]+}/payments", auth.WithJWTAuth(h.handleCreatePayment, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/orders/{id:[0-9]+}/payments", auth.WithJWTAuth(h.handleGetPayments, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/payments/{id:[0-9]+}/confirm", auth.WithJWTAuth(h.handleConfirmPayment, h.userStore)).Methods(http.MethodPost)
+
+	router.HandleFunc("/admin/payments/{id:[0-9]+}/capture", auth.WithAdminAuth(h.handleCapturePayment, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/payments/{id:[0-9]+}/refunds", auth.WithAdminAuth(h.handleRefundPayment, h.userStore)).Methods(http.MethodPost)
+
+	// Called by the provider, authenticated by the webhook signature
+	router.HandleFunc("/webhooks/payments", h.handleWebhook).Methods(http.MethodPost)
+}
+
+// handleCreatePayment starts a payment of the order total with the provider. An order can
+// be paid while it is pending and no earlier payment went through.
+func (h *Handler) handleCreatePayment(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	o, err := h.ownOrder(r, id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if o.Status != types.OrderPending {
+		writeStoreError(w, ErrOrderNotPayable)
+		return
+	}
+	payments, err := h.store.GetOrderPayments(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	for _, p := range payments {
+		if statusRank[p.Status] >= statusRank[types.PaymentAuthorized] {
+			writeStoreError(w, ErrAlreadyPaid)
+			return
+		}
+	}
+
+	intent, err := h.provider.CreateIntent(r.Context(), types.PaymentIntentRequest{
+		OrderID:  id,
+		Amount:   utils.ToCents(o.Total),
+		Currency: configs.Envs.PaymentCurrency,
+	})
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	paymentID, err := h.store.CreatePayment(types.Payment{
+		OrderID:     id,
+		Provider:    h.provider.Name(),
+		ProviderRef: intent.ID,
+		Amount:      utils.FromCents(intent.Amount),
+		Currency:    intent.Currency,
+		Status:      intent.Status,
+	})
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.writePayment(w, http.StatusCreated, paymentID)
+}
+
+func (h *Handler) handleGetPayments(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if _, err := h.ownOrder(r, id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	payments, err := h.store.GetOrderPayments(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, payments)
+}
+
+// handleConfirmPayment authorizes the payment with the customer's payment method. A
+// declined payment method answers 402 and the payment can be confirmed again.
+func (h *Handler) handleConfirmPayment(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ConfirmPaymentPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	p, err := h.store.GetPayment(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if _, err := h.ownOrder(r, p.OrderID); err != nil {
+		writeStoreError(w, ErrPaymentNotFound)
+		return
+	}
+	if p.Status != types.PaymentRequiresConfirmation && p.Status != types.PaymentFailed {
+		writeStoreError(w, ErrPaymentState)
+		return
+	}
+
+	intent, err := h.provider.Confirm(r.Context(), p.ProviderRef, payload.PaymentMethod)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	if _, err := h.store.ApplyPaymentUpdate(p.ID, *intent, ""); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if intent.Status == types.PaymentFailed {
+		utils.WriteError(w, http.StatusPaymentRequired, fmt.Errorf("payment declined: %s", intent.FailureReason))
+		return
+	}
+
+	h.writePayment(w, http.StatusOK, p.ID)
+}
+
+// handleCapturePayment collects the authorized amount, less the order refunds that were
+// settled without moving money because nothing had been captured yet. Payments of
+// cancelled orders are never captured; their authorization is released by settleRefund.
+func (h *Handler) handleCapturePayment(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	p, err := h.store.GetPayment(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if p.Status != types.PaymentAuthorized {
+		writeStoreError(w, ErrPaymentState)
+		return
+	}
+	o, err := h.orderStore.GetOrder(p.OrderID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if o.Status == types.OrderCancelled {
+		writeStoreError(w, ErrOrderCancelled)
+		return
+	}
+	refunds, err := h.orderStore.GetRefunds(p.OrderID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	amount := utils.ToCents(p.Amount)
+	for _, refund := range refunds {
+		if refund.PaymentStatus == types.RefundPaymentNone {
+			amount -= utils.ToCents(refund.Amount)
+		}
+	}
+	if amount <= 0 {
+		writeStoreError(w, ErrOrderRefunded)
+		return
+	}
+
+	intent, err := h.provider.Capture(r.Context(), p.ProviderRef, amount)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	if _, err := h.store.ApplyPaymentUpdate(p.ID, *intent, ""); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.writePayment(w, http.StatusOK, p.ID)
+}
+
+// handleRefundPayment gives back part or all of the captured amount that order refunds
+// don't claim yet. Requests sent with an Idempotency-Key pass it on to the provider, so a
+// retry after a timeout doesn't refund twice.
+func (h *Handler) handleRefundPayment(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.RefundPaymentPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	p, err := h.store.GetPayment(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if p.Status != types.PaymentCaptured {
+		writeStoreError(w, ErrPaymentState)
+		return
+	}
+	left, err := h.refundable(p, 0)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	amount := utils.ToCents(payload.Amount)
+	if amount > left {
+		writeStoreError(w, ErrRefundTooLarge)
+		return
+	}
+
+	key := ""
+	if k := r.Header.Get(idempotency.KeyHeader); k != "" {
+		key = fmt.Sprintf("payment-%d-%s", p.ID, k)
+	}
+	intent, err := h.provider.Refund(r.Context(), p.ProviderRef, amount, key)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	if _, err := h.store.ApplyPaymentUpdate(p.ID, *intent, ""); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.writePayment(w, http.StatusOK, p.ID)
+}
+
+// handleWebhook applies a payment event sent by the provider. Redelivered events are
+// acknowledged without being applied twice, and events about unknown payments are
+// acknowledged so the provider stops retrying them.
+func (h *Handler) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookSize))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("failed to read webhook: %v", err))
+		return
+	}
+
+	event, err := h.provider.VerifyWebhook(payload, r.Header)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	p, err := h.store.GetPaymentByProviderRef(h.provider.Name(), event.Intent.ID)
+	if errors.Is(err, ErrPaymentNotFound) {
+		log.Printf("ignoring %s event %s for unknown payment %s", h.provider.Name(), event.ID, event.Intent.ID)
+		utils.WriteJSON(w, http.StatusOK, map[string]bool{"applied": false})
+		return
+	}
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	applied, err := h.store.ApplyPaymentUpdate(p.ID, event.Intent, event.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]bool{"applied": applied})
+}
+
+// ownOrder returns the order if it belongs to the authenticated user or the user is an
+// admin. Other users' orders are reported as not found so their IDs are not disclosed.
+func (h *Handler) ownOrder(r *http.Request, id int) (*types.Order, error) {
+	o, err := h.orderStore.GetOrder(id)
+	if err != nil {
+		return nil, err
+	}
+	if o.UserID != auth.GetUserIDFromContext(r.Context()) && !auth.IsAdmin(r.Context()) {
+		return nil, order.ErrOrderNotFound
+	}
+	return o, nil
+}
+
+func (h *Handler) writePayment(w http.ResponseWriter, status, id int) {
+	p, err := h.store.GetPayment(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	utils.WriteJSON(w, status, p)
+}
+
+// writeProviderError reports a failure of the payment provider as a bad gateway.
+func writeProviderError(w http.ResponseWriter, err error) {
+	log.Printf("payment provider error: %v", err)
+	utils.WriteError(w, http.StatusBadGateway, fmt.Errorf("payment provider error: %v", err))
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPaymentNotFound), errors.Is(err, order.ErrOrderNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrOrderNotPayable), errors.Is(err, ErrAlreadyPaid), errors.Is(err, ErrPaymentState), errors.Is(err, ErrRefundTooLarge),
+		errors.Is(err, ErrOrderCancelled), errors.Is(err, ErrOrderRefunded):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,275 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/payments"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+func TestPaymentFlow(t *testing.T) {
+	orderStore := &mockOrderStore{orders: map[int]*types.Order{
+		1: {ID: 1, UserID: 1, Total: 40.18, Status: types.OrderPending},
+		2: {ID: 2, UserID: 1, Total: 5, Status: types.OrderPending},
+	}}
+	store := &mockPaymentStore{orders: orderStore, events: map[string]bool{}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleCustomer},
+		3: {ID: 3, Role: types.RoleAdmin},
+	}}
+	provider := payments.NewFakeProvider("whsec")
+
+	handler := NewHandler(store, orderStore, provider, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	webhook := func(eventID, intentID string) *httptest.ResponseRecorder {
+		payload, signature, err := provider.Event(eventID, intentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/payments", bytes.NewReader(payload))
+		req.Header.Set(payments.FakeSignatureHeader, signature)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should hide other users' orders", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/orders/1/payments", 2, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should charge the order total and leave the order pending when declined", func(t *testing.T) {
+		rr := send(http.MethodPost, "/orders/1/payments", 1, nil)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var p types.Payment
+		json.NewDecoder(rr.Body).Decode(&p)
+		if p.Amount != 40.18 || p.Provider != "fake" || p.Status != types.PaymentRequiresConfirmation {
+			t.Fatalf("unexpected payment %+v", p)
+		}
+
+		rr = send(http.MethodPost, "/payments/1/confirm", 1, types.ConfirmPaymentPayload{PaymentMethod: payments.FakeCardDeclined})
+		if rr.Code != http.StatusPaymentRequired {
+			t.Errorf("expected status code %d but got %d", http.StatusPaymentRequired, rr.Code)
+		}
+		if store.payments[0].Status != types.PaymentFailed || orderStore.orders[1].Status != types.OrderPending {
+			t.Errorf("expected a failed payment of a pending order but got %+v", store.payments[0])
+		}
+	})
+
+	t.Run("should move the order to processing once authorized", func(t *testing.T) {
+		rr := send(http.MethodPost, "/payments/1/confirm", 1, types.ConfirmPaymentPayload{PaymentMethod: payments.FakeCardSuccess})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if store.payments[0].Status != types.PaymentAuthorized || orderStore.orders[1].Status != types.OrderProcessing {
+			t.Errorf("expected an authorized payment of a processing order but got %+v", store.payments[0])
+		}
+		if rr := send(http.MethodPost, "/orders/1/payments", 1, nil); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d when paying twice but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should capture and refund through admin routes", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/admin/payments/1/capture", 1, nil); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d for a customer but got %d", http.StatusForbidden, rr.Code)
+		}
+		if rr := send(http.MethodPost, "/admin/payments/1/capture", 3, nil); rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if rr := send(http.MethodPost, "/admin/payments/1/refunds", 3, types.RefundPaymentPayload{Amount: 40.19}); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d when refunding too much but got %d", http.StatusConflict, rr.Code)
+		}
+
+		rr := send(http.MethodPost, "/admin/payments/1/refunds", 3, types.RefundPaymentPayload{Amount: 0.18})
+		var p types.Payment
+		json.NewDecoder(rr.Body).Decode(&p)
+		if rr.Code != http.StatusOK || p.Status != types.PaymentCaptured || p.Captured != 40.18 || p.Refunded != 0.18 {
+			t.Errorf("unexpected payment %+v", p)
+		}
+	})
+
+	t.Run("should apply webhooks once", func(t *testing.T) {
+		send(http.MethodPost, "/orders/2/payments", 1, nil)
+		intentID := store.payments[1].ProviderRef
+		if _, err := provider.Confirm(context.Background(), intentID, payments.FakeCardSuccess); err != nil {
+			t.Fatal(err)
+		}
+
+		for i, applied := range []bool{true, false} {
+			rr := webhook("evt_1", intentID)
+			var body map[string]bool
+			json.NewDecoder(rr.Body).Decode(&body)
+			if rr.Code != http.StatusOK || body["applied"] != applied {
+				t.Errorf("delivery %d: expected applied=%v but got %d %v", i+1, applied, rr.Code, body)
+			}
+		}
+		if store.payments[1].Status != types.PaymentAuthorized || orderStore.orders[2].Status != types.OrderProcessing {
+			t.Errorf("expected the webhook to authorize the payment but got %+v", store.payments[1])
+		}
+	})
+
+	t.Run("should reject webhooks with a bad signature", func(t *testing.T) {
+		payload, _, _ := provider.Event("evt_2", store.payments[1].ProviderRef)
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/payments", bytes.NewReader(payload))
+		req.Header.Set(payments.FakeSignatureHeader, "00")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+// mockPaymentStore applies updates like the real store, moving pending orders of the mock
+// order store to processing.
+type mockPaymentStore struct {
+	orders   *mockOrderStore
+	payments []types.Payment
+	events   map[string]bool
+}
+
+func (m *mockPaymentStore) CreatePayment(p types.Payment) (int, error) {
+	p.ID = len(m.payments) + 1
+	m.payments = append(m.payments, p)
+	return p.ID, nil
+}
+
+func (m *mockPaymentStore) GetPayment(id int) (*types.Payment, error) {
+	if id < 1 || id > len(m.payments) {
+		return nil, ErrPaymentNotFound
+	}
+	p := m.payments[id-1]
+	return &p, nil
+}
+
+func (m *mockPaymentStore) GetPaymentByProviderRef(provider, ref string) (*types.Payment, error) {
+	for _, p := range m.payments {
+		if p.Provider == provider && p.ProviderRef == ref {
+			return &p, nil
+		}
+	}
+	return nil, ErrPaymentNotFound
+}
+
+func (m *mockPaymentStore) GetOrderPayments(orderID int) ([]types.Payment, error) {
+	payments := []types.Payment{}
+	for _, p := range m.payments {
+		if p.OrderID == orderID {
+			payments = append(payments, p)
+		}
+	}
+	return payments, nil
+}
+
+func (m *mockPaymentStore) ApplyPaymentUpdate(paymentID int, intent types.PaymentIntent, eventID string) (bool, error) {
+	if eventID != "" {
+		if m.events[eventID] {
+			return false, nil
+		}
+		m.events[eventID] = true
+	}
+
+	p := &m.payments[paymentID-1]
+	if statusRank[intent.Status] >= statusRank[p.Status] {
+		p.Status, p.FailureReason = intent.Status, intent.FailureReason
+	}
+	p.Captured = max(p.Captured, utils.FromCents(intent.Captured))
+	p.Refunded = max(p.Refunded, utils.FromCents(intent.Refunded))
+	if o := m.orders.orders[p.OrderID]; (intent.Status == types.PaymentAuthorized || intent.Status == types.PaymentCaptured) && o.Status == types.OrderPending {
+		o.Status = types.OrderProcessing
+	}
+	return true, nil
+}
+
+type mockOrderStore struct {
+	orders  map[int]*types.Order
+	refunds map[int]*types.Refund
+}
+
+func (m *mockOrderStore) CreateOrder(o types.Order, items []types.OrderItem, reservationID int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockOrderStore) GetOrders(filter types.OrderFilter, limit, offset int) ([]types.Order, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetOrder(id int) (*types.Order, error) {
+	o, ok := m.orders[id]
+	if !ok {
+		return nil, order.ErrOrderNotFound
+	}
+	c := *o
+	return &c, nil
+}
+
+func (m *mockOrderStore) TransitionOrder(id int, to string, actorID int, note string) (*types.OrderStatusChange, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetOrderStatusHistory(orderID int) ([]types.OrderStatusChange, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) CancelOrder(id int, actorID int, note string) (*types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) RefundOrder(id int, payload types.RefundPayload, actorID int) (*types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetRefunds(orderID int) ([]types.Refund, error) {
+	refunds := []types.Refund{}
+	for id := 1; id <= len(m.refunds); id++ {
+		if r, ok := m.refunds[id]; ok && r.OrderID == orderID {
+			refunds = append(refunds, *r)
+		}
+	}
+	return refunds, nil
+}
+
+func (m *mockOrderStore) GetRefund(id int) (*types.Refund, error) {
+	r, ok := m.refunds[id]
+	if !ok {
+		return nil, order.ErrRefundNotFound
+	}
+	c := *r
+	return &c, nil
+}
+
+func (m *mockOrderStore) SettleRefund(id int, status string, paymentID *int) error {
+	r := m.refunds[id]
+	from := types.RefundPaymentPending
+	if status == types.RefundPaymentRefunded {
+		from = types.RefundPaymentRefunding
+	}
+	if r.PaymentStatus != types.RefundPaymentPending && r.PaymentStatus != from {
+		return order.ErrRefundSettled
+	}
+	r.PaymentStatus, r.PaymentID = status, paymentID
+	return nil
+}
@@ -0,0 +1,193 @@
+package payment
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+	ErrOrderNotPayable = errors.New("only pending orders can be paid")
+	ErrAlreadyPaid     = errors.New("order has already been paid")
+	ErrPaymentState    = errors.New("payment is not in a state that allows this")
+	ErrRefundTooLarge  = errors.New("refund exceeds the captured amount not refunded yet")
+	ErrOrderCancelled  = errors.New("payments of cancelled orders can't be captured")
+	ErrOrderRefunded   = errors.New("order refunds already cover the whole payment")
+)
+
+// mysqlDuplicateEntry is the MySQL error number for a unique key violation.
+const mysqlDuplicateEntry = 1062
+
+// paymentColumns lists the columns read by scanRowIntoPayment, in scan order.
+const paymentColumns = "id, orderId, provider, providerRef, amount, captured, refunded, currency, status, failureReason, created_at, updated_at"
+
+// statusRank orders the payment statuses so an update never moves a payment back, e.g.
+// when a provider delivers webhooks out of order. Cancelled and refunded payments are final.
+var statusRank = map[string]int{
+	types.PaymentRequiresConfirmation: 0,
+	types.PaymentFailed:               1,
+	types.PaymentAuthorized:           2,
+	types.PaymentCaptured:             3,
+	types.PaymentCancelled:            4,
+	types.PaymentRefunded:             4,
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreatePayment(p types.Payment) (int, error) {
+	result, err := s.db.Exec(
+		"INSERT INTO payments (orderId, provider, providerRef, amount, currency, status) VALUES (?, ?, ?, ?, ?, ?)",
+		p.OrderID, p.Provider, p.ProviderRef, utils.FormatCents(utils.ToCents(p.Amount)), p.Currency, p.Status,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert payment: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *Store) GetPayment(id int) (*types.Payment, error) {
+	return s.getPayment("id = ?", id)
+}
+
+func (s *Store) GetPaymentByProviderRef(provider, ref string) (*types.Payment, error) {
+	return s.getPayment("provider = ? AND providerRef = ?", provider, ref)
+}
+
+func (s *Store) GetOrderPayments(orderID int) ([]types.Payment, error) {
+	rows, err := s.db.Query("SELECT "+paymentColumns+" FROM payments WHERE orderId = ? ORDER BY id", orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := []types.Payment{}
+	for rows.Next() {
+		p, err := scanRowIntoPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, *p)
+	}
+	return payments, rows.Err()
+}
+
+// ApplyPaymentUpdate records the event, the payment and the order status in one
+// transaction, so a webhook that fails halfway is applied again when it is redelivered.
+func (s *Store) ApplyPaymentUpdate(paymentID int, intent types.PaymentIntent, eventID string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var orderID int
+	var provider, status string
+	err = tx.QueryRow("SELECT orderId, provider, status FROM payments WHERE id = ? FOR UPDATE", paymentID).Scan(&orderID, &provider, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrPaymentNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if eventID != "" {
+		_, err := tx.Exec(
+			"INSERT INTO payment_events (provider, eventId, paymentId, status) VALUES (?, ?, ?, ?)",
+			provider, eventID, paymentID, intent.Status,
+		)
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to record payment event: %w", err)
+		}
+	}
+
+	// The amounts only grow, so a late event can't undo a capture or a refund either
+	if statusRank[intent.Status] >= statusRank[status] {
+		_, err = tx.Exec(
+			"UPDATE payments SET status = ?, captured = GREATEST(captured, ?), refunded = GREATEST(refunded, ?), failureReason = ? WHERE id = ?",
+			intent.Status, utils.FormatCents(intent.Captured), utils.FormatCents(intent.Refunded), intent.FailureReason, paymentID,
+		)
+	} else {
+		_, err = tx.Exec(
+			"UPDATE payments SET captured = GREATEST(captured, ?), refunded = GREATEST(refunded, ?) WHERE id = ?",
+			utils.FormatCents(intent.Captured), utils.FormatCents(intent.Refunded), paymentID,
+		)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if intent.Status == types.PaymentAuthorized || intent.Status == types.PaymentCaptured {
+		if err := markPaid(tx, orderID); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// markPaid moves a pending order to processing. Orders that already moved on, or were
+// cancelled meanwhile, are left alone.
+func markPaid(tx *sql.Tx, orderID int) error {
+	var status string
+	if err := tx.QueryRow("SELECT status FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&status); err != nil {
+		return err
+	}
+	if status != types.OrderPending {
+		return nil
+	}
+	_, err := order.Transition(tx, orderID, types.OrderProcessing, 0, "payment authorized")
+	return err
+}
+
+func (s *Store) getPayment(where string, args ...any) (*types.Payment, error) {
+	rows, err := s.db.Query("SELECT "+paymentColumns+" FROM payments WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrPaymentNotFound
+	}
+	return scanRowIntoPayment(rows)
+}
+
+func scanRowIntoPayment(rows *sql.Rows) (*types.Payment, error) {
+	p := new(types.Payment)
+	err := rows.Scan(
+		&p.ID,
+		&p.OrderID,
+		&p.Provider,
+		&p.ProviderRef,
+		&p.Amount,
+		&p.Captured,
+		&p.Refunded,
+		&p.Currency,
+		&p.Status,
+		&p.FailureReason,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	)
+	return p, err
+}
@@ -0,0 +1,118 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.PricingStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.PricingStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/products/{id:[0-9]+}/price-history", auth.WithAdminAuth(h.handleGetPriceHistory, h.userStore)).Methods(http.MethodGet)
+
+	router.HandleFunc("/admin/products/{id:[0-9]+}/scheduled-prices", auth.WithAdminAuth(h.handleGetScheduledPrices, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/products/{id:[0-9]+}/scheduled-prices", auth.WithAdminAuth(h.handleCreateScheduledPrice, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/scheduled-prices/{id:[0-9]+}", auth.WithAdminAuth(h.handleCancelScheduledPrice, h.userStore)).Methods(http.MethodDelete)
+}
+
+// handleGetPriceHistory returns a page of the price changes of a product, newest first.
+func (h *Handler) handleGetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	page, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	history, err := h.store.GetPriceHistory(productID, page.Limit, page.Offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"history": history,
+		"page":    page.Page,
+		"limit":   page.Limit,
+	})
+}
+
+func (h *Handler) handleGetScheduledPrices(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	schedules, err := h.store.GetScheduledPrices(productID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, schedules)
+}
+
+// handleCreateScheduledPrice schedules a price for a product. Without an end time the
+// price stays once it takes effect; with one, the previous price comes back at the end.
+func (h *Handler) handleCreateScheduledPrice(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ScheduledPricePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+	if payload.EndsAt != nil && !payload.EndsAt.After(time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: endsAt must be in the future"))
+		return
+	}
+
+	schedule, err := h.store.CreateScheduledPrice(productID, payload, auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, schedule)
+}
+
+// handleCancelScheduledPrice cancels a schedule that has not started yet, or ends an active
+// one early.
+func (h *Handler) handleCancelScheduledPrice(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.store.CancelScheduledPrice(id, time.Now()); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrProductNotFound), errors.Is(err, ErrScheduleNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrScheduleOverlaps), errors.Is(err, ErrScheduleNotPending):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
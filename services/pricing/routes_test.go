@@ -0,0 +1,214 @@
+package pricing
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestPricingHandlers(t *testing.T) {
+	store := newMockPricingStore(map[int]float64{1: 100})
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleAdmin},
+		2: {ID: 2, Role: types.RoleCustomer},
+	}}
+
+	handler := NewHandler(store, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	start := time.Now().Add(time.Hour)
+	end := start.Add(24 * time.Hour)
+
+	t.Run("should schedule a sale", func(t *testing.T) {
+		rr := send(http.MethodPost, "/admin/products/1/scheduled-prices", 1, types.ScheduledPricePayload{Price: 80, StartsAt: start, EndsAt: &end})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+	})
+
+	t.Run("should reject overlapping schedules", func(t *testing.T) {
+		later := start.Add(time.Hour)
+		rr := send(http.MethodPost, "/admin/products/1/scheduled-prices", 1, types.ScheduledPricePayload{Price: 70, StartsAt: later})
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should reject a schedule ending before it starts", func(t *testing.T) {
+		before := start.Add(-2 * time.Hour)
+		rr := send(http.MethodPost, "/admin/products/1/scheduled-prices", 1, types.ScheduledPricePayload{Price: 70, StartsAt: start, EndsAt: &before})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should only allow admins", func(t *testing.T) {
+		rr := send(http.MethodGet, "/products/1/price-history", 2, nil)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should answer 404 for unknown products", func(t *testing.T) {
+		rr := send(http.MethodGet, "/products/9/price-history", 1, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should cancel a pending schedule only once", func(t *testing.T) {
+		rr := send(http.MethodDelete, "/admin/scheduled-prices/1", 1, nil)
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("expected status code %d but got %d", http.StatusNoContent, rr.Code)
+		}
+		rr = send(http.MethodDelete, "/admin/scheduled-prices/1", 1, nil)
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+}
+
+func TestSchedule(t *testing.T) {
+	store := newMockPricingStore(map[int]float64{1: 100})
+	now := time.Now()
+	end := now.Add(time.Hour)
+	store.CreateScheduledPrice(1, types.ScheduledPricePayload{Price: 80, StartsAt: now, EndsAt: &end}, 1)
+
+	schedule(store, now)
+	if store.prices[1] != 80 || store.schedules[1].Status != types.ScheduleActive {
+		t.Fatalf("expected the sale price to apply but the price is %v and the schedule %s", store.prices[1], store.schedules[1].Status)
+	}
+
+	schedule(store, end)
+	if store.prices[1] != 100 || store.schedules[1].Status != types.ScheduleEnded {
+		t.Errorf("expected the price to be restored but it is %v and the schedule %s", store.prices[1], store.schedules[1].Status)
+	}
+
+	history, _ := store.GetPriceHistory(1, 10, 0)
+	if len(history) != 2 || history[0].Reason != types.PriceScheduleEnded || history[1].Reason != types.PriceScheduleStarted {
+		t.Errorf("unexpected history %+v", history)
+	}
+}
+
+// mockPricingStore mirrors the scheduling rules of Store in memory.
+type mockPricingStore struct {
+	prices    map[int]float64
+	schedules map[int]*types.ScheduledPrice
+	history   []types.PriceChange
+}
+
+func newMockPricingStore(prices map[int]float64) *mockPricingStore {
+	return &mockPricingStore{prices: prices, schedules: map[int]*types.ScheduledPrice{}}
+}
+
+func (m *mockPricingStore) record(c types.PriceChange) {
+	c.ID = len(m.history) + 1
+	m.history = append(m.history, c)
+}
+
+func (m *mockPricingStore) GetPriceHistory(productID int, limit, offset int) ([]types.PriceChange, error) {
+	if _, ok := m.prices[productID]; !ok {
+		return nil, ErrProductNotFound
+	}
+	history := []types.PriceChange{}
+	for i := len(m.history) - 1; i >= 0; i-- {
+		if m.history[i].ProductID == productID {
+			history = append(history, m.history[i])
+		}
+	}
+	return history, nil
+}
+
+func (m *mockPricingStore) CreateScheduledPrice(productID int, payload types.ScheduledPricePayload, actorID int) (*types.ScheduledPrice, error) {
+	if _, ok := m.prices[productID]; !ok {
+		return nil, ErrProductNotFound
+	}
+	for _, sp := range m.schedules {
+		if sp.ProductID != productID || (sp.Status != types.SchedulePending && sp.Status != types.ScheduleActive) {
+			continue
+		}
+		if (sp.EndsAt == nil || sp.EndsAt.After(payload.StartsAt)) && (payload.EndsAt == nil || sp.StartsAt.Before(*payload.EndsAt)) {
+			return nil, ErrScheduleOverlaps
+		}
+	}
+	sp := &types.ScheduledPrice{ID: len(m.schedules) + 1, ProductID: productID, Price: payload.Price, StartsAt: payload.StartsAt, EndsAt: payload.EndsAt, Status: types.SchedulePending}
+	m.schedules[sp.ID] = sp
+	return sp, nil
+}
+
+func (m *mockPricingStore) GetScheduledPrices(productID int) ([]types.ScheduledPrice, error) {
+	schedules := []types.ScheduledPrice{}
+	for id := 1; id <= len(m.schedules); id++ {
+		if m.schedules[id].ProductID == productID {
+			schedules = append(schedules, *m.schedules[id])
+		}
+	}
+	return schedules, nil
+}
+
+func (m *mockPricingStore) CancelScheduledPrice(id int, now time.Time) error {
+	sp, ok := m.schedules[id]
+	if !ok {
+		return ErrScheduleNotFound
+	}
+	switch sp.Status {
+	case types.SchedulePending:
+		sp.Status = types.ScheduleCancelled
+	case types.ScheduleActive:
+		sp.EndsAt = &now
+	default:
+		return ErrScheduleNotPending
+	}
+	return nil
+}
+
+func (m *mockPricingStore) StartDueScheduledPrices(now time.Time) (int, error) {
+	started := 0
+	for _, sp := range m.schedules {
+		if sp.Status != types.SchedulePending || sp.StartsAt.After(now) {
+			continue
+		}
+		previous := m.prices[sp.ProductID]
+		m.prices[sp.ProductID] = sp.Price
+		sp.PreviousPrice, sp.Status = &previous, types.ScheduleActive
+		if sp.EndsAt == nil {
+			sp.Status = types.ScheduleEnded
+		}
+		m.record(types.PriceChange{ProductID: sp.ProductID, OldPrice: &previous, NewPrice: sp.Price, Reason: types.PriceScheduleStarted, ScheduleID: &sp.ID})
+		started++
+	}
+	return started, nil
+}
+
+func (m *mockPricingStore) EndDueScheduledPrices(now time.Time) (int, error) {
+	ended := 0
+	for _, sp := range m.schedules {
+		if sp.Status != types.ScheduleActive || sp.EndsAt.After(now) {
+			continue
+		}
+		if m.prices[sp.ProductID] == sp.Price {
+			m.prices[sp.ProductID] = *sp.PreviousPrice
+			m.record(types.PriceChange{ProductID: sp.ProductID, OldPrice: &sp.Price, NewPrice: *sp.PreviousPrice, Reason: types.PriceScheduleEnded, ScheduleID: &sp.ID})
+		}
+		sp.Status = types.ScheduleEnded
+		ended++
+	}
+	return ended, nil
+}
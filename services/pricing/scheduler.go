@@ -0,0 +1,47 @@
+package pricing
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// StartScheduler applies and ends scheduled prices every interval until ctx is cancelled.
+// It runs in its own goroutine and returns immediately.
+func StartScheduler(ctx context.Context, store types.PricingStore, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				schedule(store, now)
+			}
+		}
+	}()
+}
+
+// schedule runs one pass of the scheduler. Schedules are ended before new ones start so
+// back-to-back sales hand over cleanly. Errors are logged and retried on the next tick.
+func schedule(store types.PricingStore, now time.Time) {
+	ended, err := store.EndDueScheduledPrices(now)
+	if err != nil {
+		log.Printf("failed to end scheduled prices: %v", err)
+	}
+	if ended > 0 {
+		log.Printf("ended %d scheduled prices", ended)
+	}
+
+	started, err := store.StartDueScheduledPrices(now)
+	if err != nil {
+		log.Printf("failed to start scheduled prices: %v", err)
+	}
+	if started > 0 {
+		log.Printf("started %d scheduled prices", started)
+	}
+}
@@ -0,0 +1,430 @@
+package pricing
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/code-farms/go-backend/services/inventory"
+	"github.com/code-farms/go-backend/types"
+)
+
+var (
+	ErrProductNotFound    = errors.New("product not found")
+	ErrScheduleNotFound   = errors.New("scheduled price not found")
+	ErrScheduleOverlaps   = errors.New("scheduled price overlaps another schedule of the product")
+	ErrScheduleNotPending = errors.New("scheduled price has already ended or been cancelled")
+)
+
+// priceChangeColumns lists the columns read by scanRowIntoPriceChange, in scan order.
+const priceChangeColumns = "id, productId, oldPrice, newPrice, reason, actorId, scheduleId, created_at"
+
+// scheduleColumns lists the columns read by scanRowIntoSchedule, in scan order.
+const scheduleColumns = "id, productId, price, previousPrice, starts_at, ends_at, status, actorId, created_at"
+
+// RecordPriceChange appends an entry to the price history. It must run in the same
+// transaction as the change to products.price so the two never disagree.
+func RecordPriceChange(tx *sql.Tx, c types.PriceChange) error {
+	_, err := tx.Exec(
+		"INSERT INTO price_history (productId, oldPrice, newPrice, reason, actorId, scheduleId) VALUES (?, ?, ?, ?, ?, ?)",
+		c.ProductID, c.OldPrice, c.NewPrice, c.Reason, c.ActorID, c.ScheduleID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record price change: %w", err)
+	}
+	return nil
+}
+
+type Store struct {
+	db      *sql.DB
+	publish func(tx *sql.Tx, productID int) error
+}
+
+// NewStore returns a Store that calls publish in the transaction of every price the
+// scheduler changes, so the change goes out to webhooks and subscribers like any other
+// product update. The product package can't be imported from here; pass
+// product.PublishUpdate.
+func NewStore(db *sql.DB, publish func(tx *sql.Tx, productID int) error) *Store {
+	return &Store{db: db, publish: publish}
+}
+
+func (s *Store) GetPriceHistory(productID int, limit, offset int) ([]types.PriceChange, error) {
+	if err := s.ensureProductExists(productID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+priceChangeColumns+" FROM price_history WHERE productId = ? ORDER BY id DESC LIMIT ? OFFSET ?",
+		productID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []types.PriceChange{}
+	for rows.Next() {
+		c, err := scanRowIntoPriceChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *c)
+	}
+	return history, rows.Err()
+}
+
+func (s *Store) CreateScheduledPrice(productID int, payload types.ScheduledPricePayload, actorID int) (*types.ScheduledPrice, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the product serializes schedule changes so two overlapping requests can't both pass
+	var id int
+	err = tx.QueryRow("SELECT id FROM products WHERE id = ? FOR UPDATE", productID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var overlaps bool
+	err = tx.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM scheduled_prices
+			WHERE productId = ? AND status IN (?, ?)
+				AND (ends_at IS NULL OR ends_at > ?)
+				AND (? IS NULL OR starts_at < ?))`,
+		productID, types.SchedulePending, types.ScheduleActive, payload.StartsAt, payload.EndsAt, payload.EndsAt,
+	).Scan(&overlaps)
+	if err != nil {
+		return nil, err
+	}
+	if overlaps {
+		return nil, ErrScheduleOverlaps
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO scheduled_prices (productId, price, starts_at, ends_at, actorId) VALUES (?, ?, ?, ?, ?)",
+		productID, payload.Price, payload.StartsAt, payload.EndsAt, inventory.Actor(actorID),
+	)
+	if err != nil {
+		return nil, err
+	}
+	scheduleID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.getScheduledPrice(int(scheduleID))
+}
+
+func (s *Store) GetScheduledPrices(productID int) ([]types.ScheduledPrice, error) {
+	if err := s.ensureProductExists(productID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query("SELECT "+scheduleColumns+" FROM scheduled_prices WHERE productId = ? ORDER BY starts_at, id", productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := []types.ScheduledPrice{}
+	for rows.Next() {
+		sp, err := scanRowIntoSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, *sp)
+	}
+	return schedules, rows.Err()
+}
+
+func (s *Store) CancelScheduledPrice(id int, now time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM scheduled_prices WHERE id = ? FOR UPDATE", id).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrScheduleNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case types.SchedulePending:
+		_, err = tx.Exec("UPDATE scheduled_prices SET status = ? WHERE id = ?", types.ScheduleCancelled, id)
+	case types.ScheduleActive:
+		_, err = tx.Exec("UPDATE scheduled_prices SET ends_at = ? WHERE id = ?", now, id)
+	default:
+		return ErrScheduleNotPending
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) StartDueScheduledPrices(now time.Time) (int, error) {
+	ids, err := s.dueSchedules("status = ? AND starts_at <= ?", types.SchedulePending, now)
+	if err != nil {
+		return 0, err
+	}
+
+	started := 0
+	for _, id := range ids {
+		ok, err := s.startSchedule(id, now)
+		if err != nil {
+			return started, fmt.Errorf("failed to start scheduled price %d: %w", id, err)
+		}
+		if ok {
+			started++
+		}
+	}
+	return started, nil
+}
+
+func (s *Store) EndDueScheduledPrices(now time.Time) (int, error) {
+	ids, err := s.dueSchedules("status = ? AND ends_at <= ?", types.ScheduleActive, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		if err := s.endSchedule(id); err != nil {
+			return i, fmt.Errorf("failed to end scheduled price %d: %w", id, err)
+		}
+	}
+	return len(ids), nil
+}
+
+// startSchedule applies a pending schedule and reports whether it did. A schedule whose
+// end has already passed is ended without ever being applied. Schedules without an end
+// change the price for good, so they end as soon as they are applied.
+func (s *Store) startSchedule(id int, now time.Time) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	sp, err := lockSchedule(tx, id)
+	if err != nil || sp.Status != types.SchedulePending {
+		return false, err
+	}
+
+	if sp.EndsAt != nil && !sp.EndsAt.After(now) {
+		if _, err := tx.Exec("UPDATE scheduled_prices SET status = ? WHERE id = ?", types.ScheduleEnded, id); err != nil {
+			return false, err
+		}
+		return false, tx.Commit()
+	}
+
+	var previous float64
+	if err := tx.QueryRow("SELECT price FROM products WHERE id = ? FOR UPDATE", sp.ProductID).Scan(&previous); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec("UPDATE products SET price = ? WHERE id = ?", sp.Price, sp.ProductID); err != nil {
+		return false, err
+	}
+	err = RecordPriceChange(tx, types.PriceChange{
+		ProductID:  sp.ProductID,
+		OldPrice:   &previous,
+		NewPrice:   sp.Price,
+		Reason:     types.PriceScheduleStarted,
+		ActorID:    sp.ActorID,
+		ScheduleID: &sp.ID,
+	})
+	if err != nil {
+		return false, err
+	}
+	if err := s.publish(tx, sp.ProductID); err != nil {
+		return false, err
+	}
+
+	status := types.ScheduleActive
+	if sp.EndsAt == nil {
+		status = types.ScheduleEnded
+	}
+	if _, err := tx.Exec("UPDATE scheduled_prices SET status = ?, previousPrice = ? WHERE id = ?", status, previous, id); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// endSchedule ends an active schedule. The previous price is only restored while the
+// product still has the scheduled price; a price an admin set in the meantime is kept.
+func (s *Store) endSchedule(id int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	sp, err := lockSchedule(tx, id)
+	if err != nil || sp.Status != types.ScheduleActive {
+		return err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE products p JOIN scheduled_prices s ON s.productId = p.id
+		SET p.price = s.previousPrice
+		WHERE s.id = ? AND p.price = s.price AND s.previousPrice IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+	if restored, err := result.RowsAffected(); err != nil {
+		return err
+	} else if restored > 0 {
+		err := RecordPriceChange(tx, types.PriceChange{
+			ProductID:  sp.ProductID,
+			OldPrice:   &sp.Price,
+			NewPrice:   *sp.PreviousPrice,
+			Reason:     types.PriceScheduleEnded,
+			ActorID:    sp.ActorID,
+			ScheduleID: &sp.ID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.publish(tx, sp.ProductID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE scheduled_prices SET status = ? WHERE id = ?", types.ScheduleEnded, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) dueSchedules(where string, args ...any) ([]int, error) {
+	rows, err := s.db.Query("SELECT id FROM scheduled_prices WHERE "+where+" ORDER BY starts_at, id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func lockSchedule(tx *sql.Tx, id int) (*types.ScheduledPrice, error) {
+	rows, err := tx.Query("SELECT "+scheduleColumns+" FROM scheduled_prices WHERE id = ? FOR UPDATE", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrScheduleNotFound
+	}
+	return scanRowIntoSchedule(rows)
+}
+
+func (s *Store) getScheduledPrice(id int) (*types.ScheduledPrice, error) {
+	rows, err := s.db.Query("SELECT "+scheduleColumns+" FROM scheduled_prices WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrScheduleNotFound
+	}
+	return scanRowIntoSchedule(rows)
+}
+
+// ensureProductExists returns ErrProductNotFound for unknown products and nil otherwise.
+func (s *Store) ensureProductExists(productID int) error {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = ?)", productID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+func scanRowIntoPriceChange(rows *sql.Rows) (*types.PriceChange, error) {
+	c := new(types.PriceChange)
+	var oldPrice sql.NullFloat64
+	var actorID, scheduleID sql.NullInt64
+	err := rows.Scan(
+		&c.ID,
+		&c.ProductID,
+		&oldPrice,
+		&c.NewPrice,
+		&c.Reason,
+		&actorID,
+		&scheduleID,
+		&c.CreatedAt,
+	)
+	if oldPrice.Valid {
+		c.OldPrice = &oldPrice.Float64
+	}
+	if actorID.Valid {
+		c.ActorID = inventory.Actor(int(actorID.Int64))
+	}
+	if scheduleID.Valid {
+		id := int(scheduleID.Int64)
+		c.ScheduleID = &id
+	}
+	return c, err
+}
+
+func scanRowIntoSchedule(rows *sql.Rows) (*types.ScheduledPrice, error) {
+	sp := new(types.ScheduledPrice)
+	var previousPrice sql.NullFloat64
+	var endsAt sql.NullTime
+	var actorID sql.NullInt64
+	err := rows.Scan(
+		&sp.ID,
+		&sp.ProductID,
+		&sp.Price,
+		&previousPrice,
+		&sp.StartsAt,
+		&endsAt,
+		&sp.Status,
+		&actorID,
+		&sp.CreatedAt,
+	)
+	if previousPrice.Valid {
+		sp.PreviousPrice = &previousPrice.Float64
+	}
+	if endsAt.Valid {
+		sp.EndsAt = &endsAt.Time
+	}
+	if actorID.Valid {
+		sp.ActorID = inventory.Actor(int(actorID.Int64))
+	}
+	return sp, err
+}
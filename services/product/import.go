@@ -0,0 +1,348 @@
+package product
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/go-playground/validator/v10"
+)
+
+// Supported catalog file formats.
+const (
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// csvColumns is the column order written by the CSV export and accepted by the import.
+var csvColumns = []string{"sku", "name", "description", "image", "price", "quantity", "category", "taxClass"}
+
+// requiredCSVColumns must be present in the header of an imported CSV file.
+var requiredCSVColumns = []string{"sku", "name", "price", "quantity"}
+
+// handleImportProducts creates or updates products from a CSV or NDJSON body. Every row is
+// validated first; if any row is invalid, or dryRun=true is passed, nothing is written and
+// the per-row errors are returned.
+func (h *Handler) handleImportProducts(w http.ResponseWriter, r *http.Request) {
+	format, err := requestFormat(r, r.Header.Get("Content-Type"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	dryRun := false
+	if v := r.URL.Query().Get("dryRun"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid dryRun value %q", v))
+			return
+		}
+	}
+
+	// Step 1: Parse and validate every row
+	r.Body = http.MaxBytesReader(w, r.Body, configs.Envs.MaxImportSizeInBytes)
+	var parsed importParseResult
+	if format == formatCSV {
+		parsed, err = parseCSVImport(r.Body)
+	} else {
+		parsed, err = parseNDJSONImport(r.Body)
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("import file is larger than %d bytes", maxBytesErr.Limit))
+			return
+		}
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	result := types.ProductImportResult{
+		DryRun: dryRun,
+		Rows:   parsed.total,
+		Errors: parsed.errors,
+	}
+
+	// Step 2: Stop here if anything is wrong or the client only wants the report
+	if len(parsed.errors) > 0 {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, result)
+		return
+	}
+	if dryRun {
+		utils.WriteJSON(w, http.StatusOK, result)
+		return
+	}
+
+	// Step 3: Apply all rows in a single transaction
+	result.Created, result.Updated, err = h.store.UpsertProducts(parsed.rows, auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to import products: %v", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, result)
+}
+
+// handleExportProducts streams the whole catalog as CSV (the default) or NDJSON.
+func (h *Handler) handleExportProducts(w http.ResponseWriter, r *http.Request) {
+	format, err := requestFormat(r, "")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+
+	// Headers are sent with the first row, so errors past this point can only be logged
+	if format == formatNDJSON {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		err = h.store.ExportProducts(func(p types.Product) error {
+			return enc.Encode(productToImportRow(p))
+		})
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		cw.Write(csvColumns)
+		err = h.store.ExportProducts(func(p types.Product) error {
+			row := productToImportRow(p)
+			return cw.Write([]string{
+				row.SKU,
+				row.Name,
+				row.Description,
+				row.Image,
+				strconv.FormatFloat(row.Price, 'f', 2, 64),
+				strconv.Itoa(row.Quantity),
+				row.Category,
+				row.TaxClass,
+			})
+		})
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+	}
+
+	if err != nil {
+		log.Printf("failed to export products: %v", err)
+	}
+}
+
+// requestFormat reads the format from the "format" query parameter, falling back to the
+// given content type and finally to CSV.
+func requestFormat(r *http.Request, contentType string) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if format != formatCSV && format != formatNDJSON {
+			return "", fmt.Errorf("unsupported format %q, expected csv or ndjson", format)
+		}
+		return format, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return formatNDJSON, nil
+	default:
+		return formatCSV, nil
+	}
+}
+
+// importParseResult holds the outcome of parsing an import file.
+type importParseResult struct {
+	rows   []types.ProductImportRow   // Valid rows, in file order
+	errors []types.ProductImportError // Problems found in the other rows
+	total  int                        // Number of data rows read
+	skus   map[string]int             // Row number of each SKU seen so far
+}
+
+// add validates a parsed row and records it either as valid or as an error.
+func (p *importParseResult) add(line int, row types.ProductImportRow, problems []string) {
+	p.total++
+
+	if len(problems) == 0 {
+		problems = validateImportRow(row)
+	}
+	if first, ok := p.skus[row.SKU]; ok && row.SKU != "" {
+		problems = append(problems, fmt.Sprintf("duplicate sku, first used on row %d", first))
+	} else if row.SKU != "" {
+		p.skus[row.SKU] = line
+	}
+
+	if len(problems) > 0 {
+		p.errors = append(p.errors, types.ProductImportError{Row: line, SKU: row.SKU, Errors: problems})
+		return
+	}
+	p.rows = append(p.rows, row)
+}
+
+func newImportParseResult() importParseResult {
+	return importParseResult{
+		rows:   []types.ProductImportRow{},
+		errors: []types.ProductImportError{},
+		skus:   map[string]int{},
+	}
+}
+
+// parseCSVImport reads a CSV file whose first line names the columns. Columns may come in
+// any order; unknown columns are ignored.
+func parseCSVImport(r io.Reader) (importParseResult, error) {
+	result := newImportParseResult()
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return result, fmt.Errorf("import file is empty")
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, name := range requiredCSVColumns {
+		if _, ok := columns[name]; !ok {
+			return result, fmt.Errorf("CSV header is missing the %q column", name)
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			i, ok := columns[strings.ToLower(name)]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		var problems []string
+		row := types.ProductImportRow{
+			SKU:         field("sku"),
+			Name:        field("name"),
+			Description: field("description"),
+			Category:    field("category"),
+			TaxClass:    field("taxClass"),
+			Image:       field("image"),
+		}
+		if row.Price, err = strconv.ParseFloat(field("price"), 64); err != nil {
+			problems = append(problems, fmt.Sprintf("price %q is not a number", field("price")))
+		}
+		if row.Quantity, err = strconv.Atoi(field("quantity")); err != nil {
+			problems = append(problems, fmt.Sprintf("quantity %q is not an integer", field("quantity")))
+		}
+
+		result.add(line, row, problems)
+	}
+
+	return result, nil
+}
+
+// parseNDJSONImport reads one JSON object per line. Blank lines are skipped.
+func parseNDJSONImport(r io.Reader) (importParseResult, error) {
+	result := newImportParseResult()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var row types.ProductImportRow
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&row); err != nil {
+			result.add(line, row, []string{fmt.Sprintf("invalid JSON: %v", err)})
+			continue
+		}
+		row.SKU = strings.TrimSpace(row.SKU)
+		row.Name = strings.TrimSpace(row.Name)
+		row.Category = strings.TrimSpace(row.Category)
+		row.TaxClass = strings.TrimSpace(row.TaxClass)
+
+		result.add(line, row, nil)
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read NDJSON: %w", err)
+	}
+
+	if result.total == 0 {
+		return result, fmt.Errorf("import file is empty")
+	}
+	return result, nil
+}
+
+// validateImportRow turns validator errors into short messages naming the offending column.
+func validateImportRow(row types.ProductImportRow) []string {
+	err := utils.Validate.Struct(row)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []string{err.Error()}
+	}
+
+	problems := make([]string, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		field := strings.ToLower(fe.Field())
+		switch fe.Tag() {
+		case "required":
+			problems = append(problems, field+" is required")
+		case "gt", "gte", "max":
+			problems = append(problems, fmt.Sprintf("%s must be %s %s", field, ruleNames[fe.Tag()], fe.Param()))
+		default:
+			problems = append(problems, fmt.Sprintf("%s failed the %s rule", field, fe.Tag()))
+		}
+	}
+	return problems
+}
+
+var ruleNames = map[string]string{
+	"gt":  "greater than",
+	"gte": "at least",
+	"max": "at most",
+}
+
+func productToImportRow(p types.Product) types.ProductImportRow {
+	return types.ProductImportRow{
+		SKU:         p.SKU,
+		Name:        p.Name,
+		Description: p.Description,
+		Category:    p.Category,
+		TaxClass:    p.TaxClass,
+		Image:       p.Image,
+		Price:       p.Price,
+		Quantity:    p.Quantity,
+	}
+}
@@ -0,0 +1,124 @@
+package product
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestProductImportHandlers(t *testing.T) {
+	productStore := &mockProductStore{products: map[int]*types.Product{
+		1: {ID: 1, SKU: "MUG-1", Name: "Mug, large", Price: 9.5, Quantity: 3},
+	}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{1: {ID: 1, Role: types.RoleAdmin}}}
+
+	handler := NewHandler(productStore, &mockImageStore{}, nil, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, url, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 1))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should report row errors without writing anything", func(t *testing.T) {
+		csv := "sku,name,price,quantity\n" +
+			"A-1,Cup,4.50,10\n" +
+			"A-2,,abc,1\n" +
+			"A-1,Cup again,1,1\n"
+
+		rr := send(http.MethodPost, "/admin/products/import", "text/csv", csv)
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusUnprocessableEntity, rr.Code, rr.Body)
+		}
+
+		var result types.ProductImportResult
+		json.NewDecoder(rr.Body).Decode(&result)
+		if result.Rows != 3 || len(result.Errors) != 2 {
+			t.Fatalf("expected 3 rows and 2 errors but got %+v", result)
+		}
+		if result.Errors[0].Row != 3 || result.Errors[1].Row != 4 {
+			t.Errorf("expected errors on rows 3 and 4 but got %+v", result.Errors)
+		}
+		if len(productStore.upserted) != 0 {
+			t.Errorf("expected nothing to be written but got %+v", productStore.upserted)
+		}
+	})
+
+	t.Run("should not write anything on a dry run", func(t *testing.T) {
+		ndjson := `{"sku":"B-1","name":"Plate","price":3,"quantity":5}` + "\n\n" +
+			`{"sku":"B-2","name":"Bowl","price":2.25,"quantity":0}` + "\n"
+
+		rr := send(http.MethodPost, "/admin/products/import?dryRun=true", "application/x-ndjson", ndjson)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if len(productStore.upserted) != 0 {
+			t.Errorf("expected nothing to be written but got %+v", productStore.upserted)
+		}
+	})
+
+	t.Run("should upsert valid rows", func(t *testing.T) {
+		rr := send(http.MethodPost, "/admin/products/import?format=csv", "text/plain", "SKU,Name,Quantity,Price\nC-1,Fork,7,1.10\n")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		want := types.ProductImportRow{SKU: "C-1", Name: "Fork", Price: 1.10, Quantity: 7}
+		if len(productStore.upserted) != 1 || productStore.upserted[0] != want {
+			t.Errorf("expected %+v to be written but got %+v", want, productStore.upserted)
+		}
+	})
+
+	t.Run("should reject CSV files without required columns", func(t *testing.T) {
+		rr := send(http.MethodPost, "/admin/products/import", "text/csv", "sku,name\nA,B\n")
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should export the catalog as CSV", func(t *testing.T) {
+		rr := send(http.MethodGet, "/admin/products/export", "", "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		want := "sku,name,description,image,price,quantity,category,taxClass\nMUG-1,\"Mug, large\",,,9.50,3,,\n"
+		if rr.Body.String() != want {
+			t.Errorf("expected %q but got %q", want, rr.Body.String())
+		}
+	})
+
+	t.Run("should export the catalog as NDJSON", func(t *testing.T) {
+		rr := send(http.MethodGet, "/admin/products/export?format=ndjson", "", "")
+		if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("expected NDJSON content type but got %q", ct)
+		}
+		var row types.ProductImportRow
+		if err := json.Unmarshal(rr.Body.Bytes(), &row); err != nil || row.SKU != "MUG-1" {
+			t.Errorf("expected the exported row to round-trip but got %+v (%v)", row, err)
+		}
+	})
+
+	t.Run("should import an exported CSV unchanged", func(t *testing.T) {
+		productStore.products[1].Category, productStore.products[1].TaxClass = "kitchen", "reduced"
+		productStore.upserted = nil
+
+		exported := send(http.MethodGet, "/admin/products/export", "", "").Body.String()
+		if rr := send(http.MethodPost, "/admin/products/import", "text/csv", exported); rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		want := types.ProductImportRow{SKU: "MUG-1", Name: "Mug, large", Category: "kitchen", TaxClass: "reduced", Price: 9.5, Quantity: 3}
+		if len(productStore.upserted) != 1 || productStore.upserted[0] != want {
+			t.Errorf("expected %+v to be written but got %+v", want, productStore.upserted)
+		}
+	})
+}
@@ -1,23 +1,55 @@
 package product
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
 
+	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/storage"
 	"github.com/code-farms/go-backend/types"
 	"github.com/code-farms/go-backend/utils"
 	"github.com/gorilla/mux"
 )
 
+// maxImagePixels guards against decompression bombs: small files that decode to huge images.
+const maxImagePixels = 50_000_000
+
 type Handler struct {
-	store types.ProductStore
+	store      types.ProductStore
+	imageStore types.ProductImageStore
+	blobs      types.BlobStore
+	userStore  types.UserStore
+
+	imageRoute     *mux.Route // Used to build the URL of an original image
+	thumbnailRoute *mux.Route // Used to build the URL of a thumbnail
 }
 
-func NewHandler(store types.ProductStore) *Handler {
-	return &Handler{store: store}
+func NewHandler(store types.ProductStore, imageStore types.ProductImageStore, blobs types.BlobStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, imageStore: imageStore, blobs: blobs, userStore: userStore}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/products", h.handleCreateProduct).Methods(http.MethodGet)
+
+	router.HandleFunc("/products/{id:[0-9]+}/images", h.handleGetImages).Methods(http.MethodGet)
+	router.HandleFunc("/products/{id:[0-9]+}/images", auth.WithAdminAuth(h.handleUploadImages, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/products/{id:[0-9]+}/images/order", auth.WithAdminAuth(h.handleReorderImages, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/products/{id:[0-9]+}/images/{imageID:[0-9]+}", auth.WithAdminAuth(h.handleDeleteImage, h.userStore)).Methods(http.MethodDelete)
+	h.imageRoute = router.HandleFunc("/products/{id:[0-9]+}/images/{imageID:[0-9]+}", h.handleServeImage).Methods(http.MethodGet)
+	h.thumbnailRoute = router.HandleFunc("/products/{id:[0-9]+}/images/{imageID:[0-9]+}/thumbnail", h.handleServeThumbnail).Methods(http.MethodGet)
+
+	router.HandleFunc("/admin/products/import", auth.WithAdminAuth(h.handleImportProducts, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/products/export", auth.WithAdminAuth(h.handleExportProducts, h.userStore)).Methods(http.MethodGet)
 }
 
 func (h *Handler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
@@ -28,4 +60,350 @@ func (h *Handler) handleCreateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 
 	utils.WriteJSON(w, http.StatusOK, ps)
-}
\ No newline at end of file
+}
+
+// handleGetImages lists the images of a product in gallery order.
+func (h *Handler) handleGetImages(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	images, err := h.imageStore.GetProductImages(productID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	for i := range images {
+		h.setImageURLs(&images[i])
+	}
+	utils.WriteJSON(w, http.StatusOK, images)
+}
+
+// handleUploadImages accepts one or more files in the "images" field of a multipart form
+// and appends them, in the order they were sent, to the product gallery.
+func (h *Handler) handleUploadImages(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	// Step 1: Make sure the product exists
+	if _, err := h.store.GetProductByID(productID); err != nil {
+		if errors.Is(err, ErrProductNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Step 2: Read and validate every file before storing anything
+	r.Body = http.MaxBytesReader(w, r.Body, configs.Envs.MaxUploadSizeInBytes)
+	uploads, status, err := readImageUploads(r, configs.Envs.MaxImageSizeInBytes)
+	if err != nil {
+		utils.WriteError(w, status, err)
+		return
+	}
+
+	// Step 3: Store the originals, all or none of them; their thumbnails are generated by jobs
+	created, err := h.storeImages(r.Context(), productID, uploads)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to store images: %v", err))
+		return
+	}
+
+	// Step 4: Keep the product's main image in sync with the gallery
+	if err := h.syncMainImage(productID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handler) handleReorderImages(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ReorderProductImagesPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	if err := h.imageStore.ReorderProductImages(productID, payload.ImageIDs); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.syncMainImage(productID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	h.handleGetImages(w, r)
+}
+
+func (h *Handler) handleDeleteImage(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+	imageID, _ := strconv.Atoi(mux.Vars(r)["imageID"])
+
+	img, err := h.imageStore.GetProductImage(productID, imageID)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	if err := h.imageStore.DeleteProductImage(productID, imageID); err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	// The metadata is gone, so a failure here only leaves an orphaned blob behind
+	for _, key := range []string{img.BlobKey, img.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := h.blobs.Delete(r.Context(), key); err != nil {
+			log.Printf("failed to delete blob %s: %v", key, err)
+		}
+	}
+
+	if err := h.syncMainImage(productID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleServeImage(w http.ResponseWriter, r *http.Request) {
+	h.serveBlob(w, r, func(img *types.ProductImage) (string, string) {
+		return img.BlobKey, img.ContentType
+	})
+}
+
+func (h *Handler) handleServeThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.serveBlob(w, r, func(img *types.ProductImage) (string, string) {
+		return img.ThumbnailKey, thumbnailContentType(img.ContentType)
+	})
+}
+
+// serveBlob streams the blob selected by pick for the image addressed by the request.
+func (h *Handler) serveBlob(w http.ResponseWriter, r *http.Request, pick func(*types.ProductImage) (string, string)) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+	imageID, _ := strconv.Atoi(mux.Vars(r)["imageID"])
+
+	img, err := h.imageStore.GetProductImage(productID, imageID)
+	if err != nil {
+		writeImageError(w, err)
+		return
+	}
+
+	key, contentType := pick(img)
+	if key == "" {
+		utils.WriteError(w, http.StatusNotFound, ErrImageNotFound)
+		return
+	}
+	blob, err := h.blobs.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.WriteError(w, http.StatusNotFound, ErrImageNotFound)
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer blob.Close()
+
+	// Keys are never reused, so the content behind a URL never changes
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, blob)
+}
+
+// storeImages uploads the originals, then records them all in one transaction, which
+// queues the generation of their thumbnails. If anything fails, the blobs uploaded so far
+// are removed again and no image is recorded.
+func (h *Handler) storeImages(ctx context.Context, productID int, uploads []decodedImage) ([]types.ProductImage, error) {
+	images := make([]types.ProductImage, 0, len(uploads))
+	removeBlobs := func() {
+		for _, img := range images {
+			if err := h.blobs.Delete(ctx, img.BlobKey); err != nil {
+				log.Printf("failed to delete blob %s: %v", img.BlobKey, err)
+			}
+		}
+	}
+
+	for _, upload := range uploads {
+		name, err := randomName()
+		if err != nil {
+			removeBlobs()
+			return nil, err
+		}
+		img := types.ProductImage{
+			ProductID:   productID,
+			BlobKey:     fmt.Sprintf("products/%d/%s%s", productID, name, allowedImageTypes[upload.contentType]),
+			ContentType: upload.contentType,
+			Size:        int64(len(upload.data)),
+			Width:       upload.width,
+			Height:      upload.height,
+		}
+		if err := h.blobs.Put(ctx, img.BlobKey, bytes.NewReader(upload.data), img.Size, img.ContentType); err != nil {
+			removeBlobs()
+			return nil, err
+		}
+		images = append(images, img)
+	}
+
+	ids, err := h.imageStore.CreateProductImages(productID, images)
+	if err != nil {
+		removeBlobs()
+		return nil, err
+	}
+
+	created := make([]types.ProductImage, 0, len(ids))
+	for _, id := range ids {
+		stored, err := h.imageStore.GetProductImage(productID, id)
+		if err != nil {
+			return nil, err
+		}
+		h.setImageURLs(stored)
+		created = append(created, *stored)
+	}
+	return created, nil
+}
+
+// syncMainImage copies the URL of the first gallery image into products.image.
+func (h *Handler) syncMainImage(productID int) error {
+	product, err := h.store.GetProductByID(productID)
+	if err != nil {
+		return err
+	}
+	images, err := h.imageStore.GetProductImages(productID)
+	if err != nil {
+		return err
+	}
+
+	main := ""
+	if len(images) > 0 {
+		h.setImageURLs(&images[0])
+		main = images[0].URL
+	}
+	if product.Image == main {
+		return nil
+	}
+
+	product.Image = main
+	return h.store.UpdateProduct(*product)
+}
+
+func (h *Handler) setImageURLs(img *types.ProductImage) {
+	id, imageID := strconv.Itoa(img.ProductID), strconv.Itoa(img.ID)
+	if u, err := h.imageRoute.URL("id", id, "imageID", imageID); err == nil {
+		img.URL = u.String()
+	}
+	// Until its thumbnail is generated, the original stands in for it
+	img.ThumbnailURL = img.URL
+	if img.ThumbnailKey == "" {
+		return
+	}
+	if u, err := h.thumbnailRoute.URL("id", id, "imageID", imageID); err == nil {
+		img.ThumbnailURL = u.String()
+	}
+}
+
+// readImageUploads reads the image parts of a multipart request. On failure it also returns
+// the HTTP status describing the problem.
+func readImageUploads(r *http.Request, maxImageSize int64) ([]decodedImage, int, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("expected a multipart/form-data body: %v", err)
+	}
+
+	var uploads []decodedImage
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, uploadReadStatus(err), fmt.Errorf("failed to read upload: %v", err)
+		}
+		if part.FormName() != "images" || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		// Read one byte past the limit to detect oversized files
+		data, err := io.ReadAll(io.LimitReader(part, maxImageSize+1))
+		part.Close()
+		if err != nil {
+			return nil, uploadReadStatus(err), fmt.Errorf("failed to read upload: %v", err)
+		}
+		if int64(len(data)) > maxImageSize {
+			return nil, http.StatusRequestEntityTooLarge, fmt.Errorf("%s is larger than %d bytes", part.FileName(), maxImageSize)
+		}
+
+		upload, err := decodeUpload(data)
+		if err != nil {
+			return nil, http.StatusUnsupportedMediaType, fmt.Errorf("%s: %v", part.FileName(), err)
+		}
+		uploads = append(uploads, upload)
+	}
+
+	if len(uploads) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("no files found in the images field")
+	}
+	return uploads, 0, nil
+}
+
+// decodeUpload sniffs the content type from the file contents, ignoring whatever the
+// client claimed, and reads the image header so files that aren't images are rejected up
+// front. The pixels are only decoded by the thumbnail job.
+func decodeUpload(data []byte) (decodedImage, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := allowedImageTypes[contentType]; !ok {
+		return decodedImage{}, fmt.Errorf("unsupported content type %s", contentType)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return decodedImage{}, fmt.Errorf("invalid image: %v", err)
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return decodedImage{}, fmt.Errorf("image dimensions %dx%d are too large", cfg.Width, cfg.Height)
+	}
+
+	return decodedImage{data: data, contentType: contentType, width: cfg.Width, height: cfg.Height}, nil
+}
+
+func uploadReadStatus(err error) int {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func thumbnailContentType(original string) string {
+	if original == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+func writeImageError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrImageNotFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	utils.WriteError(w, http.StatusInternalServerError, err)
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,338 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/code-farms/go-backend/configs"
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/storage"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestProductImageHandlers(t *testing.T) {
+	productStore := &mockProductStore{products: map[int]*types.Product{1: {ID: 1, Name: "Mug"}}}
+	imageStore := &mockImageStore{}
+	blobDir := t.TempDir()
+	blobs, err := storage.NewLocalStore(blobDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleAdmin},
+		2: {ID: 2, Role: types.RoleCustomer},
+	}}
+
+	handler := NewHandler(productStore, imageStore, blobs, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	t.Run("should upload an image and generate a thumbnail", func(t *testing.T) {
+		rr := upload(t, router, 1, "photo.png", pngImage(t, 800, 400))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		var created []types.ProductImage
+		if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+			t.Fatal(err)
+		}
+		if len(created) != 1 || created[0].ContentType != "image/png" || created[0].Width != 800 {
+			t.Fatalf("unexpected images %+v", created)
+		}
+		if productStore.products[1].Image != created[0].URL {
+			t.Errorf("expected main image %q but got %q", created[0].URL, productStore.products[1].Image)
+		}
+		if created[0].ThumbnailURL != created[0].URL {
+			t.Errorf("expected the original to stand in for the thumbnail until it is generated but got %q", created[0].ThumbnailURL)
+		}
+
+		job := types.GenerateThumbnailJob{ProductID: 1, ImageID: created[0].ID}
+		for i := 0; i < 2; i++ {
+			if err := handler.generateThumbnail(context.Background(), job); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/products/1/images", nil))
+		var images []types.ProductImage
+		json.NewDecoder(rr.Body).Decode(&images)
+		if len(images) != 1 || images[0].ThumbnailURL == images[0].URL {
+			t.Fatalf("expected the image to have its own thumbnail but got %+v", images)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, images[0].ThumbnailURL, nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		thumb, err := png.Decode(rr.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := thumb.Bounds(); b.Dx() != thumbnailSize || b.Dy() != thumbnailSize/2 {
+			t.Errorf("expected a %dx%d thumbnail but got %dx%d", thumbnailSize, thumbnailSize/2, b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("should reject files that are not images", func(t *testing.T) {
+		rr := upload(t, router, 1, "fake.png", []byte("<html>not an image</html>"))
+		if rr.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("expected status code %d but got %d", http.StatusUnsupportedMediaType, rr.Code)
+		}
+	})
+
+	t.Run("should reject the whole upload if one file is not an image", func(t *testing.T) {
+		before := len(imageStore.images)
+		rr := upload(t, router, 1, "photo.png", pngImage(t, 10, 10), []byte("not an image"))
+		if rr.Code != http.StatusUnsupportedMediaType || len(imageStore.images) != before {
+			t.Errorf("expected status code %d without new images but got %d and %d images", http.StatusUnsupportedMediaType, rr.Code, len(imageStore.images)-before)
+		}
+	})
+
+	t.Run("should store no image if recording them fails", func(t *testing.T) {
+		before, blobsBefore := len(imageStore.images), countFiles(t, blobDir)
+		imageStore.err = errors.New("database is down")
+		defer func() { imageStore.err = nil }()
+
+		rr := upload(t, router, 1, "photo.png", pngImage(t, 10, 10), pngImage(t, 20, 20))
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status code %d but got %d", http.StatusInternalServerError, rr.Code)
+		}
+		if len(imageStore.images) != before || countFiles(t, blobDir) != blobsBefore {
+			t.Errorf("expected no images or blobs to be left behind")
+		}
+	})
+
+	t.Run("should reject images over the size limit", func(t *testing.T) {
+		defer func(old int64) { configs.Envs.MaxImageSizeInBytes = old }(configs.Envs.MaxImageSizeInBytes)
+		configs.Envs.MaxImageSizeInBytes = 100
+
+		rr := upload(t, router, 1, "big.png", pngImage(t, 300, 300))
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status code %d but got %d", http.StatusRequestEntityTooLarge, rr.Code)
+		}
+	})
+
+	t.Run("should return 404 for unknown products", func(t *testing.T) {
+		rr := upload(t, router, 42, "photo.png", pngImage(t, 10, 10))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should only allow admins to upload", func(t *testing.T) {
+		req := uploadRequest(t, 1, "photo.png", pngImage(t, 10, 10))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 2))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+}
+
+func TestScaleDown(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		src.Set(x, 0, color.White)
+		src.Set(x, 1, color.Black)
+	}
+
+	dst := scaleDown(src, 2)
+	if b := dst.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("expected a 2x1 image but got %dx%d", b.Dx(), b.Dy())
+	}
+
+	// Every destination pixel averages one white and one black row
+	r, _, _, _ := dst.At(0, 0).RGBA()
+	if r < 0x7000 || r > 0x9000 {
+		t.Errorf("expected a mid grey but got red=%#x", r)
+	}
+}
+
+func upload(t *testing.T, router *mux.Router, productID int, filename string, data ...[]byte) *httptest.ResponseRecorder {
+	req := uploadRequest(t, productID, filename, data...)
+	req.Header.Set("Authorization", "Bearer "+testutil.Token(t, 1))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+// uploadRequest builds a request uploading each of data as a file named filename.
+func uploadRequest(t *testing.T, productID int, filename string, data ...[]byte) *http.Request {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, file := range data {
+		fw, err := mw.CreateFormFile("images", filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write(file)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/products/%d/images", productID), &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// countFiles returns how many files are stored under dir.
+func countFiles(t *testing.T, dir string) int {
+	n := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			n++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func pngImage(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+type mockProductStore struct {
+	products map[int]*types.Product
+	upserted []types.ProductImportRow
+}
+
+func (m *mockProductStore) GetProductByID(id int) (*types.Product, error) {
+	p, ok := m.products[id]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	product := *p
+	return &product, nil
+}
+
+func (m *mockProductStore) GetProductsByID(ids []int) ([]types.Product, error) {
+	products := []types.Product{}
+	for _, id := range ids {
+		if p, ok := m.products[id]; ok {
+			products = append(products, *p)
+		}
+	}
+	return products, nil
+}
+
+func (m *mockProductStore) GetProducts() ([]*types.Product, error) {
+	products := []*types.Product{}
+	for _, p := range m.products {
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+func (m *mockProductStore) CreateProduct(p types.CreateProductPayload) (int, error) {
+	id := len(m.products) + 1
+	m.products[id] = &types.Product{ID: id, Name: p.Name, Description: p.Description, Price: p.Price, Quantity: p.Quantity}
+	return id, nil
+}
+
+func (m *mockProductStore) UpdateProduct(p types.Product) error {
+	m.products[p.ID] = &p
+	return nil
+}
+
+func (m *mockProductStore) UpsertProducts(rows []types.ProductImportRow, actorID int) (int, int, error) {
+	m.upserted = append(m.upserted, rows...)
+	return len(rows), 0, nil
+}
+
+func (m *mockProductStore) ExportProducts(fn func(types.Product) error) error {
+	for id := 1; id <= len(m.products); id++ {
+		if p, ok := m.products[id]; ok {
+			if err := fn(*p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+type mockImageStore struct {
+	images []types.ProductImage
+	err    error // Returned by CreateProductImages, if set
+}
+
+func (m *mockImageStore) GetProductImages(productID int) ([]types.ProductImage, error) {
+	images := []types.ProductImage{}
+	for _, img := range m.images {
+		if img.ProductID == productID {
+			images = append(images, img)
+		}
+	}
+	return images, nil
+}
+
+func (m *mockImageStore) GetProductImage(productID, imageID int) (*types.ProductImage, error) {
+	for _, img := range m.images {
+		if img.ProductID == productID && img.ID == imageID {
+			return &img, nil
+		}
+	}
+	return nil, ErrImageNotFound
+}
+
+func (m *mockImageStore) CreateProductImages(productID int, images []types.ProductImage) ([]int, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	existing, _ := m.GetProductImages(productID)
+	ids := []int{}
+	for i, img := range images {
+		img.ID = len(m.images) + 1
+		img.Position = len(existing) + i
+		m.images = append(m.images, img)
+		ids = append(ids, img.ID)
+	}
+	return ids, nil
+}
+
+func (m *mockImageStore) SetProductImageThumbnail(productID, imageID int, key string) error {
+	for i := range m.images {
+		if m.images[i].ProductID == productID && m.images[i].ID == imageID && m.images[i].ThumbnailKey == "" {
+			m.images[i].ThumbnailKey = key
+			return nil
+		}
+	}
+	return ErrImageNotFound
+}
+
+func (m *mockImageStore) DeleteProductImage(productID, imageID int) error {
+	return nil
+}
+
+func (m *mockImageStore) ReorderProductImages(productID int, imageIDs []int) error {
+	return nil
+}
@@ -2,14 +2,38 @@ package product
 
 import (
 	"database/sql" // Importing the sql package for database interaction
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/code-farms/go-backend/services/inventory"
+	"github.com/code-farms/go-backend/services/jobs"
+	"github.com/code-farms/go-backend/services/outbox"
+	"github.com/code-farms/go-backend/services/pricing"
+	"github.com/code-farms/go-backend/services/webhook"
 	"github.com/code-farms/go-backend/types"
 )
 
+var (
+	ErrProductNotFound = errors.New("product not found")
+	ErrImageNotFound   = errors.New("product image not found")
+)
+
+// productColumns lists the columns read by scanRowIntoProduct, in scan order.
+const productColumns = "id, COALESCE(sku, ''), name, description, category, taxClass, image, quantity, reserved, price, weight, length, width, height, created_at"
+
+// ratingColumns and ratingJoin add the aggregate of the approved reviews to a product query.
+const (
+	ratingColumns = "COALESCE(ratings.averageRating, 0), COALESCE(ratings.reviewCount, 0)"
+	ratingJoin    = " LEFT JOIN (SELECT productId, ROUND(AVG(rating), 2) AS averageRating, COUNT(*) AS reviewCount FROM reviews WHERE status = 'approved' GROUP BY productId) ratings ON ratings.productId = products.id"
+)
+
+// imageColumns lists the columns read by scanRowIntoImage, in scan order.
+const imageColumns = "id, productId, position, blobKey, thumbnailKey, contentType, size, width, height, created_at"
+
 type store struct {
 	db *sql.DB // Declaring a variable 'db' of type *sql.DB
-}	
+}
 
 func NewStore (db *sql.DB) *store {
 	return &store{
@@ -17,12 +41,20 @@ func NewStore (db *sql.DB) *store {
 	}
 }
 
-func (s *store) CreateProduct(product types.CreateProductPayload) (int64, error) {
+// CreateProduct inserts the product, records its initial quantity as a receipt in the
+// inventory ledger and starts its price history.
+func (s *store) CreateProduct(product types.CreateProductPayload) (int, error) {
+    tx, err := s.db.Begin()
+    if err != nil {
+        return 0, err
+    }
+    defer tx.Rollback()
+
     // Use a parameterized query to prevent SQL injection
-    query := "INSERT INTO products (name, price, image, description, quantity) VALUES (?, ?, ?, ?, ?)"
-    
+    query := "INSERT INTO products (sku, name, price, image, description, category, taxClass, quantity, weight, length, width, height) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
     // Execute the query and capture the result
-    result, err := s.db.Exec(query, product.Name, product.Price, product.Image, product.Description, product.Quantity)
+    result, err := tx.Exec(query, nullableString(product.SKU), product.Name, product.Price, product.Image, product.Description, product.Category, taxClass(product.TaxClass), product.Quantity, product.Weight, product.Length, product.Width, product.Height)
     if err != nil {
         return 0, fmt.Errorf("failed to insert product into database: %w", err)
     }
@@ -33,20 +65,90 @@ func (s *store) CreateProduct(product types.CreateProductPayload) (int64, error)
         return 0, fmt.Errorf("failed to fetch inserted product ID: %w", err)
     }
 
+    err = pricing.RecordPriceChange(tx, types.PriceChange{
+        ProductID: int(id),
+        NewPrice:  product.Price,
+        Reason:    types.PriceCreated,
+    })
+    if err != nil {
+        return 0, err
+    }
+
+    if product.Quantity != 0 {
+        _, err = inventory.RecordMovement(tx, types.StockMovement{
+            ProductID:      int(id),
+            QuantityChange: product.Quantity,
+            Reason:         types.MovementReceipt,
+            Note:           "initial stock",
+        })
+        if err != nil {
+            return 0, err
+        }
+    }
+
+    if err := publish(tx, types.EventProductCreated, int(id)); err != nil {
+        return 0, err
+    }
+
     // Return the inserted product ID and nil error
-    return id, nil
+    return int(id), tx.Commit()
 }
 
+// UpdateProduct records a change of price in the price history.
+func (s *store) UpdateProduct(product types.Product) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var price float64
+	err = tx.QueryRow("SELECT price FROM products WHERE id = ? FOR UPDATE", product.ID).Scan(&price)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrProductNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE products SET sku = ?, name = ?, description = ?, category = ?, taxClass = ?, image = ?, price = ?, weight = ?, length = ?, width = ?, height = ? WHERE id = ?",
+		nullableString(product.SKU), product.Name, product.Description, product.Category, taxClass(product.TaxClass), product.Image, product.Price,
+		product.Weight, product.Length, product.Width, product.Height, product.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	if product.Price != price {
+		err := pricing.RecordPriceChange(tx, types.PriceChange{
+			ProductID: product.ID,
+			OldPrice:  &price,
+			NewPrice:  product.Price,
+			Reason:    types.PriceUpdated,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := publish(tx, types.EventProductUpdated, product.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
 func (s *store) GetProducts () ([]*types.Product, error) {
-	rows, err := s.db.Query("SELECT * FROM products") // Querying the database to retrieve all products
+	rows, err := s.db.Query("SELECT " + productColumns + ", " + ratingColumns + " FROM products" + ratingJoin) // Querying the database to retrieve all products with their ratings
 	if err != nil {
 		return nil, err // Returning an error if the query fails
 	}
-	
+	defer rows.Close()
+
 	products := make([]*types.Product, 0) // Creating a slice to store the retrieved products
 	for rows.Next() {
-		p, err := scanRowIntoProduct(rows) // Scanning each row into a Product struct
+		p := new(types.Product)
+		err := rows.Scan(append(productDest(p), &p.AverageRating, &p.ReviewCount)...) // Scanning each row into a Product struct
 		if err != nil {
 			return nil, err // Returning an error if scanning fails
 		}
@@ -54,24 +156,419 @@ func (s *store) GetProducts () ([]*types.Product, error) {
 		products = append(products, p) // Adding the scanned Product to the slice
 	}
 
-	return products, nil // Returning the slice of products and a nil error
+	return products, rows.Err() // Returning the slice of products and any iteration error
+}
+
+func (s *store) GetProductByID(id int) (*types.Product, error) {
+	rows, err := s.db.Query("SELECT "+productColumns+" FROM products WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrProductNotFound
+	}
+
+	return scanRowIntoProduct(rows)
+}
+
+// GetProductsByID returns the products with the given IDs. Unknown IDs are skipped,
+// so callers that need every product must compare the lengths.
+func (s *store) GetProductsByID(ids []int) ([]types.Product, error) {
+	if len(ids) == 0 {
+		return []types.Product{}, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	rows, err := s.db.Query("SELECT "+productColumns+" FROM products WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []types.Product{}
+	for rows.Next() {
+		p, err := scanRowIntoProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, *p)
+	}
+
+	return products, rows.Err()
+}
+
+// UpsertProducts writes all rows in one transaction so a failing row leaves the catalog untouched.
+// An empty image, category or tax class in a row keeps the one already stored for the product. The difference
+// between the imported and the stored quantity is recorded as an adjustment, and price changes
+// are added to the price history.
+func (s *store) UpsertProducts(rows []types.ProductImportRow, actorID int) (int, int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	created, updated := 0, 0
+	for _, row := range rows {
+		var id, quantity, reserved int
+		var price float64
+		err := tx.QueryRow("SELECT id, quantity, reserved, price FROM products WHERE sku = ? FOR UPDATE", row.SKU).Scan(&id, &quantity, &reserved, &price)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, err
+		}
+		exists := err == nil
+
+		event := ""
+		if exists {
+			if row.Quantity < reserved {
+				return 0, 0, fmt.Errorf("product %s: quantity %d is below the %d reserved units", row.SKU, row.Quantity, reserved)
+			}
+			result, err := tx.Exec(
+				"UPDATE products SET name = ?, description = ?, category = IF(? = '', category, ?), taxClass = IF(? = '', taxClass, ?), image = IF(? = '', image, ?), price = ?, quantity = ? WHERE id = ?",
+				row.Name, row.Description, row.Category, row.Category, row.TaxClass, row.TaxClass, row.Image, row.Image, row.Price, row.Quantity, id,
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to update product %s: %w", row.SKU, err)
+			}
+			if affected, _ := result.RowsAffected(); affected > 0 {
+				event = types.EventProductUpdated
+				updated++
+			}
+		} else {
+			result, err := tx.Exec(
+				"INSERT INTO products (sku, name, description, category, taxClass, image, price, quantity) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+				row.SKU, row.Name, row.Description, row.Category, taxClass(row.TaxClass), row.Image, row.Price, row.Quantity,
+			)
+			if err != nil {
+				return 0, 0, fmt.Errorf("failed to insert product %s: %w", row.SKU, err)
+			}
+			lastID, err := result.LastInsertId()
+			if err != nil {
+				return 0, 0, err
+			}
+			id = int(lastID)
+			event = types.EventProductCreated
+			created++
+		}
+
+		if !exists || row.Price != price {
+			change := types.PriceChange{ProductID: id, NewPrice: row.Price, Reason: types.PriceImported, ActorID: inventory.Actor(actorID)}
+			if exists {
+				change.OldPrice = &price
+			}
+			if err := pricing.RecordPriceChange(tx, change); err != nil {
+				return 0, 0, err
+			}
+		}
+
+		if change := row.Quantity - quantity; change != 0 {
+			_, err := inventory.RecordMovement(tx, types.StockMovement{
+				ProductID:      id,
+				QuantityChange: change,
+				Reason:         types.MovementAdjustment,
+				ActorID:        inventory.Actor(actorID),
+				Note:           "catalog import",
+			})
+			if err != nil {
+				return 0, 0, err
+			}
+		}
+
+		if event != "" {
+			if err := publish(tx, event, id); err != nil {
+				return 0, 0, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
+
+func (s *store) ExportProducts(fn func(types.Product) error) error {
+	rows, err := s.db.Query("SELECT " + productColumns + " FROM products ORDER BY id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, err := scanRowIntoProduct(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(*p); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (s *store) GetProductImages(productID int) ([]types.ProductImage, error) {
+	rows, err := s.db.Query("SELECT "+imageColumns+" FROM product_images WHERE productId = ? ORDER BY position", productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	images := []types.ProductImage{}
+	for rows.Next() {
+		img, err := scanRowIntoImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, *img)
+	}
+
+	return images, rows.Err()
+}
+
+func (s *store) GetProductImage(productID, imageID int) (*types.ProductImage, error) {
+	rows, err := s.db.Query("SELECT "+imageColumns+" FROM product_images WHERE productId = ? AND id = ?", productID, imageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrImageNotFound
+	}
+
+	return scanRowIntoImage(rows)
 }
 
-// func (s *store) GetProductById (id int) (*Product, error) {
-// 	return nil, nil // Placeholder implementation, to be implemented later
-// }
+// CreateProductImages stores the images at the end of the product gallery in one
+// transaction. The product row is locked while the next position is computed so
+// concurrent uploads don't collide. The thumbnails are generated by jobs queued with the
+// images.
+func (s *store) CreateProductImages(productID int, images []types.ProductImage) ([]int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow("SELECT id FROM products WHERE id = ? FOR UPDATE", productID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProductNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var position int
+	err = tx.QueryRow("SELECT COALESCE(MAX(position) + 1, 0) FROM product_images WHERE productId = ?", productID).Scan(&position)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int, 0, len(images))
+	for i, img := range images {
+		result, err := tx.Exec(
+			"INSERT INTO product_images (productId, position, blobKey, thumbnailKey, contentType, size, width, height) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			productID, position+i, img.BlobKey, img.ThumbnailKey, img.ContentType, img.Size, img.Width, img.Height,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert product image: %w", err)
+		}
 
+		imageID, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := jobs.Enqueue(tx, types.GenerateThumbnailJob{ProductID: productID, ImageID: int(imageID)}, types.JobOptions{}); err != nil {
+			return nil, err
+		}
+		ids = append(ids, int(imageID))
+	}
+
+	return ids, tx.Commit()
+}
+
+// SetProductImageThumbnail only sets the thumbnail of an image that has none yet, so a
+// retried job can't replace a thumbnail that is already served.
+func (s *store) SetProductImageThumbnail(productID, imageID int, key string) error {
+	result, err := s.db.Exec(
+		"UPDATE product_images SET thumbnailKey = ? WHERE productId = ? AND id = ? AND thumbnailKey = ''",
+		key, productID, imageID,
+	)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrImageNotFound
+	}
+	return nil
+}
+
+func (s *store) DeleteProductImage(productID, imageID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var position int
+	err = tx.QueryRow("SELECT position FROM product_images WHERE productId = ? AND id = ? FOR UPDATE", productID, imageID).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrImageNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM product_images WHERE id = ?", imageID); err != nil {
+		return err
+	}
+
+	// Shift the following images up so positions stay contiguous
+	_, err = tx.Exec("UPDATE product_images SET position = position - 1 WHERE productId = ? AND position > ?", productID, position)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *store) ReorderProductImages(productID int, imageIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id FROM product_images WHERE productId = ? FOR UPDATE", productID)
+	if err != nil {
+		return err
+	}
+	existing := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(imageIDs) != len(existing) {
+		return fmt.Errorf("expected %d image ids, got %d", len(existing), len(imageIDs))
+	}
+	for _, id := range imageIDs {
+		if !existing[id] {
+			return fmt.Errorf("image %d is missing or listed twice", id)
+		}
+		delete(existing, id)
+	}
+
+	for position, id := range imageIDs {
+		if _, err := tx.Exec("UPDATE product_images SET position = ? WHERE id = ?", position, id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// PublishUpdate records that product id changed inside tx, for packages that change
+// products in their own transactions.
+func PublishUpdate(tx *sql.Tx, id int) error {
+	return publish(tx, types.EventProductUpdated, id)
+}
+
+// publish records a ProductUpdated event and queues a webhook event with the product as it
+// is inside tx.
+func publish(tx *sql.Tx, event string, id int) error {
+	err := outbox.Record(tx, types.ProductUpdated{ProductID: id, Created: event == types.EventProductCreated})
+	if err != nil {
+		return err
+	}
+
+	product := new(types.Product)
+	if err := tx.QueryRow("SELECT "+productColumns+" FROM products WHERE id = ?", id).Scan(productDest(product)...); err != nil {
+		return err
+	}
+	return webhook.Enqueue(tx, event, product)
+}
 
 func scanRowIntoProduct (rows *sql.Rows) (*types.Product, error) {
 	product := new(types.Product)
-	err := rows.Scan(
+	return product, rows.Scan(productDest(product)...)
+}
+
+// productDest returns the scan destinations for productColumns, in order.
+func productDest(product *types.Product) []any {
+	return []any{
 		&product.ID,       // Product ID
+		&product.SKU,      // Product SKU
 		&product.Name,     // Product name
 		&product.Description, // Product description
+		&product.Category, // Product category
+		&product.TaxClass, // Product tax class
 		&product.Image,    // Product image
 		&product.Quantity, // Product quantity
+		&product.Reserved, // Units held by reservations
 		&product.Price,    // Product price
+		&product.Weight,   // Product weight
+		&product.Length,   // Product length
+		&product.Width,    // Product width
+		&product.Height,   // Product height
 		&product.CreatedAt, // Product creation date
+	}
+}
+
+func scanRowIntoImage(rows *sql.Rows) (*types.ProductImage, error) {
+	img := new(types.ProductImage)
+	err := rows.Scan(
+		&img.ID,
+		&img.ProductID,
+		&img.Position,
+		&img.BlobKey,
+		&img.ThumbnailKey,
+		&img.ContentType,
+		&img.Size,
+		&img.Width,
+		&img.Height,
+		&img.CreatedAt,
 	)
-	return product, err
-}
\ No newline at end of file
+	return img, err
+}
+
+// nullableString stores empty strings as NULL so optional unique columns don't collide.
+func nullableString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// taxClass stores products without a tax class in the standard one.
+func taxClass(class string) string {
+	if class == "" {
+		return types.TaxClassStandard
+	}
+	return class
+}
@@ -0,0 +1,164 @@
+package product
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Registers the GIF decoder used by image.Decode
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/code-farms/go-backend/services/jobs"
+	"github.com/code-farms/go-backend/storage"
+	"github.com/code-farms/go-backend/types"
+)
+
+// thumbnailSize is the maximum width and height of generated thumbnails.
+const thumbnailSize = 256
+
+// allowedImageTypes maps the sniffed content types accepted for upload to file extensions.
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// decodedImage is an uploaded image whose content type and header were checked.
+type decodedImage struct {
+	data          []byte
+	contentType   string
+	width, height int
+}
+
+// RegisterJobs registers the handlers of the background jobs of products with worker.
+func (h *Handler) RegisterJobs(worker *jobs.Worker) {
+	jobs.Handle(worker, h.generateThumbnail)
+}
+
+// generateThumbnail stores the thumbnail of an uploaded image next to the original. Images
+// deleted in the meantime, and images that already have a thumbnail, are skipped.
+func (h *Handler) generateThumbnail(ctx context.Context, job types.GenerateThumbnailJob) error {
+	img, err := h.imageStore.GetProductImage(job.ProductID, job.ImageID)
+	if errors.Is(err, ErrImageNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if img.ThumbnailKey != "" {
+		return nil
+	}
+
+	blob, err := h.blobs.Get(ctx, img.BlobKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(blob)
+	blob.Close()
+	if err != nil {
+		return err
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode image %d: %w", img.ID, err)
+	}
+
+	thumb, thumbType, err := makeThumbnail(decoded, img.ContentType)
+	if err != nil {
+		return err
+	}
+	key := strings.TrimSuffix(img.BlobKey, path.Ext(img.BlobKey)) + "_thumb" + allowedImageTypes[thumbType]
+	if err := h.blobs.Put(ctx, key, bytes.NewReader(thumb), int64(len(thumb)), thumbType); err != nil {
+		return err
+	}
+
+	err = h.imageStore.SetProductImageThumbnail(img.ProductID, img.ID, key)
+	if errors.Is(err, ErrImageNotFound) {
+		// Another run may have stored the same key in the meantime, so the blob is only
+		// removed if the image itself was deleted
+		if _, err := h.imageStore.GetProductImage(img.ProductID, img.ID); errors.Is(err, ErrImageNotFound) {
+			h.blobs.Delete(ctx, key)
+		}
+		return nil
+	}
+	return err
+}
+
+// makeThumbnail scales img down to fit in a thumbnailSize square and encodes it. JPEG
+// originals get a JPEG thumbnail; the others are kept as PNG, which keeps transparency.
+func makeThumbnail(img image.Image, contentType string) ([]byte, string, error) {
+	thumb := scaleDown(img, thumbnailSize)
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+
+	if err := png.Encode(&buf, thumb); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// scaleDown resizes img to fit within max x max pixels, keeping its aspect ratio.
+// Each destination pixel is the average of the (premultiplied) source pixels it covers,
+// which gives good results for the large reduction factors typical of thumbnails.
+func scaleDown(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max {
+		return img
+	}
+
+	dw, dh := max, max
+	if w > h {
+		dh = h * max / w
+	} else {
+		dw = w * max / h
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA64(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy0, sy1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			sx0, sx1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,189 @@
+package promotion
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+)
+
+var (
+	ErrPromotionNotFound      = errors.New("promotion not found")
+	ErrPromotionNotApplicable = errors.New("promotion code cannot be applied")
+	ErrCodeTaken              = errors.New("promotion code already exists")
+	ErrInvalidPromotion       = errors.New("invalid promotion")
+)
+
+// ApplyCode looks up a code and applies it to a basket on behalf of userID. The per-user
+// limit is only checked for logged-in users, i.e. a positive userID.
+func ApplyCode(store types.PromotionStore, code string, userID int, items []types.PromotionItem, now time.Time) (*types.PromotionResult, error) {
+	p, err := store.GetPromotionByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	redemptions := 0
+	if userID > 0 && p.PerUserLimit != nil {
+		if redemptions, err = store.CountUserRedemptions(p.ID, userID); err != nil {
+			return nil, err
+		}
+	}
+	return Apply(*p, items, redemptions, now)
+}
+
+// Apply computes the discount a promotion gives on a basket, given how often the customer
+// already redeemed it. Amounts are computed in whole cents and every line discount is at
+// most the line total, so the result never drops below zero.
+func Apply(p types.Promotion, items []types.PromotionItem, userRedemptions int, now time.Time) (*types.PromotionResult, error) {
+	switch {
+	case !p.Active:
+		return nil, notApplicable("is not active")
+	case p.StartsAt != nil && now.Before(*p.StartsAt):
+		return nil, notApplicable("is not valid yet")
+	case p.EndsAt != nil && !now.Before(*p.EndsAt):
+		return nil, notApplicable("has expired")
+	case p.UsageLimit != nil && p.TimesUsed >= *p.UsageLimit:
+		return nil, notApplicable("has been used up")
+	case p.PerUserLimit != nil && userRedemptions >= *p.PerUserLimit:
+		return nil, notApplicable("was already used the maximum number of times")
+	}
+
+	lines := make([]int64, len(items))
+	var subtotal int64
+	eligible := []int{}
+	for i, item := range items {
+		lines[i] = utils.ToCents(item.Price) * int64(item.Quantity)
+		subtotal += lines[i]
+		if appliesTo(p, item) {
+			eligible = append(eligible, i)
+		}
+	}
+	if subtotal < utils.ToCents(p.MinOrderValue) {
+		return nil, notApplicable(fmt.Sprintf("requires an order of at least %s", utils.FormatCents(utils.ToCents(p.MinOrderValue))))
+	}
+	if len(eligible) == 0 {
+		return nil, notApplicable("does not apply to any of the products")
+	}
+
+	discounts := make([]int64, len(items))
+	result := &types.PromotionResult{PromotionID: p.ID, Code: p.Code}
+	switch p.Type {
+	case types.PromotionPercentage:
+		// The percentage has at most two decimals, so work in hundredths of a percent
+		rate := utils.ToCents(p.Value)
+		for _, i := range eligible {
+			discounts[i] = (lines[i]*rate + 5000) / 10000
+		}
+	case types.PromotionFixed:
+		spread(discounts, lines, eligible, utils.ToCents(p.Value))
+	case types.PromotionBuyXGetY:
+		freeUnits(discounts, items, eligible, p.BuyQuantity, p.GetQuantity)
+	case types.PromotionFreeShipping:
+		result.FreeShipping = true
+	}
+
+	var discount int64
+	result.Items = make([]types.PromotionItem, len(items))
+	for i, item := range items {
+		discounts[i] = min(discounts[i], lines[i])
+		discount += discounts[i]
+		item.Discount = utils.FromCents(discounts[i])
+		result.Items[i] = item
+	}
+	result.Subtotal = utils.FromCents(subtotal)
+	result.Discount = utils.FromCents(discount)
+	result.Total = utils.FromCents(subtotal - discount)
+	return result, nil
+}
+
+// appliesTo reports whether a promotion covers an item. A promotion without products and
+// categories covers everything.
+func appliesTo(p types.Promotion, item types.PromotionItem) bool {
+	if len(p.ProductIDs) == 0 && len(p.Categories) == 0 {
+		return true
+	}
+	return slices.Contains(p.ProductIDs, item.ProductID) || (item.Category != "" && slices.Contains(p.Categories, item.Category))
+}
+
+// spread divides amount cents over the eligible lines in proportion to their totals, never
+// more than the eligible subtotal. Cents lost to rounding go to the first lines with room.
+func spread(discounts, lines []int64, eligible []int, amount int64) {
+	var total int64
+	for _, i := range eligible {
+		total += lines[i]
+	}
+	amount = min(amount, total)
+	if total == 0 {
+		return
+	}
+
+	left := amount
+	for _, i := range eligible {
+		discounts[i] = amount * lines[i] / total
+		left -= discounts[i]
+	}
+	for _, i := range eligible {
+		if left == 0 {
+			break
+		}
+		if discounts[i] < lines[i] {
+			discounts[i]++
+			left--
+		}
+	}
+}
+
+// freeUnits makes getQty units free for every buyQty+getQty eligible units, starting with
+// the cheapest units.
+func freeUnits(discounts []int64, items []types.PromotionItem, eligible []int, buyQty, getQty int) {
+	if buyQty <= 0 || getQty <= 0 {
+		return
+	}
+
+	units := 0
+	for _, i := range eligible {
+		units += items[i].Quantity
+	}
+	free := units / (buyQty + getQty) * getQty
+
+	byPrice := slices.Clone(eligible)
+	slices.SortStableFunc(byPrice, func(a, b int) int {
+		return int(utils.ToCents(items[a].Price) - utils.ToCents(items[b].Price))
+	})
+	for _, i := range byPrice {
+		n := min(free, items[i].Quantity)
+		discounts[i] += utils.ToCents(items[i].Price) * int64(n)
+		if free -= n; free == 0 {
+			break
+		}
+	}
+}
+
+// validatePromotion checks the rules that depend on the type of a promotion.
+func validatePromotion(p types.PromotionPayload) error {
+	switch p.Type {
+	case types.PromotionPercentage:
+		if p.Value <= 0 || p.Value > 100 {
+			return fmt.Errorf("%w: a percentage must be above 0 and at most 100", ErrInvalidPromotion)
+		}
+	case types.PromotionFixed:
+		if p.Value <= 0 {
+			return fmt.Errorf("%w: a fixed amount must be above 0", ErrInvalidPromotion)
+		}
+	case types.PromotionBuyXGetY:
+		if p.BuyQuantity <= 0 || p.GetQuantity <= 0 {
+			return fmt.Errorf("%w: buyQuantity and getQuantity must be above 0", ErrInvalidPromotion)
+		}
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return fmt.Errorf("%w: endsAt must be after startsAt", ErrInvalidPromotion)
+	}
+	return nil
+}
+
+func notApplicable(reason string) error {
+	return fmt.Errorf("%w: the code %s", ErrPromotionNotApplicable, reason)
+}
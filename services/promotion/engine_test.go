@@ -0,0 +1,124 @@
+package promotion
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestApply(t *testing.T) {
+	now := time.Now()
+	items := []types.PromotionItem{
+		{ProductID: 1, Category: "mugs", Quantity: 3, Price: 9.99},
+		{ProductID: 2, Category: "tea", Quantity: 1, Price: 4.50},
+		{ProductID: 3, Quantity: 2, Price: 1.25},
+	}
+	limit := func(n int) *int { return &n }
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+
+	t.Run("should discount a percentage of every line", func(t *testing.T) {
+		result, err := Apply(types.Promotion{Type: types.PromotionPercentage, Value: 12.5, Active: true}, items, 0, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 29.97 * 12.5% = 3.746, 4.50 * 12.5% = 0.5625, 2.50 * 12.5% = 0.3125
+		if result.Subtotal != 36.97 || result.Discount != 4.62 || result.Total != 32.35 {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if result.Items[0].Discount != 3.75 || result.Items[1].Discount != 0.56 || result.Items[2].Discount != 0.31 {
+			t.Errorf("unexpected items %+v", result.Items)
+		}
+	})
+
+	t.Run("should spread a fixed amount over the scoped lines", func(t *testing.T) {
+		p := types.Promotion{Type: types.PromotionFixed, Value: 10, Active: true, ProductIDs: []int{3}, Categories: []string{"mugs"}}
+		result, err := Apply(p, items, 0, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Discount != 10 || result.Items[1].Discount != 0 || result.Items[0].Discount+result.Items[2].Discount != 10 {
+			t.Errorf("unexpected items %+v", result.Items)
+		}
+	})
+
+	t.Run("should not discount more than the scoped lines cost", func(t *testing.T) {
+		result, err := Apply(types.Promotion{Type: types.PromotionFixed, Value: 50, Active: true, Categories: []string{"tea"}}, items, 0, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Discount != 4.5 || result.Total != 32.47 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("should give the cheapest units for free", func(t *testing.T) {
+		p := types.Promotion{Type: types.PromotionBuyXGetY, BuyQuantity: 2, GetQuantity: 1, Active: true}
+		result, err := Apply(p, items, 0, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 6 units make 2 free ones, both of product 3
+		if result.Discount != 2.5 || result.Items[2].Discount != 2.5 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("should make shipping free without discounting the items", func(t *testing.T) {
+		result, err := Apply(types.Promotion{Type: types.PromotionFreeShipping, Active: true}, items, 0, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.FreeShipping || result.Discount != 0 || result.Total != 36.97 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("should reject codes that cannot be redeemed", func(t *testing.T) {
+		tests := map[string]struct {
+			promotion   types.Promotion
+			redemptions int
+		}{
+			"inactive":         {promotion: types.Promotion{}},
+			"not started":      {promotion: types.Promotion{Active: true, StartsAt: at(time.Hour)}},
+			"expired":          {promotion: types.Promotion{Active: true, EndsAt: at(-time.Hour)}},
+			"used up":          {promotion: types.Promotion{Active: true, UsageLimit: limit(5), TimesUsed: 5}},
+			"used by the user": {promotion: types.Promotion{Active: true, PerUserLimit: limit(1)}, redemptions: 1},
+			"below minimum":    {promotion: types.Promotion{Active: true, MinOrderValue: 50}},
+			"out of scope":     {promotion: types.Promotion{Active: true, Categories: []string{"books"}}},
+		}
+		for name, tt := range tests {
+			tt.promotion.Type, tt.promotion.Value = types.PromotionPercentage, 10
+			if _, err := Apply(tt.promotion, items, tt.redemptions, now); !errors.Is(err, ErrPromotionNotApplicable) {
+				t.Errorf("%s: expected ErrPromotionNotApplicable but got %v", name, err)
+			}
+		}
+	})
+
+	t.Run("should accept codes within their limits and window", func(t *testing.T) {
+		p := types.Promotion{
+			Type: types.PromotionPercentage, Value: 10, Active: true, MinOrderValue: 36.97,
+			UsageLimit: limit(5), TimesUsed: 4, PerUserLimit: limit(2), StartsAt: at(-time.Hour), EndsAt: at(time.Hour),
+		}
+		if _, err := Apply(p, items, 1, now); err != nil {
+			t.Errorf("expected the code to apply but got %v", err)
+		}
+	})
+}
+
+func TestValidatePromotion(t *testing.T) {
+	start := time.Now()
+	end := start.Add(-time.Minute)
+
+	for name, p := range map[string]types.PromotionPayload{
+		"percentage above 100": {Type: types.PromotionPercentage, Value: 101},
+		"fixed without amount": {Type: types.PromotionFixed},
+		"buy without get":      {Type: types.PromotionBuyXGetY, BuyQuantity: 2},
+		"window ends first":    {Type: types.PromotionFreeShipping, StartsAt: &start, EndsAt: &end},
+	} {
+		if err := validatePromotion(p); !errors.Is(err, ErrInvalidPromotion) {
+			t.Errorf("%s: expected ErrInvalidPromotion but got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package promotion
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+var ErrProductNotFound = errors.New("product not found")
+
+type Handler struct {
+	store        types.PromotionStore
+	productStore types.ProductStore
+	userStore    types.UserStore
+}
+
+func NewHandler(store types.PromotionStore, productStore types.ProductStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, productStore: productStore, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/promotions/preview", auth.WithOptionalJWTAuth(h.handlePreview, h.userStore)).Methods(http.MethodPost)
+
+	router.HandleFunc("/admin/promotions", auth.WithAdminAuth(h.handleGetPromotions, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/promotions", auth.WithAdminAuth(h.handleCreatePromotion, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/promotions/{id:[0-9]+}", auth.WithAdminAuth(h.handleGetPromotion, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/promotions/{id:[0-9]+}", auth.WithAdminAuth(h.handleUpdatePromotion, h.userStore)).Methods(http.MethodPut)
+}
+
+// handlePreview applies a code to a list of products and quantities at current prices
+// without redeeming it. Logged-in customers also have their per-user limit checked.
+func (h *Handler) handlePreview(w http.ResponseWriter, r *http.Request) {
+	var payload types.PromotionPreviewPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	ids := make([]int, len(payload.Items))
+	for i, item := range payload.Items {
+		ids[i] = item.ProductID
+	}
+	products, err := h.productStore.GetProductsByID(ids)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	items, err := Basket(payload.Items, products)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	result, err := ApplyCode(h.store, payload.Code, auth.GetUserIDFromContext(r.Context()), items, time.Now())
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) handleGetPromotions(w http.ResponseWriter, r *http.Request) {
+	page, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	promotions, err := h.store.GetPromotions(page.Limit, page.Offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"promotions": promotions,
+		"page":       page.Page,
+		"limit":      page.Limit,
+	})
+}
+
+func (h *Handler) handleGetPromotion(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	p, err := h.store.GetPromotion(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, p)
+}
+
+func (h *Handler) handleCreatePromotion(w http.ResponseWriter, r *http.Request) {
+	payload, ok := parsePromotionPayload(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := h.store.CreatePromotion(payload)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	p, err := h.store.GetPromotion(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, p)
+}
+
+func (h *Handler) handleUpdatePromotion(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	payload, ok := parsePromotionPayload(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.UpdatePromotion(id, payload); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	p, err := h.store.GetPromotion(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, p)
+}
+
+// Basket prices checkout items from the catalog for applying a promotion, adding up the
+// quantities of products listed more than once.
+func Basket(requested []types.CheckoutItem, products []types.Product) ([]types.PromotionItem, error) {
+	byID := make(map[int]types.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	items := []types.PromotionItem{}
+	index := map[int]int{}
+	for _, item := range requested {
+		if i, ok := index[item.ProductID]; ok {
+			items[i].Quantity += item.Quantity
+			continue
+		}
+		p, ok := byID[item.ProductID]
+		if !ok {
+			return nil, fmt.Errorf("%w: product %d", ErrProductNotFound, item.ProductID)
+		}
+		index[item.ProductID] = len(items)
+		items = append(items, types.PromotionItem{ProductID: p.ID, Category: p.Category, Quantity: item.Quantity, Price: p.Price})
+	}
+	return items, nil
+}
+
+// parsePromotionPayload reads and validates a promotion, writing the error response and
+// returning false if it is invalid.
+func parsePromotionPayload(w http.ResponseWriter, r *http.Request) (types.PromotionPayload, bool) {
+	var payload types.PromotionPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return payload, false
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return payload, false
+	}
+	if err := validatePromotion(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return payload, false
+	}
+	return payload, true
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrPromotionNotFound), errors.Is(err, ErrProductNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrCodeTaken):
+		utils.WriteError(w, http.StatusConflict, err)
+	case errors.Is(err, ErrInvalidPromotion):
+		utils.WriteError(w, http.StatusBadRequest, err)
+	case errors.Is(err, ErrPromotionNotApplicable):
+		utils.WriteError(w, http.StatusUnprocessableEntity, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
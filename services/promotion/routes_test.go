@@ -0,0 +1,199 @@
+package promotion
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestPromotionRoutes(t *testing.T) {
+	store := &mockPromotionStore{redemptions: map[int]int{}}
+	productStore := &mockProductStore{products: map[int]*types.Product{
+		1: {ID: 1, Price: 10, Category: "mugs"},
+		2: {ID: 2, Price: 4.50, Category: "tea"},
+	}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleAdmin},
+	}}
+
+	handler := NewHandler(store, productStore, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if userID != 0 {
+			req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should let only admins create promotions", func(t *testing.T) {
+		limit := 1
+		payload := types.PromotionPayload{Code: "mugs20", Type: types.PromotionPercentage, Value: 20, Categories: []string{"mugs"}, PerUserLimit: &limit}
+		if rr := send(http.MethodPost, "/admin/promotions", 1, payload); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d for a customer but got %d", http.StatusForbidden, rr.Code)
+		}
+
+		rr := send(http.MethodPost, "/admin/promotions", 2, payload)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var p types.Promotion
+		json.NewDecoder(rr.Body).Decode(&p)
+		if p.Code != "MUGS20" || !p.Active {
+			t.Errorf("expected an active promotion MUGS20 but got %+v", p)
+		}
+
+		if rr := send(http.MethodPost, "/admin/promotions", 2, payload); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d for a duplicate code but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should reject invalid promotions", func(t *testing.T) {
+		payload := types.PromotionPayload{Code: "HALF", Type: types.PromotionPercentage, Value: 150}
+		if rr := send(http.MethodPost, "/admin/promotions", 2, payload); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should preview a code against the catalog prices", func(t *testing.T) {
+		payload := types.PromotionPreviewPayload{Code: "mugs20", Items: []types.CheckoutItem{
+			{ProductID: 1, Quantity: 1},
+			{ProductID: 2, Quantity: 2},
+			{ProductID: 1, Quantity: 1},
+		}}
+		rr := send(http.MethodPost, "/promotions/preview", 0, payload)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		var result types.PromotionResult
+		json.NewDecoder(rr.Body).Decode(&result)
+		if result.Subtotal != 29 || result.Discount != 4 || result.Total != 25 || len(result.Items) != 2 || result.Items[0].Quantity != 2 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	})
+
+	t.Run("should check the per-user limit of logged-in customers", func(t *testing.T) {
+		store.redemptions[1] = 1
+		payload := types.PromotionPreviewPayload{Code: "MUGS20", Items: []types.CheckoutItem{{ProductID: 1, Quantity: 1}}}
+		if rr := send(http.MethodPost, "/promotions/preview", 1, payload); rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status code %d but got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+	})
+
+	t.Run("should reject unknown codes and products", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/promotions/preview", 0, types.PromotionPreviewPayload{Code: "NOPE", Items: []types.CheckoutItem{{ProductID: 1, Quantity: 1}}}); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d for an unknown code but got %d", http.StatusNotFound, rr.Code)
+		}
+		if rr := send(http.MethodPost, "/promotions/preview", 0, types.PromotionPreviewPayload{Code: "MUGS20", Items: []types.CheckoutItem{{ProductID: 9, Quantity: 1}}}); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d for an unknown product but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
+type mockPromotionStore struct {
+	promotions  []types.Promotion
+	redemptions map[int]int // Redemptions of user 1 by promotion ID
+}
+
+func (m *mockPromotionStore) GetPromotions(limit, offset int) ([]types.Promotion, error) {
+	return m.promotions, nil
+}
+
+func (m *mockPromotionStore) GetPromotion(id int) (*types.Promotion, error) {
+	if id < 1 || id > len(m.promotions) {
+		return nil, ErrPromotionNotFound
+	}
+	p := m.promotions[id-1]
+	return &p, nil
+}
+
+func (m *mockPromotionStore) GetPromotionByCode(code string) (*types.Promotion, error) {
+	for _, p := range m.promotions {
+		if p.Code == normalizeCode(code) {
+			return &p, nil
+		}
+	}
+	return nil, ErrPromotionNotFound
+}
+
+func (m *mockPromotionStore) CreatePromotion(payload types.PromotionPayload) (int, error) {
+	if _, err := m.GetPromotionByCode(payload.Code); err == nil {
+		return 0, ErrCodeTaken
+	}
+	m.promotions = append(m.promotions, types.Promotion{
+		ID:           len(m.promotions) + 1,
+		Code:         normalizeCode(payload.Code),
+		Type:         payload.Type,
+		Value:        payload.Value,
+		PerUserLimit: payload.PerUserLimit,
+		Active:       payload.Active == nil || *payload.Active,
+		ProductIDs:   payload.ProductIDs,
+		Categories:   payload.Categories,
+	})
+	return len(m.promotions), nil
+}
+
+func (m *mockPromotionStore) UpdatePromotion(id int, payload types.PromotionPayload) error {
+	return nil
+}
+
+func (m *mockPromotionStore) CountUserRedemptions(promotionID, userID int) (int, error) {
+	if userID != 1 {
+		return 0, nil
+	}
+	return m.redemptions[promotionID], nil
+}
+
+type mockProductStore struct {
+	products map[int]*types.Product
+}
+
+func (m *mockProductStore) GetProductByID(id int) (*types.Product, error) {
+	p, ok := m.products[id]
+	if !ok {
+		return nil, ErrProductNotFound
+	}
+	return p, nil
+}
+
+func (m *mockProductStore) GetProductsByID(ids []int) ([]types.Product, error) {
+	products := []types.Product{}
+	for _, id := range ids {
+		if p, ok := m.products[id]; ok {
+			products = append(products, *p)
+		}
+	}
+	return products, nil
+}
+
+func (m *mockProductStore) GetProducts() ([]*types.Product, error) {
+	return nil, nil
+}
+
+func (m *mockProductStore) CreateProduct(p types.CreateProductPayload) (int, error) {
+	return 0, nil
+}
+
+func (m *mockProductStore) UpdateProduct(p types.Product) error {
+	return nil
+}
+
+func (m *mockProductStore) UpsertProducts(rows []types.ProductImportRow, actorID int) (int, int, error) {
+	return 0, 0, nil
+}
+
+func (m *mockProductStore) ExportProducts(fn func(types.Product) error) error {
+	return nil
+}
@@ -0,0 +1,338 @@
+package promotion
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	ErrPromotionUsedUp    = errors.New("promotion code has been used up")
+	ErrPromotionUserLimit = errors.New("promotion code was already used the maximum number of times")
+)
+
+// MySQL error numbers for a unique key violation and a foreign key pointing nowhere.
+const (
+	mysqlDuplicateEntry  = 1062
+	mysqlNoReferencedRow = 1452
+)
+
+// promotionColumns lists the columns read by scanRowIntoPromotion, in scan order.
+const promotionColumns = "id, code, description, type, value, buyQuantity, getQuantity, minOrderValue, usageLimit, perUserLimit, timesUsed, starts_at, ends_at, active, created_at"
+
+// Redeem records that an order used a promotion and counts the use against its limits.
+// It must run in the same transaction as the order so a failed order does not use the code
+// up. Concurrent redemptions of the same promotion are serialized by the row lock taken by
+// the update, so the limits hold under load.
+func Redeem(tx *sql.Tx, promotionID, userID, orderID int, discount float64) error {
+	result, err := tx.Exec(
+		"UPDATE promotions SET timesUsed = timesUsed + 1 WHERE id = ? AND (usageLimit IS NULL OR timesUsed < usageLimit)",
+		promotionID,
+	)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrPromotionUsedUp
+	}
+
+	var perUserLimit sql.NullInt64
+	var used int
+	err = tx.QueryRow(
+		"SELECT p.perUserLimit, (SELECT COUNT(*) FROM promotion_redemptions r WHERE r.promotionId = p.id AND r.userId = ?) FROM promotions p WHERE p.id = ?",
+		userID, promotionID,
+	).Scan(&perUserLimit, &used)
+	if err != nil {
+		return err
+	}
+	if perUserLimit.Valid && int64(used) >= perUserLimit.Int64 {
+		return ErrPromotionUserLimit
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO promotion_redemptions (promotionId, userId, orderId, discount) VALUES (?, ?, ?, ?)",
+		promotionID, userID, orderID, utils.FormatCents(utils.ToCents(discount)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record promotion redemption: %w", err)
+	}
+	return nil
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetPromotions(limit, offset int) ([]types.Promotion, error) {
+	rows, err := s.db.Query("SELECT "+promotionColumns+" FROM promotions ORDER BY id DESC LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promotions := []types.Promotion{}
+	for rows.Next() {
+		p, err := scanRowIntoPromotion(rows)
+		if err != nil {
+			return nil, err
+		}
+		promotions = append(promotions, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range promotions {
+		if err := s.loadScope(&promotions[i]); err != nil {
+			return nil, err
+		}
+	}
+	return promotions, nil
+}
+
+func (s *Store) GetPromotion(id int) (*types.Promotion, error) {
+	return s.getPromotion("id = ?", id)
+}
+
+func (s *Store) GetPromotionByCode(code string) (*types.Promotion, error) {
+	return s.getPromotion("code = ?", normalizeCode(code))
+}
+
+func (s *Store) CreatePromotion(payload types.PromotionPayload) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO promotions (code, description, type, value, buyQuantity, getQuantity, minOrderValue, usageLimit, perUserLimit, starts_at, ends_at, active) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		promotionArgs(payload)...,
+	)
+	if err := codeError(err); err != nil {
+		return 0, err
+	}
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := replaceScope(tx, int(lastID), payload); err != nil {
+		return 0, err
+	}
+	return int(lastID), tx.Commit()
+}
+
+func (s *Store) UpdatePromotion(id int, payload types.PromotionPayload) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM promotions WHERE id = ? FOR UPDATE)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrPromotionNotFound
+	}
+
+	_, err = tx.Exec(
+		"UPDATE promotions SET code = ?, description = ?, type = ?, value = ?, buyQuantity = ?, getQuantity = ?, minOrderValue = ?, usageLimit = ?, perUserLimit = ?, starts_at = ?, ends_at = ?, active = ? WHERE id = ?",
+		append(promotionArgs(payload), id)...,
+	)
+	if err := codeError(err); err != nil {
+		return err
+	}
+
+	if err := replaceScope(tx, id, payload); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) CountUserRedemptions(promotionID, userID int) (int, error) {
+	var count int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM promotion_redemptions WHERE promotionId = ? AND userId = ?", promotionID, userID).Scan(&count)
+	return count, err
+}
+
+func (s *Store) getPromotion(where string, arg any) (*types.Promotion, error) {
+	rows, err := s.db.Query("SELECT "+promotionColumns+" FROM promotions WHERE "+where, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrPromotionNotFound
+	}
+	p, err := scanRowIntoPromotion(rows)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	return p, s.loadScope(p)
+}
+
+// loadScope reads the products and categories a promotion is limited to.
+func (s *Store) loadScope(p *types.Promotion) error {
+	p.ProductIDs, p.Categories = []int{}, []string{}
+
+	rows, err := s.db.Query("SELECT productId FROM promotion_products WHERE promotionId = ? ORDER BY productId", p.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		p.ProductIDs = append(p.ProductIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.db.Query("SELECT category FROM promotion_categories WHERE promotionId = ? ORDER BY category", p.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return err
+		}
+		p.Categories = append(p.Categories, category)
+	}
+	return rows.Err()
+}
+
+// replaceScope replaces the products and categories a promotion is limited to.
+func replaceScope(tx *sql.Tx, id int, payload types.PromotionPayload) error {
+	if _, err := tx.Exec("DELETE FROM promotion_products WHERE promotionId = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM promotion_categories WHERE promotionId = ?", id); err != nil {
+		return err
+	}
+
+	// Plain inserts over deduplicated values: INSERT IGNORE would also hide unknown products
+	for _, productID := range dedupe(payload.ProductIDs) {
+		_, err := tx.Exec("INSERT INTO promotion_products (promotionId, productId) VALUES (?, ?)", id, productID)
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlNoReferencedRow {
+			return fmt.Errorf("%w: product %d does not exist", ErrInvalidPromotion, productID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	categories := make([]string, len(payload.Categories))
+	for i, category := range payload.Categories {
+		categories[i] = strings.TrimSpace(category)
+	}
+	for _, category := range dedupe(categories) {
+		if _, err := tx.Exec("INSERT INTO promotion_categories (promotionId, category) VALUES (?, ?)", id, category); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dedupe returns the distinct values in the order they first appear.
+func dedupe[T comparable](values []T) []T {
+	seen := map[T]bool{}
+	distinct := []T{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			distinct = append(distinct, v)
+		}
+	}
+	return distinct
+}
+
+// promotionArgs returns the column values of a promotion in the order used by the insert
+// and update statements.
+func promotionArgs(p types.PromotionPayload) []any {
+	active := p.Active == nil || *p.Active
+	return []any{
+		normalizeCode(p.Code), strings.TrimSpace(p.Description), p.Type, utils.FormatCents(utils.ToCents(p.Value)),
+		p.BuyQuantity, p.GetQuantity, utils.FormatCents(utils.ToCents(p.MinOrderValue)), p.UsageLimit, p.PerUserLimit,
+		p.StartsAt, p.EndsAt, active,
+	}
+}
+
+// codeError turns a duplicate code into ErrCodeTaken.
+func codeError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return ErrCodeTaken
+	}
+	return err
+}
+
+// normalizeCode makes codes case-insensitive.
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func scanRowIntoPromotion(rows *sql.Rows) (*types.Promotion, error) {
+	p := new(types.Promotion)
+	var usageLimit, perUserLimit sql.NullInt64
+	var startsAt, endsAt sql.NullTime
+	err := rows.Scan(
+		&p.ID,
+		&p.Code,
+		&p.Description,
+		&p.Type,
+		&p.Value,
+		&p.BuyQuantity,
+		&p.GetQuantity,
+		&p.MinOrderValue,
+		&usageLimit,
+		&perUserLimit,
+		&p.TimesUsed,
+		&startsAt,
+		&endsAt,
+		&p.Active,
+		&p.CreatedAt,
+	)
+	if usageLimit.Valid {
+		limit := int(usageLimit.Int64)
+		p.UsageLimit = &limit
+	}
+	if perUserLimit.Valid {
+		limit := int(perUserLimit.Int64)
+		p.PerUserLimit = &limit
+	}
+	if startsAt.Valid {
+		p.StartsAt = &startsAt.Time
+	}
+	if endsAt.Valid {
+		p.EndsAt = &endsAt.Time
+	}
+	return p, err
+}
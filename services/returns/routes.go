@@ -0,0 +1,167 @@
+package returns
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.ReturnStore
+	orderStore types.OrderStore
+	userStore  types.UserStore
+}
+
+func NewHandler(store types.ReturnStore, orderStore types.OrderStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, orderStore: orderStore, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orders/{id:[0-9]+}/returns", auth.WithJWTAuth(h.handleCreateReturn, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/orders/{id:[0-9]+}/returns", auth.WithJWTAuth(h.handleGetOrderReturns, h.userStore)).Methods(http.MethodGet)
+
+	router.HandleFunc("/admin/returns", auth.WithAdminAuth(h.handleGetReturns, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/returns/{id:[0-9]+}", auth.WithAdminAuth(h.handleGetReturn, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/returns/{id:[0-9]+}/status", auth.WithAdminAuth(h.handleTransitionReturn, h.userStore)).Methods(http.MethodPost)
+}
+
+// handleCreateReturn lets a customer ask to send units of a delivered order back.
+func (h *Handler) handleCreateReturn(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ReturnPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	if err := h.ownOrder(r, id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	returnID, err := h.store.CreateReturn(id, payload)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.writeReturn(w, http.StatusCreated, returnID)
+}
+
+func (h *Handler) handleGetOrderReturns(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.ownOrder(r, id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	list, err := h.store.GetOrderReturns(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, list)
+}
+
+// handleGetReturns returns a page of returns, newest first, optionally only those in the
+// status given by the status parameter, e.g. the requests waiting for staff.
+func (h *Handler) handleGetReturns(w http.ResponseWriter, r *http.Request) {
+	page, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if _, ok := transitions[status]; status != "" && !ok {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid status %q", status))
+		return
+	}
+
+	list, err := h.store.GetReturns(status, page.Limit, page.Offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"returns": list,
+		"page":    page.Page,
+		"limit":   page.Limit,
+	})
+}
+
+func (h *Handler) handleGetReturn(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	h.writeReturn(w, http.StatusOK, id)
+}
+
+// handleTransitionReturn moves a return through approval, receipt and inspection,
+// answering 409 for transitions the workflow does not allow. Accepting a return refunds
+// its units.
+func (h *Handler) handleTransitionReturn(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ReturnStatusPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	if err := h.store.TransitionReturn(id, payload, auth.GetUserIDFromContext(r.Context())); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.writeReturn(w, http.StatusOK, id)
+}
+
+// ownOrder checks that the order belongs to the authenticated user or the user is an
+// admin. Other users' orders are reported as not found so their IDs are not disclosed.
+func (h *Handler) ownOrder(r *http.Request, id int) error {
+	o, err := h.orderStore.GetOrder(id)
+	if err != nil {
+		return err
+	}
+	if o.UserID != auth.GetUserIDFromContext(r.Context()) && !auth.IsAdmin(r.Context()) {
+		return order.ErrOrderNotFound
+	}
+	return nil
+}
+
+func (h *Handler) writeReturn(w http.ResponseWriter, status, id int) {
+	ret, err := h.store.GetReturn(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	utils.WriteJSON(w, status, ret)
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrReturnNotFound), errors.Is(err, order.ErrOrderNotFound), errors.Is(err, order.ErrOrderItemNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrOrderNotReturnable), errors.Is(err, ErrReturnTooLarge), errors.Is(err, ErrInvalidTransition),
+		errors.Is(err, order.ErrRefundTooLarge):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,272 @@
+package returns
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestReturnFlow(t *testing.T) {
+	orderStore := &mockOrderStore{orders: map[int]*types.Order{
+		1: {ID: 1, UserID: 1, Status: types.OrderDelivered, Items: []types.OrderItem{
+			{ID: 1, OrderID: 1, ProductID: 1, ProductName: "Mug", Quantity: 2},
+			{ID: 2, OrderID: 1, ProductID: 2, ProductName: "Tea", Quantity: 1},
+		}},
+		2: {ID: 2, UserID: 1, Status: types.OrderShipped, Items: []types.OrderItem{
+			{ID: 3, OrderID: 2, ProductID: 1, ProductName: "Mug", Quantity: 1},
+		}},
+	}}
+	store := &mockReturnStore{orders: orderStore}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleCustomer},
+		3: {ID: 3, Role: types.RoleAdmin},
+	}}
+
+	handler := NewHandler(store, orderStore, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	payload := types.ReturnPayload{
+		Items:   []types.ReturnItemPayload{{OrderItemID: 1, Quantity: 1, Reason: "damaged"}},
+		Comment: "The handle broke off",
+	}
+
+	t.Run("should only let customers return their delivered orders", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/orders/1/returns", 2, payload); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d for another user's order but got %d", http.StatusNotFound, rr.Code)
+		}
+		shipped := types.ReturnPayload{Items: []types.ReturnItemPayload{{OrderItemID: 3, Quantity: 1, Reason: "other"}}}
+		if rr := send(http.MethodPost, "/orders/2/returns", 1, shipped); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d for an order not delivered yet but got %d", http.StatusConflict, rr.Code)
+		}
+		invalid := types.ReturnPayload{Items: []types.ReturnItemPayload{{OrderItemID: 1, Quantity: 1, Reason: "changed my mind"}}}
+		if rr := send(http.MethodPost, "/orders/1/returns", 1, invalid); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d for an unknown reason but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should request a return", func(t *testing.T) {
+		rr := send(http.MethodPost, "/orders/1/returns", 1, payload)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var ret types.Return
+		json.NewDecoder(rr.Body).Decode(&ret)
+		if ret.Status != types.ReturnRequested || len(ret.Items) != 1 || ret.Items[0].Reason != "damaged" {
+			t.Errorf("unexpected return %+v", ret)
+		}
+
+		twice := types.ReturnPayload{Items: []types.ReturnItemPayload{{OrderItemID: 1, Quantity: 2, Reason: "other"}}}
+		if rr := send(http.MethodPost, "/orders/1/returns", 1, twice); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d when returning units twice but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should walk the return through the workflow and refund it", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/admin/returns/1/status", 1, types.ReturnStatusPayload{Status: types.ReturnApproved}); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d for a customer but got %d", http.StatusForbidden, rr.Code)
+		}
+		if rr := send(http.MethodPost, "/admin/returns/1/status", 3, types.ReturnStatusPayload{Status: types.ReturnAccepted}); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d when accepting before receipt but got %d", http.StatusConflict, rr.Code)
+		}
+
+		for _, status := range []string{types.ReturnApproved, types.ReturnReceived} {
+			if rr := send(http.MethodPost, "/admin/returns/1/status", 3, types.ReturnStatusPayload{Status: status}); rr.Code != http.StatusOK {
+				t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+			}
+		}
+
+		rr := send(http.MethodPost, "/admin/returns/1/status", 3, types.ReturnStatusPayload{Status: types.ReturnAccepted, Note: "handle cracked", Restock: true})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		var ret types.Return
+		json.NewDecoder(rr.Body).Decode(&ret)
+		if ret.Status != types.ReturnAccepted || ret.RefundID == nil || ret.RefundStatus != types.RefundPaymentPending || !ret.Restocked || ret.Note != "handle cracked" {
+			t.Errorf("unexpected return %+v", ret)
+		}
+		if refunded := orderStore.orders[1].Items[0].Refunded; refunded != 1 {
+			t.Errorf("expected 1 unit to be refunded but got %d", refunded)
+		}
+	})
+
+	t.Run("should list returns for staff and customers", func(t *testing.T) {
+		rr := send(http.MethodGet, "/admin/returns?status=accepted", 3, nil)
+		var page struct {
+			Returns []types.Return `json:"returns"`
+		}
+		json.NewDecoder(rr.Body).Decode(&page)
+		if rr.Code != http.StatusOK || len(page.Returns) != 1 {
+			t.Errorf("unexpected returns %d %+v", rr.Code, page.Returns)
+		}
+		if rr := send(http.MethodGet, "/admin/returns?status=lost", 3, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		rr = send(http.MethodGet, "/orders/1/returns", 1, nil)
+		var list []types.Return
+		json.NewDecoder(rr.Body).Decode(&list)
+		if rr.Code != http.StatusOK || len(list) != 1 || list[0].Comment != "The handle broke off" {
+			t.Errorf("unexpected returns %+v", list)
+		}
+	})
+}
+
+// mockReturnStore checks returns like Store and refunds the items of mockOrderStore.
+type mockReturnStore struct {
+	orders  *mockOrderStore
+	returns []types.Return
+}
+
+func (m *mockReturnStore) CreateReturn(orderID int, payload types.ReturnPayload) (int, error) {
+	o, ok := m.orders.orders[orderID]
+	if !ok {
+		return 0, order.ErrOrderNotFound
+	}
+	if o.Status != types.OrderDelivered {
+		return 0, ErrOrderNotReturnable
+	}
+	open := map[int]int{}
+	for _, ret := range m.returns {
+		if ret.OrderID == orderID && ret.Status != types.ReturnAccepted && ret.Status != types.ReturnRejected {
+			for _, item := range ret.Items {
+				open[item.OrderItemID] += item.Quantity
+			}
+		}
+	}
+	lines, err := returnLines(o.Items, open, payload.Items)
+	if err != nil {
+		return 0, err
+	}
+
+	id := len(m.returns) + 1
+	m.returns = append(m.returns, types.Return{
+		ID:        id,
+		OrderID:   orderID,
+		UserID:    o.UserID,
+		Status:    types.ReturnRequested,
+		Comment:   payload.Comment,
+		Items:     lines,
+		CreatedAt: time.Now(),
+	})
+	return id, nil
+}
+
+func (m *mockReturnStore) GetReturns(status string, limit, offset int) ([]types.Return, error) {
+	list := []types.Return{}
+	for i := len(m.returns) - 1; i >= 0; i-- {
+		if status == "" || m.returns[i].Status == status {
+			list = append(list, m.returns[i])
+		}
+	}
+	return list, nil
+}
+
+func (m *mockReturnStore) GetReturn(id int) (*types.Return, error) {
+	if id < 1 || id > len(m.returns) {
+		return nil, ErrReturnNotFound
+	}
+	ret := m.returns[id-1]
+	return &ret, nil
+}
+
+func (m *mockReturnStore) GetOrderReturns(orderID int) ([]types.Return, error) {
+	list := []types.Return{}
+	for _, ret := range m.returns {
+		if ret.OrderID == orderID {
+			list = append(list, ret)
+		}
+	}
+	return list, nil
+}
+
+func (m *mockReturnStore) TransitionReturn(id int, payload types.ReturnStatusPayload, actorID int) error {
+	if id < 1 || id > len(m.returns) {
+		return ErrReturnNotFound
+	}
+	ret := &m.returns[id-1]
+	if !canTransition(ret.Status, payload.Status) {
+		return ErrInvalidTransition
+	}
+	ret.Status, ret.Note = payload.Status, payload.Note
+	if payload.Status == types.ReturnAccepted {
+		o := m.orders.orders[ret.OrderID]
+		for _, line := range ret.Items {
+			for i := range o.Items {
+				if o.Items[i].ID == line.OrderItemID {
+					o.Items[i].Refunded += line.Quantity
+				}
+			}
+		}
+		refundID := id
+		ret.RefundID, ret.RefundStatus, ret.Restocked = &refundID, types.RefundPaymentPending, payload.Restock
+	}
+	return nil
+}
+
+type mockOrderStore struct {
+	orders map[int]*types.Order
+}
+
+func (m *mockOrderStore) CreateOrder(o types.Order, items []types.OrderItem, reservationID int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockOrderStore) GetOrders(filter types.OrderFilter, limit, offset int) ([]types.Order, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetOrder(id int) (*types.Order, error) {
+	o, ok := m.orders[id]
+	if !ok {
+		return nil, order.ErrOrderNotFound
+	}
+	c := *o
+	return &c, nil
+}
+
+func (m *mockOrderStore) TransitionOrder(id int, to string, actorID int, note string) (*types.OrderStatusChange, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetOrderStatusHistory(orderID int) ([]types.OrderStatusChange, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) CancelOrder(id int, actorID int, note string) (*types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) RefundOrder(id int, payload types.RefundPayload, actorID int) (*types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetRefunds(orderID int) ([]types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetRefund(id int) (*types.Refund, error) {
+	return nil, order.ErrRefundNotFound
+}
+
+func (m *mockOrderStore) SettleRefund(id int, status string, paymentID *int) error {
+	return nil
+}
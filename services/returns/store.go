@@ -0,0 +1,398 @@
+package returns
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+)
+
+var (
+	ErrReturnNotFound     = errors.New("return not found")
+	ErrOrderNotReturnable = errors.New("only delivered orders can be returned")
+	ErrReturnTooLarge     = errors.New("return exceeds the units not refunded or returned yet")
+	ErrInvalidTransition  = errors.New("invalid return status transition")
+)
+
+// transitions lists the statuses a return may move to from each status. Staff can turn a
+// return down until it is accepted; accepted and rejected returns are final.
+var transitions = map[string][]string{
+	types.ReturnRequested: {types.ReturnApproved, types.ReturnRejected},
+	types.ReturnApproved:  {types.ReturnReceived, types.ReturnRejected},
+	types.ReturnReceived:  {types.ReturnAccepted, types.ReturnRejected},
+	types.ReturnAccepted:  {},
+	types.ReturnRejected:  {},
+}
+
+// openStatuses are the statuses whose units can't be returned again. Accepted units are
+// refunded, so they count through order_items.refunded instead.
+const openStatuses = "'requested', 'approved', 'received'"
+
+// returnColumns lists the columns read by scanRowIntoReturn, in scan order.
+const returnColumns = "id, orderId, userId, status, comment, note, restocked, refundId, " +
+	"(SELECT paymentStatus FROM refunds WHERE refunds.id = `returns`.refundId), created_at, updated_at"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateReturn locks the order and its items so two returns requested at once can't
+// include the same units.
+func (s *Store) CreateReturn(orderID int, payload types.ReturnPayload) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var status string
+	err = tx.QueryRow("SELECT userId, status FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&userID, &status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, order.ErrOrderNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status != types.OrderDelivered {
+		return 0, ErrOrderNotReturnable
+	}
+
+	items, err := lockItems(tx, orderID)
+	if err != nil {
+		return 0, err
+	}
+	open, err := openUnits(tx, orderID)
+	if err != nil {
+		return 0, err
+	}
+	lines, err := returnLines(items, open, payload.Items)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(
+		"INSERT INTO `returns` (orderId, userId, comment) VALUES (?, ?, ?)",
+		orderID, userID, strings.TrimSpace(payload.Comment),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert return: %w", err)
+	}
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range lines {
+		if _, err := tx.Exec(
+			"INSERT INTO return_items (returnId, orderItemId, quantity, reason) VALUES (?, ?, ?, ?)",
+			lastID, line.OrderItemID, line.Quantity, line.Reason,
+		); err != nil {
+			return 0, fmt.Errorf("failed to insert return item: %w", err)
+		}
+	}
+
+	return int(lastID), tx.Commit()
+}
+
+func (s *Store) GetReturns(status string, limit, offset int) ([]types.Return, error) {
+	query := "SELECT " + returnColumns + " FROM `returns`"
+	args := []any{}
+	if status != "" {
+		query += " WHERE status = ?"
+		args = append(args, status)
+	}
+	query += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []types.Return{}
+	for rows.Next() {
+		r, err := scanRowIntoReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *r)
+	}
+	return list, rows.Err()
+}
+
+func (s *Store) GetReturn(id int) (*types.Return, error) {
+	list, err := s.queryReturns("id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrReturnNotFound
+	}
+	return &list[0], nil
+}
+
+func (s *Store) GetOrderReturns(orderID int) ([]types.Return, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM orders WHERE id = ?)", orderID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, order.ErrOrderNotFound
+	}
+	return s.queryReturns("orderId = ?", orderID)
+}
+
+// TransitionReturn locks the order before the return, in the same order as CreateReturn
+// and the order's own refunds, so accepting a return and refunding its units by hand can't
+// both go through.
+func (s *Store) TransitionReturn(id int, payload types.ReturnStatusPayload, actorID int) error {
+	var orderID int
+	err := s.db.QueryRow("SELECT orderId FROM `returns` WHERE id = ?", id).Scan(&orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrReturnNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	if err := tx.QueryRow("SELECT id FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&locked); err != nil {
+		return err
+	}
+	var from string
+	if err := tx.QueryRow("SELECT status FROM `returns` WHERE id = ? FOR UPDATE", id).Scan(&from); err != nil {
+		return err
+	}
+	if !canTransition(from, payload.Status) {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, payload.Status)
+	}
+
+	note := strings.TrimSpace(payload.Note)
+	if payload.Status != types.ReturnAccepted {
+		_, err := tx.Exec("UPDATE `returns` SET status = ?, note = ? WHERE id = ?", payload.Status, note, id)
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	rows, err := tx.Query("SELECT orderItemId, quantity FROM return_items WHERE returnId = ? ORDER BY id", id)
+	if err != nil {
+		return err
+	}
+	requested := []types.RefundItemPayload{}
+	for rows.Next() {
+		var item types.RefundItemPayload
+		if err := rows.Scan(&item.OrderItemID, &item.Quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		requested = append(requested, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	movement := ""
+	if payload.Restock {
+		movement = types.MovementReturn
+	}
+	refundID, err := order.Refund(tx, orderID, requested, movement, fmt.Sprintf("return %d", id), actorID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE `returns` SET status = ?, note = ?, restocked = ?, refundId = ? WHERE id = ?",
+		payload.Status, note, payload.Restock, refundID, id,
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryReturns returns the returns matching where, oldest first, with their items.
+func (s *Store) queryReturns(where string, arg any) ([]types.Return, error) {
+	rows, err := s.db.Query("SELECT "+returnColumns+" FROM `returns` WHERE "+where+" ORDER BY id", arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []types.Return{}
+	index := map[int]int{}
+	for rows.Next() {
+		r, err := scanRowIntoReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		r.Items = []types.ReturnItem{}
+		index[r.ID] = len(list)
+		list = append(list, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	itemRows, err := s.db.Query(
+		"SELECT ri.id, ri.returnId, ri.orderItemId, oi.productId, oi.productName, ri.quantity, ri.reason FROM return_items ri "+
+			"JOIN order_items oi ON oi.id = ri.orderItemId WHERE ri.returnId IN (SELECT id FROM `returns` WHERE "+where+") ORDER BY ri.id",
+		arg,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item types.ReturnItem
+		if err := itemRows.Scan(&item.ID, &item.ReturnID, &item.OrderItemID, &item.ProductID, &item.ProductName, &item.Quantity, &item.Reason); err != nil {
+			return nil, err
+		}
+		if i, ok := index[item.ReturnID]; ok {
+			list[i].Items = append(list[i].Items, item)
+		}
+	}
+	return list, itemRows.Err()
+}
+
+// lockItems reads the items of an order and locks them until tx ends.
+func lockItems(tx *sql.Tx, orderID int) ([]types.OrderItem, error) {
+	rows, err := tx.Query(
+		"SELECT id, orderId, productId, productName, quantity, refunded FROM order_items WHERE orderId = ? ORDER BY id FOR UPDATE",
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []types.OrderItem{}
+	for rows.Next() {
+		var item types.OrderItem
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.ProductName, &item.Quantity, &item.Refunded); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// openUnits returns the units of every item of an order that are part of an open return,
+// by order item ID.
+func openUnits(tx *sql.Tx, orderID int) (map[int]int, error) {
+	rows, err := tx.Query(
+		"SELECT ri.orderItemId, SUM(ri.quantity) FROM return_items ri JOIN `returns` r ON r.id = ri.returnId "+
+			"WHERE r.orderId = ? AND r.status IN ("+openStatuses+") GROUP BY ri.orderItemId",
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	open := map[int]int{}
+	for rows.Next() {
+		var itemID, quantity int
+		if err := rows.Scan(&itemID, &quantity); err != nil {
+			return nil, err
+		}
+		open[itemID] = quantity
+	}
+	return open, rows.Err()
+}
+
+// returnLines checks the requested units against what is left of every item once refunded
+// units and units of open returns are taken off. Units of the same item returned for the
+// same reason are added up.
+func returnLines(items []types.OrderItem, open map[int]int, requested []types.ReturnItemPayload) ([]types.ReturnItem, error) {
+	byID := make(map[int]types.OrderItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	type key struct {
+		itemID int
+		reason string
+	}
+	lines := []types.ReturnItem{}
+	index := map[key]int{}
+	total := map[int]int{}
+	for _, r := range requested {
+		item, ok := byID[r.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", order.ErrOrderItemNotFound, r.OrderItemID)
+		}
+		total[item.ID] += r.Quantity
+		if left := item.Quantity - item.Refunded - open[item.ID]; total[item.ID] > left {
+			return nil, fmt.Errorf("%w: only %d units of item %d can be returned", ErrReturnTooLarge, max(left, 0), item.ID)
+		}
+
+		k := key{item.ID, r.Reason}
+		if i, ok := index[k]; ok {
+			lines[i].Quantity += r.Quantity
+			continue
+		}
+		index[k] = len(lines)
+		lines = append(lines, types.ReturnItem{
+			OrderItemID: item.ID,
+			ProductID:   item.ProductID,
+			ProductName: item.ProductName,
+			Quantity:    r.Quantity,
+			Reason:      r.Reason,
+		})
+	}
+	return lines, nil
+}
+
+func canTransition(from, to string) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func scanRowIntoReturn(rows *sql.Rows) (*types.Return, error) {
+	r := new(types.Return)
+	var refundID sql.NullInt64
+	var refundStatus sql.NullString
+	err := rows.Scan(
+		&r.ID,
+		&r.OrderID,
+		&r.UserID,
+		&r.Status,
+		&r.Comment,
+		&r.Note,
+		&r.Restocked,
+		&refundID,
+		&refundStatus,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	if refundID.Valid {
+		id := int(refundID.Int64)
+		r.RefundID = &id
+	}
+	r.RefundStatus = refundStatus.String
+	return r, err
+}
@@ -0,0 +1,71 @@
+package returns
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestReturnLines(t *testing.T) {
+	items := []types.OrderItem{
+		{ID: 1, ProductID: 1, ProductName: "Mug", Quantity: 4, Refunded: 1},
+		{ID: 2, ProductID: 2, ProductName: "Tea", Quantity: 2},
+	}
+	open := map[int]int{2: 1}
+
+	t.Run("should add up units returned for the same reason", func(t *testing.T) {
+		lines, err := returnLines(items, open, []types.ReturnItemPayload{
+			{OrderItemID: 1, Quantity: 1, Reason: "damaged"},
+			{OrderItemID: 1, Quantity: 1, Reason: "other"},
+			{OrderItemID: 1, Quantity: 1, Reason: "damaged"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 2 || lines[0].Quantity != 2 || lines[0].ProductName != "Mug" || lines[1].Reason != "other" {
+			t.Errorf("unexpected lines %+v", lines)
+		}
+	})
+
+	t.Run("should not return refunded units or units of open returns", func(t *testing.T) {
+		for _, requested := range [][]types.ReturnItemPayload{
+			{{OrderItemID: 1, Quantity: 4, Reason: "damaged"}},
+			{{OrderItemID: 1, Quantity: 2, Reason: "damaged"}, {OrderItemID: 1, Quantity: 2, Reason: "other"}},
+			{{OrderItemID: 2, Quantity: 2, Reason: "defective"}},
+		} {
+			if _, err := returnLines(items, open, requested); !errors.Is(err, ErrReturnTooLarge) {
+				t.Errorf("expected ErrReturnTooLarge for %+v but got %v", requested, err)
+			}
+		}
+	})
+
+	t.Run("should reject items of other orders", func(t *testing.T) {
+		_, err := returnLines(items, open, []types.ReturnItemPayload{{OrderItemID: 9, Quantity: 1, Reason: "other"}})
+		if !errors.Is(err, order.ErrOrderItemNotFound) {
+			t.Errorf("expected ErrOrderItemNotFound but got %v", err)
+		}
+	})
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{types.ReturnRequested, types.ReturnApproved, true},
+		{types.ReturnRequested, types.ReturnReceived, false},
+		{types.ReturnApproved, types.ReturnReceived, true},
+		{types.ReturnReceived, types.ReturnAccepted, true},
+		{types.ReturnReceived, types.ReturnRejected, true},
+		{types.ReturnAccepted, types.ReturnRejected, false},
+		{types.ReturnRejected, types.ReturnApproved, false},
+	}
+
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.allowed {
+			t.Errorf("canTransition(%s, %s) = %v, expected %v", tt.from, tt.to, got, tt.allowed)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package review
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.ReviewStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.ReviewStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/products/{id:[0-9]+}/reviews", h.handleGetProductReviews).Methods(http.MethodGet)
+	router.HandleFunc("/products/{id:[0-9]+}/reviews", auth.WithJWTAuth(h.handleCreateReview, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/reviews/{id:[0-9]+}", auth.WithJWTAuth(h.handleUpdateReview, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/reviews/{id:[0-9]+}", auth.WithJWTAuth(h.handleDeleteReview, h.userStore)).Methods(http.MethodDelete)
+
+	router.HandleFunc("/admin/reviews", auth.WithAdminAuth(h.handleGetReviewsByStatus, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/reviews/{id:[0-9]+}/status", auth.WithAdminAuth(h.handleModerateReview, h.userStore)).Methods(http.MethodPut)
+}
+
+// handleGetProductReviews returns a page of the approved reviews of a product, newest first,
+// with the average rating and review count.
+func (h *Handler) handleGetProductReviews(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	page, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reviews, summary, err := h.store.GetProductReviews(productID, page.Limit, page.Offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"averageRating": summary.AverageRating,
+		"reviewCount":   summary.ReviewCount,
+		"reviews":       reviews,
+		"page":          page.Page,
+		"limit":         page.Limit,
+	})
+}
+
+// handleCreateReview stores the authenticated customer's review of a product. It is only
+// published once an admin approves it.
+func (h *Handler) handleCreateReview(w http.ResponseWriter, r *http.Request) {
+	productID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	payload, ok := parsePayload(w, r)
+	if !ok {
+		return
+	}
+
+	review := types.Review{
+		ProductID: productID,
+		UserID:    auth.GetUserIDFromContext(r.Context()),
+		Rating:    payload.Rating,
+		Title:     payload.Title,
+		Body:      payload.Body,
+	}
+	id, err := h.store.CreateReview(review)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	created, err := h.store.GetReview(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, created)
+}
+
+// handleUpdateReview lets customers edit their own review, which sends it back to moderation.
+func (h *Handler) handleUpdateReview(w http.ResponseWriter, r *http.Request) {
+	review, ok := h.reviewFor(w, r, false)
+	if !ok {
+		return
+	}
+
+	payload, ok := parsePayload(w, r)
+	if !ok {
+		return
+	}
+
+	review.Rating, review.Title, review.Body = payload.Rating, payload.Title, payload.Body
+	if err := h.store.UpdateReview(*review); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	updated, err := h.store.GetReview(review.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+// handleDeleteReview removes a review. Customers can delete their own reviews and admins any review.
+func (h *Handler) handleDeleteReview(w http.ResponseWriter, r *http.Request) {
+	review, ok := h.reviewFor(w, r, true)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteReview(review.ID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetReviewsByStatus lists reviews for moderation, pending ones by default.
+func (h *Handler) handleGetReviewsByStatus(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = types.ReviewPending
+	}
+	if err := utils.Validate.Struct(types.ModerateReviewPayload{Status: status}); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid status %q", status))
+		return
+	}
+
+	page, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	reviews, err := h.store.GetReviewsByStatus(status, page.Limit, page.Offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"reviews": reviews,
+		"page":    page.Page,
+		"limit":   page.Limit,
+	})
+}
+
+// handleModerateReview approves or rejects a review, or puts it back in the queue.
+func (h *Handler) handleModerateReview(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ModerateReviewPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	if err := h.store.SetReviewStatus(id, payload.Status); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	review, err := h.store.GetReview(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, review)
+}
+
+// reviewFor loads the review addressed by the request if it belongs to the authenticated
+// user, or to anyone when allowAdmin is set and the user is an admin. Other users' reviews
+// answer 404 like the reservations do.
+func (h *Handler) reviewFor(w http.ResponseWriter, r *http.Request, allowAdmin bool) (*types.Review, bool) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	review, err := h.store.GetReview(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return nil, false
+	}
+	if review.UserID != auth.GetUserIDFromContext(r.Context()) && !(allowAdmin && auth.IsAdmin(r.Context())) {
+		writeStoreError(w, ErrReviewNotFound)
+		return nil, false
+	}
+
+	return review, true
+}
+
+func parsePayload(w http.ResponseWriter, r *http.Request) (types.ReviewPayload, bool) {
+	var payload types.ReviewPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return payload, false
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return payload, false
+	}
+	return payload, true
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrReviewNotFound), errors.Is(err, ErrProductNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrAlreadyReviewed):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
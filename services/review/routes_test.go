@@ -0,0 +1,216 @@
+package review
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestReviewHandlers(t *testing.T) {
+	store := &mockReviewStore{products: map[int]bool{1: true}, reviews: map[int]*types.Review{}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleCustomer},
+		3: {ID: 3, Role: types.RoleAdmin},
+	}}
+
+	handler := NewHandler(store, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if userID != 0 {
+			req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should create a pending review", func(t *testing.T) {
+		rr := send(http.MethodPost, "/products/1/reviews", 1, types.ReviewPayload{Rating: 4, Title: "Solid"})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var review types.Review
+		json.NewDecoder(rr.Body).Decode(&review)
+		if review.Status != types.ReviewPending || review.UserID != 1 {
+			t.Errorf("unexpected review %+v", review)
+		}
+	})
+
+	t.Run("should reject a second review of the same product", func(t *testing.T) {
+		rr := send(http.MethodPost, "/products/1/reviews", 1, types.ReviewPayload{Rating: 5})
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should reject ratings outside 1 to 5", func(t *testing.T) {
+		rr := send(http.MethodPost, "/products/1/reviews", 2, types.ReviewPayload{Rating: 6})
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+
+	t.Run("should not list reviews before they are approved", func(t *testing.T) {
+		rr := send(http.MethodGet, "/products/1/reviews", 0, nil)
+		var body struct {
+			ReviewCount int            `json:"reviewCount"`
+			Reviews     []types.Review `json:"reviews"`
+		}
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body.ReviewCount != 0 || len(body.Reviews) != 0 {
+			t.Errorf("expected no published reviews but got %+v", body)
+		}
+	})
+
+	t.Run("should only let admins moderate", func(t *testing.T) {
+		rr := send(http.MethodPut, "/admin/reviews/1/status", 1, types.ModerateReviewPayload{Status: types.ReviewApproved})
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should list approved reviews with the average rating", func(t *testing.T) {
+		rr := send(http.MethodPut, "/admin/reviews/1/status", 3, types.ModerateReviewPayload{Status: types.ReviewApproved})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+
+		rr = send(http.MethodGet, "/products/1/reviews", 0, nil)
+		var body struct {
+			AverageRating float64        `json:"averageRating"`
+			ReviewCount   int            `json:"reviewCount"`
+			Reviews       []types.Review `json:"reviews"`
+		}
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body.AverageRating != 4 || body.ReviewCount != 1 || len(body.Reviews) != 1 {
+			t.Errorf("unexpected reviews %+v", body)
+		}
+	})
+
+	t.Run("should hide other users' reviews from editing", func(t *testing.T) {
+		rr := send(http.MethodPut, "/reviews/1", 2, types.ReviewPayload{Rating: 1})
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should send an edited review back to moderation", func(t *testing.T) {
+		rr := send(http.MethodPut, "/reviews/1", 1, types.ReviewPayload{Rating: 2, Body: "Broke after a week"})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if store.reviews[1].Status != types.ReviewPending || store.reviews[1].Rating != 2 {
+			t.Errorf("unexpected review %+v", store.reviews[1])
+		}
+	})
+
+	t.Run("should let admins delete any review", func(t *testing.T) {
+		rr := send(http.MethodDelete, "/reviews/1", 3, nil)
+		if rr.Code != http.StatusNoContent {
+			t.Errorf("expected status code %d but got %d", http.StatusNoContent, rr.Code)
+		}
+	})
+
+	t.Run("should answer 404 for unknown products", func(t *testing.T) {
+		rr := send(http.MethodGet, "/products/9/reviews", 0, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
+type mockReviewStore struct {
+	products map[int]bool
+	reviews  map[int]*types.Review
+	nextID   int
+}
+
+func (m *mockReviewStore) GetReview(id int) (*types.Review, error) {
+	r, ok := m.reviews[id]
+	if !ok {
+		return nil, ErrReviewNotFound
+	}
+	review := *r
+	return &review, nil
+}
+
+func (m *mockReviewStore) GetProductReviews(productID int, limit, offset int) ([]types.Review, *types.RatingSummary, error) {
+	if !m.products[productID] {
+		return nil, nil, ErrProductNotFound
+	}
+	reviews := []types.Review{}
+	summary := &types.RatingSummary{}
+	total := 0
+	for _, r := range m.reviews {
+		if r.ProductID == productID && r.Status == types.ReviewApproved {
+			reviews = append(reviews, *r)
+			total += r.Rating
+		}
+	}
+	if summary.ReviewCount = len(reviews); summary.ReviewCount > 0 {
+		summary.AverageRating = float64(total) / float64(summary.ReviewCount)
+	}
+	return reviews, summary, nil
+}
+
+func (m *mockReviewStore) GetReviewsByStatus(status string, limit, offset int) ([]types.Review, error) {
+	reviews := []types.Review{}
+	for _, r := range m.reviews {
+		if r.Status == status {
+			reviews = append(reviews, *r)
+		}
+	}
+	return reviews, nil
+}
+
+func (m *mockReviewStore) CreateReview(review types.Review) (int, error) {
+	if !m.products[review.ProductID] {
+		return 0, ErrProductNotFound
+	}
+	for _, r := range m.reviews {
+		if r.ProductID == review.ProductID && r.UserID == review.UserID {
+			return 0, ErrAlreadyReviewed
+		}
+	}
+	m.nextID++
+	review.ID, review.Status = m.nextID, types.ReviewPending
+	m.reviews[review.ID] = &review
+	return review.ID, nil
+}
+
+func (m *mockReviewStore) UpdateReview(review types.Review) error {
+	if _, ok := m.reviews[review.ID]; !ok {
+		return ErrReviewNotFound
+	}
+	review.Status = types.ReviewPending
+	m.reviews[review.ID] = &review
+	return nil
+}
+
+func (m *mockReviewStore) DeleteReview(id int) error {
+	if _, ok := m.reviews[id]; !ok {
+		return ErrReviewNotFound
+	}
+	delete(m.reviews, id)
+	return nil
+}
+
+func (m *mockReviewStore) SetReviewStatus(id int, status string) error {
+	r, ok := m.reviews[id]
+	if !ok {
+		return ErrReviewNotFound
+	}
+	r.Status = status
+	return nil
+}
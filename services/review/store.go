@@ -0,0 +1,192 @@
+package review
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	ErrReviewNotFound  = errors.New("review not found")
+	ErrProductNotFound = errors.New("product not found")
+	ErrAlreadyReviewed = errors.New("you have already reviewed this product")
+)
+
+// reviewColumns lists the columns read by scanRowIntoReview, in scan order.
+const reviewColumns = "id, productId, userId, rating, title, body, verified, status, created_at, updated_at"
+
+// verifiedPurchase is true when the customer received the product in a delivered order.
+// It takes the user ID and the product ID as arguments.
+const verifiedPurchase = `EXISTS(
+	SELECT 1 FROM orders o JOIN order_items oi ON oi.orderId = o.id
+	WHERE o.userId = ? AND oi.productId = ? AND o.status = 'delivered')`
+
+// mysqlDuplicateEntry is the MySQL error number for a unique key violation.
+const mysqlDuplicateEntry = 1062
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetReview(id int) (*types.Review, error) {
+	rows, err := s.db.Query("SELECT "+reviewColumns+" FROM reviews WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrReviewNotFound
+	}
+	return scanRowIntoReview(rows)
+}
+
+func (s *Store) GetProductReviews(productID int, limit, offset int) ([]types.Review, *types.RatingSummary, error) {
+	summary := new(types.RatingSummary)
+	var exists bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM products WHERE id = ?), COALESCE(ROUND(AVG(rating), 2), 0), COUNT(*)
+		FROM reviews WHERE productId = ? AND status = ?`,
+		productID, productID, types.ReviewApproved,
+	).Scan(&exists, &summary.AverageRating, &summary.ReviewCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, ErrProductNotFound
+	}
+
+	rows, err := s.db.Query(
+		"SELECT "+reviewColumns+" FROM reviews WHERE productId = ? AND status = ? ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?",
+		productID, types.ReviewApproved, limit, offset,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	reviews, err := scanRows(rows)
+	return reviews, summary, err
+}
+
+func (s *Store) GetReviewsByStatus(status string, limit, offset int) ([]types.Review, error) {
+	rows, err := s.db.Query(
+		"SELECT "+reviewColumns+" FROM reviews WHERE status = ? ORDER BY created_at, id LIMIT ? OFFSET ?",
+		status, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRows(rows)
+}
+
+func (s *Store) CreateReview(review types.Review) (int, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = ?)", review.ProductID).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, ErrProductNotFound
+	}
+
+	result, err := s.db.Exec(
+		"INSERT INTO reviews (productId, userId, rating, title, body, verified, status) VALUES (?, ?, ?, ?, ?, "+verifiedPurchase+", ?)",
+		review.ProductID, review.UserID, review.Rating, review.Title, review.Body, review.UserID, review.ProductID, types.ReviewPending,
+	)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return 0, ErrAlreadyReviewed
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *Store) UpdateReview(review types.Review) error {
+	result, err := s.db.Exec(
+		"UPDATE reviews SET rating = ?, title = ?, body = ?, verified = "+verifiedPurchase+", status = ? WHERE id = ?",
+		review.Rating, review.Title, review.Body, review.UserID, review.ProductID, types.ReviewPending, review.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return s.ensureReviewExists(result, review.ID)
+}
+
+func (s *Store) DeleteReview(id int) error {
+	result, err := s.db.Exec("DELETE FROM reviews WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrReviewNotFound
+	}
+	return nil
+}
+
+func (s *Store) SetReviewStatus(id int, status string) error {
+	result, err := s.db.Exec("UPDATE reviews SET status = ? WHERE id = ?", status, id)
+	if err != nil {
+		return err
+	}
+	return s.ensureReviewExists(result, id)
+}
+
+// ensureReviewExists tells an update that matched nothing apart from one that changed
+// nothing, since MySQL only counts changed rows as affected.
+func (s *Store) ensureReviewExists(result sql.Result, id int) error {
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM reviews WHERE id = ?)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrReviewNotFound
+	}
+	return nil
+}
+
+func scanRows(rows *sql.Rows) ([]types.Review, error) {
+	reviews := []types.Review{}
+	for rows.Next() {
+		r, err := scanRowIntoReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, *r)
+	}
+	return reviews, rows.Err()
+}
+
+func scanRowIntoReview(rows *sql.Rows) (*types.Review, error) {
+	r := new(types.Review)
+	err := rows.Scan(
+		&r.ID,
+		&r.ProductID,
+		&r.UserID,
+		&r.Rating,
+		&r.Title,
+		&r.Body,
+		&r.Verified,
+		&r.Status,
+		&r.CreatedAt,
+		&r.UpdatedAt,
+	)
+	return r, err
+}
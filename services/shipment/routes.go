@@ -0,0 +1,186 @@
+package shipment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store      types.ShipmentStore
+	orderStore types.OrderStore
+	notifier   types.Notifier
+	userStore  types.UserStore
+}
+
+func NewHandler(store types.ShipmentStore, orderStore types.OrderStore, notifier types.Notifier, userStore types.UserStore) *Handler {
+	return &Handler{store: store, orderStore: orderStore, notifier: notifier, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/orders/{id:[0-9]+}/shipments", auth.WithJWTAuth(h.handleGetShipments, h.userStore)).Methods(http.MethodGet)
+
+	router.HandleFunc("/admin/orders/{id:[0-9]+}/shipments", auth.WithAdminAuth(h.handleCreateShipment, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/shipments/{id:[0-9]+}/deliver", auth.WithAdminAuth(h.handleDeliverShipment, h.userStore)).Methods(http.MethodPost)
+}
+
+// RegisterSubscribers subscribes the handler to the domain events it reacts to.
+func (h *Handler) RegisterSubscribers(bus types.EventBus) {
+	bus.Subscribe(types.ShipmentCreatedEvent, "order_shipped_email", h.sendShipped)
+	bus.Subscribe(types.OrderStatusChangedEvent, "order_delivered_email", h.sendDelivered)
+}
+
+// handleGetShipments lets customers track the parcels of their orders.
+func (h *Handler) handleGetShipments(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	o, err := h.orderStore.GetOrder(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	if o.UserID != auth.GetUserIDFromContext(r.Context()) && !auth.IsAdmin(r.Context()) {
+		writeStoreError(w, order.ErrOrderNotFound)
+		return
+	}
+
+	shipments, err := h.store.GetOrderShipments(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, shipments)
+}
+
+// handleCreateShipment records a parcel handed to a carrier and tells the customer how to
+// track it. The first shipment of a processing order moves it to shipped.
+func (h *Handler) handleCreateShipment(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ShipmentPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	shipmentID, err := h.store.CreateShipment(id, payload, auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	sh, err := h.store.GetShipment(shipmentID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, sh)
+}
+
+// handleDeliverShipment records that a parcel arrived. The order moves to delivered once
+// all of it has arrived, which the customer is told about.
+func (h *Handler) handleDeliverShipment(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.store.DeliverShipment(id, auth.GetUserIDFromContext(r.Context())); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	sh, err := h.store.GetShipment(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, sh)
+}
+
+// sendShipped tells the customer how to track a parcel of their order once the shipment
+// is committed.
+func (h *Handler) sendShipped(ctx context.Context, event types.OutboxEvent) error {
+	var created types.ShipmentCreated
+	if err := json.Unmarshal(event.Payload, &created); err != nil {
+		return err
+	}
+	sh, err := h.store.GetShipment(created.ShipmentID)
+	if err != nil {
+		return err
+	}
+	o, customer, err := h.recipient(sh.OrderID)
+	if err != nil {
+		return err
+	}
+
+	return h.notifier.Notify(ctx, types.Notification{
+		Kind:       types.NotificationOrderShipped,
+		UserID:     o.UserID,
+		Recipients: []string{customer.Email},
+		Subject:    fmt.Sprintf("Your order #%d has shipped", o.ID),
+		Body:       fmt.Sprintf("Your order #%d is on its way with %s, tracking number %s.", o.ID, sh.Carrier, sh.TrackingCode),
+		Data:       map[string]any{"order": *o, "shipment": *sh, "user": *customer},
+	})
+}
+
+// sendDelivered tells the customer their order has arrived, which is once its last parcel
+// was delivered and the order moved to delivered.
+func (h *Handler) sendDelivered(ctx context.Context, event types.OutboxEvent) error {
+	var changed types.OrderStatusChanged
+	if err := json.Unmarshal(event.Payload, &changed); err != nil {
+		return err
+	}
+	if changed.ToStatus != types.OrderDelivered {
+		return nil
+	}
+	o, customer, err := h.recipient(changed.OrderID)
+	if err != nil {
+		return err
+	}
+
+	return h.notifier.Notify(ctx, types.Notification{
+		Kind:       types.NotificationOrderDelivered,
+		UserID:     o.UserID,
+		Recipients: []string{customer.Email},
+		Subject:    fmt.Sprintf("Your order #%d has been delivered", o.ID),
+		Body:       fmt.Sprintf("Your order #%d has been delivered.", o.ID),
+		Data:       map[string]any{"order": *o, "user": *customer},
+	})
+}
+
+// recipient returns an order and the customer who placed it.
+func (h *Handler) recipient(orderID int) (*types.Order, *types.User, error) {
+	o, err := h.orderStore.GetOrder(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	customer, err := h.userStore.GetUserById(o.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return o, customer, nil
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrShipmentNotFound), errors.Is(err, order.ErrOrderNotFound), errors.Is(err, order.ErrOrderItemNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrOrderNotShippable), errors.Is(err, ErrShipmentTooLarge), errors.Is(err, ErrNothingToShip),
+		errors.Is(err, ErrAlreadyDelivered), errors.Is(err, order.ErrInvalidTransition):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
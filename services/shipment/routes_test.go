@@ -0,0 +1,326 @@
+package shipment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestShipmentFlow(t *testing.T) {
+	orderStore := &mockOrderStore{orders: map[int]*types.Order{
+		1: {ID: 1, UserID: 1, Status: types.OrderProcessing, Items: []types.OrderItem{
+			{ID: 1, OrderID: 1, ProductID: 1, ProductName: "Mug", Quantity: 2},
+			{ID: 2, OrderID: 1, ProductID: 2, ProductName: "Tea", Quantity: 1},
+		}},
+		2: {ID: 2, UserID: 1, Status: types.OrderPending, Items: []types.OrderItem{
+			{ID: 3, OrderID: 2, ProductID: 1, ProductName: "Mug", Quantity: 1},
+		}},
+	}}
+	store := &mockShipmentStore{orders: orderStore}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer, Email: "jane@example.com"},
+		2: {ID: 2, Role: types.RoleCustomer},
+		3: {ID: 3, Role: types.RoleAdmin},
+	}}
+	notifier := &recordingNotifier{}
+
+	handler := NewHandler(store, orderStore, notifier, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	bus := &mockBus{}
+	handler.RegisterSubscribers(bus)
+
+	// relay publishes the events recorded by the store since the last call
+	relay := func() {
+		bus.publish(t, store.events...)
+		store.events = nil
+	}
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	payload := types.ShipmentPayload{
+		Carrier:      "UPS",
+		TrackingCode: "1Z999AA10123456784",
+		TrackingURL:  "https://www.ups.com/track?tracknum=1Z999AA10123456784",
+		Items:        []types.ShipmentItemPayload{{OrderItemID: 1, Quantity: 2}},
+	}
+
+	t.Run("should let only admins record shipments", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/admin/orders/1/shipments", 1, payload); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should reject invalid shipments", func(t *testing.T) {
+		invalid := payload
+		invalid.TrackingCode = ""
+		if rr := send(http.MethodPost, "/admin/orders/1/shipments", 3, invalid); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+		if rr := send(http.MethodPost, "/admin/orders/2/shipments", 3, payload); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d for an unpaid order but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should ship part of an order and mark it shipped", func(t *testing.T) {
+		rr := send(http.MethodPost, "/admin/orders/1/shipments", 3, payload)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var shipment types.Shipment
+		json.NewDecoder(rr.Body).Decode(&shipment)
+		if shipment.Carrier != "UPS" || len(shipment.Items) != 1 || shipment.Items[0].Quantity != 2 || shipment.DeliveredAt != nil {
+			t.Errorf("unexpected shipment %+v", shipment)
+		}
+		if status := orderStore.orders[1].Status; status != types.OrderShipped {
+			t.Errorf("expected the order to be shipped but got %s", status)
+		}
+		relay()
+		if len(notifier.sent) != 1 || notifier.sent[0].Kind != types.NotificationOrderShipped || notifier.sent[0].Recipients[0] != "jane@example.com" {
+			t.Errorf("expected the customer to be told about the shipment but got %+v", notifier.sent)
+		}
+
+		if rr := send(http.MethodPost, "/admin/orders/1/shipments", 3, payload); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d when shipping a unit twice but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should deliver the order once every shipment arrived", func(t *testing.T) {
+		rr := send(http.MethodPost, "/admin/shipments/1/deliver", 3, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if status := orderStore.orders[1].Status; status != types.OrderShipped {
+			t.Errorf("expected the order to stay shipped with units left to ship but got %s", status)
+		}
+		relay()
+		if len(notifier.sent) != 1 {
+			t.Errorf("expected no delivery email before the whole order arrived but got %+v", notifier.sent)
+		}
+
+		rest := types.ShipmentPayload{Carrier: "DHL", TrackingCode: "JD014600006281230704"}
+		if rr := send(http.MethodPost, "/admin/orders/1/shipments", 3, rest); rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		if rr := send(http.MethodPost, "/admin/shipments/2/deliver", 3, nil); rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if status := orderStore.orders[1].Status; status != types.OrderDelivered {
+			t.Errorf("expected the order to be delivered but got %s", status)
+		}
+		relay()
+		if len(notifier.sent) != 3 || notifier.sent[1].Kind != types.NotificationOrderShipped {
+			t.Errorf("expected an email for the second shipment but got %+v", notifier.sent)
+		}
+		if last := notifier.sent[len(notifier.sent)-1]; last.Kind != types.NotificationOrderDelivered || last.UserID != 1 {
+			t.Errorf("expected a delivery email but got %+v", last)
+		}
+
+		if rr := send(http.MethodPost, "/admin/shipments/2/deliver", 3, nil); rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d when delivering twice but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should let customers track their own orders", func(t *testing.T) {
+		if rr := send(http.MethodGet, "/orders/1/shipments", 2, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+
+		rr := send(http.MethodGet, "/orders/1/shipments", 1, nil)
+		var shipments []types.Shipment
+		json.NewDecoder(rr.Body).Decode(&shipments)
+		if rr.Code != http.StatusOK || len(shipments) != 2 || shipments[0].TrackingURL == "" || shipments[1].Items[0].ProductName != "Tea" {
+			t.Errorf("unexpected shipments %+v", shipments)
+		}
+	})
+}
+
+type recordingNotifier struct {
+	sent []types.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification types.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+// mockBus is an EventBus whose events are published by the test instead of a relay.
+type mockBus struct {
+	handlers map[string][]types.EventHandler
+}
+
+func (b *mockBus) Subscribe(eventType, name string, handler types.EventHandler) {
+	if b.handlers == nil {
+		b.handlers = map[string][]types.EventHandler{}
+	}
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// publish hands the events to their subscribers, failing the test if one of them fails.
+func (b *mockBus) publish(t *testing.T, events ...types.DomainEvent) {
+	for _, e := range events {
+		payload, _ := json.Marshal(e)
+		for _, handler := range b.handlers[e.EventType()] {
+			if err := handler(context.Background(), types.OutboxEvent{Type: e.EventType(), Payload: payload}); err != nil {
+				t.Fatalf("failed to handle %s: %v", e.EventType(), err)
+			}
+		}
+	}
+}
+
+// mockShipmentStore plans shipments like Store and moves the orders of mockOrderStore,
+// recording the events Store would.
+type mockShipmentStore struct {
+	orders    *mockOrderStore
+	shipments []types.Shipment
+	events    []types.DomainEvent
+}
+
+func (m *mockShipmentStore) CreateShipment(orderID int, payload types.ShipmentPayload, actorID int) (int, error) {
+	o, ok := m.orders.orders[orderID]
+	if !ok {
+		return 0, order.ErrOrderNotFound
+	}
+	if o.Status != types.OrderProcessing && o.Status != types.OrderShipped {
+		return 0, ErrOrderNotShippable
+	}
+	lines, err := plan(o.Items, payload.Items)
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range lines {
+		for i := range o.Items {
+			if o.Items[i].ID == line.OrderItemID {
+				o.Items[i].Shipped += line.Quantity
+			}
+		}
+	}
+
+	id := len(m.shipments) + 1
+	m.shipments = append(m.shipments, types.Shipment{
+		ID:           id,
+		OrderID:      orderID,
+		Carrier:      payload.Carrier,
+		TrackingCode: payload.TrackingCode,
+		TrackingURL:  payload.TrackingURL,
+		ActorID:      &actorID,
+		Items:        lines,
+		ShippedAt:    time.Now(),
+	})
+	if o.Status == types.OrderProcessing {
+		m.events = append(m.events, types.OrderStatusChanged{OrderID: orderID, UserID: o.UserID, FromStatus: o.Status, ToStatus: types.OrderShipped})
+		o.Status = types.OrderShipped
+	}
+	m.events = append(m.events, types.ShipmentCreated{ShipmentID: id, OrderID: orderID})
+	return id, nil
+}
+
+func (m *mockShipmentStore) GetShipment(id int) (*types.Shipment, error) {
+	if id < 1 || id > len(m.shipments) {
+		return nil, ErrShipmentNotFound
+	}
+	sh := m.shipments[id-1]
+	return &sh, nil
+}
+
+func (m *mockShipmentStore) GetOrderShipments(orderID int) ([]types.Shipment, error) {
+	shipments := []types.Shipment{}
+	for _, sh := range m.shipments {
+		if sh.OrderID == orderID {
+			shipments = append(shipments, sh)
+		}
+	}
+	return shipments, nil
+}
+
+func (m *mockShipmentStore) DeliverShipment(id int, actorID int) error {
+	if id < 1 || id > len(m.shipments) {
+		return ErrShipmentNotFound
+	}
+	sh := &m.shipments[id-1]
+	if sh.DeliveredAt != nil {
+		return ErrAlreadyDelivered
+	}
+	now := time.Now()
+	sh.DeliveredAt = &now
+
+	o := m.orders.orders[sh.OrderID]
+	for _, other := range m.shipments {
+		if other.OrderID == o.ID && other.DeliveredAt == nil {
+			return nil
+		}
+	}
+	for _, item := range o.Items {
+		if unshipped(item) > 0 {
+			return nil
+		}
+	}
+	m.events = append(m.events, types.OrderStatusChanged{OrderID: o.ID, UserID: o.UserID, FromStatus: o.Status, ToStatus: types.OrderDelivered})
+	o.Status = types.OrderDelivered
+	return nil
+}
+
+type mockOrderStore struct {
+	orders map[int]*types.Order
+}
+
+func (m *mockOrderStore) CreateOrder(o types.Order, items []types.OrderItem, reservationID int) (int, error) {
+	return 0, nil
+}
+
+func (m *mockOrderStore) GetOrders(filter types.OrderFilter, limit, offset int) ([]types.Order, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetOrder(id int) (*types.Order, error) {
+	o, ok := m.orders[id]
+	if !ok {
+		return nil, order.ErrOrderNotFound
+	}
+	c := *o
+	return &c, nil
+}
+
+func (m *mockOrderStore) TransitionOrder(id int, to string, actorID int, note string) (*types.OrderStatusChange, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetOrderStatusHistory(orderID int) ([]types.OrderStatusChange, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) CancelOrder(id int, actorID int, note string) (*types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) RefundOrder(id int, payload types.RefundPayload, actorID int) (*types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetRefunds(orderID int) ([]types.Refund, error) {
+	return nil, nil
+}
+
+func (m *mockOrderStore) GetRefund(id int) (*types.Refund, error) {
+	return nil, order.ErrRefundNotFound
+}
+
+func (m *mockOrderStore) SettleRefund(id int, status string, paymentID *int) error {
+	return nil
+}
@@ -0,0 +1,317 @@
+package shipment
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/code-farms/go-backend/services/inventory"
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/services/outbox"
+	"github.com/code-farms/go-backend/types"
+)
+
+var (
+	ErrShipmentNotFound  = errors.New("shipment not found")
+	ErrOrderNotShippable = errors.New("only processing or shipped orders can be shipped")
+	ErrShipmentTooLarge  = errors.New("shipment exceeds the units not shipped yet")
+	ErrNothingToShip     = errors.New("every unit of the order has been shipped or refunded")
+	ErrAlreadyDelivered  = errors.New("shipment has already been delivered")
+)
+
+// shipmentColumns lists the columns read by scanRowIntoShipment, in scan order.
+const shipmentColumns = "id, orderId, carrier, trackingCode, trackingUrl, actorId, shippedAt, deliveredAt, created_at"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// CreateShipment locks the order and its items so two shipments recorded at the same time
+// can't ship the same units twice.
+func (s *Store) CreateShipment(orderID int, payload types.ShipmentPayload, actorID int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, order.ErrOrderNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status != types.OrderProcessing && status != types.OrderShipped {
+		return 0, ErrOrderNotShippable
+	}
+
+	items, err := lockItems(tx, orderID)
+	if err != nil {
+		return 0, err
+	}
+	lines, err := plan(items, payload.Items)
+	if err != nil {
+		return 0, err
+	}
+
+	shippedAt := time.Now()
+	if payload.ShippedAt != nil {
+		shippedAt = *payload.ShippedAt
+	}
+	result, err := tx.Exec(
+		"INSERT INTO shipments (orderId, carrier, trackingCode, trackingUrl, actorId, shippedAt) VALUES (?, ?, ?, ?, ?, ?)",
+		orderID, payload.Carrier, payload.TrackingCode, payload.TrackingURL, inventory.Actor(actorID), shippedAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert shipment: %w", err)
+	}
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	id := int(lastID)
+
+	for _, line := range lines {
+		if _, err := tx.Exec(
+			"INSERT INTO shipment_items (shipmentId, orderItemId, quantity) VALUES (?, ?, ?)",
+			id, line.OrderItemID, line.Quantity,
+		); err != nil {
+			return 0, fmt.Errorf("failed to insert shipment item: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE order_items SET shipped = shipped + ? WHERE id = ?", line.Quantity, line.OrderItemID); err != nil {
+			return 0, err
+		}
+	}
+
+	if status == types.OrderProcessing {
+		note := fmt.Sprintf("shipment %d with %s, tracking code %s", id, payload.Carrier, payload.TrackingCode)
+		if _, err := order.Transition(tx, orderID, types.OrderShipped, actorID, note); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := outbox.Record(tx, types.ShipmentCreated{ShipmentID: id, OrderID: orderID}); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (s *Store) GetShipment(id int) (*types.Shipment, error) {
+	shipments, err := s.queryShipments("id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(shipments) == 0 {
+		return nil, ErrShipmentNotFound
+	}
+	return &shipments[0], nil
+}
+
+func (s *Store) GetOrderShipments(orderID int) ([]types.Shipment, error) {
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM orders WHERE id = ?)", orderID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, order.ErrOrderNotFound
+	}
+	return s.queryShipments("orderId = ?", orderID)
+}
+
+// DeliverShipment locks the order before the shipment, in the same order as
+// CreateShipment, so the last two parcels arriving at once deliver the order exactly once.
+func (s *Store) DeliverShipment(id int, actorID int) error {
+	var orderID int
+	err := s.db.QueryRow("SELECT orderId FROM shipments WHERE id = ?", id).Scan(&orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrShipmentNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow("SELECT status FROM orders WHERE id = ? FOR UPDATE", orderID).Scan(&status); err != nil {
+		return err
+	}
+	var deliveredAt sql.NullTime
+	if err := tx.QueryRow("SELECT deliveredAt FROM shipments WHERE id = ? FOR UPDATE", id).Scan(&deliveredAt); err != nil {
+		return err
+	}
+	if deliveredAt.Valid {
+		return ErrAlreadyDelivered
+	}
+	if _, err := tx.Exec("UPDATE shipments SET deliveredAt = CURRENT_TIMESTAMP WHERE id = ?", id); err != nil {
+		return err
+	}
+
+	if status == types.OrderShipped {
+		var pending bool
+		err := tx.QueryRow(
+			"SELECT EXISTS(SELECT 1 FROM shipments WHERE orderId = ? AND deliveredAt IS NULL) OR EXISTS(SELECT 1 FROM order_items WHERE orderId = ? AND quantity - refunded - shipped > 0)",
+			orderID, orderID,
+		).Scan(&pending)
+		if err != nil {
+			return err
+		}
+		if !pending {
+			if _, err := order.Transition(tx, orderID, types.OrderDelivered, actorID, "every shipment delivered"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// queryShipments returns the shipments matching where, oldest first, with their items.
+func (s *Store) queryShipments(where string, arg any) ([]types.Shipment, error) {
+	rows, err := s.db.Query("SELECT "+shipmentColumns+" FROM shipments WHERE "+where+" ORDER BY id", arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shipments := []types.Shipment{}
+	index := map[int]int{}
+	for rows.Next() {
+		sh, err := scanRowIntoShipment(rows)
+		if err != nil {
+			return nil, err
+		}
+		sh.Items = []types.ShipmentItem{}
+		index[sh.ID] = len(shipments)
+		shipments = append(shipments, *sh)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	itemRows, err := s.db.Query(
+		"SELECT si.id, si.shipmentId, si.orderItemId, oi.productId, oi.productName, si.quantity FROM shipment_items si "+
+			"JOIN order_items oi ON oi.id = si.orderItemId WHERE si.shipmentId IN (SELECT id FROM shipments WHERE "+where+") ORDER BY si.id",
+		arg,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer itemRows.Close()
+
+	for itemRows.Next() {
+		var item types.ShipmentItem
+		if err := itemRows.Scan(&item.ID, &item.ShipmentID, &item.OrderItemID, &item.ProductID, &item.ProductName, &item.Quantity); err != nil {
+			return nil, err
+		}
+		if i, ok := index[item.ShipmentID]; ok {
+			shipments[i].Items = append(shipments[i].Items, item)
+		}
+	}
+	return shipments, itemRows.Err()
+}
+
+// lockItems reads the items of an order with what is left to ship and locks them until
+// tx ends.
+func lockItems(tx *sql.Tx, orderID int) ([]types.OrderItem, error) {
+	rows, err := tx.Query(
+		"SELECT id, orderId, productId, productName, quantity, refunded, shipped FROM order_items WHERE orderId = ? ORDER BY id FOR UPDATE",
+		orderID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []types.OrderItem{}
+	for rows.Next() {
+		var item types.OrderItem
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.ProductName, &item.Quantity, &item.Refunded, &item.Shipped); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// plan picks the units of a shipment, adding up units of the same item requested more than
+// once. Without requested items, every unit left is shipped. It fails with
+// ErrNothingToShip rather than record an empty parcel.
+func plan(items []types.OrderItem, requested []types.ShipmentItemPayload) ([]types.ShipmentItem, error) {
+	byID := make(map[int]types.OrderItem, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	if len(requested) == 0 {
+		for _, item := range items {
+			if left := unshipped(item); left > 0 {
+				requested = append(requested, types.ShipmentItemPayload{OrderItemID: item.ID, Quantity: left})
+			}
+		}
+		if len(requested) == 0 {
+			return nil, ErrNothingToShip
+		}
+	}
+
+	lines := []types.ShipmentItem{}
+	index := map[int]int{}
+	for _, r := range requested {
+		item, ok := byID[r.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", order.ErrOrderItemNotFound, r.OrderItemID)
+		}
+		i, ok := index[item.ID]
+		if !ok {
+			i = len(lines)
+			index[item.ID] = i
+			lines = append(lines, types.ShipmentItem{OrderItemID: item.ID, ProductID: item.ProductID, ProductName: item.ProductName})
+		}
+		lines[i].Quantity += r.Quantity
+		if left := unshipped(item); lines[i].Quantity > left {
+			return nil, fmt.Errorf("%w: only %d units of item %d can be shipped", ErrShipmentTooLarge, left, item.ID)
+		}
+	}
+	return lines, nil
+}
+
+// unshipped returns the units of an item neither shipped nor refunded.
+func unshipped(item types.OrderItem) int {
+	return max(item.Quantity-item.Refunded-item.Shipped, 0)
+}
+
+func scanRowIntoShipment(rows *sql.Rows) (*types.Shipment, error) {
+	sh := new(types.Shipment)
+	var actorID sql.NullInt64
+	var deliveredAt sql.NullTime
+	err := rows.Scan(
+		&sh.ID,
+		&sh.OrderID,
+		&sh.Carrier,
+		&sh.TrackingCode,
+		&sh.TrackingURL,
+		&actorID,
+		&sh.ShippedAt,
+		&deliveredAt,
+		&sh.CreatedAt,
+	)
+	if actorID.Valid {
+		sh.ActorID = inventory.Actor(int(actorID.Int64))
+	}
+	if deliveredAt.Valid {
+		sh.DeliveredAt = &deliveredAt.Time
+	}
+	return sh, err
+}
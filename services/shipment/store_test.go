@@ -0,0 +1,61 @@
+package shipment
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/code-farms/go-backend/services/order"
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestPlan(t *testing.T) {
+	items := []types.OrderItem{
+		{ID: 1, ProductID: 1, ProductName: "Mug", Quantity: 3, Shipped: 1},
+		{ID: 2, ProductID: 2, ProductName: "Tea", Quantity: 2, Refunded: 1},
+		{ID: 3, ProductID: 3, ProductName: "Spoon", Quantity: 1, Shipped: 1},
+	}
+
+	t.Run("should ship every unit left without requested items", func(t *testing.T) {
+		lines, err := plan(items, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 2 || lines[0].OrderItemID != 1 || lines[0].Quantity != 2 || lines[1].OrderItemID != 2 || lines[1].Quantity != 1 {
+			t.Errorf("unexpected lines %+v", lines)
+		}
+	})
+
+	t.Run("should add up units of the same item", func(t *testing.T) {
+		lines, err := plan(items, []types.ShipmentItemPayload{{OrderItemID: 1, Quantity: 1}, {OrderItemID: 1, Quantity: 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(lines) != 1 || lines[0].Quantity != 2 || lines[0].ProductName != "Mug" {
+			t.Errorf("unexpected lines %+v", lines)
+		}
+	})
+
+	t.Run("should not ship units shipped or refunded", func(t *testing.T) {
+		for _, requested := range [][]types.ShipmentItemPayload{
+			{{OrderItemID: 1, Quantity: 2}, {OrderItemID: 1, Quantity: 1}},
+			{{OrderItemID: 2, Quantity: 2}},
+			{{OrderItemID: 3, Quantity: 1}},
+		} {
+			if _, err := plan(items, requested); !errors.Is(err, ErrShipmentTooLarge) {
+				t.Errorf("expected ErrShipmentTooLarge for %+v but got %v", requested, err)
+			}
+		}
+	})
+
+	t.Run("should reject items of other orders", func(t *testing.T) {
+		if _, err := plan(items, []types.ShipmentItemPayload{{OrderItemID: 9, Quantity: 1}}); !errors.Is(err, order.ErrOrderItemNotFound) {
+			t.Errorf("expected ErrOrderItemNotFound but got %v", err)
+		}
+	})
+
+	t.Run("should not record empty shipments", func(t *testing.T) {
+		if _, err := plan(items[2:], nil); !errors.Is(err, ErrNothingToShip) {
+			t.Errorf("expected ErrNothingToShip but got %v", err)
+		}
+	})
+}
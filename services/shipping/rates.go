@@ -0,0 +1,87 @@
+package shipping
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+)
+
+var (
+	ErrProductNotFound     = errors.New("product not found")
+	ErrShippingUnavailable = errors.New("shipping method is not available for this order")
+)
+
+// volumetricDivisor turns a volume in cubic millimetres into a weight in grams. It is the
+// 5000 cm³ per kilogram most carriers bill bulky parcels by.
+const volumetricDivisor = 5000
+
+// Weigh returns the chargeable weight in grams of the requested units. Every product counts
+// with its actual or its volumetric weight, whichever is higher, so light but bulky
+// products are not shipped below cost.
+func Weigh(items []types.CheckoutItem, products map[int]types.Product) (int, error) {
+	weight := 0
+	for _, item := range items {
+		p, ok := products[item.ProductID]
+		if !ok {
+			return 0, fmt.Errorf("%w: product %d", ErrProductNotFound, item.ProductID)
+		}
+		volumetric := p.Length * p.Width * p.Height / volumetricDivisor
+		weight += max(p.Weight, volumetric) * item.Quantity
+	}
+	return weight, nil
+}
+
+// Quote prices a parcel with every method that can carry it, cheapest and then fastest
+// first.
+func Quote(methods []types.ShippingMethod, parcel types.Parcel) []types.ShippingRate {
+	rates := []types.ShippingRate{}
+	for _, m := range methods {
+		if !m.Active || (m.MaxWeight != nil && parcel.Weight > *m.MaxWeight) {
+			continue
+		}
+		rates = append(rates, types.ShippingRate{
+			MethodID: m.ID,
+			Name:     m.Name,
+			Cost:     utils.FromCents(cost(m, parcel)),
+			MinDays:  m.MinDays,
+			MaxDays:  m.MaxDays,
+		})
+	}
+	slices.SortStableFunc(rates, func(a, b types.ShippingRate) int {
+		return cmp.Or(
+			cmp.Compare(utils.ToCents(a.Cost), utils.ToCents(b.Cost)),
+			cmp.Compare(a.MaxDays, b.MaxDays),
+			cmp.Compare(a.MethodID, b.MethodID),
+		)
+	})
+	return rates
+}
+
+// QuoteMethod prices a parcel with one method, failing with ErrShippingUnavailable if the
+// method doesn't ship to the country or can't carry the parcel.
+func QuoteMethod(methods []types.ShippingMethod, methodID int, parcel types.Parcel) (*types.ShippingRate, error) {
+	for _, rate := range Quote(methods, parcel) {
+		if rate.MethodID == methodID {
+			return &rate, nil
+		}
+	}
+	return nil, ErrShippingUnavailable
+}
+
+// cost returns the price of a parcel in cents. Weight-based methods charge every started
+// kilogram.
+func cost(m types.ShippingMethod, parcel types.Parcel) int64 {
+	if m.FreeOver != nil && utils.ToCents(parcel.Value) >= utils.ToCents(*m.FreeOver) {
+		return 0
+	}
+	cents := utils.ToCents(m.Price)
+	if m.Type == types.ShippingWeight {
+		kilograms := int64((parcel.Weight + 999) / 1000)
+		cents += utils.ToCents(m.PricePerKg) * kilograms
+	}
+	return cents
+}
@@ -0,0 +1,84 @@
+package shipping
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestWeigh(t *testing.T) {
+	products := map[int]types.Product{
+		1: {ID: 1, Weight: 500},
+		2: {ID: 2, Weight: 200, Length: 400, Width: 300, Height: 200},
+	}
+
+	weight, err := Weigh([]types.CheckoutItem{{ProductID: 1, Quantity: 2}, {ProductID: 2, Quantity: 1}}, products)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weight != 5800 {
+		t.Errorf("expected the bulky product to count with 4800g but got a total of %dg", weight)
+	}
+
+	if _, err := Weigh([]types.CheckoutItem{{ProductID: 9, Quantity: 1}}, products); !errors.Is(err, ErrProductNotFound) {
+		t.Errorf("expected ErrProductNotFound but got %v", err)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	freeOver, maxWeight := 50.0, 2000
+	methods := []types.ShippingMethod{
+		{ID: 1, Name: "Standard", Type: types.ShippingWeight, Price: 3, PricePerKg: 1.25, FreeOver: &freeOver, MinDays: 3, MaxDays: 5, Active: true},
+		{ID: 2, Name: "Express", Type: types.ShippingFlat, Price: 9.99, MinDays: 1, MaxDays: 2, Active: true},
+		{ID: 3, Name: "Letter", Type: types.ShippingFlat, Price: 1.5, MaxWeight: &maxWeight, MinDays: 3, MaxDays: 7, Active: true},
+		{ID: 4, Name: "Courier", Type: types.ShippingFlat, Price: 9.99, MinDays: 1, MaxDays: 1, Active: true},
+		{ID: 5, Name: "Retired", Type: types.ShippingFlat, Price: 1, Active: false},
+	}
+
+	tests := map[string]struct {
+		parcel types.Parcel
+		want   []types.ShippingRate
+	}{
+		"should charge every started kilogram": {
+			parcel: types.Parcel{Weight: 2100, Value: 20},
+			want: []types.ShippingRate{
+				{MethodID: 1, Name: "Standard", Cost: 6.75, MinDays: 3, MaxDays: 5},
+				{MethodID: 4, Name: "Courier", Cost: 9.99, MinDays: 1, MaxDays: 1},
+				{MethodID: 2, Name: "Express", Cost: 9.99, MinDays: 1, MaxDays: 2},
+			},
+		},
+		"should ship for free over the threshold": {
+			parcel: types.Parcel{Weight: 800, Value: 50},
+			want: []types.ShippingRate{
+				{MethodID: 1, Name: "Standard", Cost: 0, MinDays: 3, MaxDays: 5},
+				{MethodID: 3, Name: "Letter", Cost: 1.5, MinDays: 3, MaxDays: 7},
+				{MethodID: 4, Name: "Courier", Cost: 9.99, MinDays: 1, MaxDays: 1},
+				{MethodID: 2, Name: "Express", Cost: 9.99, MinDays: 1, MaxDays: 2},
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Quote(methods, tt.parcel)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %+v but got %+v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("rate %d: expected %+v but got %+v", i, tt.want[i], got[i])
+				}
+			}
+		})
+	}
+
+	t.Run("should not quote methods that cannot carry the parcel", func(t *testing.T) {
+		if _, err := QuoteMethod(methods, 3, types.Parcel{Weight: 2001}); !errors.Is(err, ErrShippingUnavailable) {
+			t.Errorf("expected ErrShippingUnavailable but got %v", err)
+		}
+		if _, err := QuoteMethod(methods, 5, types.Parcel{Weight: 100}); !errors.Is(err, ErrShippingUnavailable) {
+			t.Errorf("expected ErrShippingUnavailable for an inactive method but got %v", err)
+		}
+	})
+}
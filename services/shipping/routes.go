@@ -0,0 +1,250 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+// maxQuoteItems limits the products of one rate request, like a checkout.
+const maxQuoteItems = 100
+
+type Handler struct {
+	store        types.ShippingStore
+	productStore types.ProductStore
+	userStore    types.UserStore
+}
+
+func NewHandler(store types.ShippingStore, productStore types.ProductStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, productStore: productStore, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/shipping/rates", h.handleGetRates).Methods(http.MethodGet)
+
+	router.HandleFunc("/admin/shipping/zones", auth.WithAdminAuth(h.handleGetZones, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/shipping/zones", auth.WithAdminAuth(h.handleCreateZone, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/shipping/zones/{id:[0-9]+}", auth.WithAdminAuth(h.handleUpdateZone, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/admin/shipping/methods", auth.WithAdminAuth(h.handleGetMethods, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/shipping/methods", auth.WithAdminAuth(h.handleCreateMethod, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/shipping/methods/{id:[0-9]+}", auth.WithAdminAuth(h.handleUpdateMethod, h.userStore)).Methods(http.MethodPut)
+}
+
+// handleGetRates quotes the shipping methods available for products delivered to a
+// country, e.g. /shipping/rates?country=US&items=1:2,7:1 for two units of product 1 and
+// one of product 7. Prices are taken from the catalog before any promotion.
+func (h *Handler) handleGetRates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	country := strings.ToUpper(strings.TrimSpace(query.Get("country")))
+	if err := utils.Validate.Var(country, "required,len=2,alpha"); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid country %q", query.Get("country")))
+		return
+	}
+	items, err := parseItems(query.Get("items"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.ProductID
+	}
+	products, err := h.productStore.GetProductsByID(ids)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	byID := make(map[int]types.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	weight, err := Weigh(items, byID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	var value int64
+	for _, item := range items {
+		value += utils.ToCents(byID[item.ProductID].Price) * int64(item.Quantity)
+	}
+
+	methods, err := h.store.GetShippingMethodsForCountry(country)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, Quote(methods, types.Parcel{Weight: weight, Value: utils.FromCents(value)}))
+}
+
+func (h *Handler) handleGetZones(w http.ResponseWriter, r *http.Request) {
+	zones, err := h.store.GetShippingZones()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, zones)
+}
+
+func (h *Handler) handleCreateZone(w http.ResponseWriter, r *http.Request) {
+	var payload types.ShippingZonePayload
+	if !parsePayload(w, r, &payload) {
+		return
+	}
+
+	id, err := h.store.CreateShippingZone(payload)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	zone, err := h.store.GetShippingZone(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, zone)
+}
+
+func (h *Handler) handleUpdateZone(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ShippingZonePayload
+	if !parsePayload(w, r, &payload) {
+		return
+	}
+
+	if err := h.store.UpdateShippingZone(id, payload); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	zone, err := h.store.GetShippingZone(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, zone)
+}
+
+func (h *Handler) handleGetMethods(w http.ResponseWriter, r *http.Request) {
+	methods, err := h.store.GetShippingMethods()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, methods)
+}
+
+func (h *Handler) handleCreateMethod(w http.ResponseWriter, r *http.Request) {
+	var payload types.ShippingMethodPayload
+	if !parsePayload(w, r, &payload) {
+		return
+	}
+
+	id, err := h.store.CreateShippingMethod(payload)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	method, err := h.store.GetShippingMethod(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, method)
+}
+
+func (h *Handler) handleUpdateMethod(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.ShippingMethodPayload
+	if !parsePayload(w, r, &payload) {
+		return
+	}
+
+	if err := h.store.UpdateShippingMethod(id, payload); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	method, err := h.store.GetShippingMethod(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, method)
+}
+
+// parseItems reads a comma-separated list of productId:quantity pairs. Products listed
+// more than once are added up.
+func parseItems(raw string) ([]types.CheckoutItem, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("items are required")
+	}
+
+	items := []types.CheckoutItem{}
+	index := map[int]int{}
+	for _, pair := range strings.Split(raw, ",") {
+		id, quantity, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			quantity = "1"
+		}
+		item := types.CheckoutItem{}
+		var err error
+		if item.ProductID, err = strconv.Atoi(id); err != nil || item.ProductID <= 0 {
+			return nil, fmt.Errorf("invalid product ID %q", id)
+		}
+		if item.Quantity, err = strconv.Atoi(quantity); err != nil || item.Quantity <= 0 || item.Quantity > 1000 {
+			return nil, fmt.Errorf("invalid quantity %q for product %d", quantity, item.ProductID)
+		}
+
+		if i, ok := index[item.ProductID]; ok {
+			items[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(items)
+		items = append(items, item)
+	}
+	if len(items) > maxQuoteItems {
+		return nil, fmt.Errorf("at most %d products can be quoted at once", maxQuoteItems)
+	}
+	return items, nil
+}
+
+// parsePayload reads and validates a JSON payload into v, writing the error response and
+// returning false if it is invalid.
+func parsePayload(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := utils.ParseJSON(r, v); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return false
+	}
+	if err := utils.Validate.Struct(v); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrZoneNotFound), errors.Is(err, ErrMethodNotFound), errors.Is(err, ErrProductNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrCountryTaken):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,266 @@
+package shipping
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestShippingRoutes(t *testing.T) {
+	store := &mockShippingStore{}
+	productStore := &mockProductStore{products: map[int]*types.Product{
+		1: {ID: 1, Price: 12.5, Weight: 700},
+		2: {ID: 2, Price: 30, Weight: 300},
+	}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleAdmin},
+	}}
+
+	handler := NewHandler(store, productStore, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if userID != 0 {
+			req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	zone := types.ShippingZonePayload{Name: "North America", Countries: []string{"us", "CA"}}
+	freeOver := 50.0
+	method := types.ShippingMethodPayload{ZoneID: 1, Name: "Standard", Type: types.ShippingWeight, Price: 4, PricePerKg: 2, FreeOver: &freeOver, MinDays: 2, MaxDays: 4}
+
+	t.Run("should let only admins manage zones and methods", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/admin/shipping/zones", 1, zone); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+		if rr := send(http.MethodPost, "/admin/shipping/methods", 1, method); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should create zones and methods", func(t *testing.T) {
+		rr := send(http.MethodPost, "/admin/shipping/zones", 2, zone)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		rr = send(http.MethodPost, "/admin/shipping/methods", 2, method)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var created types.ShippingMethod
+		json.NewDecoder(rr.Body).Decode(&created)
+		if !created.Active || created.Name != "Standard" {
+			t.Errorf("expected an active method but got %+v", created)
+		}
+	})
+
+	t.Run("should reject invalid methods", func(t *testing.T) {
+		for _, p := range []types.ShippingMethodPayload{
+			{ZoneID: 1, Name: "Standard", Type: "pigeon"},
+			{ZoneID: 1, Name: "Standard", Type: types.ShippingFlat, MinDays: 5, MaxDays: 2},
+			{ZoneID: 1, Type: types.ShippingFlat},
+		} {
+			if rr := send(http.MethodPost, "/admin/shipping/methods", 2, p); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %d for %+v but got %d", http.StatusBadRequest, p, rr.Code)
+			}
+		}
+		other := method
+		other.ZoneID = 9
+		if rr := send(http.MethodPost, "/admin/shipping/methods", 2, other); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d for an unknown zone but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should quote the methods of the zone of a country", func(t *testing.T) {
+		rr := send(http.MethodGet, "/shipping/rates?country=us&items=1:1,1:1", 0, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		var rates []types.ShippingRate
+		json.NewDecoder(rr.Body).Decode(&rates)
+		if len(rates) != 1 || rates[0].Cost != 8 {
+			t.Errorf("expected one rate of 8.00 but got %+v", rates)
+		}
+
+		rr = send(http.MethodGet, "/shipping/rates?country=US&items=2:2", 0, nil)
+		json.NewDecoder(rr.Body).Decode(&rates)
+		if len(rates) != 1 || rates[0].Cost != 0 {
+			t.Errorf("expected free shipping over 50.00 but got %+v", rates)
+		}
+
+		rr = send(http.MethodGet, "/shipping/rates?country=FR&items=1:1", 0, nil)
+		json.NewDecoder(rr.Body).Decode(&rates)
+		if rr.Code != http.StatusOK || len(rates) != 0 {
+			t.Errorf("expected no rates for a country not shipped to but got %+v", rates)
+		}
+	})
+
+	t.Run("should reject invalid rate requests", func(t *testing.T) {
+		for _, query := range []string{"country=USA&items=1:1", "country=US", "country=US&items=1:0", "country=US&items=x:1"} {
+			if rr := send(http.MethodGet, "/shipping/rates?"+query, 0, nil); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %d for %q but got %d", http.StatusBadRequest, query, rr.Code)
+			}
+		}
+		if rr := send(http.MethodGet, "/shipping/rates?country=US&items=9:1", 0, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d for an unknown product but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
+// mockShippingStore keeps zones and methods by ID.
+type mockShippingStore struct {
+	zones   []types.ShippingZone
+	methods []types.ShippingMethod
+}
+
+func (m *mockShippingStore) GetShippingZones() ([]types.ShippingZone, error) {
+	return m.zones, nil
+}
+
+func (m *mockShippingStore) GetShippingZone(id int) (*types.ShippingZone, error) {
+	if id < 1 || id > len(m.zones) {
+		return nil, ErrZoneNotFound
+	}
+	return &m.zones[id-1], nil
+}
+
+func (m *mockShippingStore) CreateShippingZone(payload types.ShippingZonePayload) (int, error) {
+	m.zones = append(m.zones, shippingZone(len(m.zones)+1, payload))
+	return len(m.zones), nil
+}
+
+func (m *mockShippingStore) UpdateShippingZone(id int, payload types.ShippingZonePayload) error {
+	if _, err := m.GetShippingZone(id); err != nil {
+		return err
+	}
+	m.zones[id-1] = shippingZone(id, payload)
+	return nil
+}
+
+func (m *mockShippingStore) GetShippingMethods() ([]types.ShippingMethod, error) {
+	return m.methods, nil
+}
+
+func (m *mockShippingStore) GetShippingMethod(id int) (*types.ShippingMethod, error) {
+	if id < 1 || id > len(m.methods) {
+		return nil, ErrMethodNotFound
+	}
+	return &m.methods[id-1], nil
+}
+
+func (m *mockShippingStore) GetShippingMethodsForCountry(country string) ([]types.ShippingMethod, error) {
+	methods := []types.ShippingMethod{}
+	for _, z := range m.zones {
+		for _, c := range z.Countries {
+			if c != country {
+				continue
+			}
+			for _, method := range m.methods {
+				if method.ZoneID == z.ID && method.Active {
+					methods = append(methods, method)
+				}
+			}
+		}
+	}
+	return methods, nil
+}
+
+func (m *mockShippingStore) CreateShippingMethod(payload types.ShippingMethodPayload) (int, error) {
+	if _, err := m.GetShippingZone(payload.ZoneID); err != nil {
+		return 0, err
+	}
+	m.methods = append(m.methods, shippingMethod(len(m.methods)+1, payload))
+	return len(m.methods), nil
+}
+
+func (m *mockShippingStore) UpdateShippingMethod(id int, payload types.ShippingMethodPayload) error {
+	if _, err := m.GetShippingMethod(id); err != nil {
+		return err
+	}
+	if _, err := m.GetShippingZone(payload.ZoneID); err != nil {
+		return err
+	}
+	m.methods[id-1] = shippingMethod(id, payload)
+	return nil
+}
+
+func shippingZone(id int, payload types.ShippingZonePayload) types.ShippingZone {
+	z := types.ShippingZone{ID: id, Name: payload.Name}
+	for _, c := range payload.Countries {
+		z.Countries = append(z.Countries, strings.ToUpper(c))
+	}
+	return z
+}
+
+func shippingMethod(id int, payload types.ShippingMethodPayload) types.ShippingMethod {
+	return types.ShippingMethod{
+		ID:         id,
+		ZoneID:     payload.ZoneID,
+		Name:       payload.Name,
+		Type:       payload.Type,
+		Price:      payload.Price,
+		PricePerKg: payload.PricePerKg,
+		FreeOver:   payload.FreeOver,
+		MaxWeight:  payload.MaxWeight,
+		MinDays:    payload.MinDays,
+		MaxDays:    payload.MaxDays,
+		Active:     payload.Active == nil || *payload.Active,
+	}
+}
+
+type mockProductStore struct {
+	products map[int]*types.Product
+}
+
+func (m *mockProductStore) GetProductByID(id int) (*types.Product, error) {
+	p, ok := m.products[id]
+	if !ok {
+		return nil, fmt.Errorf("product not found")
+	}
+	return p, nil
+}
+
+func (m *mockProductStore) GetProductsByID(ids []int) ([]types.Product, error) {
+	products := []types.Product{}
+	for _, id := range ids {
+		if p, ok := m.products[id]; ok {
+			products = append(products, *p)
+		}
+	}
+	return products, nil
+}
+
+func (m *mockProductStore) GetProducts() ([]*types.Product, error) {
+	return nil, nil
+}
+
+func (m *mockProductStore) CreateProduct(p types.CreateProductPayload) (int, error) {
+	return 0, nil
+}
+
+func (m *mockProductStore) UpdateProduct(p types.Product) error {
+	return nil
+}
+
+func (m *mockProductStore) UpsertProducts(rows []types.ProductImportRow, actorID int) (int, int, error) {
+	return 0, 0, nil
+}
+
+func (m *mockProductStore) ExportProducts(fn func(types.Product) error) error {
+	return nil
+}
@@ -0,0 +1,274 @@
+package shipping
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	ErrZoneNotFound   = errors.New("shipping zone not found")
+	ErrMethodNotFound = errors.New("shipping method not found")
+	ErrCountryTaken   = errors.New("country already belongs to another shipping zone")
+)
+
+// MySQL error numbers for a unique key violation and a missing foreign key row.
+const (
+	mysqlDuplicateEntry  = 1062
+	mysqlNoReferencedRow = 1452
+)
+
+// methodColumns lists the columns read by scanRowIntoMethod, in scan order.
+const methodColumns = "id, zoneId, name, type, price, pricePerKg, freeOver, maxWeight, minDays, maxDays, active, created_at"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetShippingZones() ([]types.ShippingZone, error) {
+	rows, err := s.db.Query("SELECT id, name, created_at FROM shipping_zones ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := []types.ShippingZone{}
+	for rows.Next() {
+		var z types.ShippingZone
+		if err := rows.Scan(&z.ID, &z.Name, &z.CreatedAt); err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range zones {
+		if err := s.loadCountries(&zones[i]); err != nil {
+			return nil, err
+		}
+	}
+	return zones, nil
+}
+
+func (s *Store) GetShippingZone(id int) (*types.ShippingZone, error) {
+	z := &types.ShippingZone{}
+	err := s.db.QueryRow("SELECT id, name, created_at FROM shipping_zones WHERE id = ?", id).Scan(&z.ID, &z.Name, &z.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrZoneNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return z, s.loadCountries(z)
+}
+
+func (s *Store) CreateShippingZone(payload types.ShippingZonePayload) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO shipping_zones (name) VALUES (?)", strings.TrimSpace(payload.Name))
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert shipping zone: %w", err)
+	}
+	lastID, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := replaceCountries(tx, int(lastID), payload.Countries); err != nil {
+		return 0, err
+	}
+	return int(lastID), tx.Commit()
+}
+
+func (s *Store) UpdateShippingZone(id int, payload types.ShippingZonePayload) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM shipping_zones WHERE id = ?)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrZoneNotFound
+	}
+
+	if _, err := tx.Exec("UPDATE shipping_zones SET name = ? WHERE id = ?", strings.TrimSpace(payload.Name), id); err != nil {
+		return err
+	}
+	if err := replaceCountries(tx, id, payload.Countries); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) GetShippingMethods() ([]types.ShippingMethod, error) {
+	return s.queryMethods("SELECT " + methodColumns + " FROM shipping_methods ORDER BY zoneId, id")
+}
+
+func (s *Store) GetShippingMethod(id int) (*types.ShippingMethod, error) {
+	methods, err := s.queryMethods("SELECT "+methodColumns+" FROM shipping_methods WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) == 0 {
+		return nil, ErrMethodNotFound
+	}
+	return &methods[0], nil
+}
+
+func (s *Store) GetShippingMethodsForCountry(country string) ([]types.ShippingMethod, error) {
+	return s.queryMethods(
+		"SELECT "+methodColumns+" FROM shipping_methods WHERE active AND zoneId = (SELECT zoneId FROM shipping_zone_countries WHERE country = ?) ORDER BY id",
+		strings.ToUpper(strings.TrimSpace(country)),
+	)
+}
+
+func (s *Store) CreateShippingMethod(payload types.ShippingMethodPayload) (int, error) {
+	result, err := s.db.Exec(
+		"INSERT INTO shipping_methods (zoneId, name, type, price, pricePerKg, freeOver, maxWeight, minDays, maxDays, active) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		methodArgs(payload)...,
+	)
+	if err := zoneError(err); err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *Store) UpdateShippingMethod(id int, payload types.ShippingMethodPayload) error {
+	if _, err := s.GetShippingMethod(id); err != nil {
+		return err
+	}
+
+	_, err := s.db.Exec(
+		"UPDATE shipping_methods SET zoneId = ?, name = ?, type = ?, price = ?, pricePerKg = ?, freeOver = ?, maxWeight = ?, minDays = ?, maxDays = ?, active = ? WHERE id = ?",
+		append(methodArgs(payload), id)...,
+	)
+	return zoneError(err)
+}
+
+func (s *Store) queryMethods(query string, args ...any) ([]types.ShippingMethod, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	methods := []types.ShippingMethod{}
+	for rows.Next() {
+		m, err := scanRowIntoMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, *m)
+	}
+	return methods, rows.Err()
+}
+
+// loadCountries reads the countries of a zone.
+func (s *Store) loadCountries(z *types.ShippingZone) error {
+	rows, err := s.db.Query("SELECT country FROM shipping_zone_countries WHERE zoneId = ? ORDER BY country", z.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	z.Countries = []string{}
+	for rows.Next() {
+		var country string
+		if err := rows.Scan(&country); err != nil {
+			return err
+		}
+		z.Countries = append(z.Countries, country)
+	}
+	return rows.Err()
+}
+
+// replaceCountries replaces the countries of a zone. A country of another zone fails with
+// ErrCountryTaken rather than moving silently.
+func replaceCountries(tx *sql.Tx, zoneID int, countries []string) error {
+	if _, err := tx.Exec("DELETE FROM shipping_zone_countries WHERE zoneId = ?", zoneID); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, country := range countries {
+		country = strings.ToUpper(country)
+		if seen[country] {
+			continue
+		}
+		seen[country] = true
+
+		_, err := tx.Exec("INSERT INTO shipping_zone_countries (country, zoneId) VALUES (?, ?)", country, zoneID)
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+			return fmt.Errorf("%w: %s", ErrCountryTaken, country)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// methodArgs returns the column values of a shipping method in the order used by the
+// insert and update statements.
+func methodArgs(p types.ShippingMethodPayload) []any {
+	var freeOver any
+	if p.FreeOver != nil {
+		freeOver = utils.FormatCents(utils.ToCents(*p.FreeOver))
+	}
+	active := p.Active == nil || *p.Active
+	return []any{
+		p.ZoneID, strings.TrimSpace(p.Name), p.Type, utils.FormatCents(utils.ToCents(p.Price)),
+		utils.FormatCents(utils.ToCents(p.PricePerKg)), freeOver, p.MaxWeight, p.MinDays, p.MaxDays, active,
+	}
+}
+
+// zoneError turns a method pointing to a missing zone into ErrZoneNotFound.
+func zoneError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlNoReferencedRow {
+		return ErrZoneNotFound
+	}
+	return err
+}
+
+func scanRowIntoMethod(rows *sql.Rows) (*types.ShippingMethod, error) {
+	m := new(types.ShippingMethod)
+	err := rows.Scan(
+		&m.ID,
+		&m.ZoneID,
+		&m.Name,
+		&m.Type,
+		&m.Price,
+		&m.PricePerKg,
+		&m.FreeOver,
+		&m.MaxWeight,
+		&m.MinDays,
+		&m.MaxDays,
+		&m.Active,
+		&m.CreatedAt,
+	)
+	return m, err
+}
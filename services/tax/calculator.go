@@ -0,0 +1,123 @@
+package tax
+
+import (
+	"strings"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+)
+
+// TableCalculator computes taxes from the rates in a TaxRateStore, read again for every
+// calculation so rate changes apply to the next checkout.
+type TableCalculator struct {
+	store types.TaxRateStore
+}
+
+func NewTableCalculator(store types.TaxRateStore) *TableCalculator {
+	return &TableCalculator{store: store}
+}
+
+func (c *TableCalculator) Calculate(address types.TaxAddress, items []types.TaxableItem) ([][]types.TaxLine, error) {
+	rates, err := c.store.GetTaxRates()
+	if err != nil {
+		return nil, err
+	}
+	return Calculate(rates, address, items), nil
+}
+
+// Calculate applies the rates matching the address and the tax class of each item.
+// Inclusive taxes are taken out of the item amount and exclusive ones are charged on the
+// amount without them. Amounts are rounded half up to whole cents per line.
+func Calculate(rates []types.TaxRate, address types.TaxAddress, items []types.TaxableItem) [][]types.TaxLine {
+	lines := make([][]types.TaxLine, len(items))
+	for i, item := range items {
+		class := item.TaxClass
+		if class == "" {
+			class = types.TaxClassStandard
+		}
+		lines[i] = taxLines(match(rates, address, class), utils.ToCents(item.Amount))
+	}
+	return lines
+}
+
+// match returns the rates of a tax class that apply to an address. Of the rates sharing a
+// name only the most specific one applies, so a regional rate replaces the national rate
+// of the same tax while taxes with different names add up.
+func match(rates []types.TaxRate, address types.TaxAddress, class string) []types.TaxRate {
+	country := strings.ToUpper(strings.TrimSpace(address.Country))
+	region := strings.TrimSpace(address.Region)
+	postalCode := normalizePostalCode(address.PostalCode)
+
+	matched := []types.TaxRate{}
+	byName := map[string]int{}
+	for _, rate := range rates {
+		switch {
+		case rate.TaxClass != class, rate.Country != country,
+			rate.Region != "" && !strings.EqualFold(rate.Region, region),
+			!strings.HasPrefix(postalCode, rate.PostalPrefix):
+			continue
+		}
+		if i, ok := byName[rate.Name]; ok {
+			if specificity(rate) > specificity(matched[i]) {
+				matched[i] = rate
+			}
+			continue
+		}
+		byName[rate.Name] = len(matched)
+		matched = append(matched, rate)
+	}
+	return matched
+}
+
+// specificity ranks rates by how narrow their jurisdiction is: any postal prefix beats a
+// region alone, and longer prefixes beat shorter ones.
+func specificity(rate types.TaxRate) int {
+	s := 2 * len(rate.PostalPrefix)
+	if rate.Region != "" {
+		s++
+	}
+	return s
+}
+
+// taxLines computes the tax of every rate on an amount in cents. Rates are handled in
+// hundredths of a percent, so a rate with two decimals is exact.
+func taxLines(rates []types.TaxRate, amount int64) []types.TaxLine {
+	var inclusiveRate int64
+	for _, rate := range rates {
+		if rate.Inclusive {
+			inclusiveRate += utils.ToCents(rate.Rate)
+		}
+	}
+	included := divRound(amount*inclusiveRate, 10000+inclusiveRate)
+	net := amount - included
+
+	lines := make([]types.TaxLine, 0, len(rates))
+	for _, rate := range rates {
+		bp := utils.ToCents(rate.Rate)
+		var cents int64
+		if rate.Inclusive {
+			// Inclusive taxes share what was taken out in proportion to their rates, so the
+			// last one gets exactly what is left
+			cents = divRound(included*bp, inclusiveRate)
+			included, inclusiveRate = included-cents, inclusiveRate-bp
+		} else {
+			cents = divRound(net*bp, 10000)
+		}
+		lines = append(lines, types.TaxLine{Name: rate.Name, Rate: rate.Rate, Inclusive: rate.Inclusive, Amount: utils.FromCents(cents)})
+	}
+	return lines
+}
+
+// divRound divides non-negative numbers, rounding half up.
+func divRound(a, b int64) int64 {
+	if b == 0 {
+		return 0
+	}
+	return (a + b/2) / b
+}
+
+// normalizePostalCode upper-cases a postal code and drops its spaces, so "sw1a 1aa" and
+// "SW1A1AA" both match the prefix "SW1A".
+func normalizePostalCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
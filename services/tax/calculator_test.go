@@ -0,0 +1,86 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestCalculate(t *testing.T) {
+	rates := []types.TaxRate{
+		{Name: "VAT", Country: "GB", TaxClass: types.TaxClassStandard, Rate: 20, Inclusive: true},
+		{Name: "VAT", Country: "GB", TaxClass: "reduced", Rate: 5, Inclusive: true},
+		{Name: "GST", Country: "CA", TaxClass: types.TaxClassStandard, Rate: 5},
+		{Name: "PST", Country: "CA", Region: "BC", TaxClass: types.TaxClassStandard, Rate: 7},
+		{Name: "Sales tax", Country: "US", Region: "CA", TaxClass: types.TaxClassStandard, Rate: 7.25},
+		{Name: "Sales tax", Country: "US", PostalPrefix: "900", TaxClass: types.TaxClassStandard, Rate: 9.5},
+		{Name: "Sales tax", Country: "US", PostalPrefix: "9001", TaxClass: types.TaxClassStandard, Rate: 10.25},
+		{Name: "GST", Country: "AU", TaxClass: types.TaxClassStandard, Rate: 10, Inclusive: true},
+		{Name: "Levy", Country: "AU", TaxClass: types.TaxClassStandard, Rate: 2.5, Inclusive: true},
+		{Name: "Duty", Country: "AU", TaxClass: types.TaxClassStandard, Rate: 1},
+	}
+
+	tests := map[string]struct {
+		address types.TaxAddress
+		item    types.TaxableItem
+		want    []types.TaxLine
+	}{
+		"should take inclusive taxes out of the price": {
+			address: types.TaxAddress{Country: "gb"},
+			item:    types.TaxableItem{Amount: 12},
+			want:    []types.TaxLine{{Name: "VAT", Rate: 20, Inclusive: true, Amount: 2}},
+		},
+		"should apply the rate of the tax class": {
+			address: types.TaxAddress{Country: "GB"},
+			item:    types.TaxableItem{TaxClass: "reduced", Amount: 10},
+			want:    []types.TaxLine{{Name: "VAT", Rate: 5, Inclusive: true, Amount: 0.48}},
+		},
+		"should add up taxes with different names": {
+			address: types.TaxAddress{Country: "CA", Region: "bc"},
+			item:    types.TaxableItem{Amount: 19.99},
+			want:    []types.TaxLine{{Name: "GST", Rate: 5, Amount: 1}, {Name: "PST", Rate: 7, Amount: 1.4}},
+		},
+		"should apply the most specific rate of a tax": {
+			address: types.TaxAddress{Country: "US", Region: "CA", PostalCode: "90012"},
+			item:    types.TaxableItem{Amount: 100},
+			want:    []types.TaxLine{{Name: "Sales tax", Rate: 10.25, Amount: 10.25}},
+		},
+		"should prefer a postal prefix to a region": {
+			address: types.TaxAddress{Country: "US", Region: "CA", PostalCode: "90089"},
+			item:    types.TaxableItem{Amount: 100},
+			want:    []types.TaxLine{{Name: "Sales tax", Rate: 9.5, Amount: 9.5}},
+		},
+		"should charge exclusive taxes on the price without inclusive ones": {
+			address: types.TaxAddress{Country: "AU"},
+			item:    types.TaxableItem{Amount: 11.25},
+			want: []types.TaxLine{
+				{Name: "GST", Rate: 10, Inclusive: true, Amount: 1},
+				{Name: "Levy", Rate: 2.5, Inclusive: true, Amount: 0.25},
+				{Name: "Duty", Rate: 1, Amount: 0.1},
+			},
+		},
+		"should charge nothing outside the configured jurisdictions": {
+			address: types.TaxAddress{Country: "DE"},
+			item:    types.TaxableItem{Amount: 10},
+			want:    []types.TaxLine{},
+		},
+		"should charge nothing without an address": {
+			item: types.TaxableItem{Amount: 10},
+			want: []types.TaxLine{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := Calculate(rates, tt.address, []types.TaxableItem{tt.item})[0]
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %+v but got %+v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %+v but got %+v", tt.want, got)
+				}
+			}
+		})
+	}
+}
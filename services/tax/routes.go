@@ -0,0 +1,117 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.TaxRateStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.TaxRateStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/tax-rates", auth.WithAdminAuth(h.handleGetTaxRates, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/tax-rates", auth.WithAdminAuth(h.handleCreateTaxRate, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/tax-rates/{id:[0-9]+}", auth.WithAdminAuth(h.handleUpdateTaxRate, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/admin/tax-rates/{id:[0-9]+}", auth.WithAdminAuth(h.handleDeleteTaxRate, h.userStore)).Methods(http.MethodDelete)
+}
+
+func (h *Handler) handleGetTaxRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := h.store.GetTaxRates()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rates)
+}
+
+func (h *Handler) handleCreateTaxRate(w http.ResponseWriter, r *http.Request) {
+	payload, ok := parseTaxRatePayload(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := h.store.CreateTaxRate(payload)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	rate, err := h.store.GetTaxRate(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, rate)
+}
+
+func (h *Handler) handleUpdateTaxRate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	payload, ok := parseTaxRatePayload(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.UpdateTaxRate(id, payload); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	rate, err := h.store.GetTaxRate(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, rate)
+}
+
+func (h *Handler) handleDeleteTaxRate(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.store.DeleteTaxRate(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseTaxRatePayload reads and validates a tax rate, writing the error response and
+// returning false if it is invalid.
+func parseTaxRatePayload(w http.ResponseWriter, r *http.Request) (types.TaxRatePayload, bool) {
+	var payload types.TaxRatePayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return payload, false
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return payload, false
+	}
+	return payload, true
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrTaxRateNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrTaxRateExists):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,142 @@
+package tax
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestTaxRateRoutes(t *testing.T) {
+	store := &mockTaxRateStore{}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleAdmin},
+	}}
+
+	handler := NewHandler(store, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	payload := types.TaxRatePayload{Name: "VAT", Country: "gb", PostalPrefix: "sw1a ", Rate: 20, Inclusive: true}
+
+	t.Run("should let only admins manage tax rates", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/admin/tax-rates", 1, payload); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should create normalized tax rates", func(t *testing.T) {
+		rr := send(http.MethodPost, "/admin/tax-rates", 2, payload)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var rate types.TaxRate
+		json.NewDecoder(rr.Body).Decode(&rate)
+		if rate.Country != "GB" || rate.PostalPrefix != "SW1A" || rate.TaxClass != types.TaxClassStandard {
+			t.Errorf("unexpected rate %+v", rate)
+		}
+	})
+
+	t.Run("should reject invalid tax rates", func(t *testing.T) {
+		for _, p := range []types.TaxRatePayload{
+			{Name: "VAT", Country: "GBR", Rate: 20},
+			{Name: "VAT", Country: "GB", Rate: 120},
+			{Country: "GB", Rate: 20},
+		} {
+			if rr := send(http.MethodPost, "/admin/tax-rates", 2, p); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %d for %+v but got %d", http.StatusBadRequest, p, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should update and delete tax rates", func(t *testing.T) {
+		update := payload
+		update.Rate = 17.5
+		rr := send(http.MethodPut, "/admin/tax-rates/1", 2, update)
+		var rate types.TaxRate
+		json.NewDecoder(rr.Body).Decode(&rate)
+		if rr.Code != http.StatusOK || rate.Rate != 17.5 {
+			t.Errorf("unexpected update %d %+v", rr.Code, rate)
+		}
+
+		if rr := send(http.MethodDelete, "/admin/tax-rates/1", 2, nil); rr.Code != http.StatusNoContent {
+			t.Errorf("expected status code %d but got %d", http.StatusNoContent, rr.Code)
+		}
+		if rr := send(http.MethodDelete, "/admin/tax-rates/1", 2, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
+// mockTaxRateStore keeps rates by ID; a deleted rate leaves a nil entry.
+type mockTaxRateStore struct {
+	rates []*types.TaxRate
+}
+
+func (m *mockTaxRateStore) GetTaxRates() ([]types.TaxRate, error) {
+	rates := []types.TaxRate{}
+	for _, rate := range m.rates {
+		if rate != nil {
+			rates = append(rates, *rate)
+		}
+	}
+	return rates, nil
+}
+
+func (m *mockTaxRateStore) GetTaxRate(id int) (*types.TaxRate, error) {
+	if id < 1 || id > len(m.rates) || m.rates[id-1] == nil {
+		return nil, ErrTaxRateNotFound
+	}
+	rate := *m.rates[id-1]
+	return &rate, nil
+}
+
+func (m *mockTaxRateStore) CreateTaxRate(payload types.TaxRatePayload) (int, error) {
+	m.rates = append(m.rates, taxRate(len(m.rates)+1, payload))
+	return len(m.rates), nil
+}
+
+func (m *mockTaxRateStore) UpdateTaxRate(id int, payload types.TaxRatePayload) error {
+	if _, err := m.GetTaxRate(id); err != nil {
+		return err
+	}
+	m.rates[id-1] = taxRate(id, payload)
+	return nil
+}
+
+func (m *mockTaxRateStore) DeleteTaxRate(id int) error {
+	if _, err := m.GetTaxRate(id); err != nil {
+		return err
+	}
+	m.rates[id-1] = nil
+	return nil
+}
+
+func taxRate(id int, payload types.TaxRatePayload) *types.TaxRate {
+	payload = normalizeTaxRate(payload)
+	return &types.TaxRate{
+		ID:           id,
+		Name:         payload.Name,
+		Country:      payload.Country,
+		Region:       payload.Region,
+		PostalPrefix: payload.PostalPrefix,
+		TaxClass:     payload.TaxClass,
+		Rate:         payload.Rate,
+		Inclusive:    payload.Inclusive,
+	}
+}
@@ -0,0 +1,147 @@
+package tax
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/code-farms/go-backend/types"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	ErrTaxRateNotFound = errors.New("tax rate not found")
+	ErrTaxRateExists   = errors.New("a tax rate with this name already exists for the jurisdiction and tax class")
+)
+
+// mysqlDuplicateEntry is the MySQL error number for a unique key violation.
+const mysqlDuplicateEntry = 1062
+
+// taxRateColumns lists the columns read by scanRowIntoTaxRate, in scan order.
+const taxRateColumns = "id, name, country, region, postalPrefix, taxClass, rate, inclusive, created_at"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetTaxRates() ([]types.TaxRate, error) {
+	rows, err := s.db.Query("SELECT " + taxRateColumns + " FROM tax_rates ORDER BY country, region, postalPrefix, name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []types.TaxRate{}
+	for rows.Next() {
+		rate, err := scanRowIntoTaxRate(rows)
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, *rate)
+	}
+	return rates, rows.Err()
+}
+
+func (s *Store) GetTaxRate(id int) (*types.TaxRate, error) {
+	rows, err := s.db.Query("SELECT "+taxRateColumns+" FROM tax_rates WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrTaxRateNotFound
+	}
+	return scanRowIntoTaxRate(rows)
+}
+
+func (s *Store) CreateTaxRate(payload types.TaxRatePayload) (int, error) {
+	payload = normalizeTaxRate(payload)
+	result, err := s.db.Exec(
+		"INSERT INTO tax_rates (name, country, region, postalPrefix, taxClass, rate, inclusive) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		payload.Name, payload.Country, payload.Region, payload.PostalPrefix, payload.TaxClass, payload.Rate, payload.Inclusive,
+	)
+	if err != nil {
+		return 0, duplicateError(err)
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *Store) UpdateTaxRate(id int, payload types.TaxRatePayload) error {
+	payload = normalizeTaxRate(payload)
+	result, err := s.db.Exec(
+		"UPDATE tax_rates SET name = ?, country = ?, region = ?, postalPrefix = ?, taxClass = ?, rate = ?, inclusive = ? WHERE id = ?",
+		payload.Name, payload.Country, payload.Region, payload.PostalPrefix, payload.TaxClass, payload.Rate, payload.Inclusive, id,
+	)
+	if err != nil {
+		return duplicateError(err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	// MySQL reports no affected rows when nothing changed, so tell that apart from a missing rate
+	_, err = s.GetTaxRate(id)
+	return err
+}
+
+func (s *Store) DeleteTaxRate(id int) error {
+	result, err := s.db.Exec("DELETE FROM tax_rates WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTaxRateNotFound
+	}
+	return nil
+}
+
+// normalizeTaxRate stores jurisdictions in the form the calculator compares them in.
+func normalizeTaxRate(payload types.TaxRatePayload) types.TaxRatePayload {
+	payload.Name = strings.TrimSpace(payload.Name)
+	payload.Country = strings.ToUpper(strings.TrimSpace(payload.Country))
+	payload.Region = strings.TrimSpace(payload.Region)
+	payload.PostalPrefix = normalizePostalCode(payload.PostalPrefix)
+	payload.TaxClass = strings.TrimSpace(payload.TaxClass)
+	if payload.TaxClass == "" {
+		payload.TaxClass = types.TaxClassStandard
+	}
+	return payload
+}
+
+func duplicateError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry {
+		return ErrTaxRateExists
+	}
+	return fmt.Errorf("failed to save tax rate: %w", err)
+}
+
+func scanRowIntoTaxRate(rows *sql.Rows) (*types.TaxRate, error) {
+	rate := new(types.TaxRate)
+	err := rows.Scan(
+		&rate.ID,
+		&rate.Name,
+		&rate.Country,
+		&rate.Region,
+		&rate.PostalPrefix,
+		&rate.TaxClass,
+		&rate.Rate,
+		&rate.Inclusive,
+		&rate.CreatedAt,
+	)
+	return rate, err
+}
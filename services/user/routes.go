@@ -1,9 +1,17 @@
 package user
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"      // Importing fmt for formatted output (error messages)
+	"log"
 	"net/http" // Importing net/http for handling HTTP requests and responses
+	"net/url"
+	"time"
 
 	"github.com/code-farms/go-backend/configs"
 	"github.com/code-farms/go-backend/services/auth" // Importing the auth package for password hashing
@@ -17,12 +25,14 @@ import (
 // which will be used to interact with the database.
 type Handler struct {
 	store types.UserStore  // A reference to the UserStore interface for interacting with user data
+	passwords types.PasswordStore  // Changes passwords and keeps password reset links
+	notifier types.Notifier  // Sends the welcome and password emails
 }
 
 // NewHandler is a constructor function that creates and returns a new Handler object
-// initialized with a store for user data interaction.
-func NewHandler(store types.UserStore) *Handler {
-	return &Handler{store: store}  // Return a new Handler with the store
+// initialized with a store for user data interaction and a notifier for account emails.
+func NewHandler(store types.UserStore, passwords types.PasswordStore, notifier types.Notifier) *Handler {
+	return &Handler{store: store, passwords: passwords, notifier: notifier}  // Return a new Handler with the stores and notifier
 }
 
 // ResisterRoutes method registers the routes for login and register with the provided router.
@@ -32,6 +42,16 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/login", h.handleLogin).Methods("POST")
 	// Register route for handling user registration, which will invoke the handleRegister method for POST requests
 	router.HandleFunc("/register", h.handleRegister).Methods("POST")
+
+	router.HandleFunc("/users/me/password", auth.WithJWTAuth(h.handleChangePassword, h.store)).Methods(http.MethodPut)
+	router.HandleFunc("/password-reset", h.handleRequestPasswordReset).Methods(http.MethodPost)
+	router.HandleFunc("/password-reset/confirm", h.handleResetPassword).Methods(http.MethodPost)
+}
+
+// RegisterSubscribers subscribes the handler to the domain events it reacts to.
+func (h *Handler) RegisterSubscribers(bus types.EventBus) {
+	bus.Subscribe(types.UserRegisteredEvent, "welcome_email", h.sendWelcome)
+	bus.Subscribe(types.PasswordChangedEvent, "password_changed_email", h.sendPasswordChanged)
 }
 
 // handleLogin is the placeholder function for the login route.
@@ -77,7 +97,9 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    // Step 6: Return the JWT token in the response
+    // Step 6: Return the JWT token in the response, which must never be cached or stored
+    // for a replay of the request
+    w.Header().Set("Cache-Control", "no-store")
     utils.WriteJSON(w, http.StatusOK, map[string]string{"token": token})
 }
 
@@ -115,18 +137,189 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
     }
 
 	// Step 7: Create a new User object with the parsed data and hashed password
-	err = h.store.CreateUser(types.User{
+	user := types.User{
 		FirstName: payload.FirstName,  // First name from the payload
 		LastName: payload.LastName,    // Last name from the payload
 		Email: payload.Email,          // Email from the payload
 		Password: hashedPassword,      // Hashed password
-	})
+	}
+	err = h.store.CreateUser(user)
 
 	if err != nil {
         utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to create user: %v", err))
         return
     }
 
-	// Step 9: Return a 201 Created response
+	// Step 8: Return a 201 Created response; the welcome email follows from the UserRegistered event
     utils.WriteJSON(w, http.StatusCreated, map[string]string{"message": "user registered successfully"})
-}
\ No newline at end of file
+}
+
+// handleChangePassword replaces the password of the logged-in user, who must confirm the
+// current one. The user is emailed about the change.
+func (h *Handler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ChangePasswordPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	user, err := h.store.GetUserById(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to fetch user: %v", err))
+		return
+	}
+	if !auth.ComparePasswords(user.Password, []byte(payload.CurrentPassword)) {
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("current password is incorrect"))
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.NewPassword)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to hash password"))
+		return
+	}
+	if err := h.passwords.UpdatePassword(user.ID, hashedPassword); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to update password: %v", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "password changed"})
+}
+
+// handleRequestPasswordReset emails a link to choose a new password. It answers the same
+// whether or not the account exists, so it can't be used to find out who has one.
+func (h *Handler) handleRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var payload types.RequestPasswordResetPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	user, err := h.store.GetUserByEmail(payload.Email)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+	case err != nil:
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to fetch user: %v", err))
+		return
+	default:
+		if err := h.sendPasswordReset(r.Context(), user); err != nil {
+			log.Printf("failed to send password reset to user %d: %v", user.ID, err)
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to send password reset"))
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, map[string]string{"message": "if the account exists, a password reset link was sent"})
+}
+
+// handleResetPassword chooses a new password with the token of a reset link. The link can
+// only be used once, and the user is emailed about the change.
+func (h *Handler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var payload types.ResetPasswordPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(payload.Password)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to hash password"))
+		return
+	}
+	_, err = h.passwords.ResetPassword(hashResetToken(payload.Token), hashedPassword, time.Now())
+	if errors.Is(err, ErrInvalidResetToken) {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to reset password: %v", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "password changed"})
+}
+
+// sendPasswordReset stores a new reset token for user and emails them the link. Only the
+// hash of the token is stored.
+func (h *Handler) sendPasswordReset(ctx context.Context, user *types.User) error {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(b)
+	ttl := time.Duration(configs.Envs.PasswordResetTTLInSeconds) * time.Second
+	if err := h.passwords.CreatePasswordReset(user.ID, hashResetToken(token), time.Now().Add(ttl)); err != nil {
+		return err
+	}
+
+	resetURL := configs.Envs.PublicHost + "/reset-password?token=" + url.QueryEscape(token)
+	return h.notifier.Notify(ctx, types.Notification{
+		Kind: types.NotificationPasswordReset,
+		Recipients: []string{user.Email},
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s, choose a new password for your account %s at %s", user.FirstName, user.Email, resetURL),
+		Data: map[string]any{
+			"user": types.User{FirstName: user.FirstName, LastName: user.LastName, Email: user.Email},  // Without the password hash
+			"resetURL": resetURL,
+		},
+	})
+}
+
+// hashResetToken returns the hash a reset token is stored and looked up by.
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// sendPasswordChanged tells a user their password was changed, so they notice if it wasn't
+// them.
+func (h *Handler) sendPasswordChanged(ctx context.Context, event types.OutboxEvent) error {
+	var changed types.PasswordChanged
+	if err := json.Unmarshal(event.Payload, &changed); err != nil {
+		return err
+	}
+	user, err := h.store.GetUserById(changed.UserID)
+	if err != nil {
+		return err
+	}
+
+	return h.notifier.Notify(ctx, types.Notification{
+		Kind: types.NotificationPasswordChanged,
+		Recipients: []string{user.Email},
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf("Hi %s, the password of your account %s was just changed.", user.FirstName, user.Email),
+		Data: map[string]any{"user": types.User{FirstName: user.FirstName, LastName: user.LastName, Email: user.Email}},  // Without the password hash
+	})
+}
+
+// sendWelcome emails a new user once their account is committed.
+func (h *Handler) sendWelcome(ctx context.Context, event types.OutboxEvent) error {
+	var registered types.UserRegistered
+	if err := json.Unmarshal(event.Payload, &registered); err != nil {
+		return err
+	}
+	user, err := h.store.GetUserById(registered.UserID)
+	if err != nil {
+		return err
+	}
+
+	return h.notifier.Notify(ctx, types.Notification{
+		Kind: types.NotificationWelcome,
+		Recipients: []string{user.Email},
+		Subject: "Welcome to your new account",
+		Body: fmt.Sprintf("Hi %s, your account %s is ready.", user.FirstName, user.Email),
+		Data: map[string]any{"user": types.User{FirstName: user.FirstName, LastName: user.LastName, Email: user.Email}},  // Without the password hash
+	})
+}
@@ -2,12 +2,16 @@ package user
 
 import (
 	"bytes"             // For creating a buffer for the request body
+	"context"           // For the context passed to the recording notifier
 	"encoding/json"     // For marshaling the payload into JSON format
 	"fmt"               // For formatted I/O, used to create error messages
 	"net/http"          // For HTTP handling functions like NewRequest, MethodPost, etc.
 	"net/http/httptest" // For creating a test HTTP server and recording responses
+	"net/url"           // For reading the token out of the reset link
 	"testing"           // For writing unit tests
+	"time"              // For the expiry of password reset tokens
 
+	"github.com/code-farms/go-backend/internal/testutil"
 	"github.com/code-farms/go-backend/types" // Importing the types package for the user payload and user structure
 	"github.com/gorilla/mux"                 // Importing the Gorilla mux router for routing HTTP requests
 )
@@ -16,7 +20,10 @@ import (
 func TestUserServiceHandlers(t *testing.T) {
 	// Mock the UserStore interface to simulate the behavior of the data store during testing.
 	userStore := &mockUserStore{}
-	handler := NewHandler(userStore)  // Create a new handler with the mock user store
+	notifier := &recordingNotifier{}
+	handler := NewHandler(userStore, userStore, notifier)  // Create a new handler with the mock user store and notifier
+	bus := &mockBus{}
+	handler.RegisterSubscribers(bus)
 
 	// Test Case 1: Test if the handler fails when the payload is invalid.
 	t.Run("should fail if the user payload is invalid", func(t *testing.T) {
@@ -54,25 +61,202 @@ func TestUserServiceHandlers(t *testing.T) {
 			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
 		}
 	})
+
+	// Test Case 2: Test if a valid registration creates the user and sends the welcome email.
+	t.Run("should register the user and welcome them", func(t *testing.T) {
+		payload := types.RegisterUserPayload{
+			FirstName: "John",
+			LastName: "Doe",
+			Password: "secret123",
+			Email: "john@example.com",
+		}
+		marshalled, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(marshalled))
+		rr := httptest.NewRecorder()
+
+		router := mux.NewRouter()
+		router.HandleFunc("/register", handler.handleRegister)
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		// The welcome email is only sent once the UserRegistered event is published
+		if len(notifier.sent) != 0 {
+			t.Fatalf("expected no email before the event is published but got %+v", notifier.sent)
+		}
+		bus.publish(t, userStore.events...)
+
+		// The welcome email goes to the new user and never carries the password hash
+		if len(notifier.sent) != 1 || notifier.sent[0].Kind != types.NotificationWelcome || notifier.sent[0].Recipients[0] != "john@example.com" {
+			t.Fatalf("expected a welcome email but got %+v", notifier.sent)
+		}
+		if u := notifier.sent[0].Data["user"].(types.User); u.Password != "" {
+			t.Error("expected the password hash to be left out")
+		}
+	})
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if userID != 0 {
+			req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Test Case 3: Test if changing the password checks the current one and tells the user.
+	t.Run("should change the password and tell the user", func(t *testing.T) {
+		notifier.sent, userStore.events = nil, nil
+
+		wrong := types.ChangePasswordPayload{CurrentPassword: "wrong", NewPassword: "changed123"}
+		if rr := send(http.MethodPut, "/users/me/password", 1, wrong); rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status code %d for a wrong password but got %d", http.StatusUnauthorized, rr.Code)
+		}
+		payload := types.ChangePasswordPayload{CurrentPassword: "secret123", NewPassword: "changed123"}
+		if rr := send(http.MethodPut, "/users/me/password", 1, payload); rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		bus.publish(t, userStore.events...)
+
+		if len(notifier.sent) != 1 || notifier.sent[0].Kind != types.NotificationPasswordChanged || notifier.sent[0].Recipients[0] != "john@example.com" {
+			t.Errorf("expected a password changed email but got %+v", notifier.sent)
+		}
+	})
+
+	// Test Case 4: Test if a reset link is emailed and can be used once.
+	t.Run("should reset the password with the emailed link once", func(t *testing.T) {
+		notifier.sent, userStore.events = nil, nil
+
+		if rr := send(http.MethodPost, "/password-reset", 0, types.RequestPasswordResetPayload{Email: "nobody@example.com"}); rr.Code != http.StatusAccepted || len(notifier.sent) != 0 {
+			t.Errorf("expected status code %d without an email for an unknown account but got %d and %+v", http.StatusAccepted, rr.Code, notifier.sent)
+		}
+		if rr := send(http.MethodPost, "/password-reset", 0, types.RequestPasswordResetPayload{Email: "john@example.com"}); rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusAccepted, rr.Code, rr.Body)
+		}
+		if len(notifier.sent) != 1 || notifier.sent[0].Kind != types.NotificationPasswordReset {
+			t.Fatalf("expected a password reset email but got %+v", notifier.sent)
+		}
+		link, _ := url.Parse(notifier.sent[0].Data["resetURL"].(string))
+		token := link.Query().Get("token")
+
+		payload := types.ResetPasswordPayload{Token: token, Password: "reset1234"}
+		if rr := send(http.MethodPost, "/password-reset/confirm", 0, payload); rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if rr := send(http.MethodPost, "/password-reset/confirm", 0, payload); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d when using the link twice but got %d", http.StatusBadRequest, rr.Code)
+		}
+		bus.publish(t, userStore.events...)
+
+		if len(notifier.sent) != 2 || notifier.sent[1].Kind != types.NotificationPasswordChanged {
+			t.Errorf("expected a password changed email after the reset but got %+v", notifier.sent)
+		}
+	})
+}
+
+// recordingNotifier is a Notifier that keeps the notifications instead of sending them.
+type recordingNotifier struct {
+	sent []types.Notification
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, notification types.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+// mockBus is an EventBus whose events are published by the test instead of a relay.
+type mockBus struct {
+	handlers map[string][]types.EventHandler
+}
+
+func (b *mockBus) Subscribe(eventType, name string, handler types.EventHandler) {
+	if b.handlers == nil {
+		b.handlers = map[string][]types.EventHandler{}
+	}
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// publish hands the events to their subscribers, failing the test if one of them fails.
+func (b *mockBus) publish(t *testing.T, events ...types.DomainEvent) {
+	for _, e := range events {
+		payload, _ := json.Marshal(e)
+		for _, handler := range b.handlers[e.EventType()] {
+			if err := handler(context.Background(), types.OutboxEvent{Type: e.EventType(), Payload: payload}); err != nil {
+				t.Fatalf("failed to handle %s: %v", e.EventType(), err)
+			}
+		}
+	}
 }
 
-// mockUserStore is a mock implementation of the UserStore interface used for testing purposes.
-type mockUserStore struct{}
+// mockUserStore is a mock implementation of the UserStore and PasswordStore interfaces used
+// for testing purposes. Like the real store, it records a UserRegistered event for every
+// user it creates and a PasswordChanged event for every password it changes.
+type mockUserStore struct {
+	users []types.User
+	events []types.DomainEvent
+	resets map[string]int // User IDs by the hash of their reset tokens
+}
 
-// GetUserByEmail simulates the behavior of fetching a user by email.
-// It returns an error indicating that the user is not found, as this is a mock implementation.
+// GetUserByEmail simulates the behavior of fetching a user by email from the created users.
 func (m *mockUserStore) GetUserByEmail(email string) (*types.User, error) {
-	return nil, fmt.Errorf("user not found")
+	for i := range m.users {
+		if m.users[i].Email == email {
+			return &m.users[i], nil
+		}
+	}
+	return nil, ErrUserNotFound
 }
 
-// GetUserById simulates the behavior of fetching a user by ID.
-// Since it's a mock, it simply returns nil.
+// GetUserById simulates the behavior of fetching a user by ID from the created users.
 func (m *mockUserStore) GetUserById(id int) (*types.User, error) {
-	return nil, nil
+	if id < 1 || id > len(m.users) {
+		return nil, fmt.Errorf("user not found")
+	}
+	return &m.users[id-1], nil
 }
 
 // CreateUser simulates the behavior of creating a user in the database.
-// It returns nil (no error) to indicate successful user creation in this mock.
+// It keeps the user and its UserRegistered event in memory.
 func (m *mockUserStore) CreateUser(u types.User) error {
+	u.ID = len(m.users) + 1
+	m.users = append(m.users, u)
+	m.events = append(m.events, types.UserRegistered{UserID: u.ID})
 	return nil
-}
\ No newline at end of file
+}
+// UpdatePassword simulates the behavior of changing the password of a created user.
+func (m *mockUserStore) UpdatePassword(userID int, passwordHash string) error {
+	if userID < 1 || userID > len(m.users) {
+		return ErrUserNotFound
+	}
+	m.users[userID-1].Password = passwordHash
+	m.events = append(m.events, types.PasswordChanged{UserID: userID})
+	for hash, id := range m.resets {
+		if id == userID {
+			delete(m.resets, hash)
+		}
+	}
+	return nil
+}
+
+// CreatePasswordReset keeps the hash of a reset token in memory; tokens don't expire.
+func (m *mockUserStore) CreatePasswordReset(userID int, tokenHash string, expiresAt time.Time) error {
+	if m.resets == nil {
+		m.resets = map[string]int{}
+	}
+	m.resets[tokenHash] = userID
+	return nil
+}
+
+// ResetPassword simulates the behavior of changing a password with a reset token.
+func (m *mockUserStore) ResetPassword(tokenHash, passwordHash string, now time.Time) (int, error) {
+	userID, ok := m.resets[tokenHash]
+	if !ok {
+		return 0, ErrInvalidResetToken
+	}
+	return userID, m.UpdatePassword(userID, passwordHash)
+}
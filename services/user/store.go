@@ -3,8 +3,9 @@ package user
 import (
 	"database/sql" // Importing the sql package for database interaction
 	"errors"
-	"fmt" // Importing the fmt package for formatted output and error handling
+	"time"
 
+	"github.com/code-farms/go-backend/services/outbox"
 	"github.com/code-farms/go-backend/types" // Importing the custom types package for user model
 )
 
@@ -16,6 +17,8 @@ type Store struct {
 
 var ErrUserNotFound = errors.New("user not found")
 
+var ErrInvalidResetToken = errors.New("password reset link is invalid or has expired")
+
 // NewStore creates and returns a new Store object, initialized with a database connection.
 func NewStore(db *sql.DB) *Store {
 	// Step 4: Initialize and return a new Store with the given database connection
@@ -24,77 +27,136 @@ func NewStore(db *sql.DB) *Store {
 	}
 }
 
-// CreateUser is a placeholder method for creating a user.
+// CreateUser inserts the user and records a UserRegistered event in the same transaction,
+// so the welcome email is sent exactly when the account exists.
 func (s *Store) CreateUser (user types.User) error {
-	_, err := s.db.Exec("INSERT INTO users (firstName, lastName, email, password) VALUES (?, ?, ?, ?)", user.FirstName, user.LastName, user.Email, user.Password)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	result, err := tx.Exec("INSERT INTO users (firstName, lastName, email, password) VALUES (?, ?, ?, ?)", user.FirstName, user.LastName, user.Email, user.Password)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
 	if err != nil {
 		return err
 	}
 
-	return nil  // Return nil (not implemented)
+	if err := outbox.Record(tx, types.UserRegistered{UserID: int(id)}); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// GetUserByEmailId retrieves a user by email from the database and returns a User object.
-// GetUserByEmail retrieves a user by their email address from the database.
-func (s *Store) GetUserByEmail(email string) (*types.User, error) {
-    // Step 1: Query the database for a single user by email
-    row := s.db.QueryRow("SELECT id, first_name, last_name, email, password FROM users WHERE email = ?", email)
-
-    // Step 2: Create a new user object to hold the result
-    user := new(types.User)
-
-    // Step 3: Scan the row into the user object
-    err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.Password)
-    if err != nil {
-        if errors.Is(err, sql.ErrNoRows) {
-            return nil, ErrUserNotFound // Return a specific error for "user not found"
-        }
-        return nil, err // Return any other database error
-    }
-
-    // Step 4: Return the user object and nil (no error)
-    return user, nil
+// UpdatePassword replaces the password and records a PasswordChanged event in the same
+// transaction, so the user is told about every change.
+func (s *Store) UpdatePassword(userID int, passwordHash string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setPassword(tx, userID, passwordHash); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// GetUserById is a placeholder method for retrieving a user by their ID.
-func (s *Store) GetUserById(id int) (*types.User, error) {
-	rows, err := s.db.Query("SELECT *FROM users WHERE id = ?", id);
+// CreatePasswordReset stores the hash of a reset token; the token itself is only emailed.
+func (s *Store) CreatePasswordReset(userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := s.db.Exec("INSERT INTO password_resets (userId, tokenHash, expires_at) VALUES (?, ?, ?)", userID, tokenHash, expiresAt)
+	return err
+}
+
+// ResetPassword locks the reset token, so two requests with the same link can't both use it.
+func (s *Store) ResetPassword(tokenHash, passwordHash string, now time.Time) (int, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	defer tx.Rollback()
 
-	u, err := scanRowIntoUser(rows)
+	var userID int
+	err = tx.QueryRow("SELECT userId FROM password_resets WHERE tokenHash = ? AND expires_at > ? FOR UPDATE", tokenHash, now).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvalidResetToken
+	}
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	if u.ID == 0 {
-		return nil, fmt.Errorf("user not found")
+
+	if err := setPassword(tx, userID, passwordHash); err != nil {
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// setPassword replaces the password of a user within tx, deleting their reset links and
+// recording a PasswordChanged event.
+func setPassword(tx *sql.Tx, userID int, passwordHash string) error {
+	result, err := tx.Exec("UPDATE users SET password = ? WHERE id = ?", passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrUserNotFound
 	}
 
-	return nil, nil  // Return nil for both user and error (not implemented)
+	if _, err := tx.Exec("DELETE FROM password_resets WHERE userId = ?", userID); err != nil {
+		return err
+	}
+	return outbox.Record(tx, types.PasswordChanged{UserID: userID})
+}
+
+// GetUserByEmailId retrieves a user by email from the database and returns a User object.
+// GetUserByEmail retrieves a user by their email address from the database.
+func (s *Store) GetUserByEmail(email string) (*types.User, error) {
+    // Step 1: Query the database for a single user by email
+    row := s.db.QueryRow("SELECT id, firstName, lastName, email, password, role, created_at FROM users WHERE email = ?", email)
+
+    // Step 2: Scan the row into a new user object
+    return scanRowIntoUser(row)
+}
+
+// GetUserById retrieves a user by their ID from the database.
+func (s *Store) GetUserById(id int) (*types.User, error) {
+	row := s.db.QueryRow("SELECT id, firstName, lastName, email, password, role, created_at FROM users WHERE id = ?", id)
+
+	return scanRowIntoUser(row)
 }
 
 // scanRowIntoUser is a helper function to scan a single row from the result set into a User object.
-func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
+func scanRowIntoUser(row *sql.Row) (*types.User, error) {
 	// Step 12: Create a new user object to hold the scanned data
 	user := new(types.User)
 
 	// Step 13: Scan the columns from the current row into the user object
-	err := rows.Scan(
+	err := row.Scan(
 		&user.ID,        // User ID
 		&user.FirstName,  // User's first name
 		&user.LastName,   // User's last name
 		&user.Email,      // User's email address
 		&user.Password,   // User's hashed password
+		&user.Role,       // User's role
 		&user.CreatedAt,  // User's account creation date
 	)
 
 	// Step 14: If there is an error during scanning, return it
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound // Return a specific error for "user not found"
+		}
 		return nil, err  // Return nil and the scanning error
 	}
 
 	// Step 15: If scanning is successful, return the user object
 	return user, nil  // Return the populated user object and nil (no error)
-}
\ No newline at end of file
+}
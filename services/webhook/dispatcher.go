@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// Headers sent with every delivery. Receivers check the signature over the timestamp and
+// the body, and reject timestamps that are too old so a captured request can't be replayed.
+const (
+	EventHeader     = "Webhook-Event"
+	DeliveryHeader  = "Webhook-Delivery"
+	TimestampHeader = "Webhook-Timestamp"
+	SignatureHeader = "Webhook-Signature"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is given up.
+	MaxAttempts = 10
+
+	// firstRetry is the wait after the first failed attempt, doubled after every other one.
+	firstRetry = 30 * time.Second
+
+	// maxRetry caps the wait between two attempts.
+	maxRetry = 6 * time.Hour
+
+	// requestTimeout bounds a single attempt.
+	requestTimeout = 10 * time.Second
+
+	// batchSize is how many deliveries are claimed at once.
+	batchSize = 50
+
+	// claimLease is how long claimed deliveries are hidden from other dispatchers. The
+	// deliveries of a batch are sent one after the other, so the lease must outlast every
+	// attempt of the batch timing out, or another dispatcher sends the tail a second time.
+	claimLease = batchSize*requestTimeout + time.Minute
+
+	// maxResponseBody is how much of a response is kept in the delivery log.
+	maxResponseBody = 1024
+)
+
+// Dispatcher sends due deliveries to their webhooks and reschedules the failed ones.
+type Dispatcher struct {
+	store  types.WebhookStore
+	client *http.Client
+}
+
+// NewDispatcher sends deliveries with a copy of client that does not follow redirects, so
+// a webhook can't move deliveries to a URL staff did not configure.
+func NewDispatcher(store types.WebhookStore, client *http.Client) *Dispatcher {
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &Dispatcher{store: store, client: &c}
+}
+
+// Start runs Dispatch every interval until ctx is cancelled. It returns immediately.
+func (d *Dispatcher) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := d.Dispatch(ctx); err != nil {
+					log.Printf("failed to dispatch webhooks: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Dispatch sends the deliveries that are due, a batch at a time, until none are left.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	for ctx.Err() == nil {
+		deliveries, err := d.store.ClaimWebhookDeliveries(batchSize, claimLease)
+		if err != nil {
+			return err
+		}
+		for _, delivery := range deliveries {
+			attempt := d.send(ctx, delivery, time.Now())
+
+			var retryAfter time.Duration
+			if !attempt.Succeeded && delivery.Attempts+1 < MaxAttempts {
+				retryAfter = backoff(delivery.Attempts + 1)
+			}
+			if err := d.store.RecordWebhookAttempt(delivery.ID, attempt, retryAfter); err != nil {
+				return err
+			}
+		}
+		if len(deliveries) < batchSize {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+// send POSTs the payload of a delivery, signed with the secret of its webhook, and returns
+// the outcome. Only 2xx responses count as delivered; redirects are not followed.
+func (d *Dispatcher) send(ctx context.Context, delivery types.WebhookDelivery, now time.Time) types.WebhookAttempt {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	attempt := types.WebhookAttempt{DeliveryID: delivery.ID}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-backend-webhooks/1")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.ID))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "v1="+Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	attempt.DurationMs = int(time.Since(start).Milliseconds())
+	if err != nil {
+		attempt.Error = truncate(err.Error())
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	code := resp.StatusCode
+	attempt.ResponseCode = &code
+	attempt.ResponseBody = truncate(string(body))
+	attempt.Succeeded = code >= 200 && code < 300
+	return attempt
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns how long to wait before the attempt after the given number of failed
+// ones: 30s, 1m, 2m, 4m... up to maxRetry.
+func backoff(failed int) time.Duration {
+	wait := firstRetry
+	for i := 1; i < failed && wait < maxRetry; i++ {
+		wait *= 2
+	}
+	return min(wait, maxRetry)
+}
+
+// truncate shortens s to fit the delivery log without splitting a UTF-8 character.
+func truncate(s string) string {
+	if len(s) <= maxResponseBody {
+		return s
+	}
+	s = s[:maxResponseBody]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
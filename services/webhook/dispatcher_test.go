@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+func TestDispatch(t *testing.T) {
+	var received []*http.Request
+	var bodies []string
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received, bodies = append(received, r), append(bodies, string(body))
+		if fail {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	payload := `{"id":"abc","type":"order.created","data":{}}`
+	store := &mockWebhookStore{webhooks: map[int]*types.Webhook{
+		1: {ID: 1, URL: server.URL, Secret: "whsec_0123456789abcdef", Active: true},
+	}}
+	dispatcher := NewDispatcher(store, &http.Client{})
+
+	t.Run("should send signed deliveries", func(t *testing.T) {
+		store.deliveries = []*types.WebhookDelivery{{ID: 1, WebhookID: 1, Event: types.EventOrderCreated, Payload: []byte(payload), Status: types.WebhookDeliveryPending}}
+		if err := dispatcher.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if len(received) != 1 || bodies[0] != payload {
+			t.Fatalf("expected the payload to be sent once but got %v", bodies)
+		}
+
+		req := received[0]
+		if req.Header.Get(EventHeader) != types.EventOrderCreated || req.Header.Get(DeliveryHeader) != "1" {
+			t.Errorf("unexpected headers %v", req.Header)
+		}
+		timestamp, _ := strconv.ParseInt(req.Header.Get(TimestampHeader), 10, 64)
+		if want := "v1=" + Sign("whsec_0123456789abcdef", timestamp, []byte(payload)); req.Header.Get(SignatureHeader) != want {
+			t.Errorf("expected signature %s but got %s", want, req.Header.Get(SignatureHeader))
+		}
+
+		d := store.deliveries[0]
+		if d.Status != types.WebhookDeliverySucceeded || d.Attempts != 1 || *d.LastResponseCode != http.StatusOK {
+			t.Errorf("unexpected delivery %+v", d)
+		}
+	})
+
+	t.Run("should retry failed deliveries later", func(t *testing.T) {
+		fail = true
+		store.deliveries = []*types.WebhookDelivery{{ID: 2, WebhookID: 1, Payload: []byte(payload), Status: types.WebhookDeliveryPending, Attempts: 2}}
+		if err := dispatcher.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		d := store.deliveries[0]
+		if d.Status != types.WebhookDeliveryPending || d.Attempts != 3 || store.retryAfter[2] != 2*time.Minute {
+			t.Errorf("expected a retry in 2m but got %+v after %v", d, store.retryAfter[2])
+		}
+		if h := d.History[0]; h.Succeeded || *h.ResponseCode != http.StatusServiceUnavailable || !strings.Contains(h.ResponseBody, "unavailable") {
+			t.Errorf("unexpected attempt %+v", h)
+		}
+	})
+
+	t.Run("should give up after the last attempt", func(t *testing.T) {
+		store.deliveries = []*types.WebhookDelivery{{ID: 3, WebhookID: 1, Payload: []byte(payload), Status: types.WebhookDeliveryPending, Attempts: MaxAttempts - 1}}
+		if err := dispatcher.Dispatch(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if d := store.deliveries[0]; d.Status != types.WebhookDeliveryFailed || d.Attempts != MaxAttempts {
+			t.Errorf("expected the delivery to fail but got %+v", d)
+		}
+	})
+
+	t.Run("should not follow redirects", func(t *testing.T) {
+		redirect := httptest.NewServer(http.RedirectHandler(server.URL, http.StatusFound))
+		defer redirect.Close()
+
+		fail, received = false, nil
+		attempt := dispatcher.send(context.Background(), types.WebhookDelivery{ID: 4, URL: redirect.URL, Payload: []byte(payload)}, time.Now())
+		if attempt.Succeeded || *attempt.ResponseCode != http.StatusFound || len(received) != 0 {
+			t.Errorf("expected the redirect to fail the attempt but got %+v", attempt)
+		}
+	})
+
+	t.Run("should record requests that get no response", func(t *testing.T) {
+		attempt := dispatcher.send(context.Background(), types.WebhookDelivery{ID: 5, URL: "http://127.0.0.1:1", Payload: []byte(payload)}, time.Now())
+		if attempt.Succeeded || attempt.ResponseCode != nil || attempt.Error == "" {
+			t.Errorf("unexpected attempt %+v", attempt)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 8: 64 * time.Minute, 20: maxRetry}
+	for failed, want := range cases {
+		if got := backoff(failed); got != want {
+			t.Errorf("backoff(%d): expected %v but got %v", failed, want, got)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	want := "b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := Sign("secret", 1700000000, []byte("{}")); got != want {
+		t.Errorf("expected %s but got %s", want, got)
+	}
+	if Sign("secret", 1700000000, []byte("{}")) == Sign("secret", 1700000001, []byte("{}")) {
+		t.Error("expected the timestamp to be signed")
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	s := strings.Repeat("a", maxResponseBody-1) + "é"
+	if got := truncate(s); len(got) != maxResponseBody-1 {
+		t.Errorf("expected the split character to be dropped but got %d bytes", len(got))
+	}
+}
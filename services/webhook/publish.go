@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// Enqueue records a delivery of an event to every active webhook subscribed to it. It runs
+// inside the transaction making the change, so events are only sent for changes that are
+// committed, and none are lost if the process stops before sending them.
+func Enqueue(tx *sql.Tx, event string, data any) error {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	body, err := json.Marshal(types.WebhookEvent{
+		ID:        hex.EncodeToString(id),
+		Type:      event,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event, err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO webhook_deliveries (webhookId, eventId, event, payload)
+		SELECT w.id, ?, ?, ? FROM webhooks w JOIN webhook_events e ON e.webhookId = w.id
+		WHERE w.active AND e.event = ?`,
+		hex.EncodeToString(id), event, body, event,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue %s event: %w", event, err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store     types.WebhookStore
+	userStore types.UserStore
+}
+
+func NewHandler(store types.WebhookStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/admin/webhooks", auth.WithAdminAuth(h.handleGetWebhooks, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/webhooks", auth.WithAdminAuth(h.handleCreateWebhook, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/admin/webhooks/events", auth.WithAdminAuth(h.handleGetEvents, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/webhooks/{id:[0-9]+}", auth.WithAdminAuth(h.handleGetWebhook, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/webhooks/{id:[0-9]+}", auth.WithAdminAuth(h.handleUpdateWebhook, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/admin/webhooks/{id:[0-9]+}", auth.WithAdminAuth(h.handleDeleteWebhook, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/admin/webhooks/{id:[0-9]+}/deliveries", auth.WithAdminAuth(h.handleGetDeliveries, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/webhooks/deliveries/{id:[0-9]+}", auth.WithAdminAuth(h.handleGetDelivery, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/admin/webhooks/deliveries/{id:[0-9]+}/redeliver", auth.WithAdminAuth(h.handleRedeliver, h.userStore)).Methods(http.MethodPost)
+}
+
+func (h *Handler) handleGetWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.store.GetWebhooks()
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhooks)
+}
+
+// handleGetEvents lists the event types webhooks can subscribe to.
+func (h *Handler) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, types.WebhookEvents)
+}
+
+// handleCreateWebhook subscribes a URL to events. The signing secret is generated unless
+// one is given, and is only returned in this response.
+func (h *Handler) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var payload types.WebhookPayload
+	if !parsePayload(w, r, &payload) {
+		return
+	}
+
+	secret := payload.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+		secret = "whsec_" + hex.EncodeToString(b)
+	}
+
+	id, err := h.store.CreateWebhook(payload, secret)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	webhook, err := h.store.GetWebhook(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	webhook.Secret = secret
+	utils.WriteJSON(w, http.StatusCreated, webhook)
+}
+
+func (h *Handler) handleGetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	webhook, err := h.store.GetWebhook(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhook)
+}
+
+// handleUpdateWebhook replaces the settings of a webhook. Deliveries already queued keep
+// going to the webhook even if it no longer subscribes to their event.
+func (h *Handler) handleUpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var payload types.WebhookPayload
+	if !parsePayload(w, r, &payload) {
+		return
+	}
+
+	if err := h.store.UpdateWebhook(id, payload); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	webhook, err := h.store.GetWebhook(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, webhook)
+}
+
+func (h *Handler) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.store.DeleteWebhook(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetDeliveries returns a page of the delivery log of a webhook, newest first,
+// optionally only the deliveries in the status given by the status parameter.
+func (h *Handler) handleGetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	page, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", types.WebhookDeliveryPending, types.WebhookDeliverySucceeded, types.WebhookDeliveryFailed:
+	default:
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid status %q", status))
+		return
+	}
+
+	deliveries, err := h.store.GetWebhookDeliveries(id, status, page.Limit, page.Offset)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"deliveries": deliveries,
+		"page":       page.Page,
+		"limit":      page.Limit,
+	})
+}
+
+// handleGetDelivery returns a delivery with the response of every attempt.
+func (h *Handler) handleGetDelivery(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	delivery, err := h.store.GetWebhookDelivery(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, delivery)
+}
+
+// handleRedeliver sends a delivery again on the next dispatch, e.g. once a receiver that
+// kept failing is fixed. The payload and event ID stay the same.
+func (h *Handler) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := h.store.RedeliverWebhookDelivery(id); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+	delivery, err := h.store.GetWebhookDelivery(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, delivery)
+}
+
+// parsePayload reads and validates a JSON payload into v, writing the error response and
+// returning false if it is invalid.
+func parsePayload(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := utils.ParseJSON(r, v); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return false
+	}
+	if err := utils.Validate.Struct(v); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return false
+	}
+	return true
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrWebhookNotFound), errors.Is(err, ErrDeliveryNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
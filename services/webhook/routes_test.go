@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestWebhooks(t *testing.T) {
+	store := &mockWebhookStore{webhooks: map[int]*types.Webhook{}}
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleAdmin},
+	}}
+
+	handler := NewHandler(store, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	payload := types.WebhookPayload{URL: "https://erp.example.com/hooks", Events: []string{types.EventOrderCreated}}
+
+	t.Run("should only let admins manage webhooks", func(t *testing.T) {
+		if rr := send(http.MethodPost, "/admin/webhooks", 1, payload); rr.Code != http.StatusForbidden {
+			t.Errorf("expected status code %d but got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("should reject invalid webhooks", func(t *testing.T) {
+		invalid := []types.WebhookPayload{
+			{URL: "ftp://erp.example.com", Events: []string{types.EventOrderCreated}},
+			{URL: "https://erp.example.com"},
+			{URL: "https://erp.example.com", Events: []string{"order.deleted"}},
+			{URL: "https://erp.example.com", Events: []string{types.EventOrderCreated}, Secret: "short"},
+		}
+		for _, p := range invalid {
+			if rr := send(http.MethodPost, "/admin/webhooks", 2, p); rr.Code != http.StatusBadRequest {
+				t.Errorf("expected status code %d for %+v but got %d", http.StatusBadRequest, p, rr.Code)
+			}
+		}
+	})
+
+	t.Run("should return the secret only on creation", func(t *testing.T) {
+		rr := send(http.MethodPost, "/admin/webhooks", 2, payload)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		var created types.Webhook
+		json.NewDecoder(rr.Body).Decode(&created)
+		if !strings.HasPrefix(created.Secret, "whsec_") || !created.Active || store.secrets[created.ID] != created.Secret {
+			t.Errorf("unexpected webhook %+v", created)
+		}
+
+		rr = send(http.MethodGet, fmt.Sprintf("/admin/webhooks/%d", created.ID), 2, nil)
+		if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "secret") {
+			t.Errorf("expected the webhook without its secret but got %d: %s", rr.Code, rr.Body)
+		}
+	})
+
+	t.Run("should update and delete webhooks", func(t *testing.T) {
+		inactive := false
+		update := types.WebhookPayload{URL: "https://erp.example.com/v2", Events: []string{types.EventProductUpdated}, Active: &inactive}
+		rr := send(http.MethodPut, "/admin/webhooks/1", 2, update)
+		var updated types.Webhook
+		json.NewDecoder(rr.Body).Decode(&updated)
+		if rr.Code != http.StatusOK || updated.URL != update.URL || updated.Active || len(updated.Events) != 1 {
+			t.Errorf("unexpected response %d: %+v", rr.Code, updated)
+		}
+
+		if rr := send(http.MethodDelete, "/admin/webhooks/1", 2, nil); rr.Code != http.StatusNoContent {
+			t.Errorf("expected status code %d but got %d", http.StatusNoContent, rr.Code)
+		}
+		if rr := send(http.MethodGet, "/admin/webhooks/1", 2, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should list and redeliver deliveries", func(t *testing.T) {
+		code := http.StatusInternalServerError
+		store.webhooks[5] = &types.Webhook{ID: 5, URL: "https://erp.example.com"}
+		store.deliveries = []*types.WebhookDelivery{
+			{ID: 1, WebhookID: 5, Status: types.WebhookDeliverySucceeded},
+			{ID: 2, WebhookID: 5, Status: types.WebhookDeliveryFailed, Attempts: MaxAttempts, LastResponseCode: &code},
+		}
+
+		rr := send(http.MethodGet, "/admin/webhooks/5/deliveries?status=failed", 2, nil)
+		var page struct {
+			Deliveries []types.WebhookDelivery `json:"deliveries"`
+		}
+		json.NewDecoder(rr.Body).Decode(&page)
+		if rr.Code != http.StatusOK || len(page.Deliveries) != 1 || page.Deliveries[0].ID != 2 {
+			t.Errorf("unexpected response %d: %+v", rr.Code, page)
+		}
+		if rr := send(http.MethodGet, "/admin/webhooks/5/deliveries?status=lost", 2, nil); rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d but got %d", http.StatusBadRequest, rr.Code)
+		}
+
+		rr = send(http.MethodPost, "/admin/webhooks/deliveries/2/redeliver", 2, nil)
+		if rr.Code != http.StatusAccepted || store.deliveries[1].Status != types.WebhookDeliveryPending {
+			t.Errorf("expected the delivery to be pending again but got %d: %s", rr.Code, rr.Body)
+		}
+		if rr := send(http.MethodPost, "/admin/webhooks/deliveries/9/redeliver", 2, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
+type mockWebhookStore struct {
+	webhooks   map[int]*types.Webhook
+	secrets    map[int]string
+	deliveries []*types.WebhookDelivery
+	retryAfter map[int]time.Duration
+}
+
+func (m *mockWebhookStore) CreateWebhook(payload types.WebhookPayload, secret string) (int, error) {
+	id := len(m.webhooks) + 1
+	m.webhooks[id] = &types.Webhook{ID: id}
+	if m.secrets == nil {
+		m.secrets = map[int]string{}
+	}
+	m.secrets[id] = secret
+	return id, m.UpdateWebhook(id, payload)
+}
+
+func (m *mockWebhookStore) GetWebhooks() ([]types.Webhook, error) {
+	webhooks := []types.Webhook{}
+	for _, w := range m.webhooks {
+		webhooks = append(webhooks, *w)
+	}
+	return webhooks, nil
+}
+
+func (m *mockWebhookStore) GetWebhook(id int) (*types.Webhook, error) {
+	w, ok := m.webhooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	found := *w
+	found.Secret = ""
+	return &found, nil
+}
+
+func (m *mockWebhookStore) UpdateWebhook(id int, payload types.WebhookPayload) error {
+	w, ok := m.webhooks[id]
+	if !ok {
+		return ErrWebhookNotFound
+	}
+	w.URL, w.Events, w.Description = payload.URL, payload.Events, payload.Description
+	w.Active = payload.Active == nil || *payload.Active
+	return nil
+}
+
+func (m *mockWebhookStore) DeleteWebhook(id int) error {
+	if _, ok := m.webhooks[id]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(m.webhooks, id)
+	return nil
+}
+
+func (m *mockWebhookStore) GetWebhookDeliveries(webhookID int, status string, limit, offset int) ([]types.WebhookDelivery, error) {
+	if _, ok := m.webhooks[webhookID]; !ok {
+		return nil, ErrWebhookNotFound
+	}
+	deliveries := []types.WebhookDelivery{}
+	for _, d := range m.deliveries {
+		if d.WebhookID == webhookID && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, *d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *mockWebhookStore) GetWebhookDelivery(id int) (*types.WebhookDelivery, error) {
+	for _, d := range m.deliveries {
+		if d.ID == id {
+			return d, nil
+		}
+	}
+	return nil, ErrDeliveryNotFound
+}
+
+func (m *mockWebhookStore) RedeliverWebhookDelivery(id int) error {
+	d, err := m.GetWebhookDelivery(id)
+	if err != nil {
+		return err
+	}
+	d.Status = types.WebhookDeliveryPending
+	return nil
+}
+
+func (m *mockWebhookStore) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]types.WebhookDelivery, error) {
+	deliveries := []types.WebhookDelivery{}
+	for _, d := range m.deliveries {
+		if d.Status == types.WebhookDeliveryPending && len(deliveries) < limit {
+			claimed := *d
+			claimed.URL, claimed.Secret = m.webhooks[d.WebhookID].URL, m.webhooks[d.WebhookID].Secret
+			deliveries = append(deliveries, claimed)
+		}
+	}
+	return deliveries, nil
+}
+
+func (m *mockWebhookStore) RecordWebhookAttempt(deliveryID int, attempt types.WebhookAttempt, retryAfter time.Duration) error {
+	d, err := m.GetWebhookDelivery(deliveryID)
+	if err != nil {
+		return err
+	}
+	d.Attempts++
+	d.LastResponseCode = attempt.ResponseCode
+	d.History = append(d.History, attempt)
+	switch {
+	case attempt.Succeeded:
+		d.Status = types.WebhookDeliverySucceeded
+	case retryAfter == 0:
+		d.Status = types.WebhookDeliveryFailed
+	}
+	if m.retryAfter == nil {
+		m.retryAfter = map[int]time.Duration{}
+	}
+	m.retryAfter[deliveryID] = retryAfter
+	return nil
+}
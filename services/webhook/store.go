@@ -0,0 +1,403 @@
+package webhook
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// webhookColumns lists the columns read by scanRowIntoWebhook, in scan order.
+const webhookColumns = "id, url, description, active, created_at, updated_at"
+
+// deliveryColumns lists the columns read by scanRowIntoDelivery, in scan order.
+const deliveryColumns = "id, webhookId, eventId, event, payload, status, attempts, nextAttemptAt, lastResponseCode, created_at, deliveredAt"
+
+// attemptColumns lists the columns read by scanRowIntoAttempt, in scan order.
+const attemptColumns = "id, deliveryId, succeeded, responseCode, responseBody, error, durationMs, created_at"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateWebhook(payload types.WebhookPayload, secret string) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO webhooks (url, secret, description, active) VALUES (?, ?, ?, ?)",
+		payload.URL, secret, strings.TrimSpace(payload.Description), payload.Active == nil || *payload.Active,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert webhook: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := setEvents(tx, int(id), payload.Events); err != nil {
+		return 0, err
+	}
+
+	return int(id), tx.Commit()
+}
+
+func (s *Store) GetWebhooks() ([]types.Webhook, error) {
+	return s.queryWebhooks("1 = 1")
+}
+
+func (s *Store) GetWebhook(id int) (*types.Webhook, error) {
+	webhooks, err := s.queryWebhooks("id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, ErrWebhookNotFound
+	}
+	return &webhooks[0], nil
+}
+
+func (s *Store) UpdateWebhook(id int, payload types.WebhookPayload) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRow("SELECT id FROM webhooks WHERE id = ? FOR UPDATE", id).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE webhooks SET url = ?, secret = IF(? = '', secret, ?), description = ?, active = ? WHERE id = ?",
+		payload.URL, payload.Secret, payload.Secret, strings.TrimSpace(payload.Description), payload.Active == nil || *payload.Active, id,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM webhook_events WHERE webhookId = ?", id); err != nil {
+		return err
+	}
+	if err := setEvents(tx, id, payload.Events); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) DeleteWebhook(id int) error {
+	result, err := s.db.Exec("DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+func (s *Store) GetWebhookDeliveries(webhookID int, status string, limit, offset int) ([]types.WebhookDelivery, error) {
+	if _, err := s.GetWebhook(webhookID); err != nil {
+		return nil, err
+	}
+
+	where, args := "webhookId = ?", []any{webhookID}
+	if status != "" {
+		where, args = where+" AND status = ?", append(args, status)
+	}
+	rows, err := s.db.Query(
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE "+where+" ORDER BY id DESC LIMIT ? OFFSET ?",
+		append(args, limit, offset)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []types.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanRowIntoDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *Store) GetWebhookDelivery(id int) (*types.WebhookDelivery, error) {
+	rows, err := s.db.Query("SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrDeliveryNotFound
+	}
+	d, err := scanRowIntoDelivery(rows)
+	if err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	attemptRows, err := s.db.Query("SELECT "+attemptColumns+" FROM webhook_attempts WHERE deliveryId = ? ORDER BY id", id)
+	if err != nil {
+		return nil, err
+	}
+	defer attemptRows.Close()
+
+	d.History = []types.WebhookAttempt{}
+	for attemptRows.Next() {
+		a, err := scanRowIntoAttempt(attemptRows)
+		if err != nil {
+			return nil, err
+		}
+		d.History = append(d.History, *a)
+	}
+	return d, attemptRows.Err()
+}
+
+// RedeliverWebhookDelivery makes a delivery due again, whatever its status. One that had
+// used up its retries gets a single attempt.
+func (s *Store) RedeliverWebhookDelivery(id int) error {
+	result, err := s.db.Exec(
+		"UPDATE webhook_deliveries SET status = ?, nextAttemptAt = CURRENT_TIMESTAMP WHERE id = ?",
+		types.WebhookDeliveryPending, id,
+	)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM webhook_deliveries WHERE id = ?)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries pushes the next attempt of the claimed deliveries back by lease,
+// so a dispatcher that dies while sending leaves them to be retried rather than stuck.
+// Rows claimed by another dispatcher are skipped instead of waited for.
+func (s *Store) ClaimWebhookDeliveries(limit int, lease time.Duration) ([]types.WebhookDelivery, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		`SELECT d.id, d.webhookId, d.eventId, d.event, d.payload, d.status, d.attempts, d.nextAttemptAt, d.lastResponseCode,
+			d.created_at, d.deliveredAt, w.url, w.secret
+		FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhookId
+		WHERE d.status = ? AND d.nextAttemptAt <= CURRENT_TIMESTAMP
+		ORDER BY d.nextAttemptAt, d.id LIMIT ?
+		FOR UPDATE OF d SKIP LOCKED`,
+		types.WebhookDeliveryPending, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []types.WebhookDelivery{}
+	for rows.Next() {
+		d := types.WebhookDelivery{}
+		err := rows.Scan(append(deliveryDest(&d), &d.URL, &d.Secret)...)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, d := range deliveries {
+		_, err := tx.Exec(
+			"UPDATE webhook_deliveries SET nextAttemptAt = CURRENT_TIMESTAMP + INTERVAL ? SECOND WHERE id = ?",
+			int(lease.Seconds()), d.ID,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return deliveries, tx.Commit()
+}
+
+func (s *Store) RecordWebhookAttempt(deliveryID int, attempt types.WebhookAttempt, retryAfter time.Duration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"INSERT INTO webhook_attempts (deliveryId, succeeded, responseCode, responseBody, error, durationMs) VALUES (?, ?, ?, ?, ?, ?)",
+		deliveryID, attempt.Succeeded, attempt.ResponseCode, attempt.ResponseBody, attempt.Error, attempt.DurationMs,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert webhook attempt: %w", err)
+	}
+
+	switch {
+	case attempt.Succeeded:
+		_, err = tx.Exec(
+			`UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, lastResponseCode = ?, nextAttemptAt = NULL,
+				deliveredAt = CURRENT_TIMESTAMP WHERE id = ?`,
+			types.WebhookDeliverySucceeded, attempt.ResponseCode, deliveryID,
+		)
+	case retryAfter > 0:
+		_, err = tx.Exec(
+			`UPDATE webhook_deliveries SET attempts = attempts + 1, lastResponseCode = ?,
+				nextAttemptAt = CURRENT_TIMESTAMP + INTERVAL ? SECOND WHERE id = ?`,
+			attempt.ResponseCode, int(retryAfter.Seconds()), deliveryID,
+		)
+	default:
+		_, err = tx.Exec(
+			"UPDATE webhook_deliveries SET status = ?, attempts = attempts + 1, lastResponseCode = ?, nextAttemptAt = NULL WHERE id = ?",
+			types.WebhookDeliveryFailed, attempt.ResponseCode, deliveryID,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// queryWebhooks returns the webhooks matching where, oldest first, with their events.
+func (s *Store) queryWebhooks(where string, args ...any) ([]types.Webhook, error) {
+	rows, err := s.db.Query("SELECT "+webhookColumns+" FROM webhooks WHERE "+where+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []types.Webhook{}
+	index := map[int]int{}
+	for rows.Next() {
+		w, err := scanRowIntoWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		w.Events = []string{}
+		index[w.ID] = len(webhooks)
+		webhooks = append(webhooks, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	eventRows, err := s.db.Query(
+		"SELECT webhookId, event FROM webhook_events WHERE webhookId IN (SELECT id FROM webhooks WHERE "+where+") ORDER BY event", args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer eventRows.Close()
+
+	for eventRows.Next() {
+		var webhookID int
+		var event string
+		if err := eventRows.Scan(&webhookID, &event); err != nil {
+			return nil, err
+		}
+		if i, ok := index[webhookID]; ok {
+			webhooks[i].Events = append(webhooks[i].Events, event)
+		}
+	}
+	return webhooks, eventRows.Err()
+}
+
+// setEvents subscribes a webhook to events inside tx. Events listed twice are stored once.
+func setEvents(tx *sql.Tx, webhookID int, events []string) error {
+	for _, event := range events {
+		if _, err := tx.Exec("INSERT IGNORE INTO webhook_events (webhookId, event) VALUES (?, ?)", webhookID, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanRowIntoWebhook(rows *sql.Rows) (*types.Webhook, error) {
+	w := new(types.Webhook)
+	err := rows.Scan(
+		&w.ID,
+		&w.URL,
+		&w.Description,
+		&w.Active,
+		&w.CreatedAt,
+		&w.UpdatedAt,
+	)
+	return w, err
+}
+
+func scanRowIntoDelivery(rows *sql.Rows) (*types.WebhookDelivery, error) {
+	d := new(types.WebhookDelivery)
+	err := rows.Scan(deliveryDest(d)...)
+	return d, err
+}
+
+// deliveryDest returns the scan destinations of deliveryColumns.
+func deliveryDest(d *types.WebhookDelivery) []any {
+	return []any{
+		&d.ID,
+		&d.WebhookID,
+		&d.EventID,
+		&d.Event,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastResponseCode,
+		&d.CreatedAt,
+		&d.DeliveredAt,
+	}
+}
+
+func scanRowIntoAttempt(rows *sql.Rows) (*types.WebhookAttempt, error) {
+	a := new(types.WebhookAttempt)
+	err := rows.Scan(
+		&a.ID,
+		&a.DeliveryID,
+		&a.Succeeded,
+		&a.ResponseCode,
+		&a.ResponseBody,
+		&a.Error,
+		&a.DurationMs,
+		&a.CreatedAt,
+	)
+	return a, err
+}
@@ -0,0 +1,288 @@
+package wishlist
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/code-farms/go-backend/services/auth"
+	"github.com/code-farms/go-backend/types"
+	"github.com/code-farms/go-backend/utils"
+	"github.com/gorilla/mux"
+)
+
+type Handler struct {
+	store       types.WishlistStore
+	userStore   types.UserStore
+	sharedRoute *mux.Route
+}
+
+func NewHandler(store types.WishlistStore, userStore types.UserStore) *Handler {
+	return &Handler{store: store, userStore: userStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/wishlists", auth.WithJWTAuth(h.handleGetWishlists, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/wishlists", auth.WithJWTAuth(h.handleCreateWishlist, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/wishlists/{id:[0-9]+}", auth.WithJWTAuth(h.handleGetWishlist, h.userStore)).Methods(http.MethodGet)
+	router.HandleFunc("/wishlists/{id:[0-9]+}", auth.WithJWTAuth(h.handleUpdateWishlist, h.userStore)).Methods(http.MethodPut)
+	router.HandleFunc("/wishlists/{id:[0-9]+}", auth.WithJWTAuth(h.handleDeleteWishlist, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/wishlists/{id:[0-9]+}/items", auth.WithJWTAuth(h.handleAddItem, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/wishlists/{id:[0-9]+}/items/{productID:[0-9]+}", auth.WithJWTAuth(h.handleRemoveItem, h.userStore)).Methods(http.MethodDelete)
+	router.HandleFunc("/wishlists/{id:[0-9]+}/share", auth.WithJWTAuth(h.handleShare, h.userStore)).Methods(http.MethodPost)
+	router.HandleFunc("/wishlists/{id:[0-9]+}/share", auth.WithJWTAuth(h.handleUnshare, h.userStore)).Methods(http.MethodDelete)
+
+	h.sharedRoute = router.HandleFunc("/shared/wishlists/{token:[0-9a-f]{64}}", h.handleGetSharedWishlist).Methods(http.MethodGet)
+}
+
+// handleGetWishlists lists the wishlists of the authenticated user without their items.
+func (h *Handler) handleGetWishlists(w http.ResponseWriter, r *http.Request) {
+	wishlists, err := h.store.GetWishlists(auth.GetUserIDFromContext(r.Context()))
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	for i := range wishlists {
+		h.setShareURL(&wishlists[i])
+	}
+	utils.WriteJSON(w, http.StatusOK, wishlists)
+}
+
+func (h *Handler) handleCreateWishlist(w http.ResponseWriter, r *http.Request) {
+	var payload types.CreateWishlistPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	id, err := h.store.CreateWishlist(auth.GetUserIDFromContext(r.Context()), payload.Name)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	wishlist, err := h.store.GetWishlist(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, wishlist)
+}
+
+// handleGetWishlist returns a wishlist with its items, their current price and stock, and
+// whether each one got cheaper since it was added.
+func (h *Handler) handleGetWishlist(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.store.GetWishlistItems(wishlist.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	wishlist.Items = items
+	h.setShareURL(wishlist)
+	utils.WriteJSON(w, http.StatusOK, wishlist)
+}
+
+// handleUpdateWishlist renames a wishlist and can make it the default one.
+func (h *Handler) handleUpdateWishlist(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.UpdateWishlistPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	if err := h.store.UpdateWishlist(wishlist.ID, payload.Name, payload.IsDefault); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	updated, err := h.store.GetWishlist(wishlist.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	h.setShareURL(updated)
+	utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handler) handleDeleteWishlist(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteWishlist(wishlist.ID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleAddItem(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	var payload types.AddWishlistItemPayload
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid JSON: %v", err))
+		return
+	}
+	if err := utils.Validate.Struct(payload); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload: %v", err))
+		return
+	}
+
+	if err := h.store.AddWishlistItem(wishlist.ID, payload.ProductID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]int{"productId": payload.ProductID})
+}
+
+func (h *Handler) handleRemoveItem(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+	productID, _ := strconv.Atoi(mux.Vars(r)["productID"])
+
+	if err := h.store.RemoveWishlistItem(wishlist.ID, productID); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleShare creates a public link to a wishlist. Sharing again replaces the token, which
+// invalidates links handed out before.
+func (h *Handler) handleShare(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err := h.store.SetShareToken(wishlist.ID, token); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	wishlist.ShareToken = token
+	h.setShareURL(wishlist)
+	utils.WriteJSON(w, http.StatusOK, wishlist)
+}
+
+func (h *Handler) handleUnshare(w http.ResponseWriter, r *http.Request) {
+	wishlist, ok := h.ownWishlist(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.SetShareToken(wishlist.ID, ""); err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetSharedWishlist shows a shared wishlist to anyone with the link. The owner and
+// the token are left out of the response.
+func (h *Handler) handleGetSharedWishlist(w http.ResponseWriter, r *http.Request) {
+	wishlist, err := h.store.GetWishlistByShareToken(mux.Vars(r)["token"])
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	items, err := h.store.GetWishlistItems(wishlist.ID)
+	if err != nil {
+		writeStoreError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, map[string]any{
+		"name":  wishlist.Name,
+		"items": items,
+	})
+}
+
+// ownWishlist loads the wishlist addressed by the request, answering 404 when it belongs
+// to someone else so other users' wishlist IDs are not disclosed.
+func (h *Handler) ownWishlist(w http.ResponseWriter, r *http.Request) (*types.Wishlist, bool) {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	wishlist, err := h.store.GetWishlist(id)
+	if err != nil {
+		writeStoreError(w, err)
+		return nil, false
+	}
+	if wishlist.UserID != auth.GetUserIDFromContext(r.Context()) {
+		writeStoreError(w, ErrWishlistNotFound)
+		return nil, false
+	}
+
+	return wishlist, true
+}
+
+func (h *Handler) setShareURL(wishlist *types.Wishlist) {
+	if wishlist.ShareToken == "" {
+		return
+	}
+	if u, err := h.sharedRoute.URL("token", wishlist.ShareToken); err == nil {
+		wishlist.ShareURL = u.String()
+	}
+}
+
+// newShareToken returns 32 random bytes, hex encoded, so share links cannot be guessed.
+func newShareToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrWishlistNotFound), errors.Is(err, ErrProductNotFound), errors.Is(err, ErrItemNotFound):
+		utils.WriteError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrDeleteDefault), errors.Is(err, ErrUnsetDefault):
+		utils.WriteError(w, http.StatusConflict, err)
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,256 @@
+package wishlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-farms/go-backend/internal/testutil"
+	"github.com/code-farms/go-backend/types"
+	"github.com/gorilla/mux"
+)
+
+func TestWishlistHandlers(t *testing.T) {
+	store := newMockWishlistStore(map[int]float64{1: 20, 2: 5})
+	userStore := &testutil.UserStore{Users: map[int]*types.User{
+		1: {ID: 1, Role: types.RoleCustomer},
+		2: {ID: 2, Role: types.RoleCustomer},
+	}}
+
+	handler := NewHandler(store, userStore)
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	send := func(method, path string, userID int, payload any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, path, bytes.NewReader(body))
+		if userID != 0 {
+			req.Header.Set("Authorization", "Bearer "+testutil.Token(t, userID))
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("should create the default wishlist on first use", func(t *testing.T) {
+		rr := send(http.MethodGet, "/wishlists", 1, nil)
+		var wishlists []types.Wishlist
+		json.NewDecoder(rr.Body).Decode(&wishlists)
+		if len(wishlists) != 1 || !wishlists[0].IsDefault {
+			t.Errorf("expected a single default wishlist but got %+v", wishlists)
+		}
+	})
+
+	t.Run("should flag products that got cheaper", func(t *testing.T) {
+		rr := send(http.MethodPost, "/wishlists/1/items", 1, types.AddWishlistItemPayload{ProductID: 1})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+		store.prices[1] = 15
+
+		rr = send(http.MethodGet, "/wishlists/1", 1, nil)
+		var wishlist types.Wishlist
+		json.NewDecoder(rr.Body).Decode(&wishlist)
+		if len(wishlist.Items) != 1 || !wishlist.Items[0].PriceDropped || wishlist.Items[0].PriceDrop != 5 {
+			t.Errorf("expected a price drop of 5 but got %+v", wishlist.Items)
+		}
+	})
+
+	t.Run("should answer 404 for unknown products", func(t *testing.T) {
+		rr := send(http.MethodPost, "/wishlists/1/items", 1, types.AddWishlistItemPayload{ProductID: 9})
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should hide other users' wishlists", func(t *testing.T) {
+		rr := send(http.MethodGet, "/wishlists/1", 2, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("should not delete the default wishlist", func(t *testing.T) {
+		rr := send(http.MethodDelete, "/wishlists/1", 1, nil)
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status code %d but got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("should move the default to another wishlist", func(t *testing.T) {
+		rr := send(http.MethodPost, "/wishlists", 1, types.CreateWishlistPayload{Name: "Birthday"})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusCreated, rr.Code, rr.Body)
+		}
+
+		rr = send(http.MethodPut, "/wishlists/2", 1, types.UpdateWishlistPayload{Name: "Birthday", IsDefault: true})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d: %s", http.StatusOK, rr.Code, rr.Body)
+		}
+		if store.wishlists[1].IsDefault || !store.wishlists[2].IsDefault {
+			t.Errorf("expected wishlist 2 to be the only default")
+		}
+	})
+
+	t.Run("should share a wishlist through an unguessable link", func(t *testing.T) {
+		rr := send(http.MethodPost, "/wishlists/1/share", 1, nil)
+		var wishlist types.Wishlist
+		json.NewDecoder(rr.Body).Decode(&wishlist)
+		if len(wishlist.ShareToken) != 64 || !strings.HasSuffix(wishlist.ShareURL, wishlist.ShareToken) {
+			t.Fatalf("unexpected share link %+v", wishlist)
+		}
+
+		rr = send(http.MethodGet, wishlist.ShareURL, 0, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status code %d but got %d", http.StatusOK, rr.Code)
+		}
+		if strings.Contains(rr.Body.String(), "userId") {
+			t.Errorf("expected the owner to stay hidden but got %s", rr.Body)
+		}
+
+		send(http.MethodDelete, "/wishlists/1/share", 1, nil)
+		rr = send(http.MethodGet, wishlist.ShareURL, 0, nil)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status code %d after unsharing but got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+}
+
+type mockItem struct {
+	productID      int
+	priceWhenAdded float64
+}
+
+type mockWishlistStore struct {
+	prices    map[int]float64
+	wishlists map[int]*types.Wishlist
+	items     map[int][]mockItem
+}
+
+func newMockWishlistStore(prices map[int]float64) *mockWishlistStore {
+	return &mockWishlistStore{prices: prices, wishlists: map[int]*types.Wishlist{}, items: map[int][]mockItem{}}
+}
+
+func (m *mockWishlistStore) GetWishlists(userID int) ([]types.Wishlist, error) {
+	wishlists := []types.Wishlist{}
+	for id := 1; id <= len(m.wishlists); id++ {
+		if w := m.wishlists[id]; w.UserID == userID {
+			wishlists = append(wishlists, *w)
+		}
+	}
+	if len(wishlists) == 0 {
+		w := &types.Wishlist{ID: len(m.wishlists) + 1, UserID: userID, Name: DefaultWishlistName, IsDefault: true}
+		m.wishlists[w.ID] = w
+		wishlists = append(wishlists, *w)
+	}
+	return wishlists, nil
+}
+
+func (m *mockWishlistStore) GetWishlist(id int) (*types.Wishlist, error) {
+	w, ok := m.wishlists[id]
+	if !ok {
+		return nil, ErrWishlistNotFound
+	}
+	wishlist := *w
+	return &wishlist, nil
+}
+
+func (m *mockWishlistStore) GetWishlistByShareToken(token string) (*types.Wishlist, error) {
+	for _, w := range m.wishlists {
+		if w.ShareToken != "" && w.ShareToken == token {
+			return m.GetWishlist(w.ID)
+		}
+	}
+	return nil, ErrWishlistNotFound
+}
+
+func (m *mockWishlistStore) CreateWishlist(userID int, name string) (int, error) {
+	w := &types.Wishlist{ID: len(m.wishlists) + 1, UserID: userID, Name: name}
+	m.wishlists[w.ID] = w
+	return w.ID, nil
+}
+
+func (m *mockWishlistStore) UpdateWishlist(id int, name string, makeDefault bool) error {
+	w, ok := m.wishlists[id]
+	if !ok {
+		return ErrWishlistNotFound
+	}
+	if w.IsDefault && !makeDefault {
+		return ErrUnsetDefault
+	}
+	if makeDefault {
+		for _, other := range m.wishlists {
+			if other.UserID == w.UserID {
+				other.IsDefault = false
+			}
+		}
+	}
+	w.Name, w.IsDefault = name, makeDefault
+	return nil
+}
+
+func (m *mockWishlistStore) DeleteWishlist(id int) error {
+	w, ok := m.wishlists[id]
+	if !ok {
+		return ErrWishlistNotFound
+	}
+	if w.IsDefault {
+		return ErrDeleteDefault
+	}
+	delete(m.wishlists, id)
+	return nil
+}
+
+func (m *mockWishlistStore) SetShareToken(id int, token string) error {
+	w, ok := m.wishlists[id]
+	if !ok {
+		return ErrWishlistNotFound
+	}
+	w.ShareToken = token
+	return nil
+}
+
+func (m *mockWishlistStore) GetWishlistItems(wishlistID int) ([]types.WishlistItem, error) {
+	items := []types.WishlistItem{}
+	for _, item := range m.items[wishlistID] {
+		price := m.prices[item.productID]
+		drop := max(item.priceWhenAdded-price, 0)
+		items = append(items, types.WishlistItem{
+			ProductID:      item.productID,
+			Price:          price,
+			PriceWhenAdded: item.priceWhenAdded,
+			PriceDrop:      drop,
+			PriceDropped:   drop > 0,
+			AddedAt:        time.Now(),
+		})
+	}
+	return items, nil
+}
+
+func (m *mockWishlistStore) AddWishlistItem(wishlistID, productID int) error {
+	price, ok := m.prices[productID]
+	if !ok {
+		return ErrProductNotFound
+	}
+	for _, item := range m.items[wishlistID] {
+		if item.productID == productID {
+			return nil
+		}
+	}
+	m.items[wishlistID] = append(m.items[wishlistID], mockItem{productID: productID, priceWhenAdded: price})
+	return nil
+}
+
+func (m *mockWishlistStore) RemoveWishlistItem(wishlistID, productID int) error {
+	for i, item := range m.items[wishlistID] {
+		if item.productID == productID {
+			m.items[wishlistID] = append(m.items[wishlistID][:i], m.items[wishlistID][i+1:]...)
+			return nil
+		}
+	}
+	return ErrItemNotFound
+}
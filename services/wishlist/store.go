@@ -0,0 +1,232 @@
+package wishlist
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+var (
+	ErrWishlistNotFound = errors.New("wishlist not found")
+	ErrProductNotFound  = errors.New("product not found")
+	ErrItemNotFound     = errors.New("product is not on the wishlist")
+	ErrDeleteDefault    = errors.New("the default wishlist cannot be deleted")
+	ErrUnsetDefault     = errors.New("make another wishlist the default instead")
+)
+
+// DefaultWishlistName is the name of the wishlist created for every customer.
+const DefaultWishlistName = "Wishlist"
+
+// wishlistColumns lists the columns read by scanRowIntoWishlist, in scan order.
+const wishlistColumns = "id, userId, name, isDefault, COALESCE(shareToken, ''), created_at"
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) GetWishlists(userID int) ([]types.Wishlist, error) {
+	// The unique key on defaultUserId turns this into a no-op once the default exists
+	if _, err := s.db.Exec("INSERT IGNORE INTO wishlists (userId, name, isDefault) VALUES (?, ?, 1)", userID, DefaultWishlistName); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query("SELECT "+wishlistColumns+" FROM wishlists WHERE userId = ? ORDER BY isDefault DESC, id", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wishlists := []types.Wishlist{}
+	for rows.Next() {
+		w, err := scanRowIntoWishlist(rows)
+		if err != nil {
+			return nil, err
+		}
+		wishlists = append(wishlists, *w)
+	}
+	return wishlists, rows.Err()
+}
+
+func (s *Store) GetWishlist(id int) (*types.Wishlist, error) {
+	return s.getWishlist("id = ?", id)
+}
+
+func (s *Store) GetWishlistByShareToken(token string) (*types.Wishlist, error) {
+	return s.getWishlist("shareToken = ?", token)
+}
+
+func (s *Store) CreateWishlist(userID int, name string) (int, error) {
+	result, err := s.db.Exec("INSERT INTO wishlists (userId, name) VALUES (?, ?)", userID, name)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+func (s *Store) UpdateWishlist(id int, name string, makeDefault bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var isDefault bool
+	err = tx.QueryRow("SELECT userId, isDefault FROM wishlists WHERE id = ? FOR UPDATE", id).Scan(&userID, &isDefault)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWishlistNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if isDefault && !makeDefault {
+		return ErrUnsetDefault
+	}
+
+	if makeDefault && !isDefault {
+		// Clear the old default first so the unique key on defaultUserId is never violated
+		if _, err := tx.Exec("UPDATE wishlists SET isDefault = 0 WHERE userId = ? AND isDefault = 1", userID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("UPDATE wishlists SET name = ?, isDefault = ? WHERE id = ?", name, makeDefault, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *Store) DeleteWishlist(id int) error {
+	result, err := s.db.Exec("DELETE FROM wishlists WHERE id = ? AND isDefault = 0", id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+
+	if _, err := s.GetWishlist(id); err != nil {
+		return err
+	}
+	return ErrDeleteDefault
+}
+
+func (s *Store) SetShareToken(id int, token string) error {
+	result, err := s.db.Exec("UPDATE wishlists SET shareToken = ? WHERE id = ?", sql.NullString{String: token, Valid: token != ""}, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+
+	// Nothing changed: either the wishlist is missing or it was already in that state
+	_, err = s.GetWishlist(id)
+	return err
+}
+
+func (s *Store) GetWishlistItems(wishlistID int) ([]types.WishlistItem, error) {
+	rows, err := s.db.Query(`
+		SELECT p.id, p.name, p.image, p.price, i.priceWhenAdded, GREATEST(i.priceWhenAdded - p.price, 0),
+			GREATEST(CAST(p.quantity AS SIGNED) - CAST(p.reserved AS SIGNED), 0), i.created_at
+		FROM wishlist_items i
+		JOIN products p ON p.id = i.productId
+		WHERE i.wishlistId = ?
+		ORDER BY i.created_at DESC, i.id DESC`, wishlistID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []types.WishlistItem{}
+	for rows.Next() {
+		var item types.WishlistItem
+		err := rows.Scan(
+			&item.ProductID,
+			&item.Name,
+			&item.Image,
+			&item.Price,
+			&item.PriceWhenAdded,
+			&item.PriceDrop,
+			&item.Available,
+			&item.AddedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		item.PriceDropped = item.PriceDrop > 0
+		item.InStock = item.Available > 0
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s *Store) AddWishlistItem(wishlistID, productID int) error {
+	result, err := s.db.Exec(`
+		INSERT INTO wishlist_items (wishlistId, productId, priceWhenAdded)
+		SELECT ?, id, price FROM products WHERE id = ?
+		ON DUPLICATE KEY UPDATE wishlistId = wishlistId`, wishlistID, productID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+
+	// No row was inserted: the product is either unknown or already on the list
+	var exists bool
+	if err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM products WHERE id = ?)", productID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+func (s *Store) RemoveWishlistItem(wishlistID, productID int) error {
+	result, err := s.db.Exec("DELETE FROM wishlist_items WHERE wishlistId = ? AND productId = ?", wishlistID, productID)
+	if err != nil {
+		return err
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrItemNotFound
+	}
+	return err
+}
+
+func (s *Store) getWishlist(where string, arg any) (*types.Wishlist, error) {
+	rows, err := s.db.Query("SELECT "+wishlistColumns+" FROM wishlists WHERE "+where, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrWishlistNotFound
+	}
+	return scanRowIntoWishlist(rows)
+}
+
+func scanRowIntoWishlist(rows *sql.Rows) (*types.Wishlist, error) {
+	w := new(types.Wishlist)
+	err := rows.Scan(
+		&w.ID,
+		&w.UserID,
+		&w.Name,
+		&w.IsDefault,
+		&w.ShareToken,
+		&w.CreatedAt,
+	)
+	return w, err
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/code-farms/go-backend/types"
+)
+
+// Both implementations must satisfy the BlobStore interface.
+var (
+	_ types.BlobStore = (*LocalStore)(nil)
+	_ types.BlobStore = (*S3Store)(nil)
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testBlobStore(t, store)
+
+	t.Run("should reject keys escaping the root", func(t *testing.T) {
+		for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b"} {
+			if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+				t.Errorf("expected key %q to be rejected", key)
+			}
+		}
+	})
+}
+
+func TestS3Store(t *testing.T) {
+	fake := newFakeS3("test-bucket", "test-access-key")
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		Bucket:    "test-bucket",
+		AccessKey: "test-access-key",
+		SecretKey: "test-secret-key",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testBlobStore(t, store)
+
+	t.Run("should store objects under the bucket path", func(t *testing.T) {
+		if err := store.Put(context.Background(), "products/1/a b.png", strings.NewReader("png"), 3, "image/png"); err != nil {
+			t.Fatal(err)
+		}
+		if got := fake.contentType("/test-bucket/products/1/a b.png"); got != "image/png" {
+			t.Errorf("expected content type image/png but got %q", got)
+		}
+	})
+}
+
+// testBlobStore runs the behaviour shared by every BlobStore implementation.
+func testBlobStore(t *testing.T, store types.BlobStore) {
+	ctx := context.Background()
+
+	t.Run("should round-trip an object", func(t *testing.T) {
+		if err := store.Put(ctx, "products/1/image.jpg", strings.NewReader("hello"), 5, "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+
+		r, err := store.Get(ctx, "products/1/image.jpg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+
+		data, _ := io.ReadAll(r)
+		if string(data) != "hello" {
+			t.Errorf("expected %q but got %q", "hello", data)
+		}
+	})
+
+	t.Run("should return ErrNotFound for missing objects", func(t *testing.T) {
+		if _, err := store.Get(ctx, "products/1/missing.jpg"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound but got %v", err)
+		}
+	})
+
+	t.Run("should delete objects", func(t *testing.T) {
+		if err := store.Put(ctx, "products/2/image.jpg", strings.NewReader("bye"), 3, "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.Delete(ctx, "products/2/image.jpg"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Get(ctx, "products/2/image.jpg"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound after delete but got %v", err)
+		}
+		if err := store.Delete(ctx, "products/2/image.jpg"); err != nil {
+			t.Errorf("expected deleting a missing object to succeed but got %v", err)
+		}
+	})
+}
+
+// fakeS3 is a minimal in-memory stand-in for an S3-compatible server.
+type fakeS3 struct {
+	mu        sync.Mutex
+	bucket    string
+	accessKey string
+	objects   map[string][]byte
+	types     map[string]string
+}
+
+func newFakeS3(bucket, accessKey string) *fakeS3 {
+	return &fakeS3{bucket: bucket, accessKey: accessKey, objects: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (f *fakeS3) contentType(path string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.types[path]
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+f.accessKey+"/") ||
+		!strings.Contains(auth, "/us-east-1/s3/aws4_request") ||
+		r.Header.Get("X-Amz-Date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, "/"+f.bucket+"/") {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
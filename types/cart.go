@@ -0,0 +1,74 @@
+package types
+
+// Cart item problems reported by the cart when it is read.
+const (
+	CartItemUnavailable       = "unavailable"        // The product is out of stock
+	CartItemInsufficientStock = "insufficient_stock" // Fewer units are available than are in the cart
+)
+
+// CartStore defines the methods required to keep shopping carts. A cart belongs either to
+// a user or, for visitors who are not logged in, to an anonymous token kept in a cookie.
+type CartStore interface {
+	// GetUserCartID returns the ID of the user's cart, creating the cart if needed.
+	GetUserCartID(userID int) (int, error)
+
+	// GetGuestCartID returns the ID of the anonymous cart with the given token.
+	GetGuestCartID(token string) (int, error)
+
+	// CreateGuestCart creates an anonymous cart for token and returns its ID.
+	CreateGuestCart(token string) (int, error)
+
+	// GetCartItems returns the items of a cart with the current price and stock of each
+	// product, in the order they were added.
+	GetCartItems(cartID int) ([]CartItem, error)
+
+	// AddCartItem adds quantity units of a product, on top of any already in the cart.
+	AddCartItem(cartID, productID, quantity int) error
+
+	// SetCartItemQuantity replaces the quantity of a product already in the cart.
+	SetCartItemQuantity(cartID, productID, quantity int) error
+
+	// RemoveCartItem removes a product from the cart.
+	RemoveCartItem(cartID, productID int) error
+
+	// AcknowledgePrices remembers the current price of every item so a price change is
+	// only reported once.
+	AcknowledgePrices(cartID int) error
+
+	// MergeCarts moves the items of one cart into another, adding up the quantities of
+	// products found in both, and deletes the emptied cart.
+	MergeCarts(fromCartID, toCartID int) error
+}
+
+// Cart is the content of a shopping cart as shown to the customer. Prices and stock are
+// revalidated every time the cart is read.
+type Cart struct {
+	Items         []CartItem `json:"items"`         // The products in the cart
+	ItemCount     int        `json:"itemCount"`     // The total number of units
+	Subtotal      float64    `json:"subtotal"`      // The sum of the line totals at current prices
+	CheckoutReady bool       `json:"checkoutReady"` // Whether every item can be bought in the requested quantity
+}
+
+// CartItem is a product in a cart.
+type CartItem struct {
+	ProductID     int      `json:"productId"`               // The product in the cart
+	Name          string   `json:"name"`                    // The current name of the product
+	Image         string   `json:"image"`                   // The URL of the main image of the product
+	Quantity      int      `json:"quantity"`                // The number of units in the cart
+	Price         float64  `json:"price"`                   // The current unit price
+	PreviousPrice *float64 `json:"previousPrice,omitempty"` // The unit price last shown, set when it has changed since
+	LineTotal     float64  `json:"lineTotal"`               // Price times quantity
+	Available     int      `json:"available"`               // Units that can still be bought
+	Problem       string   `json:"problem,omitempty"`       // One of the CartItem* problems, empty when the item can be bought
+}
+
+// AddCartItemPayload represents a product to add to the cart.
+type AddCartItemPayload struct {
+	ProductID int `json:"productId" validate:"required,gt=0"`
+	Quantity  int `json:"quantity" validate:"required,gt=0,lte=1000"`
+}
+
+// UpdateCartItemPayload represents the new quantity of a product in the cart.
+type UpdateCartItemPayload struct {
+	Quantity int `json:"quantity" validate:"required,gt=0,lte=1000"`
+}
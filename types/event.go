@@ -0,0 +1,108 @@
+package types
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Domain event types, stored in the outbox_events.type column.
+const (
+	UserRegisteredEvent     = "UserRegistered"
+	PasswordChangedEvent    = "PasswordChanged"
+	OrderPlacedEvent        = "OrderPlaced"
+	OrderStatusChangedEvent = "OrderStatusChanged"
+	ProductUpdatedEvent     = "ProductUpdated"
+	ShipmentCreatedEvent    = "ShipmentCreated"
+)
+
+// DomainEvent is a change to the store that other parts of the application react to. It is
+// written to the outbox in the transaction making the change and published to subscribers
+// once that transaction has committed.
+type DomainEvent interface {
+	// EventType returns one of the *Event types.
+	EventType() string
+}
+
+type UserRegistered struct {
+	UserID int `json:"userId"`
+}
+
+func (UserRegistered) EventType() string { return UserRegisteredEvent }
+
+type PasswordChanged struct {
+	UserID int `json:"userId"`
+}
+
+func (PasswordChanged) EventType() string { return PasswordChangedEvent }
+
+type OrderPlaced struct {
+	OrderID int `json:"orderId"`
+	UserID  int `json:"userId"`
+}
+
+func (OrderPlaced) EventType() string { return OrderPlacedEvent }
+
+type OrderStatusChanged struct {
+	OrderID    int    `json:"orderId"`
+	UserID     int    `json:"userId"`
+	FromStatus string `json:"fromStatus"`
+	ToStatus   string `json:"toStatus"`
+}
+
+func (OrderStatusChanged) EventType() string { return OrderStatusChangedEvent }
+
+type ProductUpdated struct {
+	ProductID int  `json:"productId"`
+	Created   bool `json:"created"` // Whether the product was added rather than changed
+}
+
+func (ProductUpdated) EventType() string { return ProductUpdatedEvent }
+
+type ShipmentCreated struct {
+	ShipmentID int `json:"shipmentId"`
+	OrderID    int `json:"orderId"`
+}
+
+func (ShipmentCreated) EventType() string { return ShipmentCreatedEvent }
+
+// OutboxEvent is a domain event as it is stored in the outbox.
+type OutboxEvent struct {
+	ID        int             // The unique identifier for the event
+	Type      string          // One of the *Event types
+	Payload   json.RawMessage // The JSON encoded event, e.g. an OrderPlaced
+	Attempts  int             // How many times publishing it was tried before
+	Handled   []string        // The subscribers that already handled it
+	CreatedAt time.Time       // The timestamp when the change was committed
+}
+
+// EventHandler reacts to a domain event. Events are delivered at least once: an event is
+// handed to the handler again after it returned an error, and may be after a crash, so
+// handlers should tolerate seeing the same event twice.
+type EventHandler func(ctx context.Context, event OutboxEvent) error
+
+// EventBus hands published domain events to the handlers subscribed to their type.
+type EventBus interface {
+	// Subscribe registers handler for events of eventType under name. The outbox remembers
+	// which subscribers handled an event by name, so it must be unique per event type and
+	// stay the same across releases.
+	Subscribe(eventType, name string, handler EventHandler)
+}
+
+// OutboxStore keeps the domain events waiting to be published.
+type OutboxStore interface {
+	// ClaimOutboxEvents returns up to limit events that are due, oldest first, and holds
+	// them for lease so no other relay publishes them meanwhile.
+	ClaimOutboxEvents(limit int, lease time.Duration) ([]OutboxEvent, error)
+
+	// MarkOutboxEventHandled records that a subscriber handled an event, so retries of the
+	// event skip it.
+	MarkOutboxEventHandled(eventID int, subscriber string) error
+
+	// CompleteOutboxEvent marks an event as published once every subscriber handled it.
+	CompleteOutboxEvent(eventID int) error
+
+	// RetryOutboxEvent records why publishing an event failed and tries it again after
+	// retryAfter, or gives it up if it is 0.
+	RetryOutboxEvent(eventID int, retryAfter time.Duration, lastError string) error
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"net/http"
+	"time"
+)
+
+// IdempotencyStore defines the methods required to remember the responses of requests
+// sent with an Idempotency-Key header, so retries get the original response.
+type IdempotencyStore interface {
+	// BeginIdempotentRequest claims key within scope for a request with the given
+	// fingerprint until leaseUntil. It returns nil if the caller now owns the key, or the
+	// request that already claimed it. Keys that expired before now are reclaimed, and so
+	// are the keys of requests that ran past their lease, like ones that crashed.
+	BeginIdempotentRequest(scope, key, fingerprint string, now, leaseUntil time.Time) (*IdempotentRequest, error)
+
+	// CompleteIdempotentRequest stores the response of a request claimed until leaseUntil
+	// and keeps it until expiresAt. It fails if the lease ran out and another request took
+	// the key over.
+	CompleteIdempotentRequest(scope, key string, leaseUntil time.Time, status int, header http.Header, body []byte, expiresAt time.Time) error
+
+	// ReleaseIdempotentRequest forgets a request claimed until leaseUntil so it can be
+	// retried. A key taken over by another request is left alone.
+	ReleaseIdempotentRequest(scope, key string, leaseUntil time.Time) error
+
+	// DeleteExpiredIdempotentRequests deletes the requests whose key expired before now and
+	// returns how many were deleted.
+	DeleteExpiredIdempotentRequests(now time.Time) (int, error)
+}
+
+// IdempotentRequest is a request made with an Idempotency-Key header and its response.
+type IdempotentRequest struct {
+	Scope       string      // Who sent the request, so clients can't replay each other's keys
+	Key         string      // The value of the Idempotency-Key header
+	Fingerprint string      // A hash of the method, path and body of the request
+	Status      int         // The response status code, 0 while the request is in flight
+	Header      http.Header // The response headers, without hop-by-hop ones
+	Body        []byte      // The response body
+	ExpiresAt   time.Time   // When the key may be used for another request; the end of the lease while in flight
+	CreatedAt   time.Time   // The timestamp of the first request
+}
@@ -0,0 +1,168 @@
+package types
+
+import "time"
+
+// Reservation statuses stored in the inventory_reservations.status column.
+const (
+	ReservationActive    = "active"
+	ReservationCommitted = "committed"
+	ReservationReleased  = "released"
+	ReservationExpired   = "expired"
+)
+
+// Stock movement reasons stored in the stock_movements.reason column.
+const (
+	MovementReceipt     = "receipt"     // Goods received from a supplier
+	MovementSale        = "sale"        // Units sold at checkout or when a reservation is committed
+	MovementReturn      = "return"      // Units returned by a customer
+	MovementAdjustment  = "adjustment"  // Manual correction, e.g. after a stock count
+	MovementReservation = "reservation" // Units held by a new reservation
+	MovementRelease     = "release"     // Units given back by a released or expired reservation
+	MovementCancel      = "cancel"      // Units of a cancelled or refunded order put back before shipping
+)
+
+// InventoryStore defines the methods required to reserve stock during checkout.
+// Reserved units stay in products.quantity until the reservation is committed by placing
+// an order, but they are no longer available to other customers.
+type InventoryStore interface {
+	// GetStockLevel returns the on-hand, reserved and available quantity of a product,
+	// derived from its stock movements.
+	GetStockLevel(productID int) (*StockLevel, error)
+
+	// CreateReservation atomically reserves every item or none of them.
+	CreateReservation(userID int, items []ReservationItem, expiresAt time.Time) (*Reservation, error)
+
+	// GetReservation returns a reservation with its items.
+	GetReservation(id int) (*Reservation, error)
+
+	// ReleaseReservation gives the reserved units of an active reservation back.
+	ReleaseReservation(id int) error
+
+	// ReleaseExpiredReservations expires every active reservation whose expiry is
+	// before now and returns how many were expired.
+	ReleaseExpiredReservations(now time.Time) (int, error)
+
+	// AdjustStock records a manual stock change and applies it to the product.
+	// The on-hand quantity may not drop below the reserved quantity.
+	AdjustStock(productID int, payload StockAdjustmentPayload, actorID int) (*StockMovement, error)
+
+	// GetStockMovements returns the movements of a product, newest first.
+	GetStockMovements(productID int, limit, offset int) ([]StockMovement, error)
+
+	// GetStockDiscrepancies lists the products whose stored counters disagree with their ledger.
+	GetStockDiscrepancies() ([]StockDiscrepancy, error)
+}
+
+// StockLevel describes how much of a product can still be sold.
+type StockLevel struct {
+	ProductID int `json:"productId"` // The product the numbers refer to
+	Quantity  int `json:"quantity"`  // Units on hand
+	Reserved  int `json:"reserved"`  // Units held by active reservations
+	Available int `json:"available"` // Units that can still be reserved
+}
+
+// Reservation holds stock for a customer while they complete a checkout.
+type Reservation struct {
+	ID        int               `json:"id"`        // The unique identifier for the reservation
+	UserID    int               `json:"userId"`    // The customer holding the reservation
+	Status    string            `json:"status"`    // One of the Reservation* statuses
+	Items     []ReservationItem `json:"items"`     // The reserved products
+	ExpiresAt time.Time         `json:"expiresAt"` // When an active reservation is released automatically
+	CreatedAt time.Time         `json:"createdAt"` // The timestamp when the reservation was created
+}
+
+// ReservationItem is the quantity of one product held by a reservation.
+type ReservationItem struct {
+	ProductID int `json:"productId" validate:"required,gt=0"`
+	Quantity  int `json:"quantity" validate:"required,gt=0"`
+}
+
+// CreateReservationPayload represents the items a customer wants to reserve.
+type CreateReservationPayload struct {
+	Items []ReservationItem `json:"items" validate:"required,min=1,dive"`
+}
+
+// StockMovement is one entry of the append-only inventory ledger. Summing the changes of
+// every movement of a product gives its current on-hand and reserved quantities.
+type StockMovement struct {
+	ID             int       `json:"id"`             // The unique identifier for the movement
+	ProductID      int       `json:"productId"`      // The product whose stock changed
+	QuantityChange int       `json:"quantityChange"` // Change of the on-hand quantity
+	ReservedChange int       `json:"reservedChange"` // Change of the reserved quantity
+	Reason         string    `json:"reason"`         // One of the Movement* reasons
+	ActorID        *int      `json:"actorId"`        // The user who caused the change, if any
+	Note           string    `json:"note"`           // Free-form explanation
+	Reference      string    `json:"reference"`      // Related record, e.g. "reservation:12"
+	CreatedAt      time.Time `json:"createdAt"`      // The timestamp when the movement was recorded
+}
+
+// StockAdjustmentPayload represents a manual stock change made by an admin.
+type StockAdjustmentPayload struct {
+	Change int    `json:"change" validate:"required"`
+	Reason string `json:"reason" validate:"required,oneof=receipt return adjustment"`
+	Note   string `json:"note" validate:"max=255"`
+}
+
+// StockDiscrepancy compares the counters stored on a product with the totals of its ledger.
+type StockDiscrepancy struct {
+	ProductID      int `json:"productId"`
+	Quantity       int `json:"quantity"`
+	LedgerQuantity int `json:"ledgerQuantity"`
+	Reserved       int `json:"reserved"`
+	LedgerReserved int `json:"ledgerReserved"`
+}
+
+// StockAlertStore defines the methods required to send low-stock and back-in-stock notifications.
+type StockAlertStore interface {
+	// SetReorderThreshold sets the quantity below which staff are alerted. Nil disables alerts.
+	SetReorderThreshold(productID int, threshold *int) error
+
+	// GetLowStockProducts returns the products below their threshold that have not been
+	// reported yet.
+	GetLowStockProducts() ([]LowStockProduct, error)
+
+	// SetLowStockAlerted marks a product as reported so staff are alerted only once per dip.
+	SetLowStockAlerted(productID int, alerted bool) error
+
+	// ResetRestockedAlerts clears the reported flag of products back at or above their
+	// threshold, so the next dip is reported again.
+	ResetRestockedAlerts() error
+
+	// GetStaffEmails returns the email addresses of every admin.
+	GetStaffEmails() ([]string, error)
+
+	// CreateStockSubscription subscribes a user to the next restock of a product.
+	CreateStockSubscription(userID, productID int) error
+
+	// DeleteStockSubscription removes a pending subscription.
+	DeleteStockSubscription(userID, productID int) error
+
+	// GetDueStockSubscriptions returns the pending subscriptions of products that are available again.
+	GetDueStockSubscriptions() ([]StockSubscription, error)
+
+	// MarkStockSubscriptionNotified records that the subscriber has been told.
+	MarkStockSubscriptionNotified(id int) error
+}
+
+// LowStockProduct is a product whose available quantity dropped below its reorder threshold.
+type LowStockProduct struct {
+	ProductID int    `json:"productId"`
+	Name      string `json:"name"`
+	SKU       string `json:"sku"`
+	Available int    `json:"available"`
+	Threshold int    `json:"threshold"`
+}
+
+// StockSubscription is a customer's request to be told when a product is back in stock.
+type StockSubscription struct {
+	ID          int    `json:"id"`
+	UserID      int    `json:"userId"`
+	Email       string `json:"-"`
+	ProductID   int    `json:"productId"`
+	ProductName string `json:"productName"`
+}
+
+// ReorderThresholdPayload sets or clears (with null) the reorder threshold of a product.
+type ReorderThresholdPayload struct {
+	Threshold *int `json:"threshold" validate:"omitempty,gte=0"`
+}
@@ -0,0 +1,23 @@
+package types
+
+import "time"
+
+// InvoiceStore defines the methods required to number the invoices of orders.
+type InvoiceStore interface {
+	// IssueInvoice returns the invoice of an order, issuing it with the next number of the
+	// current year the first time. Numbers of a year have no gaps, and only orders that
+	// are being fulfilled or were delivered get one.
+	IssueInvoice(orderID int) (*Invoice, error)
+}
+
+// Invoice is the numbered invoice of an order. The document itself is rendered from the
+// order and kept in blob storage.
+type Invoice struct {
+	ID         int       `json:"id"`       // The unique identifier for the invoice
+	OrderID    int       `json:"orderId"`  // The invoiced order
+	Number     string    `json:"number"`   // The invoice number printed on the document, e.g. INV-2025-000042
+	Year       int       `json:"year"`     // The year the invoice was issued in
+	Sequence   int       `json:"sequence"` // The position of the invoice in its year, starting at 1
+	StorageKey string    `json:"-"`        // The blob key of the rendered document
+	IssuedAt   time.Time `json:"issuedAt"` // The timestamp when the invoice was issued
+}
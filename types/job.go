@@ -0,0 +1,85 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job statuses stored in the jobs.status column. Jobs that used up their attempts are dead
+// and stay so until staff retry them.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+// JobPayload is the input of one kind of background job. It is stored as JSON and decoded
+// into the same type for the handler of its kind.
+type JobPayload interface {
+	// JobKind names the handler that runs the job, e.g. "send_email".
+	JobKind() string
+}
+
+// JobQueue accepts work to run in the background.
+type JobQueue interface {
+	// EnqueueJob stores a job to run as soon as a worker is free, or at the time given in
+	// opts, and returns its ID.
+	EnqueueJob(payload JobPayload, opts JobOptions) (int, error)
+}
+
+// JobStore is the database-backed job queue.
+type JobStore interface {
+	JobQueue
+
+	// GetJobs returns a page of jobs, newest first.
+	GetJobs(filter JobFilter, limit, offset int) ([]Job, error)
+	GetJob(id int) (*Job, error)
+
+	// RetryJob runs a dead job again with a fresh set of attempts.
+	RetryJob(id int) error
+
+	// ClaimJob returns the next due job of one of the kinds, or nil if there is none, and
+	// holds it for lease so no other worker runs it meanwhile. A job still held when its
+	// lease runs out, because its worker stopped, is claimed again.
+	ClaimJob(kinds []string, lease time.Duration) (*Job, error)
+
+	// CompleteJob marks a claimed job as succeeded.
+	CompleteJob(id int) error
+
+	// FailJob records why a claimed job failed and runs it again after retryAfter, or marks
+	// it dead if it is 0.
+	FailJob(id int, retryAfter time.Duration, lastError string) error
+}
+
+type JobOptions struct {
+	RunAt       *time.Time    // When to run the job, instead of right away
+	Delay       time.Duration // How long to wait before running the job, if RunAt is nil
+	MaxAttempts int           // How many times the job is tried, 0 for the default
+}
+
+type JobFilter struct {
+	Status string // Only jobs in this status, if set
+	Kind   string // Only jobs of this kind, if set
+}
+
+// Job is a unit of background work.
+type Job struct {
+	ID          int             `json:"id"`          // The unique identifier for the job
+	Kind        string          `json:"kind"`        // The handler that runs it
+	Payload     json.RawMessage `json:"payload"`     // The JSON encoded JobPayload
+	Status      string          `json:"status"`      // One of the Job* statuses
+	Attempts    int             `json:"attempts"`    // How many times it was started
+	MaxAttempts int             `json:"maxAttempts"` // How many times it is tried before it is dead
+	RunAt       time.Time       `json:"runAt"`       // When it is due, or when the lease of a running job ends
+	LastError   string          `json:"lastError"`   // Why the last attempt failed
+	CreatedAt   time.Time       `json:"createdAt"`   // The timestamp when the job was enqueued
+	FinishedAt  *time.Time      `json:"finishedAt"`  // The timestamp when it succeeded or died
+}
+
+// SendEmailJob delivers an email that was already rendered.
+type SendEmailJob struct {
+	Email Email `json:"email"`
+}
+
+func (SendEmailJob) JobKind() string { return "send_email" }
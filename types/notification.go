@@ -0,0 +1,79 @@
+package types
+
+import "context"
+
+// Notification kinds, used by notifiers to pick a template or channel.
+const (
+	NotificationLowStock          = "low_stock"
+	NotificationBackInStock       = "back_in_stock"
+	NotificationWelcome           = "welcome"
+	NotificationOrderConfirmation = "order_confirmation"
+	NotificationOrderShipped      = "order_shipped"
+	NotificationOrderDelivered    = "order_delivered"
+	NotificationPasswordChanged   = "password_changed"
+	NotificationPasswordReset     = "password_reset"
+)
+
+// OptionalNotifications are the kinds users can turn off. Account and security emails are
+// always sent.
+var OptionalNotifications = []string{
+	NotificationOrderConfirmation,
+	NotificationOrderShipped,
+	NotificationOrderDelivered,
+}
+
+// Notifier delivers notifications to people, e.g. by email.
+type Notifier interface {
+	// Notify sends n to every recipient. Implementations should be safe for concurrent use.
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Notification is a message for one or more recipients.
+type Notification struct {
+	Kind       string         // One of the Notification* kinds
+	UserID     int            // The user whose preferences apply, 0 for staff alerts and account emails
+	Recipients []string       // Email addresses of the recipients
+	Subject    string         // Short summary, used as the email subject
+	Body       string         // Plain text message
+	Data       map[string]any // Extra values for notifiers that render templates
+}
+
+// Mailer sends email messages.
+type Mailer interface {
+	// Send delivers e to all of its recipients at once.
+	Send(ctx context.Context, e Email) error
+}
+
+// Email is a rendered message ready to be sent.
+type Email struct {
+	From    string   // Sender address, optionally with a display name
+	To      []string // Recipient addresses
+	Subject string   // Subject line
+	Text    string   // Plain text body
+	HTML    string   // HTML body, empty for text-only messages
+}
+
+// NotificationPreferenceStore keeps which optional notifications each user receives.
+type NotificationPreferenceStore interface {
+	// GetNotificationPreferences returns the preference of the user for every optional
+	// kind. Kinds the user never changed are enabled.
+	GetNotificationPreferences(userID int) ([]NotificationPreference, error)
+
+	// UpdateNotificationPreferences changes the given preferences and leaves the others.
+	UpdateNotificationPreferences(userID int, prefs []NotificationPreferencePayload) error
+}
+
+// NotificationPreference tells whether a user gets one kind of notification by email.
+type NotificationPreference struct {
+	Kind  string `json:"kind"`
+	Email bool   `json:"email"`
+}
+
+type NotificationPreferencesPayload struct {
+	Preferences []NotificationPreferencePayload `json:"preferences" validate:"required,min=1,dive"`
+}
+
+type NotificationPreferencePayload struct {
+	Kind  string `json:"kind" validate:"required,oneof=order_confirmation order_shipped order_delivered"`
+	Email *bool  `json:"email" validate:"required"`
+}
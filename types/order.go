@@ -0,0 +1,188 @@
+package types
+
+import "time"
+
+// Order statuses stored in the orders.status column.
+const (
+	OrderPending    = "pending"
+	OrderProcessing = "processing"
+	OrderShipped    = "shipped"
+	OrderDelivered  = "delivered"
+	OrderCancelled  = "cancelled"
+)
+
+// Refund payment statuses stored in the refunds.paymentStatus column. A refund is pending
+// until its money went back through the payment provider, or none if nothing was captured.
+// It is refunding while the provider is asked to give the money back.
+const (
+	RefundPaymentPending   = "pending"
+	RefundPaymentRefunding = "refunding"
+	RefundPaymentRefunded  = "refunded"
+	RefundPaymentNone      = "none"
+)
+
+// OrderStore defines the methods required to place and read orders.
+type OrderStore interface {
+	// CreateOrder inserts an order with its items and takes the ordered units out of stock,
+	// all in one transaction. Every item must still be in stock at the price snapshotted
+	// in the item, otherwise nothing is written. A non-zero reservationID commits that
+	// active reservation of the customer, so the units it holds are sold to them. It
+	// returns the ID of the new order.
+	CreateOrder(order Order, items []OrderItem, reservationID int) (int, error)
+
+	// GetOrders returns the orders matching filter, newest first, without their items.
+	GetOrders(filter OrderFilter, limit, offset int) ([]Order, error)
+
+	// GetOrder returns an order with its items.
+	GetOrder(id int) (*Order, error)
+
+	// TransitionOrder moves an order to a new status if the order lifecycle allows it and
+	// records the transition on behalf of actorID.
+	TransitionOrder(id int, to string, actorID int, note string) (*OrderStatusChange, error)
+
+	// GetOrderStatusHistory returns the status transitions of an order, oldest first.
+	GetOrderStatusHistory(orderID int) ([]OrderStatusChange, error)
+
+	// CancelOrder cancels a pending or processing order, puts every unit not refunded yet
+	// back in stock and refunds it. It returns the refund, or nil if everything had already
+	// been refunded. The money goes back through the payment provider in the background.
+	CancelOrder(id int, actorID int, note string) (*Refund, error)
+
+	// RefundOrder refunds some or all of the units of an order's items. The money goes back
+	// through the payment provider in the background.
+	RefundOrder(id int, payload RefundPayload, actorID int) (*Refund, error)
+
+	// GetRefunds returns the refunds of an order with their items, oldest first.
+	GetRefunds(orderID int) ([]Refund, error)
+
+	// GetRefund returns a refund with its items.
+	GetRefund(id int) (*Refund, error)
+
+	// SettleRefund records how the money of a refund is given back: status is
+	// RefundPaymentRefunding or RefundPaymentRefunded with the payment refunded, or
+	// RefundPaymentNone without one. Only pending refunds can become refunding or none.
+	SettleRefund(id int, status string, paymentID *int) error
+}
+
+// OrderFilter narrows down a list of orders. Zero values match every order.
+type OrderFilter struct {
+	UserID int        // Only orders placed by this user
+	Status string     // Only orders in this status
+	From   *time.Time // Only orders placed at or after this time
+	To     *time.Time // Only orders placed before this time
+}
+
+// Order is a purchase placed by a customer.
+type Order struct {
+	ID               int         `json:"id"`                      // The unique identifier for the order
+	UserID           int         `json:"userId"`                  // The customer who placed the order
+	Subtotal         float64     `json:"subtotal"`                // The sum of the item prices before the discount
+	Discount         float64     `json:"discount"`                // The discount of the promotion code, the sum of the item discounts
+	PromotionID      *int        `json:"-"`                       // The redeemed promotion, nil without a code
+	PromotionCode    string      `json:"promotionCode,omitempty"` // The redeemed code
+	Tax              float64     `json:"tax"`                     // The tax added to the discounted subtotal, the sum of the item taxes
+	Taxes            []TaxLine   `json:"taxes,omitempty"`         // The taxes of all items summed up by tax and rate, including taxes included in the prices
+	ShippingMethodID *int        `json:"shippingMethodId"`        // The chosen shipping method, nil if none was chosen
+	ShippingMethod   string      `json:"shippingMethod"`          // The name of the shipping method at checkout
+	Shipping         float64     `json:"shipping"`                // The shipping cost
+	Total            float64     `json:"total"`                   // The amount charged, subtotal minus discount plus tax and shipping
+	Status           string      `json:"status"`                  // One of the Order* statuses
+	Address          string      `json:"address"`                 // The delivery address
+	Country          string      `json:"country"`                 // The ISO 3166-1 alpha-2 country code of the delivery address
+	Region           string      `json:"region"`                  // The region or state of the delivery address
+	PostalCode       string      `json:"postalCode"`              // The postal code of the delivery address
+	Items            []OrderItem `json:"items,omitempty"`         // The ordered products
+	CreatedAt        time.Time   `json:"createdAt"`               // The timestamp when the order was placed
+}
+
+// OrderItem is one product of an order. The price, name, SKU and image are snapshots taken
+// at checkout, so the order keeps showing what was bought even if the product changes.
+type OrderItem struct {
+	ID           int       `json:"id"`              // The unique identifier for the item
+	OrderID      int       `json:"orderId"`         // The order the item belongs to
+	ProductID    int       `json:"productId"`       // The ordered product
+	ProductName  string    `json:"productName"`     // The name of the product at checkout
+	ProductSKU   string    `json:"productSku"`      // The SKU of the product at checkout
+	ProductImage string    `json:"productImage"`    // The main image of the product at checkout
+	Quantity     int       `json:"quantity"`        // The number of units ordered
+	Price        float64   `json:"price"`           // The unit price before the discount
+	Discount     float64   `json:"discount"`        // The discount on the whole line
+	Tax          float64   `json:"tax"`             // The tax added to the discounted line, not counting taxes included in the price
+	Taxes        []TaxLine `json:"taxes,omitempty"` // The taxes of the line, including taxes included in the price
+	Refunded     int       `json:"refunded"`        // The number of units refunded so far
+	Shipped      int       `json:"shipped"`         // The number of units shipped so far
+}
+
+// OrderStatusChange is one transition in the lifecycle of an order.
+type OrderStatusChange struct {
+	ID         int       `json:"id"`         // The unique identifier for the transition
+	OrderID    int       `json:"orderId"`    // The order that changed status
+	FromStatus string    `json:"fromStatus"` // The previous status, empty when the order was placed
+	ToStatus   string    `json:"toStatus"`   // The new status
+	ActorID    *int      `json:"actorId"`    // The user who made the change, nil for the system
+	Note       string    `json:"note"`       // An optional explanation
+	CreatedAt  time.Time `json:"createdAt"`  // The timestamp of the transition
+}
+
+// OrderStatusPayload represents an admin moving an order to another status.
+type OrderStatusPayload struct {
+	Status string `json:"status" validate:"required,oneof=pending processing shipped delivered cancelled"`
+	Note   string `json:"note" validate:"max=255"`
+}
+
+// Refund is money given back for some units of an order.
+type Refund struct {
+	ID            int          `json:"id"`            // The unique identifier for the refund
+	OrderID       int          `json:"orderId"`       // The refunded order
+	Amount        float64      `json:"amount"`        // The amount refunded, the sum of the item amounts and shipping
+	Shipping      float64      `json:"shipping"`      // The shipping cost refunded, only when an order is cancelled
+	Reason        string       `json:"reason"`        // An optional explanation
+	ActorID       *int         `json:"actorId"`       // The user who issued the refund
+	PaymentStatus string       `json:"paymentStatus"` // One of the RefundPayment* statuses
+	PaymentID     *int         `json:"paymentId"`     // The payment the money went back to, nil until then
+	Items         []RefundItem `json:"items"`         // The refunded units
+	CreatedAt     time.Time    `json:"createdAt"`     // The timestamp of the refund
+}
+
+// RefundItem is the number of units of one order item covered by a refund.
+type RefundItem struct {
+	ID          int     `json:"id"`          // The unique identifier for the refund item
+	RefundID    int     `json:"refundId"`    // The refund the item belongs to
+	OrderItemID int     `json:"orderItemId"` // The refunded order item
+	ProductID   int     `json:"productId"`   // The refunded product
+	Quantity    int     `json:"quantity"`    // The number of units refunded
+	Amount      float64 `json:"amount"`      // The price paid for the units: their share of the discounted line plus its tax
+	Restocked   bool    `json:"restocked"`   // Whether the units were put back in stock
+}
+
+// RefundPayload represents an admin refunding units of an order. Restock puts the units
+// back in stock and is only allowed before the order has shipped.
+type RefundPayload struct {
+	Items   []RefundItemPayload `json:"items" validate:"required,min=1,max=100,dive"`
+	Reason  string              `json:"reason" validate:"max=255"`
+	Restock bool                `json:"restock"`
+}
+
+// RefundItemPayload is the number of units of an order item to refund.
+type RefundItemPayload struct {
+	OrderItemID int `json:"orderItemId" validate:"required,gt=0"`
+	Quantity    int `json:"quantity" validate:"required,gt=0"`
+}
+
+// CheckoutPayload represents the data a customer sends to place an order.
+type CheckoutPayload struct {
+	Items            []CheckoutItem `json:"items" validate:"required,min=1,max=100,dive"`
+	Address          string         `json:"address" validate:"required,max=1000"`
+	Country          string         `json:"country" validate:"omitempty,len=2,alpha"` // Decides the taxes with region and postal code; no taxes apply without it
+	Region           string         `json:"region" validate:"max=64"`
+	PostalCode       string         `json:"postalCode" validate:"max=16"`
+	PromoCode        string         `json:"promoCode" validate:"max=64"`                // An optional promotion code
+	ShippingMethodID int            `json:"shippingMethodId" validate:"omitempty,gt=0"` // A method quoted by GET /shipping/rates; requires a country
+	ReservationID    int            `json:"reservationId" validate:"omitempty,gt=0"`    // An optional active reservation holding the items
+}
+
+// CheckoutItem is a product and quantity to order.
+type CheckoutItem struct {
+	ProductID int `json:"productId" validate:"required,gt=0"`
+	Quantity  int `json:"quantity" validate:"required,gt=0,lte=1000"`
+}
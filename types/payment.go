@@ -0,0 +1,127 @@
+package types
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Payment statuses stored in the payments.status column and reported by providers. A
+// partially refunded payment stays captured; it becomes refunded once nothing is left.
+const (
+	PaymentRequiresConfirmation = "requires_confirmation" // Created, waiting for the customer's payment method
+	PaymentFailed               = "failed"                // The payment method was declined
+	PaymentAuthorized           = "authorized"            // The amount is held on the payment method
+	PaymentCaptured             = "captured"              // The held amount was collected
+	PaymentRefunded             = "refunded"              // The whole captured amount was given back
+	PaymentCancelled            = "cancelled"             // The authorization was released without capturing anything
+)
+
+// PaymentProvider is a payment gateway. Amounts are whole cents in the currency of the
+// payment, and payments are authorized first and captured later.
+type PaymentProvider interface {
+	// Name identifies the provider, e.g. in the payments.provider column.
+	Name() string
+
+	// CreateIntent starts a payment for an order.
+	CreateIntent(ctx context.Context, req PaymentIntentRequest) (*PaymentIntent, error)
+
+	// Confirm authorizes an intent with a payment method. A declined payment method is not
+	// an error: the intent is returned as failed with a failure reason.
+	Confirm(ctx context.Context, intentID string, paymentMethod string) (*PaymentIntent, error)
+
+	// Capture collects amount cents of an authorized intent.
+	Capture(ctx context.Context, intentID string, amount int64) (*PaymentIntent, error)
+
+	// Refund gives back amount cents of a captured intent. A call repeating the non-empty
+	// idempotencyKey of an earlier one returns the intent without refunding again.
+	Refund(ctx context.Context, intentID string, amount int64, idempotencyKey string) (*PaymentIntent, error)
+
+	// Cancel releases the amount held by an authorized intent, which can't be captured
+	// afterwards. Cancelling an intent that is already cancelled is not an error.
+	Cancel(ctx context.Context, intentID string) (*PaymentIntent, error)
+
+	// VerifyWebhook checks the signature of a webhook request sent by the provider and
+	// returns the event it carries.
+	VerifyWebhook(payload []byte, header http.Header) (*PaymentEvent, error)
+}
+
+// PaymentIntentRequest describes the payment to start.
+type PaymentIntentRequest struct {
+	OrderID  int    // The order being paid
+	Amount   int64  // The amount to charge, in cents
+	Currency string // The ISO 4217 currency code, lower case
+}
+
+// PaymentIntent is the state of a payment as known by the provider.
+type PaymentIntent struct {
+	ID            string `json:"id"`                      // The provider's reference for the payment
+	Amount        int64  `json:"amount"`                  // The amount to charge, in cents
+	Captured      int64  `json:"captured"`                // The amount collected, in cents
+	Refunded      int64  `json:"refunded"`                // The amount given back, in cents
+	Currency      string `json:"currency"`                // The ISO 4217 currency code
+	Status        string `json:"status"`                  // One of the Payment* statuses
+	FailureReason string `json:"failureReason,omitempty"` // Why the payment method was declined
+}
+
+// PaymentEvent is a notification sent by the provider when a payment changes.
+type PaymentEvent struct {
+	ID     string        `json:"id"`     // The provider's unique identifier for the event
+	Type   string        `json:"type"`   // What happened, e.g. "payment.captured"
+	Intent PaymentIntent `json:"intent"` // The state of the payment after the event
+}
+
+// PaymentStore defines the methods required to keep track of the payments of orders.
+type PaymentStore interface {
+	// CreatePayment records a payment started with a provider and returns its ID.
+	CreatePayment(p Payment) (int, error)
+
+	// GetPayment returns a payment by ID.
+	GetPayment(id int) (*Payment, error)
+
+	// GetPaymentByProviderRef returns the payment a provider knows under ref.
+	GetPaymentByProviderRef(provider, ref string) (*Payment, error)
+
+	// GetOrderPayments returns the payments of an order, oldest first.
+	GetOrderPayments(orderID int) ([]Payment, error)
+
+	// ApplyPaymentUpdate stores the state reported by the provider and moves a pending order
+	// to processing once its payment is authorized. Neither the status nor the captured and
+	// refunded amounts ever go back, e.g. a late "captured" event does not undo a refund.
+	// When eventID is set, an event that was already applied is ignored and false is returned.
+	ApplyPaymentUpdate(paymentID int, intent PaymentIntent, eventID string) (bool, error)
+}
+
+// Payment is a payment of an order through a provider.
+type Payment struct {
+	ID            int       `json:"id"`                      // The unique identifier for the payment
+	OrderID       int       `json:"orderId"`                 // The order being paid
+	Provider      string    `json:"provider"`                // The name of the provider handling the payment
+	ProviderRef   string    `json:"providerRef"`             // The provider's reference for the payment
+	Amount        float64   `json:"amount"`                  // The amount to charge
+	Captured      float64   `json:"captured"`                // The amount collected
+	Refunded      float64   `json:"refunded"`                // The amount given back
+	Currency      string    `json:"currency"`                // The ISO 4217 currency code
+	Status        string    `json:"status"`                  // One of the Payment* statuses
+	FailureReason string    `json:"failureReason,omitempty"` // Why the last attempt was declined
+	CreatedAt     time.Time `json:"createdAt"`               // The timestamp when the payment was started
+	UpdatedAt     time.Time `json:"updatedAt"`               // The timestamp of the last change
+}
+
+// ConfirmPaymentPayload represents the payment method a customer pays with. With real
+// gateways this is a token created by the gateway's client library, never a card number.
+type ConfirmPaymentPayload struct {
+	PaymentMethod string `json:"paymentMethod" validate:"required,max=255"`
+}
+
+// RefundPaymentPayload represents an amount to give back to the customer.
+type RefundPaymentPayload struct {
+	Amount float64 `json:"amount" validate:"required,gt=0"`
+}
+
+// SettleRefundJob gives the money of an order refund back through the payment provider.
+type SettleRefundJob struct {
+	RefundID int `json:"refundId"`
+}
+
+func (SettleRefundJob) JobKind() string { return "settle_refund" }
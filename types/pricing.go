@@ -0,0 +1,78 @@
+package types
+
+import "time"
+
+// Price change reasons stored in the price_history.reason column.
+const (
+	PriceCreated         = "created"          // The product was created with its first price
+	PriceUpdated         = "updated"          // An admin edited the product
+	PriceImported        = "imported"         // A catalog import changed the price
+	PriceScheduleStarted = "schedule_started" // A scheduled price took effect
+	PriceScheduleEnded   = "schedule_ended"   // A scheduled price ended and the previous price was restored
+)
+
+// Scheduled price statuses stored in the scheduled_prices.status column.
+const (
+	SchedulePending   = "pending"   // Waiting for its start time
+	ScheduleActive    = "active"    // Currently applied to the product
+	ScheduleEnded     = "ended"     // Applied and ended, or expired before it could start
+	ScheduleCancelled = "cancelled" // Cancelled by an admin before it started
+)
+
+// PricingStore defines the methods required to keep the price history of products and
+// to apply scheduled prices.
+type PricingStore interface {
+	// GetPriceHistory returns the price changes of a product, newest first.
+	GetPriceHistory(productID int, limit, offset int) ([]PriceChange, error)
+
+	// CreateScheduledPrice schedules a price for a product. Schedules of the same product
+	// may not overlap.
+	CreateScheduledPrice(productID int, payload ScheduledPricePayload, actorID int) (*ScheduledPrice, error)
+
+	// GetScheduledPrices returns the schedules of a product ordered by start time.
+	GetScheduledPrices(productID int) ([]ScheduledPrice, error)
+
+	// CancelScheduledPrice cancels a pending schedule. An active schedule is ended at
+	// once, which restores the price it replaced on the next run of the scheduler.
+	CancelScheduledPrice(id int, now time.Time) error
+
+	// StartDueScheduledPrices applies every pending schedule whose start time has come and
+	// returns how many were started.
+	StartDueScheduledPrices(now time.Time) (int, error)
+
+	// EndDueScheduledPrices ends every active schedule whose end time has come, restoring
+	// the price it replaced, and returns how many were ended.
+	EndDueScheduledPrices(now time.Time) (int, error)
+}
+
+// PriceChange is one entry of the price history of a product.
+type PriceChange struct {
+	ID         int       `json:"id"`                   // The unique identifier for the entry
+	ProductID  int       `json:"productId"`            // The product whose price changed
+	OldPrice   *float64  `json:"oldPrice"`             // The price before the change, nil for a new product
+	NewPrice   float64   `json:"newPrice"`             // The price after the change
+	Reason     string    `json:"reason"`               // One of the Price* reasons
+	ActorID    *int      `json:"actorId"`              // The user who made the change, nil for the system
+	ScheduleID *int      `json:"scheduleId,omitempty"` // The scheduled price that caused the change, if any
+	CreatedAt  time.Time `json:"createdAt"`            // The timestamp when the price changed
+}
+
+// ScheduledPrice is a price that applies to a product between two points in time.
+type ScheduledPrice struct {
+	ID            int        `json:"id"`            // The unique identifier for the schedule
+	ProductID     int        `json:"productId"`     // The product the price applies to
+	Price         float64    `json:"price"`         // The price applied while the schedule is active
+	PreviousPrice *float64   `json:"previousPrice"` // The price replaced when the schedule started
+	StartsAt      time.Time  `json:"startsAt"`      // When the price takes effect
+	EndsAt        *time.Time `json:"endsAt"`        // When the previous price is restored, nil to keep the price
+	Status        string     `json:"status"`        // One of the Schedule* statuses
+	ActorID       *int       `json:"actorId"`       // The admin who scheduled the price
+	CreatedAt     time.Time  `json:"createdAt"`     // The timestamp when the schedule was created
+}
+
+// ScheduledPricePayload represents the data required to schedule a price.
+type ScheduledPricePayload struct {
+	Price    float64    `json:"price" validate:"required,gt=0"`
+	StartsAt time.Time  `json:"startsAt" validate:"required"`
+	EndsAt   *time.Time `json:"endsAt" validate:"omitempty,gtfield=StartsAt"`
+}
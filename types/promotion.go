@@ -0,0 +1,98 @@
+package types
+
+import "time"
+
+// Promotion types stored in the promotions.type column.
+const (
+	PromotionPercentage   = "percentage"    // Value percent off the eligible items
+	PromotionFixed        = "fixed"         // Value off the eligible items, at most their subtotal
+	PromotionFreeShipping = "free_shipping" // No discount on the items, shipping is free
+	PromotionBuyXGetY     = "buy_x_get_y"   // For every BuyQuantity eligible units, GetQuantity more are free, cheapest first
+)
+
+// PromotionStore defines the methods required to manage discount codes and count how
+// often they were redeemed.
+type PromotionStore interface {
+	// GetPromotions returns promotions, newest first.
+	GetPromotions(limit, offset int) ([]Promotion, error)
+
+	// GetPromotion returns a promotion with its product and category scope.
+	GetPromotion(id int) (*Promotion, error)
+
+	// GetPromotionByCode returns the promotion with the given code, ignoring case.
+	GetPromotionByCode(code string) (*Promotion, error)
+
+	// CreatePromotion creates a promotion and returns its ID. Codes are unique.
+	CreatePromotion(payload PromotionPayload) (int, error)
+
+	// UpdatePromotion replaces the settings and scope of a promotion.
+	UpdatePromotion(id int, payload PromotionPayload) error
+
+	// CountUserRedemptions returns how many orders of a user redeemed a promotion.
+	CountUserRedemptions(promotionID, userID int) (int, error)
+}
+
+// Promotion is a discount code and the rules deciding when and how much it discounts.
+type Promotion struct {
+	ID            int        `json:"id"`            // The unique identifier for the promotion
+	Code          string     `json:"code"`          // The code customers enter, stored upper case
+	Description   string     `json:"description"`   // A description shown to customers
+	Type          string     `json:"type"`          // One of the Promotion* types
+	Value         float64    `json:"value"`         // The percentage or amount off, unused by the other types
+	BuyQuantity   int        `json:"buyQuantity"`   // Units to buy for a buy-X-get-Y promotion
+	GetQuantity   int        `json:"getQuantity"`   // Units given for free for a buy-X-get-Y promotion
+	MinOrderValue float64    `json:"minOrderValue"` // The subtotal of the whole order needed to redeem the code
+	UsageLimit    *int       `json:"usageLimit"`    // How often the code can be redeemed overall, nil for no limit
+	PerUserLimit  *int       `json:"perUserLimit"`  // How often one customer can redeem the code, nil for no limit
+	TimesUsed     int        `json:"timesUsed"`     // How often the code was redeemed
+	StartsAt      *time.Time `json:"startsAt"`      // When the code becomes valid, nil for right away
+	EndsAt        *time.Time `json:"endsAt"`        // When the code stops being valid, nil for never
+	Active        bool       `json:"active"`        // Whether the code can be redeemed at all
+	ProductIDs    []int      `json:"productIds"`    // The products the code applies to
+	Categories    []string   `json:"categories"`    // The categories the code applies to; with no products or categories it applies to everything
+	CreatedAt     time.Time  `json:"createdAt"`     // The timestamp when the promotion was created
+}
+
+// PromotionPayload represents the data required to create or update a promotion.
+type PromotionPayload struct {
+	Code          string     `json:"code" validate:"required,max=64"`
+	Description   string     `json:"description" validate:"max=255"`
+	Type          string     `json:"type" validate:"required,oneof=percentage fixed free_shipping buy_x_get_y"`
+	Value         float64    `json:"value" validate:"gte=0"`
+	BuyQuantity   int        `json:"buyQuantity" validate:"gte=0"`
+	GetQuantity   int        `json:"getQuantity" validate:"gte=0"`
+	MinOrderValue float64    `json:"minOrderValue" validate:"gte=0"`
+	UsageLimit    *int       `json:"usageLimit" validate:"omitempty,gt=0"`
+	PerUserLimit  *int       `json:"perUserLimit" validate:"omitempty,gt=0"`
+	StartsAt      *time.Time `json:"startsAt"`
+	EndsAt        *time.Time `json:"endsAt"`
+	Active        *bool      `json:"active"` // Defaults to true
+	ProductIDs    []int      `json:"productIds" validate:"max=1000,dive,gt=0"`
+	Categories    []string   `json:"categories" validate:"max=100,dive,required,max=64"`
+}
+
+// PromotionItem is a product in a basket a promotion is applied to.
+type PromotionItem struct {
+	ProductID int     `json:"productId"` // The product
+	Category  string  `json:"category"`  // The category of the product
+	Quantity  int     `json:"quantity"`  // The number of units
+	Price     float64 `json:"price"`     // The unit price
+	Discount  float64 `json:"discount"`  // The discount on the whole line
+}
+
+// PromotionResult is the outcome of applying a promotion to a basket.
+type PromotionResult struct {
+	PromotionID  int             `json:"-"`            // The applied promotion
+	Code         string          `json:"code"`         // The applied code
+	Items        []PromotionItem `json:"items"`        // The basket with the discount of every line
+	Subtotal     float64         `json:"subtotal"`     // The sum of the lines before the discount
+	Discount     float64         `json:"discount"`     // The sum of the line discounts
+	Total        float64         `json:"total"`        // Subtotal minus discount
+	FreeShipping bool            `json:"freeShipping"` // Whether shipping is free
+}
+
+// PromotionPreviewPayload represents a code to try against a basket.
+type PromotionPreviewPayload struct {
+	Code  string         `json:"code" validate:"required,max=64"`
+	Items []CheckoutItem `json:"items" validate:"required,min=1,max=100,dive"`
+}
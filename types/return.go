@@ -0,0 +1,84 @@
+package types
+
+import "time"
+
+// Return statuses stored in the returns.status column.
+const (
+	ReturnRequested = "requested" // Asked for by the customer, waiting for staff
+	ReturnApproved  = "approved"  // The customer may send the units back
+	ReturnReceived  = "received"  // The units arrived and wait for inspection
+	ReturnAccepted  = "accepted"  // The units passed inspection and were refunded
+	ReturnRejected  = "rejected"  // Turned down before or after inspection
+)
+
+// ReturnStore defines the methods required to take units of delivered orders back.
+type ReturnStore interface {
+	// CreateReturn records the customer of a delivered order asking to return some of its
+	// units and returns its ID. Units refunded or part of another open return can't be
+	// returned.
+	CreateReturn(orderID int, payload ReturnPayload) (int, error)
+
+	// GetReturns returns the returns in a status, every return if status is empty, newest
+	// first, without their items.
+	GetReturns(status string, limit, offset int) ([]Return, error)
+
+	// GetReturn returns a return with its items.
+	GetReturn(id int) (*Return, error)
+
+	// GetOrderReturns returns the returns of an order with their items, oldest first.
+	GetOrderReturns(orderID int) ([]Return, error)
+
+	// TransitionReturn moves a return to its next status on behalf of actorID. Accepting
+	// a return refunds its units and, if asked to, puts them back in stock. The money goes
+	// back through the payment provider in the background.
+	TransitionReturn(id int, payload ReturnStatusPayload, actorID int) error
+}
+
+// Return is a customer sending units of a delivered order back for a refund.
+type Return struct {
+	ID           int          `json:"id"`                     // The unique identifier for the return
+	OrderID      int          `json:"orderId"`                // The order the units were bought with
+	UserID       int          `json:"userId"`                 // The customer returning the units
+	Status       string       `json:"status"`                 // One of the Return* statuses
+	Comment      string       `json:"comment"`                // The customer's explanation
+	Note         string       `json:"note"`                   // The staff's note on the last status change
+	Restocked    bool         `json:"restocked"`              // Whether the units were put back in stock when accepted
+	RefundID     *int         `json:"refundId"`               // The refund created when the return was accepted
+	RefundStatus string       `json:"refundStatus,omitempty"` // Whether the money of the refund went back, one of the RefundPayment* statuses
+	Items        []ReturnItem `json:"items,omitempty"`        // The returned units
+	CreatedAt    time.Time    `json:"createdAt"`              // The timestamp when the return was requested
+	UpdatedAt    time.Time    `json:"updatedAt"`              // The timestamp of the last status change
+}
+
+// ReturnItem is the number of units of one order item in a return and why they are sent
+// back.
+type ReturnItem struct {
+	ID          int    `json:"id"`          // The unique identifier for the return item
+	ReturnID    int    `json:"returnId"`    // The return the item belongs to
+	OrderItemID int    `json:"orderItemId"` // The returned order item
+	ProductID   int    `json:"productId"`   // The returned product
+	ProductName string `json:"productName"` // The name of the product at checkout
+	Quantity    int    `json:"quantity"`    // The number of units returned
+	Reason      string `json:"reason"`      // Why the units are returned, e.g. damaged
+}
+
+// ReturnPayload represents a customer asking to return units of an order.
+type ReturnPayload struct {
+	Items   []ReturnItemPayload `json:"items" validate:"required,min=1,max=100,dive"`
+	Comment string              `json:"comment" validate:"max=1000"`
+}
+
+// ReturnItemPayload is the number of units of an order item to return.
+type ReturnItemPayload struct {
+	OrderItemID int    `json:"orderItemId" validate:"required,gt=0"`
+	Quantity    int    `json:"quantity" validate:"required,gt=0"`
+	Reason      string `json:"reason" validate:"required,oneof=damaged defective wrong_item not_as_described no_longer_needed other"`
+}
+
+// ReturnStatusPayload represents staff moving a return to another status. Restock only
+// applies when the return is accepted.
+type ReturnStatusPayload struct {
+	Status  string `json:"status" validate:"required,oneof=approved received accepted rejected"`
+	Note    string `json:"note" validate:"max=255"`
+	Restock bool   `json:"restock"`
+}
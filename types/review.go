@@ -0,0 +1,70 @@
+package types
+
+import "time"
+
+// Review statuses stored in the reviews.status column. New and edited reviews wait for
+// an admin to approve them; only approved reviews are listed and counted in ratings.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// ReviewStore defines the methods required to manage product reviews.
+type ReviewStore interface {
+	// GetReview returns a review in any status.
+	GetReview(id int) (*Review, error)
+
+	// GetProductReviews returns the approved reviews of a product, newest first, together
+	// with the rating summary of all of its approved reviews.
+	GetProductReviews(productID int, limit, offset int) ([]Review, *RatingSummary, error)
+
+	// GetReviewsByStatus returns reviews in the given status, oldest first, for moderation.
+	GetReviewsByStatus(status string, limit, offset int) ([]Review, error)
+
+	// CreateReview stores a pending review and returns its ID. A customer can review a
+	// product once; the verified-purchase flag is computed from their delivered orders.
+	CreateReview(Review) (int, error)
+
+	// UpdateReview saves the rating, title and body of a review, recomputes the
+	// verified-purchase flag and sends it back to moderation.
+	UpdateReview(Review) error
+
+	// DeleteReview removes a review.
+	DeleteReview(id int) error
+
+	// SetReviewStatus records a moderation decision.
+	SetReviewStatus(id int, status string) error
+}
+
+// Review is a customer's rating of a product.
+type Review struct {
+	ID        int       `json:"id"`        // The unique identifier for the review
+	ProductID int       `json:"productId"` // The product being reviewed
+	UserID    int       `json:"userId"`    // The customer who wrote the review
+	Rating    int       `json:"rating"`    // The rating from 1 to 5
+	Title     string    `json:"title"`     // A short headline
+	Body      string    `json:"body"`      // The review text
+	Verified  bool      `json:"verified"`  // Whether the customer received the product in a delivered order
+	Status    string    `json:"status"`    // One of the Review* statuses
+	CreatedAt time.Time `json:"createdAt"` // The timestamp when the review was written
+	UpdatedAt time.Time `json:"updatedAt"` // The timestamp when the review was last edited or moderated
+}
+
+// RatingSummary aggregates the approved reviews of a product.
+type RatingSummary struct {
+	AverageRating float64 `json:"averageRating"` // The mean rating rounded to two decimals, 0 without reviews
+	ReviewCount   int     `json:"reviewCount"`   // The number of approved reviews
+}
+
+// ReviewPayload represents the data a customer sends when writing or editing a review.
+type ReviewPayload struct {
+	Rating int    `json:"rating" validate:"required,min=1,max=5"`
+	Title  string `json:"title" validate:"max=255"`
+	Body   string `json:"body" validate:"max=5000"`
+}
+
+// ModerateReviewPayload represents an admin's moderation decision.
+type ModerateReviewPayload struct {
+	Status string `json:"status" validate:"required,oneof=pending approved rejected"`
+}
@@ -0,0 +1,61 @@
+package types
+
+import "time"
+
+// ShipmentStore defines the methods required to record the parcels an order is sent in.
+type ShipmentStore interface {
+	// CreateShipment records a parcel with some or all of the units of an order not shipped
+	// yet, on behalf of actorID, and moves a processing order to shipped. It returns the ID
+	// of the new shipment.
+	CreateShipment(orderID int, payload ShipmentPayload, actorID int) (int, error)
+
+	// GetShipment returns a shipment with its items.
+	GetShipment(id int) (*Shipment, error)
+
+	// GetOrderShipments returns the shipments of an order with their items, oldest first.
+	GetOrderShipments(orderID int) ([]Shipment, error)
+
+	// DeliverShipment records that a shipment arrived. The order moves to delivered once
+	// every unit was shipped and every shipment arrived.
+	DeliverShipment(id int, actorID int) error
+}
+
+// Shipment is a parcel handed to a carrier with some of the units of an order.
+type Shipment struct {
+	ID           int            `json:"id"`           // The unique identifier for the shipment
+	OrderID      int            `json:"orderId"`      // The shipped order
+	Carrier      string         `json:"carrier"`      // The carrier delivering the parcel, e.g. UPS
+	TrackingCode string         `json:"trackingCode"` // The carrier's tracking number
+	TrackingURL  string         `json:"trackingUrl"`  // Where customers can follow the parcel, empty if unknown
+	ActorID      *int           `json:"actorId"`      // The user who recorded the shipment
+	Items        []ShipmentItem `json:"items"`        // The shipped units
+	ShippedAt    time.Time      `json:"shippedAt"`    // The timestamp when the parcel was handed to the carrier
+	DeliveredAt  *time.Time     `json:"deliveredAt"`  // The timestamp when the parcel arrived, nil while in transit
+	CreatedAt    time.Time      `json:"createdAt"`    // The timestamp when the shipment was recorded
+}
+
+// ShipmentItem is the number of units of one order item in a shipment.
+type ShipmentItem struct {
+	ID          int    `json:"id"`          // The unique identifier for the shipment item
+	ShipmentID  int    `json:"shipmentId"`  // The shipment the item belongs to
+	OrderItemID int    `json:"orderItemId"` // The shipped order item
+	ProductID   int    `json:"productId"`   // The shipped product
+	ProductName string `json:"productName"` // The name of the product at checkout
+	Quantity    int    `json:"quantity"`    // The number of units shipped
+}
+
+// ShipmentPayload represents an admin recording a shipment. Without items, every unit not
+// shipped or refunded yet is shipped.
+type ShipmentPayload struct {
+	Carrier      string                `json:"carrier" validate:"required,max=64"`
+	TrackingCode string                `json:"trackingCode" validate:"required,max=128"`
+	TrackingURL  string                `json:"trackingUrl" validate:"omitempty,url,max=255"`
+	ShippedAt    *time.Time            `json:"shippedAt"` // Defaults to now
+	Items        []ShipmentItemPayload `json:"items" validate:"max=100,dive"`
+}
+
+// ShipmentItemPayload is the number of units of an order item to ship.
+type ShipmentItemPayload struct {
+	OrderItemID int `json:"orderItemId" validate:"required,gt=0"`
+	Quantity    int `json:"quantity" validate:"required,gt=0"`
+}
@@ -0,0 +1,102 @@
+package types
+
+import "time"
+
+// Shipping method types stored in the shipping_methods.type column.
+const (
+	ShippingFlat   = "flat"   // Price per order
+	ShippingWeight = "weight" // Price plus PricePerKg for every started kilogram
+)
+
+// ShippingStore defines the methods required to manage the zones shipped to and the
+// shipping methods of every zone.
+type ShippingStore interface {
+	// GetShippingZones returns every zone with its countries.
+	GetShippingZones() ([]ShippingZone, error)
+
+	// GetShippingZone returns a zone with its countries.
+	GetShippingZone(id int) (*ShippingZone, error)
+
+	// CreateShippingZone creates a zone and returns its ID. A country belongs to one zone.
+	CreateShippingZone(payload ShippingZonePayload) (int, error)
+
+	// UpdateShippingZone replaces the name and countries of a zone.
+	UpdateShippingZone(id int, payload ShippingZonePayload) error
+
+	// GetShippingMethods returns every shipping method, active or not.
+	GetShippingMethods() ([]ShippingMethod, error)
+
+	// GetShippingMethod returns a shipping method.
+	GetShippingMethod(id int) (*ShippingMethod, error)
+
+	// GetShippingMethodsForCountry returns the active methods of the zone of a country,
+	// none if no zone includes it.
+	GetShippingMethodsForCountry(country string) ([]ShippingMethod, error)
+
+	// CreateShippingMethod creates a shipping method and returns its ID.
+	CreateShippingMethod(payload ShippingMethodPayload) (int, error)
+
+	// UpdateShippingMethod replaces a shipping method. Orders keep the name and cost they
+	// were placed with.
+	UpdateShippingMethod(id int, payload ShippingMethodPayload) error
+}
+
+// ShippingZone is a group of countries sharing shipping methods.
+type ShippingZone struct {
+	ID        int       `json:"id"`        // The unique identifier for the zone
+	Name      string    `json:"name"`      // The name of the zone, e.g. Domestic
+	Countries []string  `json:"countries"` // The ISO 3166-1 alpha-2 codes of the countries in the zone
+	CreatedAt time.Time `json:"createdAt"` // The timestamp when the zone was created
+}
+
+// ShippingZonePayload represents the data required to create or update a zone.
+type ShippingZonePayload struct {
+	Name      string   `json:"name" validate:"required,max=64"`
+	Countries []string `json:"countries" validate:"required,min=1,max=250,dive,len=2,alpha"`
+}
+
+// ShippingMethod is a way of delivering orders to the countries of a zone and how much it
+// costs.
+type ShippingMethod struct {
+	ID         int       `json:"id"`         // The unique identifier for the method
+	ZoneID     int       `json:"zoneId"`     // The zone the method ships to
+	Name       string    `json:"name"`       // The name shown to customers, e.g. Standard
+	Type       string    `json:"type"`       // One of the Shipping* types
+	Price      float64   `json:"price"`      // The flat price, or the base price of a weight-based method
+	PricePerKg float64   `json:"pricePerKg"` // The price of every started kilogram of a weight-based method
+	FreeOver   *float64  `json:"freeOver"`   // Orders worth at least this after discounts ship for free, nil for never
+	MaxWeight  *int      `json:"maxWeight"`  // The heaviest parcel in grams the method carries, nil for no limit
+	MinDays    int       `json:"minDays"`    // The fewest business days delivery takes
+	MaxDays    int       `json:"maxDays"`    // The most business days delivery takes
+	Active     bool      `json:"active"`     // Whether customers can choose the method
+	CreatedAt  time.Time `json:"createdAt"`  // The timestamp when the method was created
+}
+
+// ShippingMethodPayload represents the data required to create or update a shipping method.
+type ShippingMethodPayload struct {
+	ZoneID     int      `json:"zoneId" validate:"required,gt=0"`
+	Name       string   `json:"name" validate:"required,max=64"`
+	Type       string   `json:"type" validate:"required,oneof=flat weight"`
+	Price      float64  `json:"price" validate:"gte=0"`
+	PricePerKg float64  `json:"pricePerKg" validate:"gte=0"`
+	FreeOver   *float64 `json:"freeOver" validate:"omitempty,gte=0"`
+	MaxWeight  *int     `json:"maxWeight" validate:"omitempty,gt=0"`
+	MinDays    int      `json:"minDays" validate:"gte=0"`
+	MaxDays    int      `json:"maxDays" validate:"gtefield=MinDays"`
+	Active     *bool    `json:"active"` // Defaults to true
+}
+
+// ShippingRate is the price of delivering a parcel with one shipping method.
+type ShippingRate struct {
+	MethodID int     `json:"methodId"` // The shipping method
+	Name     string  `json:"name"`     // The name of the method
+	Cost     float64 `json:"cost"`     // The price of delivering the parcel
+	MinDays  int     `json:"minDays"`  // The fewest business days delivery takes
+	MaxDays  int     `json:"maxDays"`  // The most business days delivery takes
+}
+
+// Parcel is what the shipping cost of an order depends on.
+type Parcel struct {
+	Weight int     // The chargeable weight in grams
+	Value  float64 // The value of the items after discounts
+}
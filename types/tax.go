@@ -0,0 +1,78 @@
+package types
+
+import "time"
+
+// TaxClassStandard is the tax class of products that don't set one.
+const TaxClassStandard = "standard"
+
+// TaxCalculator works out the taxes owed on the items of an order.
+type TaxCalculator interface {
+	// Calculate returns the tax lines of every item, in the order of items. Items no tax
+	// rate applies to get no lines.
+	Calculate(address TaxAddress, items []TaxableItem) ([][]TaxLine, error)
+}
+
+// TaxRateStore defines the methods required to manage the tax rates of the jurisdictions
+// the shop sells to.
+type TaxRateStore interface {
+	// GetTaxRates returns every tax rate, ordered by country, region and postal prefix.
+	GetTaxRates() ([]TaxRate, error)
+
+	// GetTaxRate returns a tax rate.
+	GetTaxRate(id int) (*TaxRate, error)
+
+	// CreateTaxRate creates a tax rate and returns its ID.
+	CreateTaxRate(payload TaxRatePayload) (int, error)
+
+	// UpdateTaxRate replaces a tax rate.
+	UpdateTaxRate(id int, payload TaxRatePayload) error
+
+	// DeleteTaxRate deletes a tax rate. Orders keep the tax lines computed with it.
+	DeleteTaxRate(id int) error
+}
+
+// TaxRate is a tax charged on one tax class of products delivered to a jurisdiction. A
+// jurisdiction is a country, optionally narrowed down to a region and a postal code prefix.
+type TaxRate struct {
+	ID           int       `json:"id"`           // The unique identifier for the rate
+	Name         string    `json:"name"`         // The name of the tax, e.g. VAT; rates with the same name replace each other
+	Country      string    `json:"country"`      // The ISO 3166-1 alpha-2 country code
+	Region       string    `json:"region"`       // The region or state, empty for the whole country
+	PostalPrefix string    `json:"postalPrefix"` // The postal code prefix, empty for the whole region
+	TaxClass     string    `json:"taxClass"`     // The tax class of the products taxed
+	Rate         float64   `json:"rate"`         // The percentage charged
+	Inclusive    bool      `json:"inclusive"`    // Whether prices already include the tax
+	CreatedAt    time.Time `json:"createdAt"`    // The timestamp when the rate was created
+}
+
+// TaxRatePayload represents the data required to create or update a tax rate.
+type TaxRatePayload struct {
+	Name         string  `json:"name" validate:"required,max=64"`
+	Country      string  `json:"country" validate:"required,len=2,alpha"`
+	Region       string  `json:"region" validate:"max=64"`
+	PostalPrefix string  `json:"postalPrefix" validate:"max=16"`
+	TaxClass     string  `json:"taxClass" validate:"max=32"` // Defaults to TaxClassStandard
+	Rate         float64 `json:"rate" validate:"gte=0,lte=100"`
+	Inclusive    bool    `json:"inclusive"`
+}
+
+// TaxAddress is the part of a delivery address that decides which taxes apply.
+type TaxAddress struct {
+	Country    string `json:"country"`    // The ISO 3166-1 alpha-2 country code
+	Region     string `json:"region"`     // The region or state
+	PostalCode string `json:"postalCode"` // The postal code
+}
+
+// TaxableItem is an order line to compute taxes for.
+type TaxableItem struct {
+	TaxClass string  // The tax class of the product
+	Amount   float64 // The price of the line after discounts
+}
+
+// TaxLine is one tax charged on an order item, or the sum of a tax over a whole order.
+type TaxLine struct {
+	Name      string  `json:"name"`      // The name of the tax
+	Rate      float64 `json:"rate"`      // The percentage charged
+	Inclusive bool    `json:"inclusive"` // Whether the tax was included in the price rather than added to it
+	Amount    float64 `json:"amount"`    // The tax amount
+}
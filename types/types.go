@@ -1,6 +1,8 @@
 package types
 
 import (
+	"context"
+	"io"
 	"time"
 )
 
@@ -20,12 +22,76 @@ type UserStore interface {
 	CreateUser(User) error
 }
 
+// PasswordStore defines the methods required to change passwords and reset forgotten ones.
+// Changing a password records a PasswordChanged event and invalidates the reset links of
+// the user.
+type PasswordStore interface {
+	// UpdatePassword replaces the password hash of a user.
+	UpdatePassword(userID int, passwordHash string) error
+
+	// CreatePasswordReset stores the hash of a reset token that is valid until expiresAt.
+	CreatePasswordReset(userID int, tokenHash string, expiresAt time.Time) error
+
+	// ResetPassword replaces the password hash of the user a reset token was created for,
+	// if it has not expired by now, and returns the user's ID.
+	ResetPassword(tokenHash, passwordHash string, now time.Time) (int, error)
+}
+
 type ProductStore interface {
 	GetProductByID(id int) (*Product, error)
 	GetProductsByID(ids []int) ([]Product, error)
 	GetProducts() ([]*Product, error)
-	CreateProduct(CreateProductPayload) error
+	CreateProduct(CreateProductPayload) (int, error)
+	// UpdateProduct saves the descriptive fields and price of a product, recording a
+	// changed price in the price history. The quantity is left untouched; stock only
+	// changes through the inventory ledger.
 	UpdateProduct(Product) error
+
+	// UpsertProducts creates or updates the given rows, matched by SKU, in a single
+	// transaction. Quantity changes are recorded in the inventory ledger on behalf of
+	// actorID. It returns how many products were created and updated.
+	UpsertProducts(rows []ProductImportRow, actorID int) (created int, updated int, err error)
+
+	// ExportProducts calls fn for every product ordered by ID, stopping at the first error.
+	ExportProducts(fn func(Product) error) error
+}
+
+// ProductImageStore defines the methods required to manage the ordered images of a product.
+// Image bytes live in a BlobStore; this store only keeps their metadata.
+type ProductImageStore interface {
+	// GetProductImages returns the images of a product ordered by position.
+	GetProductImages(productID int) ([]ProductImage, error)
+
+	// GetProductImage returns a single image that belongs to the given product.
+	GetProductImage(productID, imageID int) (*ProductImage, error)
+
+	// CreateProductImages appends images after the existing ones of a product, all or none
+	// of them, and returns their IDs. An image has no thumbnail until the
+	// GenerateThumbnailJob queued with it ran.
+	CreateProductImages(productID int, images []ProductImage) ([]int, error)
+
+	// SetProductImageThumbnail records the thumbnail of an image that has none yet.
+	SetProductImageThumbnail(productID, imageID int, key string) error
+
+	// DeleteProductImage removes an image and closes the gap in the positions.
+	DeleteProductImage(productID, imageID int) error
+
+	// ReorderProductImages assigns positions following the order of imageIDs,
+	// which must contain every image of the product exactly once.
+	ReorderProductImages(productID int, imageIDs []int) error
+}
+
+// BlobStore is an interface for storing binary objects such as uploaded images.
+// Keys are slash-separated paths like "products/1/abc.jpg".
+type BlobStore interface {
+	// Put stores size bytes read from r under key, replacing any existing object.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Get opens the object stored under key. The caller must close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the object stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
 }
 
 // User represents a user in the system.
@@ -36,21 +102,55 @@ type User struct {
 	LastName  string    `json:"lastName"`   // The user's last name
 	Email     string    `json:"email"`      // The user's email address (unique)
 	Password  string    `json:"-"`          // The user's password (never returned in the JSON response)
+	Role      string    `json:"role"`       // The user's role, either RoleCustomer or RoleAdmin
 	CreatedAt time.Time `json:"createdAt"`  // The timestamp when the user was created in the system
 }
 
+// User roles stored in the users.role column.
+const (
+	RoleCustomer = "customer"
+	RoleAdmin    = "admin"
+)
+
 // Product represents a product in the system.	
 // It contains all the necessary fields required to store product information in the database.
 type Product struct {
 	ID        int       `json:"id"`        // The unique identifier for the product
+	SKU       string    `json:"sku"`       // The stock keeping unit used to match imported rows
 	Name      string    `json:"name"`      // The name of the product
 	Description string    `json:"description"`  // The description of the product
-	Image     string    `json:"image"`     // The image URL of the product
+	Category  string    `json:"category"`  // The category the product is listed under, empty if none
+	TaxClass  string    `json:"taxClass"`  // The tax class deciding which tax rates apply, TaxClassStandard by default
+	Image     string    `json:"image"`     // The URL of the first image of the product
 	Quantity  int       `json:"quantity"`  // The quantity of the product
+	Reserved  int       `json:"-"`         // The units of the quantity held by checkout reservations
 	Price     float64   `json:"price"`     // The price of the product
+	Weight    int       `json:"weight"`    // The shipping weight of one unit in grams
+	Length    int       `json:"length"`    // The packed length of one unit in millimetres
+	Width     int       `json:"width"`     // The packed width of one unit in millimetres
+	Height    int       `json:"height"`    // The packed height of one unit in millimetres
+	AverageRating float64 `json:"averageRating"` // The mean rating of the approved reviews, 0 without reviews
+	ReviewCount   int     `json:"reviewCount"`   // The number of approved reviews
 	CreatedAt time.Time `json:"createdAt"`  // The timestamp when the product was created in the system
 }
 
+// ProductImage represents one uploaded image of a product.
+// Images are ordered by Position, starting at 0; the first one is the product's main image.
+type ProductImage struct {
+	ID           int       `json:"id"`           // The unique identifier for the image
+	ProductID    int       `json:"productId"`    // The product the image belongs to
+	Position     int       `json:"position"`     // The position of the image in the product gallery
+	BlobKey      string    `json:"-"`            // The BlobStore key of the original image
+	ThumbnailKey string    `json:"-"`            // The BlobStore key of the generated thumbnail, empty until generated
+	ContentType  string    `json:"contentType"`  // The sniffed content type of the original image
+	Size         int64     `json:"size"`         // The size of the original image in bytes
+	Width        int       `json:"width"`        // The width of the original image in pixels
+	Height       int       `json:"height"`       // The height of the original image in pixels
+	URL          string    `json:"url"`          // The URL the original image is served from
+	ThumbnailURL string    `json:"thumbnailUrl"` // The URL the thumbnail is served from, the original's until generated
+	CreatedAt    time.Time `json:"createdAt"`    // The timestamp when the image was uploaded
+}
+
 // RegisterUserPayload represents the data required to register a new user.
 // This is the structure that the client will send in the request body when registering.
 type RegisterUserPayload struct {
@@ -67,12 +167,81 @@ type LoginUserPayload struct {
 	Password  string `json:"password" validate:"required"`   // The password of the user (to be hashed before storing)
 }
 
+// ChangePasswordPayload represents the data required to change the password of the
+// logged-in user.
+type ChangePasswordPayload struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`  // The password the user logs in with now
+	NewPassword     string `json:"newPassword" validate:"required,min=3,max=130"`  // The password to replace it with
+}
+
+// RequestPasswordResetPayload represents the data required to email a password reset link.
+type RequestPasswordResetPayload struct {
+	Email string `json:"email" validate:"required,email"`  // The email address of the account
+}
+
+// ResetPasswordPayload represents the data required to choose a new password with the
+// token of a reset link.
+type ResetPasswordPayload struct {
+	Token    string `json:"token" validate:"required"`  // The token from the reset link
+	Password string `json:"password" validate:"required,min=3,max=130"`  // The new password
+}
+
 // CreateProductPayload represents the data required to create a new product.
 // This is the structure that the client will send in the request body when creating a new product.
 type CreateProductPayload struct {
+	SKU         string  `json:"sku" validate:"max=64"`
 	Name        string  `json:"name" validate:"required"`
 	Description string  `json:"description"`
+	Category    string  `json:"category" validate:"max=64"`
+	TaxClass    string  `json:"taxClass" validate:"max=32"`
 	Image       string  `json:"image"`
 	Price       float64 `json:"price" validate:"required"`
 	Quantity    int     `json:"quantity" validate:"required"`
-}
\ No newline at end of file
+	Weight      int     `json:"weight" validate:"gte=0"`
+	Length      int     `json:"length" validate:"gte=0"`
+	Width       int     `json:"width" validate:"gte=0"`
+	Height      int     `json:"height" validate:"gte=0"`
+}
+
+// GenerateThumbnailJob generates the thumbnail of an uploaded product image.
+type GenerateThumbnailJob struct {
+	ProductID int `json:"productId"`
+	ImageID   int `json:"imageId"`
+}
+
+func (GenerateThumbnailJob) JobKind() string { return "generate_thumbnail" }
+
+// ReorderProductImagesPayload represents the new order of a product's images.
+type ReorderProductImagesPayload struct {
+	ImageIDs []int `json:"imageIds" validate:"required,min=1"`
+}
+
+// ProductImportRow is one product read from a CSV or NDJSON catalog import.
+type ProductImportRow struct {
+	SKU         string  `json:"sku" validate:"required,max=64"`
+	Name        string  `json:"name" validate:"required,max=255"`
+	Description string  `json:"description"`
+	Category    string  `json:"category" validate:"max=64"`
+	TaxClass    string  `json:"taxClass" validate:"max=32"`
+	Image       string  `json:"image" validate:"max=255"`
+	Price       float64 `json:"price" validate:"gt=0"`
+	Quantity    int     `json:"quantity" validate:"gte=0"`
+}
+
+// ProductImportError lists the problems found in one row of an import.
+// Row is the 1-based line number in the uploaded file.
+type ProductImportError struct {
+	Row    int      `json:"row"`
+	SKU    string   `json:"sku,omitempty"`
+	Errors []string `json:"errors"`
+}
+
+// ProductImportResult is returned by the import endpoint. On a dry run, or when any row is
+// invalid, nothing is written and Created and Updated are zero.
+type ProductImportResult struct {
+	DryRun  bool                 `json:"dryRun"`
+	Rows    int                  `json:"rows"`
+	Created int                  `json:"created"`
+	Updated int                  `json:"updated"`
+	Errors  []ProductImportError `json:"errors"`
+}
@@ -0,0 +1,129 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Business events webhooks can subscribe to.
+const (
+	EventOrderCreated       = "order.created"
+	EventOrderStatusChanged = "order.status_changed"
+	EventOrderRefunded      = "order.refunded"
+	EventProductCreated     = "product.created"
+	EventProductUpdated     = "product.updated"
+)
+
+// WebhookEvents lists every event type, in the order they are documented.
+var WebhookEvents = []string{
+	EventOrderCreated,
+	EventOrderStatusChanged,
+	EventOrderRefunded,
+	EventProductCreated,
+	EventProductUpdated,
+}
+
+// Webhook delivery statuses stored in the webhook_deliveries.status column.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookStore manages webhook subscriptions and the log of their deliveries.
+type WebhookStore interface {
+	// CreateWebhook subscribes a URL to events, signing deliveries with secret.
+	CreateWebhook(payload WebhookPayload, secret string) (int, error)
+
+	GetWebhooks() ([]Webhook, error)
+	GetWebhook(id int) (*Webhook, error)
+
+	// UpdateWebhook replaces the URL, events and description. An empty secret in the
+	// payload keeps the current one.
+	UpdateWebhook(id int, payload WebhookPayload) error
+
+	// DeleteWebhook removes the subscription together with its delivery log.
+	DeleteWebhook(id int) error
+
+	// GetWebhookDeliveries returns a page of the deliveries of a webhook, newest first,
+	// optionally only those in status.
+	GetWebhookDeliveries(webhookID int, status string, limit, offset int) ([]WebhookDelivery, error)
+
+	// GetWebhookDelivery returns a delivery with every attempt made to send it.
+	GetWebhookDelivery(id int) (*WebhookDelivery, error)
+
+	// RedeliverWebhookDelivery schedules one more attempt of a delivery right away.
+	RedeliverWebhookDelivery(id int) error
+
+	// ClaimWebhookDeliveries returns up to limit deliveries that are due, with the URL and
+	// secret of their webhook, and holds them for lease so no other dispatcher sends them.
+	ClaimWebhookDeliveries(limit int, lease time.Duration) ([]WebhookDelivery, error)
+
+	// RecordWebhookAttempt logs an attempt. Failed deliveries are tried again after
+	// retryAfter, or given up if it is 0.
+	RecordWebhookAttempt(deliveryID int, attempt WebhookAttempt, retryAfter time.Duration) error
+}
+
+// Webhook is a subscription of an external system to business events.
+type Webhook struct {
+	ID          int       `json:"id"`               // The unique identifier for the webhook
+	URL         string    `json:"url"`              // Where events are POSTed
+	Events      []string  `json:"events"`           // The event types sent to the URL
+	Description string    `json:"description"`      // What the subscriber is, for staff
+	Active      bool      `json:"active"`           // Inactive webhooks get no new deliveries
+	Secret      string    `json:"secret,omitempty"` // The signing secret, only returned when it is set
+	CreatedAt   time.Time `json:"createdAt"`        // The timestamp when the webhook was created
+	UpdatedAt   time.Time `json:"updatedAt"`        // The timestamp when the webhook was last changed
+}
+
+type WebhookPayload struct {
+	URL         string   `json:"url" validate:"required,http_url,max=2048"`
+	Events      []string `json:"events" validate:"required,min=1,dive,oneof=order.created order.status_changed order.refunded product.created product.updated"`
+	Description string   `json:"description" validate:"max=255"`
+	Active      *bool    `json:"active"`                                     // Defaults to true
+	Secret      string   `json:"secret" validate:"omitempty,min=16,max=128"` // Generated when left out on creation
+}
+
+// WebhookEvent is the JSON body POSTed to webhooks.
+type WebhookEvent struct {
+	ID        string    `json:"id"`        // Unique per event and the same for every webhook, for deduplication
+	Type      string    `json:"type"`      // One of the Event* types
+	CreatedAt time.Time `json:"createdAt"` // When the event happened
+	Data      any       `json:"data"`      // The order, refund or product the event is about
+}
+
+// OrderEvent is the data of order events: the order as it is after the event.
+type OrderEvent struct {
+	Order
+	PreviousStatus string `json:"previousStatus,omitempty"` // The status before a status change
+}
+
+// WebhookDelivery is one event to be sent to one webhook.
+type WebhookDelivery struct {
+	ID               int              `json:"id"`                // The unique identifier for the delivery
+	WebhookID        int              `json:"webhookId"`         // The webhook the event is sent to
+	EventID          string           `json:"eventId"`           // The ID of the event in the payload
+	Event            string           `json:"event"`             // The event type
+	Payload          json.RawMessage  `json:"payload"`           // The exact body that is sent
+	Status           string           `json:"status"`            // One of the WebhookDelivery* statuses
+	Attempts         int              `json:"attempts"`          // How many times sending was tried
+	NextAttemptAt    *time.Time       `json:"nextAttemptAt"`     // When it is tried next, nil once succeeded or failed
+	LastResponseCode *int             `json:"lastResponseCode"`  // The HTTP status of the last attempt, nil if no response
+	History          []WebhookAttempt `json:"history,omitempty"` // Every attempt, oldest first, only when reading one delivery
+	CreatedAt        time.Time        `json:"createdAt"`         // The timestamp when the event happened
+	DeliveredAt      *time.Time       `json:"deliveredAt"`       // The timestamp of the successful attempt
+	URL              string           `json:"-"`                 // The URL of the webhook, set when claimed
+	Secret           string           `json:"-"`                 // The secret of the webhook, set when claimed
+}
+
+// WebhookAttempt is the outcome of one try to send a delivery.
+type WebhookAttempt struct {
+	ID           int       `json:"id"`           // The unique identifier for the attempt
+	DeliveryID   int       `json:"deliveryId"`   // The delivery that was tried
+	Succeeded    bool      `json:"succeeded"`    // Whether the webhook answered with a 2xx status
+	ResponseCode *int      `json:"responseCode"` // The HTTP status, nil if the request failed
+	ResponseBody string    `json:"responseBody"` // The start of the response body
+	Error        string    `json:"error"`        // Why the request failed, empty if it got a response
+	DurationMs   int       `json:"durationMs"`   // How long the request took
+	CreatedAt    time.Time `json:"createdAt"`    // The timestamp of the attempt
+}
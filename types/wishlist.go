@@ -0,0 +1,83 @@
+package types
+
+import "time"
+
+// WishlistStore defines the methods required to manage the wishlists of customers.
+// Every customer has exactly one default wishlist, created the first time their lists are read.
+type WishlistStore interface {
+	// GetWishlists returns the wishlists of a user, default first, creating the default
+	// wishlist if the user has none yet.
+	GetWishlists(userID int) ([]Wishlist, error)
+
+	// GetWishlist returns a wishlist without its items.
+	GetWishlist(id int) (*Wishlist, error)
+
+	// GetWishlistByShareToken returns the wishlist shared under token.
+	GetWishlistByShareToken(token string) (*Wishlist, error)
+
+	// CreateWishlist stores a new, non-default wishlist and returns its ID.
+	CreateWishlist(userID int, name string) (int, error)
+
+	// UpdateWishlist renames a wishlist and, when makeDefault is set, makes it the
+	// owner's default wishlist in place of the current one.
+	UpdateWishlist(id int, name string, makeDefault bool) error
+
+	// DeleteWishlist removes a wishlist and its items. The default wishlist cannot be deleted.
+	DeleteWishlist(id int) error
+
+	// SetShareToken shares a wishlist under token, or stops sharing it when token is empty.
+	SetShareToken(id int, token string) error
+
+	// GetWishlistItems returns the items of a wishlist with the current price and stock
+	// of each product, most recently added first.
+	GetWishlistItems(wishlistID int) ([]WishlistItem, error)
+
+	// AddWishlistItem saves a product with its current price. Adding a product that is
+	// already on the list keeps the price it was first added at.
+	AddWishlistItem(wishlistID, productID int) error
+
+	// RemoveWishlistItem removes a product from a wishlist.
+	RemoveWishlistItem(wishlistID, productID int) error
+}
+
+// Wishlist is a named list of products a customer saved for later.
+type Wishlist struct {
+	ID         int            `json:"id"`                   // The unique identifier for the wishlist
+	UserID     int            `json:"userId"`               // The customer who owns the wishlist
+	Name       string         `json:"name"`                 // The name chosen by the customer
+	IsDefault  bool           `json:"isDefault"`            // Whether products are saved here when no list is chosen
+	ShareToken string         `json:"shareToken,omitempty"` // The unguessable token of the public link, empty when not shared
+	ShareURL   string         `json:"shareUrl,omitempty"`   // The public link built from ShareToken
+	Items      []WishlistItem `json:"items,omitempty"`      // The saved products, only set when a single wishlist is returned
+	CreatedAt  time.Time      `json:"createdAt"`            // The timestamp when the wishlist was created
+}
+
+// WishlistItem is a product saved on a wishlist together with its current price and stock.
+type WishlistItem struct {
+	ProductID      int       `json:"productId"`      // The saved product
+	Name           string    `json:"name"`           // The current name of the product
+	Image          string    `json:"image"`          // The URL of the main image of the product
+	Price          float64   `json:"price"`          // The current price of the product
+	PriceWhenAdded float64   `json:"priceWhenAdded"` // The price of the product when it was added
+	PriceDrop      float64   `json:"priceDrop"`      // How much cheaper the product is now, 0 if it is not
+	PriceDropped   bool      `json:"priceDropped"`   // Whether the product is cheaper than when it was added
+	Available      int       `json:"available"`      // Units that can still be bought
+	InStock        bool      `json:"inStock"`        // Whether at least one unit can be bought
+	AddedAt        time.Time `json:"addedAt"`        // The timestamp when the product was added
+}
+
+// CreateWishlistPayload represents the data required to create a wishlist.
+type CreateWishlistPayload struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// UpdateWishlistPayload represents the data required to rename a wishlist or make it the default.
+type UpdateWishlistPayload struct {
+	Name      string `json:"name" validate:"required,max=100"`
+	IsDefault bool   `json:"isDefault"`
+}
+
+// AddWishlistItemPayload represents the product to save on a wishlist.
+type AddWishlistItemPayload struct {
+	ProductID int `json:"productId" validate:"required,gt=0"`
+}
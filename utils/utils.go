@@ -3,7 +3,9 @@ package utils
 import (
 	"encoding/json" // For encoding and decoding JSON data
 	"fmt"           // For formatted I/O operations
+	"math"          // For rounding amounts to whole cents
 	"net/http"      // For HTTP request and response handling
+	"strconv"       // For parsing query parameters
 
 	"github.com/go-playground/validator/v10" // For data validation
 )
@@ -43,4 +45,68 @@ func WriteError(w http.ResponseWriter, status int, err error) error {
 	// Step 1: Create a map containing the error message in a key-value pair
 	// Step 2: Call WriteJSON to send the error as a JSON response
 	return WriteJSON(w, status, map[string]string{"error": err.Error()})
-}
\ No newline at end of file
+}
+
+// Pagination limits applied by ParsePagination.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Pagination describes which page of a list the client asked for.
+type Pagination struct {
+	Page   int // 1-based page number
+	Limit  int // Number of items per page
+	Offset int // Number of items to skip, derived from Page and Limit
+}
+
+// ParsePagination reads the `page` and `limit` query parameters.
+// Missing values fall back to the first page of DefaultPageSize items,
+// and limit is capped at MaxPageSize.
+func ParsePagination(r *http.Request) (Pagination, error) {
+	p := Pagination{Page: 1, Limit: DefaultPageSize}
+
+	// Step 1: Parse the page number, if any
+	if v := r.URL.Query().Get("page"); v != "" {
+		page, err := strconv.Atoi(v)
+		if err != nil || page < 1 {
+			return p, fmt.Errorf("invalid page %q", v)
+		}
+		p.Page = page
+	}
+
+	// Step 2: Parse the page size, if any
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return p, fmt.Errorf("invalid limit %q", v)
+		}
+		p.Limit = min(limit, MaxPageSize)
+	}
+
+	// Step 3: Derive the offset used in SQL queries
+	p.Offset = (p.Page - 1) * p.Limit
+	return p, nil
+}
+
+// ToCents converts an amount with at most two decimals, such as a price read from a
+// DECIMAL(10, 2) column, to a whole number of cents. Money is added up in cents so
+// totals never drift.
+func ToCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromCents converts a number of cents back to an amount.
+func FromCents(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// FormatCents renders cents as a decimal string, e.g. "19.99", so DECIMAL columns and
+// payment gateways receive the exact value.
+func FormatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    Pagination
+		wantErr bool
+	}{
+		{query: "", want: Pagination{Page: 1, Limit: DefaultPageSize, Offset: 0}},
+		{query: "?page=3&limit=10", want: Pagination{Page: 3, Limit: 10, Offset: 20}},
+		{query: "?limit=1000", want: Pagination{Page: 1, Limit: MaxPageSize, Offset: 0}},
+		{query: "?page=0", wantErr: true},
+		{query: "?limit=abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePagination(httptest.NewRequest("GET", "/items"+tt.query, nil))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.query)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%q: expected %+v but got %+v (%v)", tt.query, tt.want, got, err)
+		}
+	}
+}
+
+func TestCents(t *testing.T) {
+	tests := []struct {
+		amount float64
+		cents  int64
+		text   string
+	}{
+		{amount: 19.99, cents: 1999, text: "19.99"},
+		{amount: 0.1 + 0.2, cents: 30, text: "0.30"},
+		{amount: 1.1 * 3, cents: 330, text: "3.30"},
+		{amount: -4.5, cents: -450, text: "-4.50"},
+	}
+
+	for _, tt := range tests {
+		if got := ToCents(tt.amount); got != tt.cents {
+			t.Errorf("ToCents(%v): expected %d but got %d", tt.amount, tt.cents, got)
+		}
+		if got := FormatCents(tt.cents); got != tt.text {
+			t.Errorf("FormatCents(%d): expected %q but got %q", tt.cents, tt.text, got)
+		}
+	}
+}